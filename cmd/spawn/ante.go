@@ -0,0 +1,146 @@
+package main
+
+import (
+	"os"
+	"path"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rollchains/spawn/spawn"
+)
+
+const (
+	FlagFilterAllow       = "allow"
+	FlagFilterUntilHeight = "until-height"
+
+	FlagDecoratorPost   = "post"
+	FlagDecoratorAfter  = "after"
+	FlagDecoratorBefore = "before"
+)
+
+// ---
+// spawn ante filter add /cosmos.bank.v1beta1.MsgSend --until-height 100000
+// spawn ante new fee-burn --after NewDeductFeeDecorator
+// ---
+func AnteCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ante",
+		Short: "Manage the ante handler decorators of app/ante.go",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := cmd.Help(); err != nil {
+				GetLogger().Error("Error showing help", "err", err)
+			}
+		},
+	}
+
+	cmd.AddCommand(anteNewCmd())
+	cmd.AddCommand(anteFilterCmd())
+
+	return cmd
+}
+
+func anteNewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "new [name]",
+		Short: "Create an ante or post handler decorator",
+		Long: `Create a decorator skeleton & its test in app/decorators and register it in the decorators of NewAnteHandler in
+app/ante.go, at the end of the chain or --after / --before the first decorator containing the text. With --post a post
+handler decorator, run after the messages of the transaction, is registered in the setPostHandler of app/app.go instead.`,
+		Example: `  - spawn ante new fee-burn --after NewDeductFeeDecorator
+  - spawn ante new min-commission --before NewIncrementSequenceDecorator
+  - spawn ante new refund-gas --post`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			logger := GetLogger()
+
+			cwd, err := os.Getwd()
+			if err != nil {
+				logger.Error("Error getting current working directory", "err", err)
+				return
+			}
+
+			if _, err := os.Stat(path.Join(cwd, "app", "decorators")); err != nil {
+				logger.Error("app/decorators not found, run this command from the root of the chain", "err", err)
+				return
+			}
+
+			post, _ := cmd.Flags().GetBool(FlagDecoratorPost)
+			after, _ := cmd.Flags().GetString(FlagDecoratorAfter)
+			before, _ := cmd.Flags().GetString(FlagDecoratorBefore)
+
+			d := spawn.AnteDecorator{Name: args[0], Post: post, After: after, Before: before}
+			if err := spawn.NewAnteDecorator(logger, cwd, d); err != nil {
+				logger.Error("Error creating the decorator", "name", d.Name, "err", err)
+				return
+			}
+
+			logger.Info("Decorator created", "type", d.TypeName(), "path", path.Join("app", "decorators", d.FileName()+".go"))
+		},
+	}
+
+	cmd.Flags().Bool(FlagDecoratorPost, false, "create a post handler decorator, run after the messages")
+	cmd.Flags().String(FlagDecoratorAfter, "", "add the decorator after the first decorator of the chain containing this text")
+	cmd.Flags().String(FlagDecoratorBefore, "", "add the decorator before the first decorator of the chain containing this text")
+
+	return cmd
+}
+
+func anteFilterCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "filter",
+		Short: "Manage the message filter decorators of the ante handler",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := cmd.Help(); err != nil {
+				GetLogger().Error("Error showing help", "err", err)
+			}
+		},
+	}
+
+	cmd.AddCommand(anteFilterAddCmd())
+
+	return cmd
+}
+
+func anteFilterAddCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "add [msg-type-url]...",
+		Short: "Filter messages in the ante handler",
+		Long: `Add the messages to the decorators.MsgFilterDecorator of app/ante.go, which is inserted after the SetUpContextDecorator
+when the chain has none. The filter rejects the transactions with a listed message, or with --allow every message that is
+not listed. Messages nested in an authz MsgExec are filtered too, with --allow the MsgExec itself needs no entry. With --until-height the filter is disabled from that
+block height, so a chain can launch with transfers disabled and enable them later.`,
+		Example: `  - spawn ante filter add /cosmos.bank.v1beta1.MsgSend /cosmos.bank.v1beta1.MsgMultiSend --until-height 100000
+  - spawn ante filter add /cosmos.staking.v1beta1.MsgCreateValidator /cosmos.gov.v1.MsgVote --allow`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			logger := GetLogger()
+
+			cwd, err := os.Getwd()
+			if err != nil {
+				logger.Error("Error getting current working directory", "err", err)
+				return
+			}
+
+			if _, err := os.Stat(path.Join(cwd, "app", "decorators")); err != nil {
+				logger.Error("app/decorators not found, run this command from the root of the chain", "err", err)
+				return
+			}
+
+			allow, _ := cmd.Flags().GetBool(FlagFilterAllow)
+			untilHeight, _ := cmd.Flags().GetInt64(FlagFilterUntilHeight)
+
+			filter := spawn.MsgFilter{MsgTypeURLs: args, AllowList: allow, UntilHeight: untilHeight}
+			if err := spawn.AddAnteMsgFilter(cwd, filter); err != nil {
+				logger.Error("Error adding the message filter", "err", err)
+				return
+			}
+
+			logger.Info("Message filter added to app/ante.go", "mode", filter.Mode(), "msgs", args, "until-height", untilHeight)
+		},
+	}
+
+	cmd.Flags().Bool(FlagFilterAllow, false, "only allow the listed messages instead of blocking them")
+	cmd.Flags().Int64(FlagFilterUntilHeight, 0, "disable the filter from this block height (0 keeps it enabled)")
+
+	return cmd
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/rollchains/spawn/spawn"
+)
+
+// ---
+// spawn explorer config
+// ---
+func ExplorerCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "explorer",
+		Short:   "Manage the ping.pub block explorer of the chain",
+		Aliases: []string{"pingpub"},
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := cmd.Help(); err != nil {
+				GetLogger().Error("Error showing help", "err", err)
+			}
+		},
+	}
+
+	cmd.AddCommand(explorerConfigCmd())
+
+	return cmd
+}
+
+func explorerConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config [chain-dir]",
+		Short: "Regenerate the ping.pub chain config from the chain registry files",
+		Long:  "Regenerate explorer/chains/mainnet/<chain>.json from " + spawn.ChainRegistryFileName + " & " + spawn.ChainRegistryAssetsFileName + ". Run the explorer with `make explorer`.",
+		Example: `  - spawn explorer config
+  - spawn explorer config ./mychain`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			logger := GetLogger()
+
+			dir, err := registryHomeDir(args)
+			if err != nil {
+				logger.Error("Error getting current working directory", "err", err)
+				return
+			}
+
+			loc, err := spawn.WriteExplorerConfig(dir)
+			if err != nil {
+				logger.Error("Error writing the explorer config", "err", err)
+				return
+			}
+
+			logger.Info("Explorer config written", "file", loc)
+		},
+	}
+
+	return cmd
+}
@@ -34,6 +34,11 @@ var LocalICCmd = &cobra.Command{
 		logger := GetLogger()
 
 		loc := spawn.WhereIsBinInstalled("local-ic")
+		if loc == "" {
+			logger.Error(spawn.LocalICInstallMsg)
+			return
+		}
+
 		if debugBinaryLoc {
 			logger.Debug("local-ic binary", "location", loc)
 			return
@@ -41,6 +41,10 @@ func main() {
 
 	rootCmd.AddCommand(newChain)
 	rootCmd.AddCommand(LocalICCmd)
+	rootCmd.AddCommand(TestnetCmd())
+	rootCmd.AddCommand(RegistryCmd())
+	rootCmd.AddCommand(MetadataCmd())
+	rootCmd.AddCommand(ExplorerCmd())
 	rootCmd.AddCommand(&cobra.Command{
 		Use:   "version",
 		Short: "Print the version number of spawn",
@@ -49,6 +53,8 @@ func main() {
 		},
 	})
 	rootCmd.AddCommand(ModuleCmd())
+	rootCmd.AddCommand(UpgradeHandlerCmd())
+	rootCmd.AddCommand(AnteCmd())
 	rootCmd.AddCommand(ProtoServiceGenerate())
 	rootCmd.AddCommand(DocsCmd)
 
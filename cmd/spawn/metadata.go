@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rollchains/spawn/spawn"
+)
+
+// ---
+// spawn metadata set name="My Chain" links.website=https://mychain.xyz logo=./logo.png
+// ---
+func MetadataCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "metadata",
+		Short:   "Update the chain metadata, registry & explorer files together",
+		Aliases: []string{"meta", "md"},
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := cmd.Help(); err != nil {
+				GetLogger().Error("Error showing help", "err", err)
+			}
+		},
+	}
+
+	cmd.AddCommand(metadataSetCmd())
+
+	return cmd
+}
+
+func metadataSetCmd() *cobra.Command {
+	var keys strings.Builder
+	for _, k := range spawn.MetadataKeys() {
+		fmt.Fprintf(&keys, "\n  %-18s %s", k.Name, k.Description)
+	}
+
+	cmd := &cobra.Command{
+		Use:   "set [key=value]...",
+		Short: "Set chain metadata values",
+		Long: fmt.Sprintf("Set values in %s, %s, %s and the explorer config, keeping them consistent.\nKeys:%s",
+			spawn.ChainMetadataFileName, spawn.ChainRegistryFileName, spawn.ChainRegistryAssetsFileName, keys.String()),
+		Example: `  - spawn metadata set links.website=https://mychain.xyz links.twitter=https://x.com/mychain
+  - spawn metadata set name="My Chain" logo=./logo.png theme_color=#1A2B3C
+  - spawn metadata set network_type=mainnet chain_id=mychain-1`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			logger := GetLogger()
+
+			cwd, err := os.Getwd()
+			if err != nil {
+				logger.Error("Error getting current working directory", "err", err)
+				return
+			}
+
+			values := make(map[string]string, len(args))
+			for _, arg := range args {
+				k, v, ok := strings.Cut(arg, "=")
+				if !ok {
+					logger.Error("Error parsing argument, expected key=value", "arg", arg)
+					return
+				}
+
+				// local files are relative to where the command is run
+				if (k == "logo" || k == "links.logo") && !path.IsAbs(v) {
+					v = path.Join(cwd, v)
+				}
+
+				values[k] = v
+			}
+
+			if err := spawn.SetChainMetadata(cwd, values); err != nil {
+				logger.Error("Error setting chain metadata", "err", err)
+				return
+			}
+
+			logger.Info("Chain metadata updated", "keys", len(values))
+		},
+	}
+
+	return cmd
+}
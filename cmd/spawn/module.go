@@ -6,9 +6,9 @@ import (
 	"log/slog"
 	"os"
 	"path"
+	"slices"
 	"strings"
 
-	"github.com/rollchains/spawn/simapp"
 	"github.com/rollchains/spawn/spawn"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -20,11 +20,29 @@ import (
 const (
 	FlagIsIBCMiddleware = "ibc-middleware"
 	FlagIsIBCModule     = "ibc-module"
+	FlagModuleTemplate  = "template"
+	FlagTemplateDir     = "template-dir"
+	FlagModuleDeps      = "deps"
 )
 
 type features struct {
 	ibcMiddleware bool
 	ibcModule     bool
+
+	// template is the module generated, the IBC flags select their own template.
+	template spawn.ModuleTemplate
+	// templateFS is a custom module template replacing the embedded x/example & proto/example, see --template-dir.
+	templateFS fs.FS
+	// templateGoMod is the go module path of the templateFS imports.
+	templateGoMod string
+}
+
+// replaceGoModule replaces the go module path of the template imports with the one of the chain.
+func (f features) replaceGoModule(fc *spawn.FileContent, goModName string) {
+	if f.templateGoMod != "" && f.templateGoMod != spawn.SimappGoModule {
+		fc.ReplaceAll(f.templateGoMod, goModName)
+	}
+	fc.ReplaceAll(spawn.SimappGoModule, goModName)
 }
 
 func (f features) validate() error {
@@ -35,7 +53,57 @@ func (f features) validate() error {
 	return nil
 }
 
+// setTemplate sets the module template by name. The IBC flags are the ibcmiddleware & ibcmodule templates.
+func (f *features) setTemplate(name string) error {
+	flagTemplate := ""
+	if f.ibcMiddleware {
+		flagTemplate = "ibcmiddleware"
+	} else if f.ibcModule {
+		flagTemplate = "ibcmodule"
+	}
+
+	if name != "" && flagTemplate != "" {
+		return fmt.Errorf("cannot set both --%s and the IBC flags", FlagModuleTemplate)
+	} else if name == "" {
+		name = flagTemplate
+	}
+
+	template, err := spawn.GetModuleTemplate(name)
+	if err != nil {
+		return err
+	}
+
+	f.template = template
+	f.ibcMiddleware = template.Base == "ibcmiddleware"
+	f.ibcModule = template.Base == "ibcmodule"
+
+	return nil
+}
+
+// addDeps makes the module keeper depend on the keepers of other modules, see spawn.KeeperDeps.
+func (f *features) addDeps(names []string) error {
+	if f.ibcMiddleware {
+		return fmt.Errorf("--%s is not supported by IBC middlewares", FlagModuleDeps)
+	}
+
+	deps, err := spawn.GetKeeperDeps(append(slices.Clone(f.template.Deps), names...))
+	if err != nil {
+		return err
+	}
+
+	f.template.Deps = make([]string, len(deps))
+	for i, d := range deps {
+		f.template.Deps[i] = d.Name
+	}
+	return nil
+}
+
+// getModuleType returns the embedded module the template is generated from.
 func (f features) getModuleType() string {
+	if f.template.Base != "" {
+		return f.template.Base
+	}
+
 	if f.ibcMiddleware {
 		return "ibcmiddleware"
 	} else if f.ibcModule {
@@ -68,6 +136,10 @@ func ModuleCmd() *cobra.Command {
 
 	cmd.AddCommand(
 		NewCmd(),
+		ModuleGenCLICmd(),
+		ModuleStateCmd(),
+		ModuleMsgCmd(),
+		ModuleQueryCmd(),
 		// TODO: remove, import/add from upstream -> app.go
 	)
 
@@ -76,9 +148,18 @@ func ModuleCmd() *cobra.Command {
 
 func NewCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:     "new [name]",
-		Short:   "Create a new module scaffolding",
-		Example: `spawn module new mymodule [--ibc-middleware]`,
+		Use:   "new [name]",
+		Short: "Create a new module scaffolding",
+		Long: "Create a new module scaffolding from a template:\n" + moduleTemplatesHelp() + `
+Or from your own module with --template-dir, a directory or git repository with an x/example module (& proto/example).
+'example' is replaced by the new module name, the module path of its go.mod by the one of the chain, and the keeper is wired into app.go as the standard one:
+NewKeeper(appCodec, storeService, logger, authority).
+Chains generated with --wiring=depinject register the module config into app/app_config.go instead (IBC modules are not supported).`,
+		Example: `  - spawn module new mymodule [--ibc-middleware]
+  - spawn module new mymodule --template escrow
+  - spawn module new mymodule --deps bank,staking,account
+  - spawn module new mymodule --template-dir ./templates/mymodule
+  - spawn module new mymodule --template-dir https://github.com/myorg/module-template.git`,
 		Args:    cobra.ExactArgs(1),
 		Aliases: []string{"c", "create"},
 		Run: func(cmd *cobra.Command, args []string) {
@@ -133,6 +214,51 @@ func NewCmd() *cobra.Command {
 				return
 			}
 
+			templateName, _ := cmd.Flags().GetString(FlagModuleTemplate)
+			if err := feats.setTemplate(templateName); err != nil {
+				logger.Error("Error selecting the module template", "err", err)
+				return
+			}
+
+			// IBC modules have no depinject support (capability scopes & IBC router)
+			depinjectApp := spawn.IsDepinjectApp(cwd)
+			if depinjectApp && feats.isIBC() {
+				logger.Error("IBC modules can not be added to a depinject wired app", "template", feats.template.Name)
+				return
+			}
+
+			// keepers of other modules the module keeper depends on, on top of the template ones
+			if deps, _ := cmd.Flags().GetStringSlice(FlagModuleDeps); len(deps) > 0 {
+				if err := feats.addDeps(deps); err != nil {
+					logger.Error("Error adding the module keeper dependencies", "err", err)
+					return
+				}
+			}
+
+			// custom module template, a directory or git repository with an x/example module
+			if templateDir, _ := cmd.Flags().GetString(FlagTemplateDir); templateDir != "" {
+				if feats.template.Name != spawn.ModuleTemplatePlaceholder {
+					logger.Error("Cannot set both --template-dir and a module template or IBC flag", "template", feats.template.Name)
+					return
+				}
+
+				templateFS, cleanup, err := spawn.LoadModuleTemplateDir(templateDir)
+				if err != nil {
+					logger.Error("Error loading the module template directory", "err", err)
+					return
+				}
+				defer cleanup()
+
+				templateGoMod, err := spawn.ModuleTemplateGoModule(templateFS)
+				if err != nil {
+					logger.Error("Error reading the module template go.mod", "err", err)
+					return
+				}
+
+				feats.templateFS = templateFS
+				feats.templateGoMod = templateGoMod
+			}
+
 			// Setup Proto files to match the new x/ cosmos module name & go.mod module namespace (i.e. github org).
 			if err := SetupModuleProtoBase(GetLogger(), extName, feats); err != nil {
 				logger.Error("Error setting up proto for module", "err", err)
@@ -145,31 +271,76 @@ func NewCmd() *cobra.Command {
 				return
 			}
 
-			// Import the files to app.go
-			if err := AddModuleToAppGo(GetLogger(), extName, feats); err != nil {
+			// adds the files, keeper dependencies & RPCs of the template on top of its base module
+			if err := SetupModuleTemplateFiles(GetLogger(), extName, feats); err != nil {
+				logger.Error("Error setting up the module template files", "template", feats.template.Name, "err", err)
+				return
+			}
+			if err := spawn.ApplyModuleTemplate(GetLogger(), cwd, extName, feats.template); err != nil {
+				logger.Error("Error applying the module template", "template", feats.template.Name, "err", err)
+				return
+			}
+
+			// Import the files to app.go, or register the module config of a depinject app
+			if depinjectApp {
+				if err := spawn.AddModuleToAppConfig(cwd, extName, feats.template.ModuleAccount, feats.template.SendRestriction); err != nil {
+					logger.Error("Error adding new x/ module to app_config.go", "err", err)
+					return
+				}
+			} else if err := AddModuleToAppGo(GetLogger(), extName, feats); err != nil {
 				logger.Error("Error adding new x/ module to app.go", "err", err)
 				return
 			}
 
+			// e2e test of the module params, or of the packets relayed by the IBC templates
+			var ictestErr error
+			switch {
+			case !feats.isIBC():
+				ictestErr = spawn.AddModuleInterchainTest(GetLogger(), cwd, extName)
+			case feats.template.Name == "ibcmodule" || feats.template.Name == "ibcmiddleware":
+				ictestErr = spawn.AddIBCInterchainTest(GetLogger(), cwd, extName, feats.ibcMiddleware)
+			default:
+				ictestErr = fmt.Errorf("no interchaintest for the %s template", feats.template.Name)
+			}
+			if ictestErr != nil {
+				logger.Warn("Skipping the module interchaintest", "err", ictestErr)
+			}
+
 			// Announce the new module & how to code gen the proto files.
 			fmt.Printf("\n🎉 New Module '%s' generated!\n", extName)
 			fmt.Println("🏅 Commands:")
 			fmt.Println("  - $ make proto-gen     # convert proto files into code")
+			if ictestErr == nil {
+				fmt.Printf("  - $ make ictest-%s   # run the module e2e test (after make local-image)\n", extName)
+			}
 		},
 	}
 
 	cmd.Flags().Bool(FlagIsIBCMiddleware, false, "Set the module as an IBC Middleware")
 	cmd.Flags().Bool(FlagIsIBCModule, false, "Set the module as an IBC Module")
+	cmd.Flags().String(FlagModuleTemplate, "", fmt.Sprintf("module template to generate (%s)", strings.Join(spawn.ModuleTemplateNames(), ", ")))
+	cmd.Flags().StringSlice(FlagModuleDeps, []string{}, fmt.Sprintf("keepers the module keeper depends on (%s)", strings.Join(spawn.KeeperDepNames(), ",")))
+	cmd.Flags().String(FlagTemplateDir, "", "directory or git URL of a custom module template with an x/example module (& proto/example)")
 	cmd.Flags().SetNormalizeFunc(normalizeModuleFlags)
 
 	return cmd
 }
 
+// moduleVersionProfile returns the version profile templates matching the chain's Cosmos SDK version.
+// Chains on a stack spawn has no templates for fallback to the default profile.
+func moduleVersionProfile(logger *slog.Logger, cwd string) spawn.VersionProfile {
+	profile, err := spawn.VersionProfileFromGoMod(path.Join(cwd, "go.mod"))
+	if err != nil {
+		logger.Warn("Using default version profile templates", "profile", spawn.DefaultVersionProfile, "reason", err)
+		profile, _ = spawn.GetVersionProfile(spawn.DefaultVersionProfile)
+	}
+
+	return profile
+}
+
 // SetupModuleProtoBase iterates through the proto embedded fs and replaces the paths and goMod names to match
 // the new desired module.
 func SetupModuleProtoBase(logger *slog.Logger, extName string, feats *features) error {
-	protoFS := simapp.ProtoModuleFS
-
 	if err := os.MkdirAll("proto", 0755); err != nil {
 		panic(err)
 	}
@@ -180,6 +351,11 @@ func SetupModuleProtoBase(logger *slog.Logger, extName string, feats *features)
 		return err
 	}
 
+	var protoFS fs.FS = moduleVersionProfile(logger, cwd).ProtoModuleFS
+	if feats.templateFS != nil {
+		protoFS = feats.templateFS
+	}
+
 	goModName := spawn.ReadCurrentGoModuleName(path.Join(cwd, "go.mod"))
 	protoNamespace := convertGoModuleNameToProtoNamespace(goModName)
 
@@ -188,6 +364,12 @@ func SetupModuleProtoBase(logger *slog.Logger, extName string, feats *features)
 	logger.Debug("proto namespace", "goModName", goModName, "protoNamespace", protoNamespace, "moduleName", moduleName)
 
 	return fs.WalkDir(protoFS, ".", func(relPath string, d fs.DirEntry, e error) error {
+		if e != nil {
+			return e
+		} else if !inModuleRoot(relPath, "proto") {
+			return skipPath(d)
+		}
+
 		newPath := path.Join(cwd, relPath)
 		fc, err := spawn.GetFileContent(logger, newPath, protoFS, relPath, d)
 		if err != nil {
@@ -219,7 +401,7 @@ func SetupModuleProtoBase(logger *slog.Logger, extName string, feats *features)
 			fc.NewPath = strings.ReplaceAll(fc.NewPath, exampleProtoPath, newBinPath)
 		}
 
-		fc.ReplaceAll("github.com/rollchains/spawn/simapp", goModName)
+		feats.replaceGoModule(fc, goModName)
 
 		// replace example -> the new x/ name
 		fc.ReplaceAll(moduleName, extName)
@@ -231,8 +413,6 @@ func SetupModuleProtoBase(logger *slog.Logger, extName string, feats *features)
 // SetupModuleExtensionFiles iterates through the x/example embedded fs and replaces the paths and goMod names to match
 // the new desired module.
 func SetupModuleExtensionFiles(logger *slog.Logger, extName string, feats *features) error {
-	extFS := simapp.ExtensionFS
-
 	if err := os.MkdirAll(path.Join("x", extName), 0755); err != nil {
 		panic(err)
 	}
@@ -243,11 +423,22 @@ func SetupModuleExtensionFiles(logger *slog.Logger, extName string, feats *featu
 		return err
 	}
 
+	var extFS fs.FS = moduleVersionProfile(logger, cwd).ExtensionFS
+	if feats.templateFS != nil {
+		extFS = feats.templateFS
+	}
+
 	moduleName := feats.getModuleType()
 	goModName := spawn.ReadCurrentGoModuleName(path.Join(cwd, "go.mod"))
 
 	// copy x/example to x/extName
 	return fs.WalkDir(extFS, ".", func(relPath string, d fs.DirEntry, e error) error {
+		if e != nil {
+			return e
+		} else if !inModuleRoot(relPath, "x") {
+			return skipPath(d)
+		}
+
 		newPath := path.Join(cwd, relPath)
 		fc, err := spawn.GetFileContent(logger, newPath, extFS, relPath, d)
 		if err != nil {
@@ -281,15 +472,81 @@ func SetupModuleExtensionFiles(logger *slog.Logger, extName string, feats *featu
 			fc.NewPath = strings.ReplaceAll(fc.NewPath, examplePath, newBinPath)
 		}
 
-		fc.ReplaceAll("github.com/rollchains/spawn/simapp", goModName)
+		feats.replaceGoModule(fc, goModName)
 		fc.ReplaceAll(fmt.Sprintf("x/%s", moduleName), fmt.Sprintf("x/%s", extName))
 		fc.ReplaceAll(fmt.Sprintf("package %s", moduleName), fmt.Sprintf("package %s", extName))
 		fc.ReplaceAll(moduleName, extName)
 
+		fc.RemoveTaggedLines("", false)
+
 		return fc.Save()
 	})
 }
 
+// inModuleRoot returns true for the paths of a module FS in its root directory (x or proto). Custom template
+// directories can hold other files, like a README or their .git.
+func inModuleRoot(relPath string, root string) bool {
+	return relPath == "." || relPath == root || strings.HasPrefix(relPath, root+"/")
+}
+
+// skipPath skips a file or directory of fs.WalkDir.
+func skipPath(d fs.DirEntry) error {
+	if d.IsDir() {
+		return fs.SkipDir
+	}
+	return nil
+}
+
+// SetupModuleTemplateFiles adds the files of the template on top of its base module, with the paths and goMod names
+// replaced to match the new desired module.
+func SetupModuleTemplateFiles(logger *slog.Logger, extName string, feats *features) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		fmt.Println("Error getting current working directory", err)
+		return err
+	}
+
+	files, err := feats.template.Files(moduleVersionProfile(logger, cwd).ModuleTemplateFS)
+	if err != nil {
+		return err
+	}
+
+	moduleName := feats.getModuleType()
+	goModName := spawn.ReadCurrentGoModuleName(path.Join(cwd, "go.mod"))
+
+	for relPath, bz := range files {
+		newPath := path.Join(cwd, relPath)
+		for _, dir := range []string{"x", "proto"} {
+			newPath = strings.Replace(newPath, path.Join(cwd, dir, moduleName), path.Join(cwd, dir, extName), 1)
+		}
+
+		fc := spawn.NewFileContent(logger, relPath, newPath)
+		fc.Contents = string(bz)
+
+		logger.Debug("template file", "template", feats.template.Name, "path", fc.NewPath)
+
+		feats.replaceGoModule(fc, goModName)
+		fc.ReplaceAll(moduleName, extName)
+
+		fc.RemoveTaggedLines("", false)
+
+		if err := fc.Save(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// moduleTemplatesHelp lists the module templates with their description.
+func moduleTemplatesHelp() string {
+	var b strings.Builder
+	for _, t := range spawn.ModuleTemplates {
+		fmt.Fprintf(&b, "  - %s: %s\n", t.Name, t.Description)
+	}
+	return b.String()
+}
+
 // AddModuleToAppGo adds the new module to the app.go file.
 func AddModuleToAppGo(logger *slog.Logger, extName string, feats *features) error {
 	extNameTitle := textcases.Title(lang.AmericanEnglish).String(extName)
@@ -341,6 +598,17 @@ func AddModuleToAppGo(logger *slog.Logger, extName string, feats *features) erro
 	evidenceTextLine := spawn.FindLineWithText(appGoLines, "app.EvidenceKeeper = *evidenceKeeper")
 	logger.Debug("evidence keeper", "extName", extName, "line", evidenceTextLine)
 
+	deps, err := feats.template.KeeperDeps()
+	if err != nil {
+		return err
+	}
+
+	// keepers of other modules, passed before the authority
+	var depArgs string
+	for _, dep := range deps {
+		depArgs += fmt.Sprintf("\t\t%s,\n", dep.App)
+	}
+
 	var keeperText string
 	if feats.ibcMiddleware {
 		keeperText = fmt.Sprintf(`	// Create the %s Middleware Keeper
@@ -357,16 +625,20 @@ func AddModuleToAppGo(logger *slog.Logger, extName string, feats *features) erro
 		app.IBCKeeper.ChannelKeeper,
 		app.IBCKeeper.PortKeeper,
 		scoped%s,
-		authtypes.NewModuleAddress(govtypes.ModuleName).String(),
-	)`+"\n", extName, extNameTitle, extName, extName, extNameTitle)
+%s		authtypes.NewModuleAddress(govtypes.ModuleName).String(),
+	)`+"\n", extName, extNameTitle, extName, extName, extNameTitle, depArgs)
 	} else {
 		keeperText = fmt.Sprintf(`	// Create the %s Keeper
 	app.%sKeeper = %skeeper.NewKeeper(
 		appCodec,
 		runtime.NewKVStoreService(keys[%stypes.StoreKey]),
 		logger,
-		authtypes.NewModuleAddress(govtypes.ModuleName).String(),
-	)`+"\n", extName, extNameTitle, extName, extName)
+%s		authtypes.NewModuleAddress(govtypes.ModuleName).String(),
+	)`+"\n", extName, extNameTitle, extName, extName, depArgs)
+
+		if feats.template.SendRestriction {
+			keeperText += fmt.Sprintf("\tapp.BankKeeper.AppendSendRestriction(app.%sKeeper.SendRestriction)\n", extNameTitle)
+		}
 	}
 
 	appGoLines = append(appGoLines[:evidenceTextLine+2], append([]string{keeperText}, appGoLines[evidenceTextLine+2:]...)...)
@@ -401,6 +673,23 @@ func AddModuleToAppGo(logger *slog.Logger, extName string, feats *features) erro
 		appGoLines = append(appGoLines[:ibcKeeperSetRouter], append([]string{newLine}, appGoLines[ibcKeeperSetRouter:]...)...)
 	}
 
+	// The middleware wraps the transfer stack, its callbacks run for the ICS-20 packets.
+	if feats.ibcMiddleware {
+		transferStackLine := spawn.FindLineWithText(appGoLines, "transferStack = transfer.NewIBCModule(")
+		logger.Debug("transferStack", "extName", extName, "line", transferStackLine)
+
+		line := fmt.Sprintf(`	transferStack = %s.NewIBCMiddleware(transferStack, app.%sKeeper)`, extName, extNameTitle)
+		appGoLines = append(appGoLines[:transferStackLine+1], append([]string{line}, appGoLines[transferStackLine+1:]...)...)
+	}
+
+	// Give the module an account to hold coins.
+	if feats.template.ModuleAccount {
+		start, end := spawn.FindLinesWithText(appGoLines, "maccPerms = map[string][]string{")
+		logger.Debug("module account permissions", "extName", extName, "start", start, "end", end)
+		line := fmt.Sprintf(`	%stypes.ModuleName: nil,`, extName)
+		appGoLines = append(appGoLines[:end-1], append([]string{line}, appGoLines[end-1:]...)...)
+	}
+
 	// Register the app module.
 	start, end = spawn.FindLinesWithText(appGoLines, "NewManager(")
 	logger.Debug("module manager", "extName", extName, "start", start, "end", end)
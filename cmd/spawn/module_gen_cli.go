@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"path"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rollchains/spawn/spawn"
+)
+
+// ---
+// spawn module gen-cli mymodule
+// ---
+func ModuleGenCLICmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "gen-cli [name]",
+		Short: "Generate the module's autocli.go commands from its proto services",
+		Long: `Generate or update the autocli.go RpcCommandOptions of a module from its tx.proto & query.proto.
+Request fields become positional args, the Msg signer is set by --from. Existing commands keep their descriptions and commands set to Skip are not changed.`,
+		Example: `  - spawn module gen-cli mymodule`,
+		Args:    cobra.ExactArgs(1),
+		Aliases: []string{"cli", "autocli"},
+		Run: func(cmd *cobra.Command, args []string) {
+			logger := GetLogger()
+
+			cwd, err := os.Getwd()
+			if err != nil {
+				logger.Error("Error getting current working directory", "err", err)
+				return
+			}
+
+			extName := strings.ToLower(args[0])
+			if _, err := os.Stat(path.Join(cwd, "proto", extName)); err != nil {
+				logger.Error("Module proto directory not found", "module", extName, "err", err)
+				return
+			}
+
+			if err := spawn.GenerateModuleAutoCLI(logger, cwd, extName); err != nil {
+				logger.Error("Error generating autocli commands", "module", extName, "err", err)
+				return
+			}
+
+			logger.Info("AutoCLI commands generated", "module", extName)
+		},
+	}
+
+	return cmd
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"os"
+	"path"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rollchains/spawn/spawn"
+)
+
+const (
+	FlagMsgSigner     = "signer"
+	FlagQueryResponse = "response"
+)
+
+// ---
+// spawn module msg mymodule CreatePost title:string body:string amount:coin
+// ---
+func ModuleMsgCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "msg [module] [Name] [field:type]...",
+		Short: "Add a Msg to a module's tx.proto and generate its stubs",
+		Long: `Add an rpc to the Msg service of a module with its Msg<Name> & Msg<Name>Response messages. The Msg is signed by
+the --signer address field (added first when it is not one of the fields) and gets an amino name. The msg server stub,
+codec registration, sdk.Msg methods, autocli command & test are then generated as with stub-gen.
+Fields are name:type, with the types string, bytes, bool, uint64, uint32, int64, int32, address, coin or coins (string when omitted).`,
+		Example: `  - spawn module msg mymodule CreatePost title:string body:string amount:coin
+  - spawn module msg mymodule Transfer to:address amount:coins --signer from`,
+		Args: cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			signer, _ := cmd.Flags().GetString(FlagMsgSigner)
+
+			addModuleRPC(cmd, args, spawn.ModuleRPC{FType: spawn.Tx, Signer: signer})
+		},
+	}
+
+	cmd.Flags().String(FlagMsgSigner, "sender", "address field which signs the message")
+
+	return cmd
+}
+
+// ---
+// spawn module query mymodule Post id:uint64 --response title:string,body:string
+// ---
+func ModuleQueryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "query [module] [Name] [field:type]...",
+		Short: "Add a Query to a module's query.proto and generate its stubs",
+		Long: `Add an rpc to the Query service of a module with its Query<Name>Request & Query<Name>Response messages. The request
+has the given fields, the response the --response fields. The querier stub & autocli command are then generated as with stub-gen.
+Fields are name:type, with the types string, bytes, bool, uint64, uint32, int64, int32, address, coin or coins (string when omitted).`,
+		Example: `  - spawn module query mymodule Post id:uint64
+  - spawn module query mymodule Post id:uint64 --response title:string,body:string`,
+		Args: cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			logger := GetLogger()
+
+			resArgs, _ := cmd.Flags().GetStringSlice(FlagQueryResponse)
+			resFields, err := spawn.ParseRPCFields(resArgs)
+			if err != nil {
+				logger.Error("Error parsing response fields", "err", err)
+				return
+			}
+
+			addModuleRPC(cmd, args, spawn.ModuleRPC{FType: spawn.Query, ResFields: resFields})
+		},
+	}
+
+	cmd.Flags().StringSlice(FlagQueryResponse, []string{}, "fields of the response (name:type,...)")
+
+	return cmd
+}
+
+// addModuleRPC adds the [module] [Name] [field:type]... rpc of the args.
+func addModuleRPC(cmd *cobra.Command, args []string, rpc spawn.ModuleRPC) {
+	logger := GetLogger()
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		logger.Error("Error getting current working directory", "err", err)
+		return
+	}
+
+	extName := strings.ToLower(args[0])
+	if _, err := os.Stat(path.Join(cwd, "proto", extName)); err != nil {
+		logger.Error("Module proto directory not found", "module", extName, "err", err)
+		return
+	}
+
+	fields, err := spawn.ParseRPCFields(args[2:])
+	if err != nil {
+		logger.Error("Error parsing fields", "err", err)
+		return
+	}
+
+	rpc.Module, rpc.Name, rpc.Fields = extName, args[1], fields
+
+	if err := spawn.AddModuleRPC(logger, cwd, rpc); err != nil {
+		logger.Error("Error adding "+cmd.Name(), "module", extName, "name", rpc.Name, "err", err)
+		return
+	}
+
+	logger.Info("RPC added", "module", extName, "type", rpc.FType, "name", rpc.Name)
+	logger.Info("Run `make proto-gen` to generate the new types")
+}
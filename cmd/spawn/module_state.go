@@ -0,0 +1,110 @@
+package main
+
+import (
+	"os"
+	"path"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rollchains/spawn/spawn"
+)
+
+const (
+	FlagStateFields      = "fields"
+	FlagStatePrimaryKey  = "primary-key"
+	FlagStateORM         = "orm"
+	FlagStateCollections = "collections"
+)
+
+// ---
+// spawn module state add mymodule Pool --fields id:uint64,denom:string --primary-key id
+// ---
+func ModuleStateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "state",
+		Short: "Manage the state types of a module",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := cmd.Help(); err != nil {
+				GetLogger().Error("Error showing help", "err", err)
+			}
+		},
+	}
+
+	cmd.AddCommand(moduleStateAddCmd())
+
+	return cmd
+}
+
+func moduleStateAddCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "add [module] [Name]",
+		Short: "Add a collections map or ORM table to a module's state",
+		Long: `Add a new type to the state of a module. The message is added to state.proto and to the GenesisState, the keeper
+imports & exports it in InitGenesis & ExportGenesis, the Query service gets <Name> & <Names> RPCs and the Msg service
+authority gated Set<Name> & Delete<Name> RPCs, with a keeper test.
+Fields are name:type, with the types string, bytes, bool, uint64, uint32, int64, int32, address, coin or coins (string when omitted).
+Coins can not be the primary key or part of an ORM table.`,
+		Example: `  - spawn module state add mymodule Pool --fields id:uint64,denom:string --primary-key id
+  - spawn module state add mymodule Deposit --fields owner:address,amount:coins
+  - spawn module state add mymodule Balance --fields account:bytes,amount:uint64 --orm`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			logger := GetLogger()
+
+			cwd, err := os.Getwd()
+			if err != nil {
+				logger.Error("Error getting current working directory", "err", err)
+				return
+			}
+
+			extName := strings.ToLower(args[0])
+			if _, err := os.Stat(path.Join(cwd, "proto", extName)); err != nil {
+				logger.Error("Module proto directory not found", "module", extName, "err", err)
+				return
+			}
+
+			fieldArgs, _ := cmd.Flags().GetStringSlice(FlagStateFields)
+			fields, err := spawn.ParseStateFields(fieldArgs)
+			if err != nil {
+				logger.Error("Error parsing fields", "err", err)
+				return
+			}
+
+			primaryKey, _ := cmd.Flags().GetString(FlagStatePrimaryKey)
+			if primaryKey == "" && len(fields) > 0 {
+				primaryKey = fields[0].Name
+			}
+
+			storage := spawn.StateCollections
+			if orm, _ := cmd.Flags().GetBool(FlagStateORM); orm {
+				storage = spawn.StateORM
+			}
+
+			state := spawn.ModuleState{
+				Module:     extName,
+				Name:       args[1],
+				Fields:     fields,
+				PrimaryKey: primaryKey,
+				Storage:    storage,
+			}
+
+			if err := spawn.AddModuleState(logger, cwd, state); err != nil {
+				logger.Error("Error adding module state", "module", extName, "name", state.Name, "err", err)
+				return
+			}
+
+			logger.Info("Module state added", "module", extName, "name", state.Name, "storage", storage)
+			logger.Info("Run `make proto-gen` to generate the new types")
+		},
+	}
+
+	cmd.Flags().StringSlice(FlagStateFields, []string{}, "fields of the type (name:type,...)")
+	cmd.Flags().String(FlagStatePrimaryKey, "", "field the entries are keyed by (default: the first field)")
+	cmd.Flags().Bool(FlagStateORM, false, "store the entries in a cosmos-orm table")
+	cmd.Flags().Bool(FlagStateCollections, false, "store the entries in a collections.Map (default)")
+	cmd.MarkFlagsMutuallyExclusive(FlagStateORM, FlagStateCollections)
+	_ = cmd.MarkFlagRequired(FlagStateFields)
+
+	return cmd
+}
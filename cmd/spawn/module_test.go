@@ -32,6 +32,7 @@ func TestModuleGeneration(t *testing.T) {
 		Name           string
 		Args           []string
 		OutputContains string
+		Wiring         string
 	}
 
 	mcs := []mc{
@@ -47,6 +48,35 @@ func TestModuleGeneration(t *testing.T) {
 			Name: "standard",
 			Args: []string{"new", "standard"},
 		},
+		{
+			Name: "abci",
+			Args: []string{"new", "myabci", "--template", "abci"},
+		},
+		{
+			Name: "escrow",
+			Args: []string{"new", "myescrow", "--template", "escrow"},
+		},
+		{
+			Name: "icacontroller",
+			Args: []string{"new", "myica", "--template", "icacontroller"},
+		},
+		{
+			Name: "icq",
+			Args: []string{"new", "myicq", "--template", "icq"},
+		},
+		{
+			Name: "deps",
+			Args: []string{"new", "mydeps", "--deps", "bank,staking,account"},
+		},
+		{
+			Name: "templatedir",
+			Args: []string{"new", "mytmpl", "--template-dir", "../../../simapp"},
+		},
+		{
+			Name:   "depinject",
+			Args:   []string{"new", "mydi", "--template", "escrow"},
+			Wiring: spawn.WiringDepinject,
+		},
 	}
 
 	for _, c := range mcs {
@@ -56,6 +86,11 @@ func TestModuleGeneration(t *testing.T) {
 
 			cfg.ProjectName = name
 			cfg.HomeDir = "." + name
+			cfg.Wiring = c.Wiring
+			cfg.DisabledModules = []string{"explorer"}
+			if c.Wiring == spawn.WiringDepinject {
+				cfg.DisabledModules = append(cfg.DisabledModules, spawn.InterchainSecurity)
+			}
 			fmt.Println("=====\nName", name)
 
 			dirPath := path.Join(cwd, name)
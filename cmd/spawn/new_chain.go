@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -39,17 +40,40 @@ var (
 )
 
 const (
-	FlagWalletPrefix = "wallet-prefix"
-	FlagBinDaemon    = "binary"
-	FlagDebugging    = "debug"
-	FlagTokenDenom   = "denom"
-	FlagGithubOrg    = "org"
-	FlagDisabled     = "disable"
-	FlagConsensus    = "consensus"
-	FlagNoGit        = "skip-git"
-	FlagBypassPrompt = "bypass-prompt"
+	FlagWalletPrefix   = "wallet-prefix"
+	FlagBinDaemon      = "binary"
+	FlagDebugging      = "debug"
+	FlagTokenDenom     = "denom"
+	FlagGithubOrg      = "org"
+	FlagDisabled       = "disable"
+	FlagConsensus      = "consensus"
+	FlagNoGit          = "skip-git"
+	FlagBypassPrompt   = "bypass-prompt"
+	FlagVersionProfile = "version-profile"
+	FlagWiring         = "wiring"
+
+	FlagMsgFilter            = "msg-filter"
+	FlagMsgFilterAllow       = "msg-filter-allow"
+	FlagMsgFilterUntilHeight = "msg-filter-until-height"
 )
 
+// featureItems are the features of the UI selector. The ones depinject does not support are not selected for its wiring.
+func featureItems(wiring string) items {
+	if wiring != spawn.WiringDepinject {
+		return SupportedFeatures
+	}
+
+	feats := make(items, len(SupportedFeatures))
+	for i, feat := range SupportedFeatures {
+		f := *feat
+		if !f.IsConsensus && slices.Contains(spawn.DepinjectUnsupportedFeatures, spawn.AliasName(f.ID)) {
+			f.IsSelected = false
+		}
+		feats[i] = &f
+	}
+	return feats
+}
+
 func init() {
 	features := make([]string, 0)
 	consensus := make([]string, 0)
@@ -71,6 +95,13 @@ func init() {
 	newChain.Flags().Bool(FlagDebugging, false, "enable debugging")
 	newChain.Flags().Bool(FlagNoGit, false, "ignore git init")
 	newChain.Flags().Bool(FlagBypassPrompt, false, "bypass UI prompt")
+	newChain.Flags().String(FlagVersionProfile, spawn.DefaultVersionProfile, "cosmos-sdk + ibc-go stack to generate for: "+spawn.VersionProfilesHelp())
+	// only v0.50 has templates, the flag is hidden until another stack is added to spawn.VersionProfiles.
+	_ = newChain.Flags().MarkHidden(FlagVersionProfile)
+	newChain.Flags().String(FlagWiring, spawn.WiringManual, "how modules are wired into the app: "+strings.Join(spawn.Wirings, ","))
+	newChain.Flags().StringSlice(FlagMsgFilter, []string{}, "message type urls the ante handler filters (/cosmos.bank.v1beta1.MsgSend)")
+	newChain.Flags().Bool(FlagMsgFilterAllow, false, "only allow the --msg-filter messages instead of blocking them")
+	newChain.Flags().Int64(FlagMsgFilterUntilHeight, 0, "disable the message filter from this block height (0 keeps it enabled)")
 	newChain.Flags().SetNormalizeFunc(normalizeWhitelistVarRun)
 }
 
@@ -81,8 +112,10 @@ var newChain = &cobra.Command{
 		`  - spawn new rollchain --consensus=proof-of-stake --%s=cosmos --%s=simd --%s=token --org=abcde
   - spawn new rollchain --consensus=proof-of-authority --%s=tokenfactory
   - spawn new rollchain --consensus=interchain-security --%s=cosmwasm --%s
+  - spawn new rollchain --%s=%s --consensus=proof-of-stake
+  - spawn new rollchain --%s=/cosmos.bank.v1beta1.MsgSend --%s=100000
   - spawn new rollchain --%s`,
-		FlagWalletPrefix, FlagBinDaemon, FlagTokenDenom, FlagDisabled, FlagDisabled, FlagNoGit, FlagBypassPrompt,
+		FlagWalletPrefix, FlagBinDaemon, FlagTokenDenom, FlagDisabled, FlagDisabled, FlagNoGit, FlagWiring, spawn.WiringDepinject, FlagMsgFilter, FlagMsgFilterUntilHeight, FlagBypassPrompt,
 	),
 	Args:    cobra.ExactArgs(1),
 	Aliases: []string{"new", "init", "create"},
@@ -99,6 +132,12 @@ var newChain = &cobra.Command{
 		ignoreGitInit, _ := cmd.Flags().GetBool(FlagNoGit)
 		githubOrg, _ := cmd.Flags().GetString(FlagGithubOrg)
 		consensus, _ := cmd.Flags().GetString(FlagConsensus)
+		versionProfile, _ := cmd.Flags().GetString(FlagVersionProfile)
+		wiring, _ := cmd.Flags().GetString(FlagWiring)
+
+		msgFilter, _ := cmd.Flags().GetStringSlice(FlagMsgFilter)
+		msgFilterAllow, _ := cmd.Flags().GetBool(FlagMsgFilterAllow)
+		msgFilterUntilHeight, _ := cmd.Flags().GetInt64(FlagMsgFilterUntilHeight)
 
 		bypassPrompt, _ := cmd.Flags().GetBool(FlagBypassPrompt)
 
@@ -140,9 +179,10 @@ var newChain = &cobra.Command{
 
 		// Disable all features not selected
 		// Show a UI if the user did not specific to bypass it, or if nothing is disabled.
-		if len(disabled) == 0 && !bypassPrompt {
+		selected := len(disabled) == 0 && !bypassPrompt
+		if selected {
 			text := "Feature Selector (( enter to toggle ))"
-			items, err := selectItems(text, 0, SupportedFeatures, true, false, false)
+			items, err := selectItems(text, 0, featureItems(wiring), true, false, false)
 			if err != nil {
 				logger.Error("Error selecting disabled", "err", err)
 				return
@@ -154,6 +194,14 @@ var newChain = &cobra.Command{
 		disabled = append(disabled, disabledConsensus...)
 		disabled = spawn.NormalizeDisabledNames(disabled, parentDeps)
 
+		// features selected in the UI are not silently disabled, the others are with a warning
+		if selected && wiring == spawn.WiringDepinject {
+			if feats := spawn.DepinjectUnsupported(disabled); len(feats) > 0 {
+				logger.Error("Features not supported with depinject wiring were selected", "features", feats)
+				return
+			}
+		}
+
 		logger.Debug("Disabled features final", "features", disabled)
 
 		cfg := &spawn.NewChainConfig{
@@ -165,7 +213,14 @@ var newChain = &cobra.Command{
 			GithubOrg:       githubOrg,
 			IgnoreGitInit:   ignoreGitInit,
 			DisabledModules: disabled,
-			Logger:          logger,
+			VersionProfile:  versionProfile,
+			Wiring:          wiring,
+			MsgFilter: spawn.MsgFilter{
+				MsgTypeURLs: msgFilter,
+				AllowList:   msgFilterAllow,
+				UntilHeight: msgFilterUntilHeight,
+			},
+			Logger: logger,
 		}
 
 		if err := cfg.ValidateAndRun(true); err != nil {
@@ -191,6 +246,8 @@ func normalizeWhitelistVarRun(f *pflag.FlagSet, name string) pflag.NormalizedNam
 		name = FlagWalletPrefix
 	case "organization", "namespace":
 		name = FlagGithubOrg
+	case "profile", "sdk", "sdk-version", "stack":
+		name = FlagVersionProfile
 	}
 
 	return pflag.NormalizedName(name)
@@ -1,20 +1,27 @@
 package main
 
 import (
-	"fmt"
 	"os"
 
 	"github.com/rollchains/spawn/spawn"
 	"github.com/spf13/cobra"
 )
 
+const (
+	FlagPrune = "prune"
+)
+
 func ProtoServiceGenerate() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:     "stub-gen [module (optional)]",
-		Short:   "Auto generate the MsgService & Querier from proto -> Cosmos-SDK methods",
-		Long:    `Auto generate the interface stubs for the types.QueryServer and types.MsgServer for your module. If no module is provided, it will do for all modules in your proto folder.`,
-		Example: `spawn stub-gen [module_name]`,
-		Args:    cobra.MaximumNArgs(1),
+		Use:   "stub-gen [module (optional)]",
+		Short: "Auto generate the MsgService & Querier from proto -> Cosmos-SDK methods",
+		Long: `Auto generate the interface stubs for the types.QueryServer and types.MsgServer for your module. New Msgs are also registered in types/codec.go, get their sdk.Msg methods in types/msgs.go, a test case in keeper/msg_server_test.go and a simulation operation in simulation/operations.go. New RPCs are added to autocli.go. Methods whose RPC was renamed (same request & response types) are renamed in place, other methods no longer in proto are reported and removed with --prune. If no module is provided, it will do for all modules in your proto folder.
+
+Stubs are added to the Go types implementing types.MsgServer & types.QueryServer, which are created when missing. Modules outside of x/<module> or with specific server files are set in ` + "`" + spawn.StubGenConfigFileName + "`" + `:
+  {"modules": {"amm": {"dir": "x/amm", "services": {"Query": "x/amm/keeper/grpc_query.go"}}}}`,
+		Example: `  - spawn stub-gen [module_name]
+  - spawn stub-gen --prune`,
+		Args: cobra.MaximumNArgs(1),
 		Aliases: []string{
 			"stub", "stub-generate", "stub-interface", "stub-interfaces",
 			"service-generate", "sg",
@@ -30,10 +37,40 @@ func ProtoServiceGenerate() *cobra.Command {
 
 			missingRPCMethods, err := spawn.GetMissingRPCMethodsFromModuleProto(logger, cwd)
 			if err != nil {
-				fmt.Println("Error: ", err)
+				logger.Error("Error parsing proto files", "error", err)
 				return
 			}
 
+			orphans, err := spawn.GetOrphanedRPCMethods(logger, cwd, missingRPCMethods)
+			if err != nil {
+				logger.Error("Error finding orphaned methods", "error", err)
+				return
+			}
+
+			if err := spawn.ApplyRenamedRPCMethods(logger, cwd, orphans, missingRPCMethods); err != nil {
+				logger.Error("Error renaming methods", "error", err)
+				return
+			}
+
+			prune, _ := cmd.Flags().GetBool(FlagPrune)
+			if prune {
+				if err := spawn.PruneOrphanedRPCMethods(logger, cwd, orphans); err != nil {
+					logger.Error("Error pruning orphaned methods", "error", err)
+					return
+				}
+			}
+
+			for _, o := range orphans {
+				switch {
+				case o.RenamedTo != nil:
+					logger.Info("Renamed RPC Stub", "module", o.Module, "type", o.FType, "from", o.Name, "to", o.RenamedTo.Name, "file", o.FileLoc)
+				case prune:
+					logger.Info("Pruned RPC Stub", "module", o.Module, "type", o.FType, "name", o.Name, "file", o.FileLoc)
+				default:
+					logger.Warn("Orphaned RPC method, not in proto anymore (remove with --prune)", "module", o.Module, "type", o.FType, "name", o.Name, "file", o.FileLoc)
+				}
+			}
+
 			hasChanges := false
 			for _, v := range missingRPCMethods {
 				if len(v) > 0 {
@@ -42,7 +79,9 @@ func ProtoServiceGenerate() *cobra.Command {
 				}
 			}
 			if !hasChanges {
-				logger.Info("No missing methods to apply")
+				if len(orphans) == 0 {
+					logger.Info("No missing methods to apply")
+				}
 				return
 			}
 
@@ -51,6 +90,11 @@ func ProtoServiceGenerate() *cobra.Command {
 				return
 			}
 
+			if err := spawn.ScaffoldMissingRPCs(logger, cwd, missingRPCMethods); err != nil {
+				logger.Error("Error scaffolding the new RPCs", "error", err)
+				return
+			}
+
 			for _, v := range missingRPCMethods {
 				for _, rpc := range v {
 					logger.Info("Applied RPC Stub", "module", rpc.Module, "type", rpc.FType, "name", rpc.Name, "req", rpc.Req, "res", rpc.Res, "file", rpc.FileLoc)
@@ -60,5 +104,7 @@ func ProtoServiceGenerate() *cobra.Command {
 		},
 	}
 
+	cmd.Flags().Bool(FlagPrune, false, "remove methods (and their autocli commands, tests & msgs) that are no longer in proto")
+
 	return cmd
 }
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"os"
+	"path"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/rollchains/spawn/spawn"
+)
+
+const (
+	FlagRegistryDir = "dir"
+	FlagRegistryIBC = "ibc"
+)
+
+// ---
+// spawn registry validate
+// spawn registry export --dir ../chain-registry --ibc ibc.json
+// ---
+func RegistryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "registry",
+		Short:   "Validate and export the chain's cosmos/chain-registry files",
+		Long:    "Validate chain_registry.json & chain_registry_assets.json against the vendored cosmos/chain-registry schemas, or export them in the upstream layout to submit a PR.",
+		Aliases: []string{"chain-registry", "cr"},
+		Example: `  - spawn registry validate
+  - spawn registry export --dir ../chain-registry --ibc ./ibc-cosmoshub.json`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := cmd.Help(); err != nil {
+				GetLogger().Error("Error showing help", "err", err)
+			}
+		},
+	}
+
+	cmd.AddCommand(
+		registryValidateCmd(),
+		registryExportCmd(),
+	)
+
+	return cmd
+}
+
+func registryValidateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate [chain-dir]",
+		Short: "Validate the chain registry files against the chain-registry schemas",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			logger := GetLogger()
+
+			dir, err := registryHomeDir(args)
+			if err != nil {
+				logger.Error("Error getting current working directory", "err", err)
+				return
+			}
+
+			if err := spawn.ValidateChainRegistryDir(dir); err != nil {
+				logger.Error("Chain registry files are invalid", "err", err)
+				return
+			}
+
+			logger.Info("Chain registry files are valid", "dir", dir)
+		},
+	}
+
+	return cmd
+}
+
+func registryExportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export [chain-dir]",
+		Short: "Export the chain registry files in the cosmos/chain-registry layout",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			logger := GetLogger()
+
+			dir, err := registryHomeDir(args)
+			if err != nil {
+				logger.Error("Error getting current working directory", "err", err)
+				return
+			}
+
+			registryDir, _ := cmd.Flags().GetString(FlagRegistryDir)
+			ibcFiles, _ := cmd.Flags().GetStringSlice(FlagRegistryIBC)
+
+			chainDir, err := spawn.ExportChainRegistry(dir, registryDir, ibcFiles)
+			if err != nil {
+				logger.Error("Error exporting chain registry", "err", err)
+				return
+			}
+
+			logger.Info("Chain registry exported", "dir", chainDir)
+		},
+	}
+
+	cmd.Flags().String(FlagRegistryDir, "chain-registry", "output directory, usually a cosmos/chain-registry checkout (alias: --out)")
+	cmd.Flags().StringSlice(FlagRegistryIBC, nil, "IBC connection files (_IBC/<chain_1>-<chain_2>.json) to include")
+	cmd.Flags().SetNormalizeFunc(normalizeRegistryFlags)
+
+	return cmd
+}
+
+// normalizeRegistryFlags keeps --out working for --dir.
+func normalizeRegistryFlags(f *pflag.FlagSet, name string) pflag.NormalizedName {
+	if name == "out" {
+		name = FlagRegistryDir
+	}
+	return pflag.NormalizedName(name)
+}
+
+func registryHomeDir(args []string) (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+
+	if len(args) == 0 {
+		return cwd, nil
+	}
+
+	if path.IsAbs(args[0]) {
+		return args[0], nil
+	}
+
+	return path.Join(cwd, args[0]), nil
+}
@@ -0,0 +1,382 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	localictypes "github.com/strangelove-ventures/interchaintest/local-interchain/interchain/types"
+
+	"github.com/rollchains/spawn/spawn"
+	"github.com/rollchains/spawn/spawn/types"
+)
+
+const (
+	FlagAPIAddress = "api-address"
+	FlagAPIPort    = "api-port"
+	FlagFollow     = "follow"
+	FlagTail       = "tail"
+	FlagChainID    = "chain-id"
+	FlagNative     = "native"
+	FlagNumVals    = "validators"
+	FlagIBCSmoke   = "ibc-smoke"
+	FlagTimeout    = "timeout"
+)
+
+// ---
+// make local-image && spawn testnet start testnet
+// spawn testnet status
+// ---
+func TestnetCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "testnet",
+		Short:   "Start, stop, and inspect local testnets (chains/*.json)",
+		Long:    "Manage the local-interchain testnets found in the chains/ directory. local-ic is used as the engine, download with `make get-localic`.",
+		Aliases: []string{"tn", "localnet"},
+		Example: `  - spawn testnet start testnet
+  - spawn testnet start self-ibc --native --validators=2 --ibc-smoke
+  - spawn testnet status
+  - spawn testnet logs testnet --chain-id=localchain-1
+  - spawn testnet stop testnet`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := cmd.Help(); err != nil {
+				GetLogger().Error("Error showing help", "err", err)
+			}
+		},
+	}
+
+	cmd.AddCommand(
+		testnetStartCmd(),
+		testnetStopCmd(),
+		testnetStatusCmd(),
+		testnetLogsCmd(),
+	)
+
+	return cmd
+}
+
+func testnetStartCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "start [config]",
+		Short:   "Validate and start a testnet from chains/<config>.json",
+		Example: `spawn testnet start self-ibc`,
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			logger := GetLogger()
+
+			cwd, err := os.Getwd()
+			if err != nil {
+				logger.Error("Error getting current working directory", "err", err)
+				return
+			}
+
+			loc, cfg, err := loadAndValidateTestnet(cwd, args[0])
+			if err != nil {
+				logger.Error("Error loading testnet", "err", err)
+				return
+			}
+
+			if native, _ := cmd.Flags().GetBool(FlagNative); native {
+				numVals, _ := cmd.Flags().GetInt(FlagNumVals)
+				ibcSmoke, _ := cmd.Flags().GetBool(FlagIBCSmoke)
+				timeout, _ := cmd.Flags().GetDuration(FlagTimeout)
+
+				if err := startNativeTestnet(cwd, loc, cfg, numVals, ibcSmoke, timeout); err != nil {
+					logger.Error("Error starting native testnet", "err", err)
+				}
+				return
+			}
+
+			for _, img := range spawn.LocalDockerImages(cfg) {
+				if !spawn.DockerImageExists(img) {
+					logger.Error("Error starting testnet", "err", types.ErrTestnetMissingImage, "image", img.Ref(), "fix", "make local-image")
+					return
+				}
+			}
+
+			localIC := spawn.WhereIsBinInstalled("local-ic")
+			if localIC == "" {
+				logger.Error(spawn.LocalICInstallMsg)
+				return
+			}
+
+			apiAddr, _ := cmd.Flags().GetString(FlagAPIAddress)
+			apiPort, _ := cmd.Flags().GetUint16(FlagAPIPort)
+
+			for _, c := range cfg.Chains {
+				logger.Info("Starting chain", "chain_id", c.ChainID, "image", c.DockerImage.Ref(), "validators", c.NumberVals, "ibc_paths", c.IBCPaths)
+			}
+			logger.Info("Endpoints will be available with `spawn testnet status` once all chains are running", "api", spawn.LocalICAPIURL(apiAddr, apiPort))
+
+			if err := os.Setenv("ICTEST_HOME", cwd); err != nil {
+				logger.Error("Error setting ICTEST_HOME", "err", err)
+				return
+			}
+
+			if err := spawn.ExecCommand(localIC, "start", path.Base(loc),
+				"--api-address", apiAddr,
+				"--api-port", fmt.Sprintf("%d", apiPort),
+			); err != nil {
+				logger.Error("Error running local-ic", "err", err)
+			}
+		},
+	}
+
+	addLocalICAPIFlags(cmd)
+	cmd.Flags().Bool(FlagNative, false, "run the chains as local processes instead of docker")
+	cmd.Flags().Int(FlagNumVals, 0, "override the number of validators per chain (native)")
+	cmd.Flags().Bool(FlagIBCSmoke, false, "run the in-process ibctesting smoke tests before starting (native)")
+	cmd.Flags().Duration(FlagTimeout, 2*time.Minute, "time to wait for the first block (native)")
+
+	return cmd
+}
+
+// addLocalICAPIFlags adds the local-interchain REST server host and port flags.
+func addLocalICAPIFlags(cmd *cobra.Command) {
+	cmd.Flags().String(FlagAPIAddress, spawn.DefaultLocalICAPIAddress, "local-interchain API address")
+	cmd.Flags().Uint16(FlagAPIPort, spawn.DefaultLocalICAPIPort, "local-interchain API port")
+}
+
+func testnetStopCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "stop [config]",
+		Short:   "Stop a running testnet and remove its containers",
+		Example: `spawn testnet stop testnet`,
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			logger := GetLogger()
+
+			if native, _ := cmd.Flags().GetBool(FlagNative); native {
+				cwd, err := os.Getwd()
+				if err != nil {
+					logger.Error("Error getting current working directory", "err", err)
+					return
+				}
+
+				if err := spawn.StopNativeTestnet(spawn.NativeTestnetHome(cwd, args[0])); err != nil {
+					logger.Error("Error stopping native testnet", "err", err)
+					return
+				}
+
+				logger.Info("Native testnet stopped", "name", args[0])
+				return
+			}
+
+			apiAddr, _ := cmd.Flags().GetString(FlagAPIAddress)
+			apiPort, _ := cmd.Flags().GetUint16(FlagAPIPort)
+
+			name := spawn.TestnetName(args[0])
+			if err := spawn.StopTestnet(spawn.LocalICAPIURL(apiAddr, apiPort), name); err != nil {
+				logger.Error("Error stopping testnet", "err", err)
+				return
+			}
+
+			logger.Info("Testnet stopped", "name", args[0])
+		},
+	}
+
+	addLocalICAPIFlags(cmd)
+	cmd.Flags().Bool(FlagNative, false, "stop a testnet started with --native")
+
+	return cmd
+}
+
+func testnetStatusCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "status [config (native only)]",
+		Short:   "Show the endpoints of the running testnet",
+		Example: `spawn testnet status`,
+		Args:    cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			logger := GetLogger()
+
+			cwd, err := os.Getwd()
+			if err != nil {
+				logger.Error("Error getting current working directory", "err", err)
+				return
+			}
+
+			if native, _ := cmd.Flags().GetBool(FlagNative); native {
+				if len(args) == 0 {
+					logger.Error("A config name is required with --native", "available", spawn.AvailableTestnets(cwd))
+					return
+				}
+
+				state, err := spawn.ReadNativeState(spawn.NativeTestnetHome(cwd, args[0]))
+				if err != nil {
+					logger.Error("Error reading native testnet status", "err", err)
+					return
+				}
+
+				printNativeNodes(state)
+				return
+			}
+
+			info, err := spawn.ReadTestnetInfo(cwd)
+			if err != nil {
+				logger.Error("Error reading testnet status", "err", err, "available", spawn.AvailableTestnets(cwd))
+				return
+			}
+
+			for _, c := range info.Chains {
+				fmt.Printf("🔗 %s (%s)\n", c.ChainID, c.ChainName)
+				fmt.Printf("  - RPC:  %s\n", c.RPCAddress)
+				fmt.Printf("  - REST: %s\n", c.RESTAddress)
+				fmt.Printf("  - GRPC: %s\n", c.GRPCAddress)
+				fmt.Printf("  - P2P:  %s\n", c.P2PAddress)
+				if len(c.IBCPath) > 0 {
+					fmt.Printf("  - IBC:  %s\n", strings.Join(c.IBCPath, ", "))
+				}
+			}
+
+			for _, ch := range info.Channels {
+				if ch.Channel == nil {
+					continue
+				}
+				fmt.Printf("🌉 %s %s/%s <-> %s\n", ch.ChainID, ch.Channel.PortID, ch.Channel.ChannelID, ch.Channel.Counterparty.ChannelID)
+			}
+		},
+	}
+
+	cmd.Flags().Bool(FlagNative, false, "show a testnet started with --native")
+
+	return cmd
+}
+
+func testnetLogsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "logs [config]",
+		Short:   "Stream the container logs of a running testnet",
+		Example: `spawn testnet logs testnet --chain-id=localchain-1`,
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			logger := GetLogger()
+
+			follow, _ := cmd.Flags().GetBool(FlagFollow)
+			tail, _ := cmd.Flags().GetString(FlagTail)
+			chainID, _ := cmd.Flags().GetString(FlagChainID)
+
+			if native, _ := cmd.Flags().GetBool(FlagNative); native {
+				cwd, err := os.Getwd()
+				if err != nil {
+					logger.Error("Error getting current working directory", "err", err)
+					return
+				}
+
+				state, err := spawn.ReadNativeState(spawn.NativeTestnetHome(cwd, args[0]))
+				if err != nil {
+					logger.Error("Error reading native testnet", "err", err)
+					return
+				}
+
+				logFiles := make([]string, 0, len(state.Nodes))
+				for _, n := range state.Nodes {
+					if chainID == "" || n.ChainID == chainID {
+						logFiles = append(logFiles, n.LogFile())
+					}
+				}
+
+				tailArgs := []string{"-n", tail}
+				if follow {
+					tailArgs = append(tailArgs, "-f")
+				}
+
+				if err := spawn.ExecCommand("tail", append(tailArgs, logFiles...)...); err != nil {
+					logger.Error("Error streaming logs", "err", err)
+				}
+				return
+			}
+
+			containers, err := spawn.TestnetContainers(spawn.TestnetName(args[0]))
+			if err != nil {
+				logger.Error("Error finding testnet containers", "err", err)
+				return
+			}
+
+			filtered := make([]string, 0, len(containers))
+			for _, c := range containers {
+				if chainID == "" || strings.Contains(c, chainID) {
+					filtered = append(filtered, c)
+				}
+			}
+
+			if len(filtered) == 0 {
+				logger.Error("Error streaming logs", "err", types.ErrTestnetNotRunning, "testnet", args[0], "chain-id", chainID)
+				return
+			}
+
+			if err := spawn.StreamTestnetLogs(os.Stdout, filtered, follow, tail); err != nil {
+				logger.Error("Error streaming logs", "err", err)
+			}
+		},
+	}
+
+	cmd.Flags().BoolP(FlagFollow, "f", true, "follow log output")
+	cmd.Flags().String(FlagTail, "100", "number of lines to show from the end of the logs")
+	cmd.Flags().String(FlagChainID, "", "only show logs for this chain-id")
+	cmd.Flags().Bool(FlagNative, false, "show logs of a testnet started with --native")
+
+	return cmd
+}
+
+// loadAndValidateTestnet resolves, reads and validates the chains/<name>.json config.
+func loadAndValidateTestnet(cwd, name string) (string, *localictypes.ChainsConfig, error) {
+	loc, err := spawn.TestnetConfigPath(cwd, name)
+	if err != nil {
+		return "", nil, err
+	}
+
+	cfg, err := spawn.LoadTestnetConfig(loc)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if err := spawn.ValidateTestnetConfig(cfg); err != nil {
+		return "", nil, err
+	}
+
+	return loc, cfg, nil
+}
+
+// startNativeTestnet runs the config's chains as host processes (no docker).
+func startNativeTestnet(cwd, loc string, cfg *localictypes.ChainsConfig, numVals int, ibcSmoke bool, timeout time.Duration) error {
+	logger := GetLogger()
+
+	if numVals > 0 {
+		for i := range cfg.Chains {
+			cfg.Chains[i].NumberVals = numVals
+		}
+	}
+
+	if ibcSmoke {
+		logger.Info("Running in-process IBC smoke tests", "package", "./app/...")
+		if err := spawn.ExecCommand("go", "test", "./app/...", "-run", spawn.IBCSmokeTestPattern, "-count=1"); err != nil {
+			return fmt.Errorf("ibc smoke tests failed: %w", err)
+		}
+	}
+
+	nt := spawn.NewNativeTestnet(logger, cwd, loc, cfg)
+
+	state, err := nt.Start(timeout)
+	if err != nil {
+		return err
+	}
+
+	printNativeNodes(state)
+	fmt.Printf("\n🛑 Stop with: spawn testnet stop %s --native\n", strings.TrimSuffix(path.Base(loc), ".json"))
+
+	return nil
+}
+
+func printNativeNodes(state *spawn.NativeState) {
+	for _, n := range state.Nodes {
+		fmt.Printf("🔗 %s (pid %d)\n", n.Moniker, n.PID)
+		fmt.Printf("  - RPC:  %s\n", n.RPCAddress())
+		fmt.Printf("  - REST: %s\n", n.RESTAddress())
+		fmt.Printf("  - GRPC: %s\n", n.GRPCAddress())
+		fmt.Printf("  - Home: %s\n", n.Home)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rollchains/spawn/spawn"
+)
+
+const (
+	FlagAddedStores   = "added-stores"
+	FlagDeletedStores = "deleted-stores"
+	FlagUpgradeKeeper = "keepers"
+	FlagUpgradeCreate = "create"
+)
+
+// ---
+// spawn upgrade-handler new v2 --added-stores mymod --deleted-stores old
+// spawn upgrade-handler detect v1.0.0 --create v2
+// ---
+func UpgradeHandlerCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "upgrade-handler",
+		Short:   "Manage the chain upgrade handlers of app/upgrades",
+		Aliases: []string{"upgrade", "upgrades"},
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := cmd.Help(); err != nil {
+				GetLogger().Error("Error showing help", "err", err)
+			}
+		},
+	}
+
+	cmd.AddCommand(upgradeHandlerNewCmd())
+	cmd.AddCommand(upgradeHandlerDetectCmd())
+
+	return cmd
+}
+
+func upgradeHandlerNewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "new [name]",
+		Short: "Create a chain upgrade handler with its store upgrades",
+		Long: `Create the app/upgrades/<name> package of a chain upgrade. Its StoreUpgrades add & delete the given store keys and
+its handler runs the module migrations. The upgrade is registered in the Upgrades of app/upgrades.go, the --keepers of
+the ChainApp it uses are added to the upgrades.AppKeepers and a test applies the upgrade to an in-memory app.`,
+		Example: `  - spawn upgrade-handler new v2
+  - spawn upgrade-handler new v2 --added-stores mymod --deleted-stores old
+  - spawn upgrade-handler new v3 --keepers bank,staking`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			logger := GetLogger()
+
+			cwd, err := os.Getwd()
+			if err != nil {
+				logger.Error("Error getting current working directory", "err", err)
+				return
+			}
+
+			if _, err := os.Stat(path.Join(cwd, "app", "upgrades")); err != nil {
+				logger.Error("app/upgrades not found, run this command from the root of the chain", "err", err)
+				return
+			}
+
+			added, _ := cmd.Flags().GetStringSlice(FlagAddedStores)
+			deleted, _ := cmd.Flags().GetStringSlice(FlagDeletedStores)
+			keepers, _ := cmd.Flags().GetStringSlice(FlagUpgradeKeeper)
+
+			u := spawn.UpgradeHandler{Name: args[0], AddedStores: added, DeletedStores: deleted, Keepers: keepers}
+			if err := spawn.NewUpgradeHandler(logger, cwd, u); err != nil {
+				logger.Error("Error creating the upgrade handler", "name", u.Name, "err", err)
+				return
+			}
+
+			logger.Info("Upgrade handler created", "name", u.Name, "path", path.Join("app", "upgrades", u.Package()))
+		},
+	}
+
+	cmd.Flags().StringSlice(FlagAddedStores, []string{}, "store keys the upgrade adds")
+	cmd.Flags().StringSlice(FlagDeletedStores, []string{}, "store keys the upgrade deletes")
+	cmd.Flags().StringSlice(FlagUpgradeKeeper, []string{}, "keepers of the ChainApp the handler uses (bank,staking)")
+
+	return cmd
+}
+
+func upgradeHandlerDetectCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "detect [git-ref]",
+		Short: "Detect the store upgrades & module migrations since a release",
+		Long: `Compare the store keys registered in NewKVStoreKeys of app/app.go and the ConsensusVersion of the x/ modules
+between a git ref (the latest tag by default) and the working tree, then print the StoreUpgrades the next upgrade needs
+and the module migrations. With --create the upgrade handler is created with them and a Migrate<N>to<N+1> stub is
+registered in every module whose ConsensusVersion was bumped.`,
+		Example: `  - spawn upgrade-handler detect
+  - spawn upgrade-handler detect v1.0.0
+  - spawn upgrade-handler detect v1.0.0 --create v2 --keepers bank`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			logger := GetLogger()
+
+			cwd, err := os.Getwd()
+			if err != nil {
+				logger.Error("Error getting current working directory", "err", err)
+				return
+			}
+
+			ref := ""
+			if len(args) > 0 {
+				ref = args[0]
+			} else if ref, err = spawn.LatestGitTag(cwd); err != nil {
+				logger.Error("Error finding the git ref to compare with", "err", err)
+				return
+			}
+
+			diff, err := spawn.DetectUpgrade(cwd, ref)
+			if err != nil {
+				logger.Error("Error detecting the upgrade", "ref", ref, "err", err)
+				return
+			}
+
+			create, _ := cmd.Flags().GetString(FlagUpgradeCreate)
+			if create == "" {
+				if diff.Empty() {
+					logger.Info("No store or module version changes", "ref", ref)
+					return
+				}
+
+				fmt.Println(diff.StoreUpgrades())
+				for _, m := range diff.Migrations {
+					fmt.Printf("x/%s: ConsensusVersion %d -> %d needs a migration\n", m.Module, m.From, m.To)
+				}
+				return
+			}
+
+			keepers, _ := cmd.Flags().GetStringSlice(FlagUpgradeKeeper)
+			u := spawn.UpgradeHandler{
+				Name:             create,
+				AddedStoreKeys:   diff.AddedStoreKeys,
+				DeletedStoreKeys: diff.DeletedStoreKeys,
+				Keepers:          keepers,
+			}
+			if err := spawn.NewUpgradeHandler(logger, cwd, u); err != nil {
+				logger.Error("Error creating the upgrade handler", "name", u.Name, "err", err)
+				return
+			}
+
+			for _, m := range diff.Migrations {
+				if err := spawn.AddModuleMigrations(cwd, m); err != nil {
+					logger.Error("Error adding the module migrations", "module", m.Module, "err", err)
+					return
+				}
+				logger.Info("Module migrations added", "module", m.Module, "from", m.From, "to", m.To)
+			}
+
+			logger.Info("Upgrade handler created", "name", u.Name, "ref", ref, "path", path.Join("app", "upgrades", u.Package()))
+		},
+	}
+
+	cmd.Flags().String(FlagUpgradeCreate, "", "create the upgrade handler of this name with the detected changes")
+	cmd.Flags().StringSlice(FlagUpgradeKeeper, []string{}, "keepers of the ChainApp the handler uses (bank,staking)")
+
+	return cmd
+}
@@ -5,12 +5,35 @@ import (
 
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/cosmos/cosmos-sdk/x/authz"
-	"github.com/cosmos/gogoproto/proto"
 )
 
+// MsgFilterMode is how a MsgFilterDecorator treats the messages of its list.
+type MsgFilterMode int
+
+const (
+	// MsgFilterBlockList rejects the transactions with one of the listed messages.
+	MsgFilterBlockList MsgFilterMode = iota
+	// MsgFilterAllowList rejects the transactions with a message that is not listed. Genesis transactions go through
+	// the ante handler too, so the list must allow them (/cosmos.staking.v1beta1.MsgCreateValidator). An authz MsgExec
+	// is allowed when all of its nested messages are, it does not have to be listed.
+	MsgFilterAllowList
+)
+
+// MsgFilterConfig configures a MsgFilterDecorator.
+type MsgFilterConfig struct {
+	Mode MsgFilterMode
+	// MsgTypeURLs of the listed messages (/cosmos.bank.v1beta1.MsgSend)
+	MsgTypeURLs []string
+	// UntilHeight disables the filter from this block height on, 0 keeps it always enabled. A chain can launch with
+	// transfers disabled and enable them later.
+	UntilHeight int64
+}
+
 // MsgFilterDecorator is an ante.go decorator template for filtering messages.
 type MsgFilterDecorator struct {
-	blockedTypes []sdk.Msg
+	mode        MsgFilterMode
+	typeURLs    map[string]struct{}
+	untilHeight int64
 }
 
 // FilterDecorator returns a new MsgFilterDecorator. This errors if the transaction
@@ -20,13 +43,42 @@ type MsgFilterDecorator struct {
 // - decorators.FilterDecorator(&banktypes.MsgSend{})
 // This would block any MsgSend messages from being included in a transaction if set in ante.go
 func FilterDecorator(blockedMsgTypes ...sdk.Msg) MsgFilterDecorator {
+	typeURLs := make([]string, len(blockedMsgTypes))
+	for i, msg := range blockedMsgTypes {
+		typeURLs[i] = sdk.MsgTypeURL(msg)
+	}
+
+	return NewMsgFilterDecorator(MsgFilterConfig{
+		Mode:        MsgFilterBlockList,
+		MsgTypeURLs: typeURLs,
+	})
+}
+
+// NewMsgFilterDecorator returns a new MsgFilterDecorator from its config.
+//
+// Example:
+//   - decorators.NewMsgFilterDecorator(decorators.MsgFilterConfig{
+//     Mode:        decorators.MsgFilterBlockList,
+//     MsgTypeURLs: []string{"/cosmos.bank.v1beta1.MsgSend"},
+//     UntilHeight: 100_000,
+//     })
+//
+// This would block any MsgSend messages until the block 100000 if set in ante.go
+func NewMsgFilterDecorator(cfg MsgFilterConfig) MsgFilterDecorator {
+	typeURLs := make(map[string]struct{}, len(cfg.MsgTypeURLs))
+	for _, typeURL := range cfg.MsgTypeURLs {
+		typeURLs[typeURL] = struct{}{}
+	}
+
 	return MsgFilterDecorator{
-		blockedTypes: blockedMsgTypes,
+		mode:        cfg.Mode,
+		typeURLs:    typeURLs,
+		untilHeight: cfg.UntilHeight,
 	}
 }
 
 func (mfd MsgFilterDecorator) AnteHandle(ctx sdk.Context, tx sdk.Tx, simulate bool, next sdk.AnteHandler) (newCtx sdk.Context, err error) {
-	if mfd.HasDisallowedMessage(ctx, tx.GetMsgs()) {
+	if mfd.IsEnabled(ctx) && mfd.HasDisallowedMessage(ctx, tx.GetMsgs()) {
 		currHeight := ctx.BlockHeight()
 		return ctx, fmt.Errorf("tx contains unsupported message types at height %d", currHeight)
 	}
@@ -34,6 +86,11 @@ func (mfd MsgFilterDecorator) AnteHandle(ctx sdk.Context, tx sdk.Tx, simulate bo
 	return next(ctx, tx, simulate)
 }
 
+// IsEnabled is false once the chain reached the UntilHeight of the filter.
+func (mfd MsgFilterDecorator) IsEnabled(ctx sdk.Context) bool {
+	return mfd.untilHeight == 0 || ctx.BlockHeight() < mfd.untilHeight
+}
+
 func (mfd MsgFilterDecorator) HasDisallowedMessage(ctx sdk.Context, msgs []sdk.Msg) bool {
 	for _, msg := range msgs {
 		// check nested messages in a recursive manner
@@ -46,13 +103,17 @@ func (mfd MsgFilterDecorator) HasDisallowedMessage(ctx sdk.Context, msgs []sdk.M
 			if mfd.HasDisallowedMessage(ctx, msgs) {
 				return true
 			}
-		}
 
-		for _, blockedType := range mfd.blockedTypes {
-			if proto.MessageName(msg) == proto.MessageName(blockedType) {
-				return true
+			// the nested messages are what an allow-list permits, not the wrapper
+			if mfd.mode == MsgFilterAllowList {
+				continue
 			}
 		}
+
+		_, listed := mfd.typeURLs[sdk.MsgTypeURL(msg)]
+		if listed == (mfd.mode == MsgFilterBlockList) {
+			return true
+		}
 	}
 
 	return false
@@ -7,6 +7,7 @@ import (
 
 	"github.com/cometbft/cometbft/crypto/secp256k1"
 	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/authz"
 	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
 	"github.com/stretchr/testify/suite"
 
@@ -45,3 +46,48 @@ func (s *AnteTestSuite) TestAnteMsgFilterLogic() {
 	_, err = ante.AnteHandle(s.ctx, decorators.NewMockTx(msgMultiSend), false, decorators.EmptyAnte)
 	s.Require().NoError(err)
 }
+
+// Test the allow-list mode only lets the listed messages through, nested authz messages included.
+func (s *AnteTestSuite) TestAnteMsgFilterAllowList() {
+	acc := sdk.AccAddress(secp256k1.GenPrivKey().PubKey().Address())
+	coins := sdk.NewCoins(sdk.NewCoin("stake", sdkmath.NewInt(1)))
+
+	ante := decorators.NewMsgFilterDecorator(decorators.MsgFilterConfig{
+		Mode:        decorators.MsgFilterAllowList,
+		MsgTypeURLs: []string{sdk.MsgTypeURL(&banktypes.MsgSend{})},
+	})
+
+	_, err := ante.AnteHandle(s.ctx, decorators.NewMockTx(banktypes.NewMsgSend(acc, acc, coins)), false, decorators.EmptyAnte)
+	s.Require().NoError(err)
+
+	msgMultiSend := banktypes.NewMsgMultiSend(banktypes.NewInput(acc, coins), []banktypes.Output{banktypes.NewOutput(acc, coins)})
+	_, err = ante.AnteHandle(s.ctx, decorators.NewMockTx(msgMultiSend), false, decorators.EmptyAnte)
+	s.Require().Error(err)
+
+	execMsg := authz.NewMsgExec(acc, []sdk.Msg{msgMultiSend})
+	_, err = ante.AnteHandle(s.ctx, decorators.NewMockTx(&execMsg), false, decorators.EmptyAnte)
+	s.Require().Error(err)
+
+	// MsgExec is not listed, its nested messages are checked instead
+	execMsg = authz.NewMsgExec(acc, []sdk.Msg{banktypes.NewMsgSend(acc, acc, coins)})
+	_, err = ante.AnteHandle(s.ctx, decorators.NewMockTx(&execMsg), false, decorators.EmptyAnte)
+	s.Require().NoError(err)
+}
+
+// Test the filter is disabled from its UntilHeight.
+func (s *AnteTestSuite) TestAnteMsgFilterUntilHeight() {
+	acc := sdk.AccAddress(secp256k1.GenPrivKey().PubKey().Address())
+	msg := banktypes.NewMsgSend(acc, acc, sdk.NewCoins(sdk.NewCoin("stake", sdkmath.NewInt(1))))
+
+	ante := decorators.NewMsgFilterDecorator(decorators.MsgFilterConfig{
+		Mode:        decorators.MsgFilterBlockList,
+		MsgTypeURLs: []string{sdk.MsgTypeURL(&banktypes.MsgSend{})},
+		UntilHeight: 10,
+	})
+
+	_, err := ante.AnteHandle(s.ctx.WithBlockHeight(9), decorators.NewMockTx(msg), false, decorators.EmptyAnte)
+	s.Require().Error(err)
+
+	_, err = ante.AnteHandle(s.ctx.WithBlockHeight(10), decorators.NewMockTx(msg), false, decorators.EmptyAnte)
+	s.Require().NoError(err)
+}
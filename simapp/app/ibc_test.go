@@ -0,0 +1,55 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	sdkmath "cosmossdk.io/math"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	transfertypes "github.com/cosmos/ibc-go/v8/modules/apps/transfer/types"
+	clienttypes "github.com/cosmos/ibc-go/v8/modules/core/02-client/types"
+	ibctesting "github.com/cosmos/ibc-go/v8/testing"
+)
+
+// TestIBCTransfer connects 2 in-memory chains and relays an ICS-20 transfer between them.
+func TestIBCTransfer(t *testing.T) {
+	ibctesting.DefaultTestingAppInit = SetupTestingApp
+
+	coordinator := ibctesting.NewCoordinator(t, 2)
+	chainA := coordinator.GetChain(ibctesting.GetChainID(1))
+	chainB := coordinator.GetChain(ibctesting.GetChainID(2))
+
+	path := ibctesting.NewTransferPath(chainA, chainB)
+	coordinator.Setup(path)
+
+	amount := sdk.NewCoin(sdk.DefaultBondDenom, sdkmath.NewInt(100))
+	receiver := chainB.SenderAccount.GetAddress()
+
+	msg := transfertypes.NewMsgTransfer(
+		path.EndpointA.ChannelConfig.PortID,
+		path.EndpointA.ChannelID,
+		amount,
+		chainA.SenderAccount.GetAddress().String(),
+		receiver.String(),
+		clienttypes.NewHeight(1, 110),
+		0,
+		"",
+	)
+
+	res, err := chainA.SendMsgs(msg)
+	require.NoError(t, err)
+
+	packet, err := ibctesting.ParsePacketFromEvents(res.Events)
+	require.NoError(t, err)
+	require.NoError(t, path.RelayPacket(packet))
+
+	denom := transfertypes.GetPrefixedDenom(path.EndpointB.ChannelConfig.PortID, path.EndpointB.ChannelID, sdk.DefaultBondDenom)
+	ibcDenom := transfertypes.ParseDenomTrace(denom).IBCDenom()
+
+	app := chainB.App.(IBCTestingApp)
+	balance := app.BankKeeper.GetBalance(chainB.GetContext(), receiver, ibcDenom)
+	require.Equal(t, amount.Amount, balance.Amount)
+}
@@ -48,6 +48,7 @@ import (
 	clienttypes "github.com/cosmos/ibc-go/v8/modules/core/02-client/types"
 	commitmenttypes "github.com/cosmos/ibc-go/v8/modules/core/23-commitment/types"
 	ibctmtypes "github.com/cosmos/ibc-go/v8/modules/light-clients/07-tendermint"
+	ibctesting "github.com/cosmos/ibc-go/v8/testing"
 	consumertypes "github.com/cosmos/interchain-security/v5/x/ccv/consumer/types"
 	ccvprovidertypes "github.com/cosmos/interchain-security/v5/x/ccv/provider/types"
 	ccvtypes "github.com/cosmos/interchain-security/v5/x/ccv/types"
@@ -301,6 +302,23 @@ func initAccountWithCoins(app *ChainApp, ctx sdk.Context, addr sdk.AccAddress, c
 
 var emptyWasmOptions = []wasmkeeper.Option{}
 
+// SetupTestingApp creates an in-memory ChainApp for the ibc-go testing package.
+// i.e. ibctesting.DefaultTestingAppInit = SetupTestingApp
+func SetupTestingApp() (ibctesting.TestingApp, map[string]json.RawMessage) {
+	dir, err := os.MkdirTemp("", "ibctesting")
+	if err != nil {
+		panic(fmt.Sprintf("failed creating temporary directory: %v", err))
+	}
+	defer os.RemoveAll(dir)
+
+	app := NewChainApp(
+		log.NewNopLogger(), dbm.NewMemDB(), nil, true,
+		simtestutil.NewAppOptionsWithFlagHome(dir),
+		emptyWasmOptions, // spawntag:wasm
+	)
+	return IBCTestingApp{app}, app.DefaultGenesis()
+}
+
 // NewTestNetworkFixture returns a new ChainApp AppConstructor for network simulation tests
 func NewTestNetworkFixture() network.TestFixture {
 	dir, err := os.MkdirTemp("", "simapp")
@@ -1,13 +1,25 @@
 package app
 
 import (
+	"encoding/json"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	tmtypes "github.com/cometbft/cometbft/types"
+
 	capabilitykeeper "github.com/cosmos/ibc-go/modules/capability/keeper"
 	ibckeeper "github.com/cosmos/ibc-go/v8/modules/core/keeper"
+	ibctestingtypes "github.com/cosmos/ibc-go/v8/testing/types"
 
 	"github.com/cosmos/cosmos-sdk/baseapp"
+	"github.com/cosmos/cosmos-sdk/client"
+	cryptocodec "github.com/cosmos/cosmos-sdk/crypto/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
 	authkeeper "github.com/cosmos/cosmos-sdk/x/auth/keeper"
 	bankkeeper "github.com/cosmos/cosmos-sdk/x/bank/keeper"
 	stakingkeeper "github.com/cosmos/cosmos-sdk/x/staking/keeper"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+
+	consumertypes "github.com/cosmos/interchain-security/v5/x/ccv/consumer/types"
 
 	wasmkeeper "github.com/CosmWasm/wasmd/x/wasm/keeper"
 )
@@ -42,3 +54,67 @@ func (app *ChainApp) GetAccountKeeper() authkeeper.AccountKeeper {
 func (app *ChainApp) GetWasmKeeper() wasmkeeper.Keeper {
 	return app.WasmKeeper
 }
+
+// IBCTestingApp wraps the ChainApp to satisfy the ibc-go testing.TestingApp interface.
+type IBCTestingApp struct {
+	*ChainApp
+}
+
+func (app IBCTestingApp) GetStakingKeeper() ibctestingtypes.StakingKeeper {
+	return app.StakingKeeper
+}
+
+func (app IBCTestingApp) GetTxConfig() client.TxConfig {
+	return app.TxConfig()
+}
+
+// <spawntag:ics
+// InitChain sets the initial validator set of the ICS consumer to the staking validators, the ibc-go testing
+// chains only create those.
+func (app IBCTestingApp) InitChain(req *abci.RequestInitChain) (*abci.ResponseInitChain, error) {
+	var genesisState GenesisState
+	if err := json.Unmarshal(req.AppStateBytes, &genesisState); err != nil {
+		return nil, err
+	}
+
+	var stakingGenesis stakingtypes.GenesisState
+	if err := app.AppCodec().UnmarshalJSON(genesisState[stakingtypes.ModuleName], &stakingGenesis); err != nil {
+		return nil, err
+	}
+
+	initValPowers := make([]abci.ValidatorUpdate, 0, len(stakingGenesis.Validators))
+	for _, val := range stakingGenesis.Validators {
+		pk, err := val.ConsPubKey()
+		if err != nil {
+			return nil, err
+		}
+
+		pub, err := cryptocodec.ToCmtProtoPublicKey(pk)
+		if err != nil {
+			return nil, err
+		}
+
+		initValPowers = append(initValPowers, abci.ValidatorUpdate{
+			Power:  val.ConsensusPower(sdk.DefaultPowerReduction),
+			PubKey: pub,
+		})
+	}
+
+	tmVals, err := tmtypes.PB2TM.ValidatorUpdates(initValPowers)
+	if err != nil {
+		return nil, err
+	}
+
+	consumerGenesisState := CreateMinimalConsumerTestGenesis(req.ChainId)
+	consumerGenesisState.Provider.InitialValSet = initValPowers
+	consumerGenesisState.Provider.ConsensusState.NextValidatorsHash = tmtypes.NewValidatorSet(tmVals).Hash()
+	genesisState[consumertypes.ModuleName] = app.AppCodec().MustMarshalJSON(consumerGenesisState)
+
+	if req.AppStateBytes, err = json.Marshal(genesisState); err != nil {
+		return nil, err
+	}
+
+	return app.ChainApp.InitChain(req)
+}
+
+// spawntag:ics>
@@ -6,7 +6,7 @@ import (
 
 // !IMPORTANT: interchaintest/ has its own `InterchainTest` embed.FS that will need to be iterated on.
 
-//go:embed .github/* app/* chains/* cmd/* contrib/* scripts/* Makefile Dockerfile proto/*.* *.*
+//go:embed .github/* app/* chains/* cmd/* contrib/* explorer/* scripts/* Makefile Dockerfile proto/*.* *.*
 var SimAppFS embed.FS
 
 // To embed the interchaintest/ directory, rename the go.mod file to `go.mod_`
@@ -19,3 +19,13 @@ var ProtoModuleFS embed.FS
 
 //go:embed x/*
 var ExtensionFS embed.FS
+
+// Module templates built on top of x/ & proto/, see spawn.ModuleTemplates.
+//
+//go:embed templates/modules/*
+var ModuleTemplateFS embed.FS
+
+// App wirings which replace the app/ files of SimAppFS, see spawn.Wirings.
+//
+//go:embed templates/wiring/*
+var WiringFS embed.FS
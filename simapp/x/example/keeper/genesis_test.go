@@ -14,9 +14,9 @@ func TestGenesis(t *testing.T) {
 		Params: types.DefaultParams(),
 	}
 
-	f.k.InitGenesis(f.ctx, genesisState)
+	require.NoError(t, f.k.InitGenesis(f.ctx, genesisState))
 
-	got := f.k.ExportGenesis(f.ctx)
+	got, err := f.k.ExportGenesis(f.ctx)
+	require.NoError(t, err)
 	require.NotNil(t, got)
-
 }
@@ -90,13 +90,13 @@ func (k *Keeper) InitGenesis(ctx context.Context, data *types.GenesisState) erro
 }
 
 // ExportGenesis exports the module's state to a genesis state.
-func (k *Keeper) ExportGenesis(ctx context.Context) *types.GenesisState {
+func (k *Keeper) ExportGenesis(ctx context.Context) (*types.GenesisState, error) {
 	params, err := k.Params.Get(ctx)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	return &types.GenesisState{
 		Params: params,
-	}
+	}, nil
 }
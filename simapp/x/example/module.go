@@ -17,8 +17,10 @@ import (
 	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/cosmos/cosmos-sdk/types/module"
+	simtypes "github.com/cosmos/cosmos-sdk/types/simulation"
 
 	"github.com/rollchains/spawn/simapp/x/example/keeper"
+	"github.com/rollchains/spawn/simapp/x/example/simulation"
 	"github.com/rollchains/spawn/simapp/x/example/types"
 )
 
@@ -32,6 +34,9 @@ var (
 	_ module.AppModuleGenesis = AppModule{}
 	_ module.AppModule        = AppModule{}
 
+	_ module.AppModuleSimulation = AppModule{}
+	_ module.HasProposalMsgs     = AppModule{}
+
 	_ autocli.HasAutoCLIConfig = AppModule{}
 )
 
@@ -113,7 +118,7 @@ func (a AppModule) InitGenesis(ctx sdk.Context, marshaler codec.JSONCodec, messa
 	var genesisState types.GenesisState
 	marshaler.MustUnmarshalJSON(message, &genesisState)
 
-	if err := a.keeper.Params.Set(ctx, genesisState.Params); err != nil {
+	if err := a.keeper.InitGenesis(ctx, &genesisState); err != nil {
 		panic(err)
 	}
 
@@ -121,7 +126,11 @@ func (a AppModule) InitGenesis(ctx sdk.Context, marshaler codec.JSONCodec, messa
 }
 
 func (a AppModule) ExportGenesis(ctx sdk.Context, marshaler codec.JSONCodec) json.RawMessage {
-	genState := a.keeper.ExportGenesis(ctx)
+	genState, err := a.keeper.ExportGenesis(ctx)
+	if err != nil {
+		panic(err)
+	}
+
 	return marshaler.MustMarshalJSON(genState)
 }
 
@@ -137,6 +146,26 @@ func (a AppModule) RegisterServices(cfg module.Configurator) {
 	types.RegisterQueryServer(cfg.QueryServer(), keeper.NewQuerier(a.keeper))
 }
 
+// GenerateGenesisState creates a randomized GenState of the module.
+func (a AppModule) GenerateGenesisState(simState *module.SimulationState) {
+	simulation.RandomizedGenState(simState)
+}
+
+// RegisterStoreDecoder registers a decoder for the module store.
+func (a AppModule) RegisterStoreDecoder(sdr simtypes.StoreDecoderRegistry) {
+	sdr[types.StoreKey] = simulation.NewDecodeStore(a.cdc)
+}
+
+// WeightedOperations returns the module operations with their weights.
+func (a AppModule) WeightedOperations(simState module.SimulationState) []simtypes.WeightedOperation {
+	return simulation.WeightedOperations(simState.AppParams, simState.TxConfig, a.keeper)
+}
+
+// ProposalMsgs returns the msgs used to simulate the governance proposals of the module.
+func (a AppModule) ProposalMsgs(_ module.SimulationState) []simtypes.WeightedProposalMsg {
+	return simulation.ProposalMsgs()
+}
+
 // ConsensusVersion is a sequence number for state-breaking change of the
 // module. It should be incremented on each consensus-breaking change
 // introduced by the module. To avoid wrong/empty versions, the initial version
@@ -0,0 +1,28 @@
+package simulation
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	"github.com/cosmos/cosmos-sdk/types/kv"
+
+	"github.com/rollchains/spawn/simapp/x/example/types"
+)
+
+// NewDecodeStore returns a decoder function closure that unmarshals the KVPair's values of the module store.
+func NewDecodeStore(cdc codec.BinaryCodec) func(kvA, kvB kv.Pair) string {
+	return func(kvA, kvB kv.Pair) string {
+		switch {
+		case bytes.Equal(kvA.Key, types.ParamsKey.Bytes()):
+			var paramsA, paramsB types.Params
+			cdc.MustUnmarshal(kvA.Value, &paramsA)
+			cdc.MustUnmarshal(kvB.Value, &paramsB)
+			return fmt.Sprintf("%v\n%v", paramsA, paramsB)
+
+		// ORM tables and collections added to the keeper (i.e. with `spawn module state add`)
+		default:
+			return fmt.Sprintf("%X\n%X", kvA.Value, kvB.Value)
+		}
+	}
+}
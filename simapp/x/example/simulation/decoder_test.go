@@ -0,0 +1,32 @@
+package simulation_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	"github.com/cosmos/cosmos-sdk/types/kv"
+
+	"github.com/rollchains/spawn/simapp/x/example/simulation"
+	"github.com/rollchains/spawn/simapp/x/example/types"
+)
+
+func TestDecodeStore(t *testing.T) {
+	cdc := codec.NewProtoCodec(codectypes.NewInterfaceRegistry())
+	dec := simulation.NewDecodeStore(cdc)
+
+	params := types.DefaultParams()
+	paramsKV := kv.Pair{Key: types.ParamsKey.Bytes(), Value: cdc.MustMarshal(&params)}
+	require.Equal(t, fmt.Sprintf("%v\n%v", params, params), dec(paramsKV, paramsKV))
+
+	// ExampleData table of the ORM
+	ormKV := kv.Pair{Key: []byte{0, 1, 2}, Value: []byte{3}}
+	require.Equal(t, "03\n03", dec(ormKV, ormKV))
+
+	// a collection without a decoder case
+	collKV := kv.Pair{Key: []byte{0x99, 1}, Value: []byte{4}}
+	require.Equal(t, "04\n04", dec(collKV, collKV))
+}
@@ -0,0 +1,33 @@
+package simulation
+
+import (
+	"math/rand"
+
+	"github.com/cosmos/cosmos-sdk/types/module"
+
+	"github.com/rollchains/spawn/simapp/x/example/types"
+)
+
+// Simulation parameter constants
+const (
+	SomeValue = "some_value"
+)
+
+// GenSomeValue randomizes the SomeValue param.
+func GenSomeValue(r *rand.Rand) bool {
+	return r.Intn(2) == 0
+}
+
+// RandomizedGenState generates a random GenesisState for the module.
+func RandomizedGenState(simState *module.SimulationState) {
+	var someValue bool
+	simState.AppParams.GetOrGenerate(SomeValue, &someValue, simState.Rand, func(r *rand.Rand) { someValue = GenSomeValue(r) })
+
+	genesis := types.GenesisState{
+		Params: types.Params{
+			SomeValue: someValue,
+		},
+	}
+
+	simState.GenState[types.ModuleName] = simState.Cdc.MustMarshalJSON(&genesis)
+}
@@ -0,0 +1,38 @@
+package simulation_test
+
+import (
+	"encoding/json"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	sdkmath "cosmossdk.io/math"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	"github.com/cosmos/cosmos-sdk/types/module"
+	simtypes "github.com/cosmos/cosmos-sdk/types/simulation"
+
+	"github.com/rollchains/spawn/simapp/x/example/simulation"
+	"github.com/rollchains/spawn/simapp/x/example/types"
+)
+
+func TestRandomizedGenState(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	simState := module.SimulationState{
+		AppParams:    make(simtypes.AppParams),
+		Cdc:          codec.NewProtoCodec(codectypes.NewInterfaceRegistry()),
+		Rand:         r,
+		NumBonded:    3,
+		Accounts:     simtypes.RandomAccounts(r, 3),
+		InitialStake: sdkmath.NewInt(1000),
+		GenState:     make(map[string]json.RawMessage),
+	}
+
+	simulation.RandomizedGenState(&simState)
+
+	var genesis types.GenesisState
+	simState.Cdc.MustUnmarshalJSON(simState.GenState[types.ModuleName], &genesis)
+	require.NoError(t, genesis.Validate())
+}
@@ -0,0 +1,17 @@
+package simulation
+
+import (
+	"github.com/cosmos/cosmos-sdk/client"
+	simtypes "github.com/cosmos/cosmos-sdk/types/simulation"
+	"github.com/cosmos/cosmos-sdk/x/simulation"
+
+	"github.com/rollchains/spawn/simapp/x/example/keeper"
+)
+
+// WeightedOperations returns the operations of the module msgs with their weights. MsgUpdateParams is submitted
+// through governance, see ProposalMsgs. `spawn stub-gen` adds an operation for each new Msg.
+func WeightedOperations(appParams simtypes.AppParams, txGen client.TxConfig, k keeper.Keeper) simulation.WeightedOperations {
+	operations := simulation.WeightedOperations{}
+
+	return operations
+}
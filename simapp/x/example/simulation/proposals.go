@@ -0,0 +1,43 @@
+package simulation
+
+import (
+	"math/rand"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	simtypes "github.com/cosmos/cosmos-sdk/types/simulation"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	govtypes "github.com/cosmos/cosmos-sdk/x/gov/types"
+	"github.com/cosmos/cosmos-sdk/x/simulation"
+
+	"github.com/rollchains/spawn/simapp/x/example/types"
+)
+
+// Simulation operation weights constants
+const (
+	OpWeightMsgUpdateParams      = "op_weight_msg_update_params"
+	DefaultWeightMsgUpdateParams = 100
+)
+
+// ProposalMsgs returns the msgs of the module that are only executed through governance proposals.
+func ProposalMsgs() []simtypes.WeightedProposalMsg {
+	return []simtypes.WeightedProposalMsg{
+		simulation.NewWeightedProposalMsg(
+			OpWeightMsgUpdateParams,
+			DefaultWeightMsgUpdateParams,
+			SimulateMsgUpdateParams,
+		),
+	}
+}
+
+// SimulateMsgUpdateParams returns a MsgUpdateParams with random params.
+func SimulateMsgUpdateParams(r *rand.Rand, _ sdk.Context, _ []simtypes.Account) sdk.Msg {
+	// the default gov module account is the authority
+	authority := authtypes.NewModuleAddress(govtypes.ModuleName)
+
+	return &types.MsgUpdateParams{
+		Authority: authority.String(),
+		Params: types.Params{
+			SomeValue: GenSomeValue(r),
+		},
+	}
+}
@@ -140,7 +140,7 @@ func (im IBCMiddleware) SendPacket(
 	return im.keeper.SendPacket(
 		ctx,
 		chanCap,
-		sourceChannel,
+		sourcePort,
 		sourceChannel,
 		timeoutHeight,
 		timeoutTimestamp,
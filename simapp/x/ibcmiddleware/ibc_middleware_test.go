@@ -0,0 +1,126 @@
+package ibcmiddleware_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	sdkmath "cosmossdk.io/math"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	transfertypes "github.com/cosmos/ibc-go/v8/modules/apps/transfer/types"
+	clienttypes "github.com/cosmos/ibc-go/v8/modules/core/02-client/types"
+	channeltypes "github.com/cosmos/ibc-go/v8/modules/core/04-channel/types"
+	ibctesting "github.com/cosmos/ibc-go/v8/testing"
+
+	"github.com/rollchains/spawn/simapp/app"
+)
+
+// The middleware wraps the transfer stack of the app, the ICS-20 packets go through its callbacks.
+
+// setupTransferPath creates 2 in-memory chains with a path between their transfer ports.
+func setupTransferPath(t *testing.T) (*ibctesting.Coordinator, *ibctesting.Path) {
+	ibctesting.DefaultTestingAppInit = app.SetupTestingApp
+
+	coordinator := ibctesting.NewCoordinator(t, 2)
+	path := ibctesting.NewTransferPath(coordinator.GetChain(ibctesting.GetChainID(1)), coordinator.GetChain(ibctesting.GetChainID(2)))
+
+	return coordinator, path
+}
+
+func TestOnChanOpen(t *testing.T) {
+	coordinator, path := setupTransferPath(t)
+	coordinator.Setup(path)
+
+	for _, endpoint := range []*ibctesting.Endpoint{path.EndpointA, path.EndpointB} {
+		channel := endpoint.GetChannel()
+		require.Equal(t, channeltypes.OPEN, channel.State)
+		require.Equal(t, transfertypes.Version, channel.Version)
+	}
+}
+
+func TestOnRecvAndAcknowledgementPacket(t *testing.T) {
+	coordinator, path := setupTransferPath(t)
+	coordinator.Setup(path)
+
+	amount := sdk.NewCoin(sdk.DefaultBondDenom, sdkmath.NewInt(100))
+	packet := sendTransferPacket(t, path, amount, time.Hour)
+
+	// OnRecvPacket of chainB mints the vouchers & writes a result acknowledgement
+	res, err := path.EndpointB.RecvPacketWithResult(packet)
+	require.NoError(t, err)
+
+	ack, err := ibctesting.ParseAckFromEvents(res.Events)
+	require.NoError(t, err)
+	require.Equal(t, channeltypes.NewResultAcknowledgement([]byte{byte(1)}).Acknowledgement(), ack)
+
+	chainB := path.EndpointB.Chain
+	denom := transfertypes.GetPrefixedDenom(path.EndpointB.ChannelConfig.PortID, path.EndpointB.ChannelID, amount.Denom)
+	balance := getBalance(chainB, chainB.SenderAccount.GetAddress(), transfertypes.ParseDenomTrace(denom).IBCDenom())
+	require.Equal(t, amount.Amount, balance.Amount)
+
+	// OnAcknowledgementPacket of chainA
+	require.NoError(t, path.EndpointA.AcknowledgePacket(packet, ack))
+	requirePacketDone(t, path.EndpointA, packet)
+}
+
+func TestOnTimeoutPacket(t *testing.T) {
+	coordinator, path := setupTransferPath(t)
+	coordinator.Setup(path)
+
+	chainA := path.EndpointA.Chain
+	sender := chainA.SenderAccount.GetAddress()
+	before := getBalance(chainA, sender, sdk.DefaultBondDenom)
+
+	packet := sendTransferPacket(t, path, sdk.NewCoin(sdk.DefaultBondDenom, sdkmath.NewInt(100)), time.Minute)
+
+	// chainB passes the timeout without receiving the packet
+	coordinator.IncrementTimeBy(time.Hour)
+	coordinator.CommitBlock(path.EndpointB.Chain)
+	require.NoError(t, path.EndpointA.UpdateClient())
+
+	// OnTimeoutPacket of chainA refunds the sender
+	require.NoError(t, path.EndpointA.TimeoutPacket(packet))
+	requirePacketDone(t, path.EndpointA, packet)
+	require.Equal(t, before, getBalance(chainA, sender, sdk.DefaultBondDenom))
+}
+
+// sendTransferPacket transfers the coin from chainA, ready to be received by chainB.
+func sendTransferPacket(t *testing.T, path *ibctesting.Path, coin sdk.Coin, timeout time.Duration) channeltypes.Packet {
+	t.Helper()
+
+	chain := path.EndpointA.Chain
+	res, err := chain.SendMsgs(transfertypes.NewMsgTransfer(
+		path.EndpointA.ChannelConfig.PortID,
+		path.EndpointA.ChannelID,
+		coin,
+		chain.SenderAccount.GetAddress().String(),
+		path.EndpointB.Chain.SenderAccount.GetAddress().String(),
+		clienttypes.ZeroHeight(),
+		uint64(chain.GetContext().BlockTime().Add(timeout).UnixNano()),
+		"",
+	))
+	require.NoError(t, err)
+
+	packet, err := ibctesting.ParsePacketFromEvents(res.Events)
+	require.NoError(t, err)
+
+	// chainB tracks the block with the packet commitment
+	require.NoError(t, path.EndpointB.UpdateClient())
+	return packet
+}
+
+func getBalance(chain *ibctesting.TestChain, addr sdk.AccAddress, denom string) sdk.Coin {
+	return chain.App.(app.IBCTestingApp).BankKeeper.GetBalance(chain.GetContext(), addr, denom)
+}
+
+// requirePacketDone checks the commitment of the sent packet is deleted, once acknowledged or timed out.
+func requirePacketDone(t *testing.T, endpoint *ibctesting.Endpoint, packet channeltypes.Packet) {
+	t.Helper()
+
+	commitment := endpoint.Chain.App.GetIBCKeeper().ChannelKeeper.GetPacketCommitment(
+		endpoint.Chain.GetContext(), packet.GetSourcePort(), packet.GetSourceChannel(), packet.GetSequence())
+	require.Empty(t, commitment)
+}
@@ -55,9 +55,9 @@ func NewSomeDataTxCmd() *cobra.Command {
 				return err
 			}
 
+			// the timeout is relative to the current time
 			if timeoutTimestamp != 0 {
-				now := time.Now().UnixNano()
-				timeoutTimestamp = uint64(now + time.Duration(1*time.Hour).Nanoseconds())
+				timeoutTimestamp += uint64(time.Now().UnixNano())
 			}
 
 			msg := &types.MsgSendExampleTx{
@@ -0,0 +1,134 @@
+package ibcmodule_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	channeltypes "github.com/cosmos/ibc-go/v8/modules/core/04-channel/types"
+	ibctesting "github.com/cosmos/ibc-go/v8/testing"
+
+	"github.com/rollchains/spawn/simapp/app"
+	"github.com/rollchains/spawn/simapp/x/ibcmodule/types"
+)
+
+// setupPath creates 2 in-memory chains with a path between their module ports.
+func setupPath(t *testing.T) (*ibctesting.Coordinator, *ibctesting.Path) {
+	ibctesting.DefaultTestingAppInit = app.SetupTestingApp
+
+	coordinator := ibctesting.NewCoordinator(t, 2)
+	chainA := coordinator.GetChain(ibctesting.GetChainID(1))
+	// simapp only has the module template, it is routed once added to a chain
+	if !chainA.App.GetIBCKeeper().Router.HasRoute(types.ModuleName) {
+		t.Skip("the module is not wired into app.go")
+	}
+
+	path := ibctesting.NewPath(chainA, coordinator.GetChain(ibctesting.GetChainID(2)))
+	for _, endpoint := range []*ibctesting.Endpoint{path.EndpointA, path.EndpointB} {
+		endpoint.ChannelConfig.PortID = types.PortID
+		endpoint.ChannelConfig.Version = types.Version
+		endpoint.ChannelConfig.Order = channeltypes.UNORDERED
+	}
+
+	return coordinator, path
+}
+
+func TestOnChanOpen(t *testing.T) {
+	coordinator, path := setupPath(t)
+	coordinator.Setup(path)
+
+	for _, endpoint := range []*ibctesting.Endpoint{path.EndpointA, path.EndpointB} {
+		channel := endpoint.GetChannel()
+		require.Equal(t, channeltypes.OPEN, channel.State)
+		require.Equal(t, types.Version, channel.Version)
+	}
+}
+
+func TestOnChanOpenInitInvalidCounterparty(t *testing.T) {
+	coordinator, path := setupPath(t)
+	coordinator.SetupConnections(path)
+
+	path.EndpointB.ChannelConfig.PortID = ibctesting.TransferPort
+	require.Error(t, path.EndpointA.ChanOpenInit())
+}
+
+func TestOnRecvAndAcknowledgementPacket(t *testing.T) {
+	coordinator, path := setupPath(t)
+	coordinator.Setup(path)
+
+	packet := sendExamplePacket(t, path, time.Hour)
+
+	// OnRecvPacket of chainB writes a result acknowledgement
+	res, err := path.EndpointB.RecvPacketWithResult(packet)
+	require.NoError(t, err)
+
+	ack, err := ibctesting.ParseAckFromEvents(res.Events)
+	require.NoError(t, err)
+	require.Equal(t, channeltypes.NewResultAcknowledgement([]byte{byte(1)}).Acknowledgement(), ack)
+
+	// OnAcknowledgementPacket of chainA
+	require.NoError(t, path.EndpointA.AcknowledgePacket(packet, ack))
+	requirePacketDone(t, path.EndpointA, packet)
+}
+
+func TestOnTimeoutPacket(t *testing.T) {
+	coordinator, path := setupPath(t)
+	coordinator.Setup(path)
+
+	packet := sendExamplePacket(t, path, time.Minute)
+
+	// chainB passes the timeout without receiving the packet
+	coordinator.IncrementTimeBy(time.Hour)
+	coordinator.CommitBlock(path.EndpointB.Chain)
+	require.NoError(t, path.EndpointA.UpdateClient())
+
+	// OnTimeoutPacket of chainA
+	require.NoError(t, path.EndpointA.TimeoutPacket(packet))
+	requirePacketDone(t, path.EndpointA, packet)
+}
+
+// sendExamplePacket sends a packet from chainA with the example tx, ready to be received by chainB.
+func sendExamplePacket(t *testing.T, path *ibctesting.Path, timeout time.Duration) channeltypes.Packet {
+	t.Helper()
+
+	chain := path.EndpointA.Chain
+	events := deliverMsg(t, chain, &types.MsgSendExampleTx{
+		Sender:           chain.SenderAccount.GetAddress().String(),
+		SourcePort:       path.EndpointA.ChannelConfig.PortID,
+		SourceChannel:    path.EndpointA.ChannelID,
+		TimeoutTimestamp: uint64(chain.GetContext().BlockTime().Add(timeout).UnixNano()),
+		SomeData:         "some data",
+	})
+
+	packet, err := ibctesting.ParsePacketFromEvents(events)
+	require.NoError(t, err)
+
+	// chainB tracks the block with the packet commitment
+	require.NoError(t, path.EndpointB.UpdateClient())
+	return packet
+}
+
+// deliverMsg runs the msg through the msg router of the chain and commits the block. ICS consumer chains only accept
+// IBC txs in their ante handler until the provider channel is established, which the in-memory chains do not have.
+func deliverMsg(t *testing.T, chain *ibctesting.TestChain, msg sdk.Msg) []abci.Event {
+	t.Helper()
+
+	res, err := chain.App.GetBaseApp().MsgServiceRouter().Handler(msg)(chain.GetContext(), msg)
+	require.NoError(t, err)
+
+	chain.Coordinator.CommitBlock(chain)
+	return res.Events
+}
+
+// requirePacketDone checks the commitment of the sent packet is deleted, once acknowledged or timed out.
+func requirePacketDone(t *testing.T, endpoint *ibctesting.Endpoint, packet channeltypes.Packet) {
+	t.Helper()
+
+	commitment := endpoint.Chain.App.GetIBCKeeper().ChannelKeeper.GetPacketCommitment(
+		endpoint.Chain.GetContext(), packet.GetSourcePort(), packet.GetSourceChannel(), packet.GetSequence())
+	require.Empty(t, commitment)
+}
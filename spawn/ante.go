@@ -0,0 +1,231 @@
+package spawn
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	gotypes "go/types"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rollchains/spawn/spawn/types"
+)
+
+var msgTypeURLRegex = regexp.MustCompile(`^/[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)+$`)
+
+// MsgFilter is a message filter decorator of the ante handler, see AddAnteMsgFilter.
+type MsgFilter struct {
+	// MsgTypeURLs of the filtered messages (/cosmos.bank.v1beta1.MsgSend)
+	MsgTypeURLs []string
+	// AllowList rejects every message but the listed ones, instead of only the listed ones
+	AllowList bool
+	// UntilHeight disables the filter from this block height, 0 keeps it always enabled
+	UntilHeight int64
+}
+
+// Mode returns the decorators.MsgFilterMode of the filter.
+func (f MsgFilter) Mode() string {
+	if f.AllowList {
+		return "decorators.MsgFilterAllowList"
+	}
+	return "decorators.MsgFilterBlockList"
+}
+
+// Validate checks the filter can be added to the ante handler.
+func (f MsgFilter) Validate() error {
+	if len(f.MsgTypeURLs) == 0 {
+		return fmt.Errorf("%w: no message type url to filter", types.ErrAnteInvalid)
+	}
+	for _, typeURL := range f.MsgTypeURLs {
+		if !msgTypeURLRegex.MatchString(typeURL) {
+			return fmt.Errorf("%w: %q is not a message type url (/cosmos.bank.v1beta1.MsgSend)", types.ErrAnteInvalid, typeURL)
+		}
+	}
+	if f.UntilHeight < 0 {
+		return fmt.Errorf("%w: until height %d must not be negative", types.ErrAnteInvalid, f.UntilHeight)
+	}
+	return nil
+}
+
+// AddAnteMsgFilter adds the messages to the decorators.MsgFilterDecorator of the filter mode in the NewAnteHandler
+// decorators of app/ante.go. The decorator is added after the SetUpContextDecorator when the chain has none, so the
+// filtered transactions are rejected before any other check.
+func AddAnteMsgFilter(cwd string, filter MsgFilter) error {
+	if err := filter.Validate(); err != nil {
+		return err
+	}
+
+	goModName := ReadCurrentGoModuleName(path.Join(cwd, "go.mod"))
+	if goModName == "" {
+		return fmt.Errorf("go.mod not found in %s", cwd)
+	}
+
+	appDir := path.Join(cwd, "app")
+	err := editGoFunc(appDir, "NewAnteHandler", func(fset *token.FileSet, f *ast.File, src []byte, fn *ast.FuncDecl) ([]sourceEdit, error) {
+		decorators := anteDecoratorsLit(fn)
+		if decorators == nil {
+			return nil, fmt.Errorf("%w: the []sdk.AnteDecorator of NewAnteHandler not found", types.ErrAnteInvalid)
+		}
+
+		cfg := msgFilterConfigLit(decorators, filter.Mode())
+		if cfg == nil {
+			return []sourceEdit{insertAnteDecorator(fset, src, decorators, filter.decoratorSource())}, nil
+		}
+
+		var edits []sourceEdit
+		typeURLs, _ := compositeFieldValue(cfg, "MsgTypeURLs").(*ast.CompositeLit)
+		if typeURLs == nil {
+			edits = append(edits, setCompositeField(fset, src, cfg, "MsgTypeURLs", filter.typeURLsSource()))
+		} else {
+			edits = append(edits, appendTypeURLs(fset, src, typeURLs, filter.MsgTypeURLs)...)
+		}
+		if filter.UntilHeight > 0 {
+			edits = append(edits, setCompositeField(fset, src, cfg, "UntilHeight", strconv.FormatInt(filter.UntilHeight, 10)))
+		}
+		return edits, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return addAppGoImports(appDir, "NewAnteHandler", []string{goModName + "/app/decorators"})
+}
+
+func (f MsgFilter) decoratorSource() string {
+	return fmt.Sprintf(`decorators.NewMsgFilterDecorator(decorators.MsgFilterConfig{
+Mode: %s,
+MsgTypeURLs: %s,
+UntilHeight: %d,
+})`, f.Mode(), f.typeURLsSource(), f.UntilHeight)
+}
+
+func (f MsgFilter) typeURLsSource() string {
+	quoted := make([]string, len(f.MsgTypeURLs))
+	for i, typeURL := range f.MsgTypeURLs {
+		quoted[i] = strconv.Quote(typeURL)
+	}
+	return stringSliceSource(quoted)
+}
+
+// stringSliceSource returns a []string literal with one element per line.
+func stringSliceSource(elts []string) string {
+	return "[]string{\n" + strings.Join(elts, ",\n") + ",\n}"
+}
+
+// appendTypeURLs adds the missing type URLs to the MsgTypeURLs literal. A literal on a single line is rewritten with
+// one element per line.
+func appendTypeURLs(fset *token.FileSet, src []byte, lit *ast.CompositeLit, typeURLs []string) []sourceEdit {
+	var missing []string
+	for _, typeURL := range typeURLs {
+		quoted := strconv.Quote(typeURL)
+		if !slices.ContainsFunc(lit.Elts, func(e ast.Expr) bool { return gotypes.ExprString(e) == quoted }) && !slices.Contains(missing, quoted) {
+			missing = append(missing, quoted)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	if fset.Position(lit.Lbrace).Line == fset.Position(lit.Rbrace).Line {
+		elts := make([]string, 0, len(lit.Elts)+len(missing))
+		for _, e := range lit.Elts {
+			elts = append(elts, string(src[fset.Position(e.Pos()).Offset:fset.Position(e.End()).Offset]))
+		}
+		return []sourceEdit{replaceEdit(fset, lit, stringSliceSource(append(elts, missing...)))}
+	}
+
+	edits := make([]sourceEdit, len(missing))
+	for i, quoted := range missing {
+		edits[i] = appendCompositeElt(fset, src, lit, quoted)
+	}
+	return edits
+}
+
+// anteDecoratorsLit returns the []sdk.AnteDecorator literal of the function.
+func anteDecoratorsLit(fn *ast.FuncDecl) *ast.CompositeLit {
+	var lit *ast.CompositeLit
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		if l, ok := n.(*ast.CompositeLit); ok && gotypes.ExprString(l.Type) == "[]sdk.AnteDecorator" {
+			lit = l
+		}
+		return lit == nil
+	})
+	return lit
+}
+
+// msgFilterConfigLit returns the MsgFilterConfig of the decorators.NewMsgFilterDecorator with the mode.
+func msgFilterConfigLit(decorators *ast.CompositeLit, mode string) *ast.CompositeLit {
+	for _, elt := range decorators.Elts {
+		call, ok := elt.(*ast.CallExpr)
+		if !ok || gotypes.ExprString(call.Fun) != "decorators.NewMsgFilterDecorator" || len(call.Args) != 1 {
+			continue
+		}
+
+		cfg, ok := call.Args[0].(*ast.CompositeLit)
+		if !ok {
+			continue
+		}
+		if m := compositeFieldValue(cfg, "Mode"); m != nil && gotypes.ExprString(m) == mode ||
+			m == nil && mode == "decorators.MsgFilterBlockList" {
+			return cfg
+		}
+	}
+	return nil
+}
+
+// compositeFieldValue returns the value of a key in a composite literal, nil when it is not set.
+func compositeFieldValue(lit *ast.CompositeLit, key string) ast.Expr {
+	for _, elt := range lit.Elts {
+		if kv, ok := elt.(*ast.KeyValueExpr); ok && compositeKey(kv) == key {
+			return kv.Value
+		}
+	}
+	return nil
+}
+
+// insertAnteDecorator adds the decorator after the SetUpContextDecorator, which must stay the outermost decorator.
+func insertAnteDecorator(fset *token.FileSet, src []byte, decorators *ast.CompositeLit, decorator string) sourceEdit {
+	if elt := findDecorator(decorators, "NewSetUpContextDecorator()"); elt != nil {
+		return insertAfterDecorator(fset, src, elt, decorator)
+	}
+	return sourceEdit{offset: fset.Position(decorators.Lbrace).Offset + 1, text: "\n" + decorator + ","}
+}
+
+// findDecorator returns the first decorator of the chain containing the text.
+func findDecorator(decorators *ast.CompositeLit, text string) ast.Expr {
+	for _, elt := range decorators.Elts {
+		if strings.Contains(gotypes.ExprString(elt), text) {
+			return elt
+		}
+	}
+	return nil
+}
+
+// insertAfterDecorator adds the decorator on the line after elt, past its comma & comment.
+func insertAfterDecorator(fset *token.FileSet, src []byte, elt ast.Expr, decorator string) sourceEdit {
+	end := fset.Position(elt.End()).Offset
+	lineEnd := end + strings.IndexByte(string(src[end:]), '\n') + 1
+	return sourceEdit{offset: lineEnd, text: decorator + ",\n"}
+}
+
+// addAppGoImports adds the imports to the file of app/ declaring the function.
+func addAppGoImports(appDir, funcName string, imports []string) error {
+	loc, err := findGoDecl(appDir, funcName)
+	if err != nil {
+		return err
+	}
+
+	src, err := os.ReadFile(loc)
+	if err != nil {
+		return err
+	}
+	out, err := addGoImports(loc, src, imports)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(loc, out, 0644)
+}
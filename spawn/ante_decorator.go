@@ -0,0 +1,288 @@
+package spawn
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/token"
+	gotypes "go/types"
+	"log/slog"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/rollchains/spawn/spawn/types"
+)
+
+var decoratorNameRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+
+// AnteDecorator is a new decorator of app/decorators, see NewAnteDecorator.
+type AnteDecorator struct {
+	// Name of the decorator (fee-burn, FeeBurn)
+	Name string
+	// Post generates a decorator of the post handler, run after the messages, instead of the ante handler
+	Post bool
+	// After & Before place the decorator next to the first decorator of the chain containing the text
+	// (NewDeductFeeDecorator). It is added at the end of the chain when both are empty.
+	After  string
+	Before string
+}
+
+// TypeName returns the Go type of the decorator (FeeBurnDecorator, FeeBurnPostDecorator).
+func (d AnteDecorator) TypeName() string {
+	name := goCamelCase(strings.ReplaceAll(d.Name, "-", "_"))
+	name = strings.TrimSuffix(strings.TrimSuffix(name, "Decorator"), "Post")
+	if d.Post {
+		return name + "PostDecorator"
+	}
+	return name + "Decorator"
+}
+
+// FileName returns the file of the decorator in app/decorators, without the extension (fee_burn).
+func (d AnteDecorator) FileName() string {
+	name := strings.TrimSuffix(strings.TrimSuffix(d.TypeName(), "Decorator"), "Post")
+	if d.Post {
+		name += "Post"
+	}
+	return toSnakeCase(name)
+}
+
+// Validate checks the decorator can be generated.
+func (d AnteDecorator) Validate() error {
+	if !decoratorNameRegex.MatchString(d.Name) {
+		return fmt.Errorf("%w: name %q must only contain letters, digits, '-' & '_'", types.ErrAnteInvalid, d.Name)
+	}
+	if d.After != "" && d.Before != "" {
+		return fmt.Errorf("%w: the decorator can not be both after %q and before %q", types.ErrAnteInvalid, d.After, d.Before)
+	}
+	return nil
+}
+
+// NewAnteDecorator creates the decorator & its test in app/decorators and registers it in the decorators of
+// NewAnteHandler in app/ante.go. A post decorator is registered in the setPostHandler of app/app.go instead, which is
+// changed to chain the post decorators the first time.
+func NewAnteDecorator(logger *slog.Logger, cwd string, d AnteDecorator) error {
+	if err := d.Validate(); err != nil {
+		return err
+	}
+
+	goModName := ReadCurrentGoModuleName(path.Join(cwd, "go.mod"))
+	if goModName == "" {
+		return fmt.Errorf("go.mod not found in %s", cwd)
+	}
+
+	appDir := path.Join(cwd, "app")
+	decoratorsDir := path.Join(appDir, "decorators")
+	if _, err := findGoDecl(decoratorsDir, d.TypeName()); err == nil {
+		return fmt.Errorf("%w: %s in %s", types.ErrAnteExists, d.TypeName(), decoratorsDir)
+	}
+
+	for _, name := range []string{d.FileName() + ".go", d.FileName() + "_test.go"} {
+		if _, err := os.Stat(path.Join(decoratorsDir, name)); err == nil {
+			return fmt.Errorf("%w: %s", types.ErrAnteExists, path.Join(decoratorsDir, name))
+		}
+	}
+
+	constructor := fmt.Sprintf("decorators.New%s()", d.TypeName())
+	if err := d.register(appDir, goModName, constructor); err != nil {
+		return err
+	}
+
+	files := map[string]string{
+		d.FileName() + ".go":      d.decoratorSource(),
+		d.FileName() + "_test.go": d.testSource(goModName),
+	}
+	for name, src := range files {
+		loc := path.Join(decoratorsDir, name)
+		formatted, err := format.Source([]byte(src))
+		if err != nil {
+			return fmt.Errorf("formatting %s: %w", name, err)
+		}
+		if err := os.MkdirAll(decoratorsDir, 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(loc, formatted, 0644); err != nil {
+			return err
+		}
+		logger.Debug("decorator file", "path", loc)
+	}
+
+	return nil
+}
+
+// register adds the decorator to the ante handler, or the post handler.
+func (d AnteDecorator) register(appDir, goModName, constructor string) error {
+	if d.Post {
+		return addPostDecorator(appDir, goModName, constructor, d.After, d.Before)
+	}
+
+	err := editGoFunc(appDir, "NewAnteHandler", func(fset *token.FileSet, f *ast.File, src []byte, fn *ast.FuncDecl) ([]sourceEdit, error) {
+		decorators := anteDecoratorsLit(fn)
+		if decorators == nil {
+			return nil, fmt.Errorf("%w: the []sdk.AnteDecorator of NewAnteHandler not found", types.ErrAnteInvalid)
+		}
+
+		edit, err := placeDecorator(fset, src, decorators, constructor, d.After, d.Before)
+		return []sourceEdit{edit}, err
+	})
+	if err != nil {
+		return err
+	}
+
+	return addAppGoImports(appDir, "NewAnteHandler", []string{goModName + "/app/decorators"})
+}
+
+// placeDecorator adds the decorator to the chain after or before the first decorator containing the text, or at the
+// end of the chain.
+func placeDecorator(fset *token.FileSet, src []byte, decorators *ast.CompositeLit, decorator, after, before string) (sourceEdit, error) {
+	switch {
+	case after != "":
+		elt := findDecorator(decorators, after)
+		if elt == nil {
+			return sourceEdit{}, fmt.Errorf("%w: no decorator of the chain contains %q", types.ErrAnteInvalid, after)
+		}
+		return insertAfterDecorator(fset, src, elt, decorator), nil
+	case before != "":
+		elt := findDecorator(decorators, before)
+		if elt == nil {
+			return sourceEdit{}, fmt.Errorf("%w: no decorator of the chain contains %q", types.ErrAnteInvalid, before)
+		}
+		return lineStartEdit(src, fset.Position(elt.Pos()).Offset, decorator+",\n"), nil
+	}
+	return appendCompositeElt(fset, src, decorators, decorator), nil
+}
+
+// addPostDecorator adds the decorator to the []sdk.PostDecorator of setPostHandler. The default SDK post handler
+// has no decorators, so it is replaced with a chain of them the first time.
+func addPostDecorator(appDir, goModName, decorator, after, before string) error {
+	err := editGoFunc(appDir, "setPostHandler", func(fset *token.FileSet, f *ast.File, src []byte, fn *ast.FuncDecl) ([]sourceEdit, error) {
+		if fn == nil {
+			return nil, fmt.Errorf("%w: setPostHandler not found", types.ErrAnteInvalid)
+		}
+
+		var decorators *ast.CompositeLit
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			if l, ok := n.(*ast.CompositeLit); ok && gotypes.ExprString(l.Type) == "[]sdk.PostDecorator" {
+				decorators = l
+			}
+			return decorators == nil
+		})
+		if decorators != nil {
+			edit, err := placeDecorator(fset, src, decorators, decorator, after, before)
+			return []sourceEdit{edit}, err
+		}
+
+		lbrace, rbrace := fset.Position(fn.Body.Lbrace).Offset, fset.Position(fn.Body.Rbrace).Offset
+		body := fmt.Sprintf(`
+	postDecorators := []sdk.PostDecorator{
+		%s,
+	}
+
+	app.SetPostHandler(sdk.ChainPostDecorators(postDecorators...))
+`, decorator)
+		return []sourceEdit{{offset: lbrace + 1, remove: rbrace - lbrace - 1, text: body}}, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	loc, err := findGoDecl(appDir, "setPostHandler")
+	if err != nil {
+		return err
+	}
+	src, err := os.ReadFile(loc)
+	if err != nil {
+		return err
+	}
+	// the posthandler package of the default post handler is not used anymore
+	out, err := removeUnusedImports(loc, src)
+	if err != nil {
+		return err
+	}
+	if out, err = addGoImports(loc, out, []string{goModName + "/app/decorators"}); err != nil {
+		return err
+	}
+	return os.WriteFile(loc, out, 0644)
+}
+
+func (d AnteDecorator) decoratorSource() string {
+	if d.Post {
+		return fmt.Sprintf(`package decorators
+
+import (
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+var _ sdk.PostDecorator = %[1]s{}
+
+// %[1]s is a post handler decorator of app.go, run after the messages of the transaction in the same store
+// branch. Its state changes are reverted with the messages if it errors.
+type %[1]s struct{}
+
+// New%[1]s returns a new %[1]s.
+func New%[1]s() %[1]s {
+	return %[1]s{}
+}
+
+func (d %[1]s) PostHandle(ctx sdk.Context, tx sdk.Tx, simulate, success bool, next sdk.PostHandler) (sdk.Context, error) {
+	// TODO: act on the executed transaction, success is false when its messages failed
+	return next(ctx, tx, simulate, success)
+}
+`, d.TypeName())
+	}
+
+	return fmt.Sprintf(`package decorators
+
+import (
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+var _ sdk.AnteDecorator = %[1]s{}
+
+// %[1]s is an ante.go decorator, run before the messages of the transaction.
+type %[1]s struct{}
+
+// New%[1]s returns a new %[1]s.
+func New%[1]s() %[1]s {
+	return %[1]s{}
+}
+
+func (d %[1]s) AnteHandle(ctx sdk.Context, tx sdk.Tx, simulate bool, next sdk.AnteHandler) (sdk.Context, error) {
+	// TODO: check the transaction, an error rejects it
+	return next(ctx, tx, simulate)
+}
+`, d.TypeName())
+}
+
+func (d AnteDecorator) testSource(goModName string) string {
+	handle := "_, err := decorator.AnteHandle(s.ctx, decorators.NewMockTx(msg), false, decorators.EmptyAnte)"
+	if d.Post {
+		handle = `emptyPost := func(ctx sdk.Context, tx sdk.Tx, simulate, success bool) (sdk.Context, error) {
+		return ctx, nil
+	}
+	_, err := decorator.PostHandle(s.ctx, decorators.NewMockTx(msg), false, true, emptyPost)`
+	}
+
+	return fmt.Sprintf(`package decorators_test
+
+import (
+	sdkmath "cosmossdk.io/math"
+
+	"github.com/cometbft/cometbft/crypto/secp256k1"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+
+	"%[1]s/app/decorators"
+)
+
+func (s *AnteTestSuite) Test%[2]s() {
+	acc := sdk.AccAddress(secp256k1.GenPrivKey().PubKey().Address())
+	msg := banktypes.NewMsgSend(acc, acc, sdk.NewCoins(sdk.NewCoin("stake", sdkmath.NewInt(1))))
+
+	decorator := decorators.New%[2]s()
+	%[3]s
+	s.Require().NoError(err)
+}
+`, goModName, d.TypeName(), handle)
+}
@@ -0,0 +1,62 @@
+package spawn
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rollchains/spawn/simapp"
+	"github.com/rollchains/spawn/spawn/types"
+)
+
+func TestAnteDecoratorNames(t *testing.T) {
+	for _, name := range []string{"fee-burn", "fee_burn", "FeeBurn", "feeBurnDecorator"} {
+		d := AnteDecorator{Name: name}
+		require.Equal(t, "FeeBurnDecorator", d.TypeName(), name)
+		require.Equal(t, "fee_burn", d.FileName(), name)
+	}
+
+	post := AnteDecorator{Name: "refund-gas", Post: true}
+	require.Equal(t, "RefundGasPostDecorator", post.TypeName())
+	require.Equal(t, "refund_gas_post", post.FileName())
+
+	require.ErrorIs(t, AnteDecorator{Name: "fee burn"}.Validate(), types.ErrAnteInvalid)
+	require.ErrorIs(t, AnteDecorator{Name: "fee", After: "a", Before: "b"}.Validate(), types.ErrAnteInvalid)
+}
+
+func TestNewAnteDecorator(t *testing.T) {
+	cwd := setupAnteChain(t)
+	bz, err := fs.ReadFile(simapp.SimAppFS, "app/app.go")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path.Join(cwd, "app/app.go"), bz, 0644))
+
+	require.NoError(t, NewAnteDecorator(logger, cwd, AnteDecorator{Name: "fee-burn", After: "NewDeductFeeDecorator"}))
+	require.NoError(t, NewAnteDecorator(logger, cwd, AnteDecorator{Name: "min-commission", Before: "NewIncrementSequenceDecorator"}))
+	require.NoError(t, NewAnteDecorator(logger, cwd, AnteDecorator{Name: "gasless"}))
+
+	require.Contains(t, readTestFile(t, cwd, "app/decorators/fee_burn.go"), "func (d FeeBurnDecorator) AnteHandle(")
+	require.Contains(t, readTestFile(t, cwd, "app/decorators/fee_burn_test.go"), "func (s *AnteTestSuite) TestFeeBurnDecorator() {")
+
+	ante := readTestFile(t, cwd, "app/ante.go")
+	require.Contains(t, ante, `"github.com/rollchains/spawn/simapp/app/decorators"`)
+	require.Contains(t, ante, "options.TxFeeChecker),\n\t\tdecorators.NewFeeBurnDecorator(),\n")
+	require.Contains(t, ante, "decorators.NewMinCommissionDecorator(),\n\t\tante.NewIncrementSequenceDecorator(")
+	require.Contains(t, ante, "decorators.NewGaslessDecorator(),\n\t}")
+
+	require.NoError(t, NewAnteDecorator(logger, cwd, AnteDecorator{Name: "refund-gas", Post: true}))
+	require.NoError(t, NewAnteDecorator(logger, cwd, AnteDecorator{Name: "first", Post: true, Before: "RefundGas"}))
+	require.Contains(t, readTestFile(t, cwd, "app/decorators/refund_gas_post.go"), "func (d RefundGasPostDecorator) PostHandle(")
+
+	appGo := readTestFile(t, cwd, "app/app.go")
+	require.Contains(t, appGo, "postDecorators := []sdk.PostDecorator{\n\t\tdecorators.NewFirstPostDecorator(),\n\t\tdecorators.NewRefundGasPostDecorator(),\n\t}")
+	require.Contains(t, appGo, "app.SetPostHandler(sdk.ChainPostDecorators(postDecorators...))")
+	require.False(t, strings.Contains(appGo, "posthandler"))
+
+	require.ErrorIs(t, NewAnteDecorator(logger, cwd, AnteDecorator{Name: "FeeBurn"}), types.ErrAnteExists)
+	require.ErrorIs(t, NewAnteDecorator(logger, cwd, AnteDecorator{Name: "unplaced", After: "NewUnknownDecorator"}), types.ErrAnteInvalid)
+	require.NoFileExists(t, path.Join(cwd, "app/decorators/unplaced.go"))
+}
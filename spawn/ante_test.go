@@ -0,0 +1,73 @@
+package spawn
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rollchains/spawn/simapp"
+	"github.com/rollchains/spawn/spawn/types"
+)
+
+// setupAnteChain copies the go.mod & ante handler of the simapp.
+func setupAnteChain(t *testing.T) string {
+	t.Helper()
+
+	cwd := t.TempDir()
+	for _, relPath := range []string{"go.mod", "app/ante.go"} {
+		bz, err := fs.ReadFile(simapp.SimAppFS, relPath)
+		require.NoError(t, err)
+
+		loc := path.Join(cwd, relPath)
+		require.NoError(t, os.MkdirAll(path.Dir(loc), 0755))
+		require.NoError(t, os.WriteFile(loc, bz, 0644))
+	}
+
+	return cwd
+}
+
+func TestMsgFilterValidate(t *testing.T) {
+	require.NoError(t, MsgFilter{MsgTypeURLs: []string{"/cosmos.bank.v1beta1.MsgSend"}, UntilHeight: 10}.Validate())
+	require.ErrorIs(t, MsgFilter{}.Validate(), types.ErrAnteInvalid)
+	require.ErrorIs(t, MsgFilter{MsgTypeURLs: []string{"cosmos.bank.v1beta1.MsgSend"}}.Validate(), types.ErrAnteInvalid)
+	require.ErrorIs(t, MsgFilter{MsgTypeURLs: []string{"/MsgSend"}}.Validate(), types.ErrAnteInvalid)
+	require.ErrorIs(t, MsgFilter{MsgTypeURLs: []string{"/cosmos.bank.v1beta1.MsgSend"}, UntilHeight: -1}.Validate(), types.ErrAnteInvalid)
+}
+
+func TestAddAnteMsgFilter(t *testing.T) {
+	cwd := setupAnteChain(t)
+
+	require.NoError(t, AddAnteMsgFilter(cwd, MsgFilter{MsgTypeURLs: []string{"/cosmos.bank.v1beta1.MsgSend"}}))
+
+	ante := readTestFile(t, cwd, "app/ante.go")
+	require.Contains(t, ante, `"github.com/rollchains/spawn/simapp/app/decorators"`)
+	require.Contains(t, ante, "ante.NewSetUpContextDecorator(), // outermost AnteDecorator. SetUpContext must be called first\n\t\tdecorators.NewMsgFilterDecorator(decorators.MsgFilterConfig{")
+	require.Regexp(t, `Mode:\s+decorators.MsgFilterBlockList,`, ante)
+	require.Regexp(t, `MsgTypeURLs: \[\]string\{\n\s+"/cosmos.bank.v1beta1.MsgSend",\n\s+\},`, ante)
+
+	// the messages of the same mode are added to its filter
+	require.NoError(t, AddAnteMsgFilter(cwd, MsgFilter{MsgTypeURLs: []string{"/cosmos.bank.v1beta1.MsgSend", "/cosmos.bank.v1beta1.MsgMultiSend"}, UntilHeight: 100}))
+	ante = readTestFile(t, cwd, "app/ante.go")
+	require.Equal(t, 1, strings.Count(ante, "decorators.NewMsgFilterDecorator("))
+	require.Equal(t, 1, strings.Count(ante, `"/cosmos.bank.v1beta1.MsgSend"`))
+	require.Contains(t, ante, `"/cosmos.bank.v1beta1.MsgMultiSend"`)
+	require.Contains(t, ante, "UntilHeight: 100,")
+
+	// a single line list is rewritten with one message per line
+	require.NoError(t, os.WriteFile(path.Join(cwd, "app/ante.go"), []byte(regexp.MustCompile(`\[\]string\{[^}]*\}`).ReplaceAllString(ante, `[]string{"/cosmos.bank.v1beta1.MsgSend"}`)), 0644))
+	require.NoError(t, AddAnteMsgFilter(cwd, MsgFilter{MsgTypeURLs: []string{"/cosmos.bank.v1beta1.MsgMultiSend"}}))
+	ante = readTestFile(t, cwd, "app/ante.go")
+	require.Regexp(t, `MsgTypeURLs: \[\]string\{\n\s+"/cosmos.bank.v1beta1.MsgSend",\n\s+"/cosmos.bank.v1beta1.MsgMultiSend",\n\s+\},`, ante)
+
+	require.NoError(t, AddAnteMsgFilter(cwd, MsgFilter{MsgTypeURLs: []string{"/cosmos.gov.v1.MsgVote"}, AllowList: true}))
+	ante = readTestFile(t, cwd, "app/ante.go")
+	require.Equal(t, 2, strings.Count(ante, "decorators.NewMsgFilterDecorator("))
+	require.Regexp(t, `Mode:\s+decorators.MsgFilterAllowList,`, ante)
+
+	require.ErrorIs(t, AddAnteMsgFilter(cwd, MsgFilter{MsgTypeURLs: []string{"MsgSend"}}), types.ErrAnteInvalid)
+}
@@ -0,0 +1,272 @@
+package spawn
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"log/slog"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// autoCLIArg is a positional argument of an autocli command.
+type autoCLIArg struct {
+	field   string
+	varargs bool
+}
+
+// GenerateModuleAutoCLI generates or updates the RpcCommandOptions of the module's autocli.go from its proto services.
+// Each unary RPC gets a command with positional args for the fields of its request. Existing commands only have a
+// missing Use & PositionalArgs filled in, customized ones & commands set to Skip are left as is.
+func GenerateModuleAutoCLI(logger *slog.Logger, cwd, module string) error {
+	files, err := parseProtoDir(logger, path.Join(cwd, "proto"))
+	if err != nil {
+		return err
+	}
+
+	var rpcs []*ProtoRPC
+	services := make(map[FileType]*ProtoRPC)
+	apiPath := ""
+	for _, pf := range files {
+		if pf.Module() != module {
+			continue
+		}
+
+		for _, rpc := range pf.RPCs {
+			if rpc.ClientStreaming || rpc.ServerStreaming {
+				logger.Debug("skipping streaming rpc", "rpc", rpc.Name)
+				continue
+			}
+
+			rpcs = append(rpcs, rpc)
+			if _, ok := services[rpc.FType]; !ok {
+				services[rpc.FType] = rpc
+				apiPath = path.Dir(pf.Name)
+			}
+		}
+	}
+
+	if len(rpcs) == 0 {
+		return fmt.Errorf("no Msg or Query RPCs found in proto/%s", module)
+	}
+
+	cfg, err := LoadStubGenConfig(cwd)
+	if err != nil {
+		return err
+	}
+	moduleDir := cfg.ModuleDir(cwd, module)
+
+	loc := path.Join(moduleDir, "autocli.go")
+	if _, err := os.Stat(loc); os.IsNotExist(err) {
+		goModName := ReadCurrentGoModuleName(path.Join(cwd, "go.mod"))
+		src := newAutoCLIFile(moduleDir, path.Join(goModName, "api", apiPath), services)
+
+		if err := os.WriteFile(loc, []byte(src), 0644); err != nil {
+			return err
+		}
+		logger.Debug("created autocli.go", "file", loc)
+	}
+
+	return editGoFile(loc, func(fset *token.FileSet, f *ast.File, src []byte) []sourceEdit {
+		options := autoCLIOptions(f)
+
+		var edits []sourceEdit
+		for _, rpc := range rpcs {
+			cmds, ok := options[rpc.FType]
+			if !ok {
+				logger.Warn("autocli.go has no service descriptor for the rpc", "rpc", rpc.Name, "type", rpc.FType, "file", loc)
+				continue
+			}
+
+			opt := autoCLIOption(cmds, rpc.Name)
+			if opt == nil {
+				edits = append(edits, appendCompositeElt(fset, src, cmds, autoCLIEntry(rpc)))
+				continue
+			}
+
+			// a set Use is customized, its positional args are described by it
+			if compositeField(opt, "Skip") == "true" || compositeField(opt, "Use") != "" {
+				continue
+			}
+
+			args := autoCLIPositionalArgs(rpc)
+			edits = append(edits, setCompositeField(fset, src, opt, "Use", strconv.Quote(autoCLIUse(rpc.Name, args))))
+			if len(args) > 0 && compositeField(opt, "PositionalArgs") == "" {
+				edits = append(edits, setCompositeField(fset, src, opt, "PositionalArgs", autoCLIPositionalArgsCode(args)))
+			}
+		}
+		return edits
+	})
+}
+
+// autoCLIEntry returns the RpcCommandOptions of the rpc.
+func autoCLIEntry(rpc *ProtoRPC) string {
+	short := fmt.Sprintf("Broadcast the %s message", rpc.Req)
+	if rpc.FType == Query {
+		short = fmt.Sprintf("Query %s", strings.ReplaceAll(toSnakeCase(rpc.Name), "_", " "))
+	}
+
+	args := autoCLIPositionalArgs(rpc)
+
+	entry := fmt.Sprintf("{\nRpcMethod: %q,\nUse: %q,\nShort: %q,\n", rpc.Name, autoCLIUse(rpc.Name, args), short)
+	if len(args) > 0 {
+		entry += fmt.Sprintf("PositionalArgs: %s,\n", autoCLIPositionalArgsCode(args))
+	}
+	return entry + "}"
+}
+
+// autoCLIPositionalArgs returns the request fields to set as positional args. The signer is set from --from,
+// pagination & map fields are left as flags. Repeated fields can only be the last argument.
+func autoCLIPositionalArgs(rpc *ProtoRPC) []autoCLIArg {
+	if rpc.ReqMsg == nil {
+		return nil
+	}
+
+	signer := ""
+	if rpc.FType == Tx && len(rpc.ReqMsg.Signers) > 0 {
+		signer = rpc.ReqMsg.Signers[0]
+	}
+
+	var fields []ProtoField
+	for _, f := range rpc.ReqMsg.Fields {
+		if f.Name == signer || strings.HasSuffix(f.Type, "PageRequest") || (f.Repeated && strings.HasSuffix(f.Type, "Entry")) {
+			continue
+		}
+		fields = append(fields, f)
+	}
+
+	var args []autoCLIArg
+	for i, f := range fields {
+		if f.Repeated && i != len(fields)-1 {
+			continue
+		}
+		args = append(args, autoCLIArg{field: f.Name, varargs: f.Repeated})
+	}
+	return args
+}
+
+// autoCLIUse returns the command usage (swap [pool_id] [amount]...).
+func autoCLIUse(rpcName string, args []autoCLIArg) string {
+	use := toKebabCase(rpcName)
+	for _, arg := range args {
+		use += fmt.Sprintf(" [%s]", arg.field)
+		if arg.varargs {
+			use += "..."
+		}
+	}
+	return use
+}
+
+func autoCLIPositionalArgsCode(args []autoCLIArg) string {
+	descs := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg.varargs {
+			descs = append(descs, fmt.Sprintf("{ProtoField: %q, Varargs: true}", arg.field))
+		} else {
+			descs = append(descs, fmt.Sprintf("{ProtoField: %q}", arg.field))
+		}
+	}
+	return fmt.Sprintf("[]*autocliv1.PositionalArgDescriptor{%s}", strings.Join(descs, ", "))
+}
+
+// newAutoCLIFile returns an autocli.go with an empty descriptor for each service. Modules with their own tx or query
+// command (GetTxCmd, GetQueryCmd) have them enhanced with the autocli commands.
+func newAutoCLIFile(moduleDir, apiImport string, services map[FileType]*ProtoRPC) string {
+	pkgName := path.Base(moduleDir)
+	custom := make(map[string]bool)
+
+	entries, _ := os.ReadDir(moduleDir)
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".go") || strings.HasSuffix(e.Name(), "_test.go") {
+			continue
+		}
+
+		f, err := parser.ParseFile(token.NewFileSet(), path.Join(moduleDir, e.Name()), nil, 0)
+		if err != nil {
+			continue
+		}
+		pkgName = f.Name.Name
+
+		for _, decl := range f.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok {
+				custom[fn.Name.Name] = true
+			}
+		}
+	}
+
+	var descs strings.Builder
+	for _, svc := range []struct {
+		key    string
+		ft     FileType
+		custom string
+	}{{"Query", Query, "GetQueryCmd"}, {"Tx", Tx, "GetTxCmd"}} {
+		rpc, ok := services[svc.ft]
+		if !ok {
+			continue
+		}
+
+		enhance := ""
+		if custom[svc.custom] {
+			enhance = "\t\t\tEnhanceCustomCommand: true,\n"
+		}
+
+		fmt.Fprintf(&descs, `		%s: &autocliv1.ServiceCommandDescriptor{
+			Service:           modulev1.%s_ServiceDesc.ServiceName,
+%s			RpcCommandOptions: []*autocliv1.RpcCommandOptions{},
+		},
+`, svc.key, rpc.serviceName(), enhance)
+	}
+
+	return fmt.Sprintf(`package %s
+
+import (
+	autocliv1 "cosmossdk.io/api/cosmos/autocli/v1"
+	modulev1 %q
+)
+
+// AutoCLIOptions implements the autocli.HasAutoCLIConfig interface.
+func (am AppModule) AutoCLIOptions() *autocliv1.ModuleOptions {
+	return &autocliv1.ModuleOptions{
+%s	}
+}
+`, pkgName, apiImport, descs.String())
+}
+
+// appendCompositeElt adds an element to the end of a composite literal.
+func appendCompositeElt(fset *token.FileSet, src []byte, lit *ast.CompositeLit, elt string) sourceEdit {
+	if len(lit.Elts) == 0 {
+		return sourceEdit{offset: fset.Position(lit.Lbrace).Offset + 1, text: "\n" + elt + ",\n"}
+	}
+
+	end := fset.Position(lit.Elts[len(lit.Elts)-1].End()).Offset
+	if hasTrailingComma(src, end) {
+		return sourceEdit{offset: fset.Position(lit.Rbrace).Offset, text: elt + ",\n"}
+	}
+	return sourceEdit{offset: end, text: ",\n" + elt + ",\n"}
+}
+
+// setCompositeField replaces the value of a key in a composite literal, or adds the key when it is not set.
+func setCompositeField(fset *token.FileSet, src []byte, lit *ast.CompositeLit, key, value string) sourceEdit {
+	for _, elt := range lit.Elts {
+		if kv, ok := elt.(*ast.KeyValueExpr); ok && compositeKey(kv) == key {
+			return replaceEdit(fset, kv.Value, value)
+		}
+	}
+
+	return appendCompositeElt(fset, src, lit, key+": "+value)
+}
+
+func compositeKey(kv *ast.KeyValueExpr) string {
+	if id, ok := kv.Key.(*ast.Ident); ok {
+		return id.Name
+	}
+	return ""
+}
+
+func hasTrailingComma(src []byte, offset int) bool {
+	rest := strings.TrimLeft(string(src[offset:]), " \t")
+	return strings.HasPrefix(rest, ",")
+}
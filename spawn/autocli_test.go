@@ -0,0 +1,109 @@
+package spawn
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const autoCLITxProto = `syntax = "proto3";
+package amm.v1;
+
+import "cosmos/msg/v1/msg.proto";
+import "cosmos/base/v1beta1/coin.proto";
+
+option go_package = "github.com/rollchains/mychain/x/amm/types";
+
+service Msg {
+  option (cosmos.msg.v1.service) = true;
+
+  rpc UpdateParams(MsgUpdateParams) returns (MsgUpdateParamsResponse);
+  rpc AddLiquidity(MsgAddLiquidity) returns (MsgAddLiquidityResponse);
+  rpc Batch(MsgBatch) returns (MsgBatchResponse);
+}
+
+message MsgUpdateParams {
+  option (cosmos.msg.v1.signer) = "authority";
+  string authority = 1;
+  Params params = 2;
+}
+message MsgUpdateParamsResponse {}
+message Params {}
+
+message MsgAddLiquidity {
+  option (cosmos.msg.v1.signer) = "sender";
+  string sender = 1;
+  uint64 pool_id = 2;
+  repeated cosmos.base.v1beta1.Coin amounts = 3;
+}
+message MsgAddLiquidityResponse {}
+
+message MsgBatch {
+  option (cosmos.msg.v1.signer) = "sender";
+  repeated string ids = 1;
+  string sender = 2;
+  string memo = 3;
+}
+message MsgBatchResponse {}
+`
+
+func TestGenerateModuleAutoCLI(t *testing.T) {
+	cwd := setupStubGenModule(t, autoCLITxProto)
+
+	require.NoError(t, GenerateModuleAutoCLI(logger, cwd, "amm"))
+
+	autocli := readModuleFile(t, cwd, "autocli.go")
+	require.Contains(t, autocli, `Use:            "add-liquidity [pool_id] [amounts]...",`)
+	require.Contains(t, autocli, `PositionalArgs: []*autocliv1.PositionalArgDescriptor{{ProtoField: "pool_id"}, {ProtoField: "amounts", Varargs: true}},`)
+	require.Contains(t, autocli, `Use:            "batch [memo]",`, "repeated fields are only positional as the last arg")
+	require.Contains(t, autocli, `PositionalArgs: []*autocliv1.PositionalArgDescriptor{{ProtoField: "params"}},`)
+	require.Contains(t, autocli, "Skip:           false, // set to true if authority gated", "existing options are kept")
+	require.Contains(t, autocli, `Short:     "Query the current consensus parameters",`, "rpcs of other files are untouched")
+	require.Less(t, strings.Index(autocli, `"AddLiquidity"`), strings.Index(autocli, `"Batch"`), "commands follow the proto order")
+
+	// regenerating does not change anything
+	require.NoError(t, GenerateModuleAutoCLI(logger, cwd, "amm"))
+	require.Equal(t, autocli, readModuleFile(t, cwd, "autocli.go"))
+
+	// customized commands are left as is
+	loc := path.Join(cwd, "x", "amm", "autocli.go")
+	custom := strings.Replace(autocli, `"add-liquidity [pool_id] [amounts]..."`, `"add-liq [pool] [coins]..."`, 1)
+	require.NoError(t, os.WriteFile(loc, []byte(custom), 0644))
+	require.NoError(t, GenerateModuleAutoCLI(logger, cwd, "amm"))
+	require.Equal(t, custom, readModuleFile(t, cwd, "autocli.go"))
+
+	// skipped commands are left as is
+	require.NoError(t, os.WriteFile(loc, []byte(strings.Replace(autocli, "Skip:           false,", "Skip: true,", 1)), 0644))
+	require.NoError(t, GenerateModuleAutoCLI(logger, cwd, "amm"))
+	require.Contains(t, readModuleFile(t, cwd, "autocli.go"), `PositionalArgs: []*autocliv1.PositionalArgDescriptor{{ProtoField: "params"}},`)
+
+	require.Error(t, GenerateModuleAutoCLI(logger, cwd, "unknown"))
+}
+
+func TestGenerateModuleAutoCLICreatesFile(t *testing.T) {
+	cwd := t.TempDir()
+
+	writeTestFiles(t, cwd, map[string]string{
+		"go.mod":                "module github.com/rollchains/mychain\n\ngo 1.22\n",
+		"proto/amm/v1/tx.proto": autoCLITxProto,
+		"x/amm/module.go": `package amm
+
+func (AppModuleBasic) GetTxCmd() *cobra.Command {
+	return cli.NewTxCmd()
+}
+`,
+	})
+
+	require.NoError(t, GenerateModuleAutoCLI(logger, cwd, "amm"))
+
+	autocli := readModuleFile(t, cwd, "autocli.go")
+	require.Contains(t, autocli, "package amm")
+	require.Contains(t, autocli, `modulev1 "github.com/rollchains/mychain/api/amm/v1"`)
+	require.Contains(t, autocli, "Service:              modulev1.Msg_ServiceDesc.ServiceName,")
+	require.Contains(t, autocli, "EnhanceCustomCommand: true,")
+	require.Contains(t, autocli, `RpcMethod:      "AddLiquidity",`)
+	require.NotContains(t, autocli, "Query:", "there is no query service")
+}
@@ -1,7 +1,6 @@
 package spawn
 
 import (
-	"embed"
 	"fmt"
 	"io/fs"
 	"log/slog"
@@ -11,7 +10,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/rollchains/spawn/simapp"
 	"github.com/rollchains/spawn/spawn/types"
 	localictypes "github.com/strangelove-ventures/interchaintest/local-interchain/interchain/types"
 	"github.com/strangelove-ventures/interchaintest/v8/chain/cosmos"
@@ -60,7 +58,28 @@ type NewChainConfig struct {
 	// IgnoreGitInit is a flag to ignore git init
 	IgnoreGitInit   bool
 	DisabledModules []string
-	Logger          *slog.Logger
+	// VersionProfile is the Cosmos SDK + ibc-go stack to generate for (e.g. v0.50). Empty uses the default.
+	VersionProfile string
+	// Wiring is how the modules are wired into the app (manual or depinject). Empty uses manual.
+	Wiring string
+	// MsgFilter is added to the ante handler when it has messages
+	MsgFilter MsgFilter
+	Logger    *slog.Logger
+}
+
+// Profile returns the version profile (templates & stack versions) the chain is generated with.
+func (cfg NewChainConfig) Profile() (VersionProfile, error) {
+	return GetVersionProfile(cfg.VersionProfile)
+}
+
+// StackVersions returns the Cosmos SDK, ibc-go, CometBFT and CosmWasm versions the chain is generated with.
+func (cfg NewChainConfig) StackVersions() (StackVersions, error) {
+	p, err := cfg.Profile()
+	if err != nil {
+		return StackVersions{}, err
+	}
+
+	return p.Versions()
 }
 
 // NodeHome returns the full path to the node home directory
@@ -95,6 +114,15 @@ func (cfg *NewChainConfig) SetProperFeaturePairs() {
 		d = append(d, POA)
 	}
 
+	if cfg.Wiring == WiringDepinject {
+		for _, feat := range DepinjectUnsupportedFeatures {
+			if cfg.IsFeatureEnabled(feat) {
+				cfg.Logger.Warn("Feature is not supported with depinject wiring, disabling it", "feature", feat)
+				d = append(d, feat)
+			}
+		}
+	}
+
 	cfg.DisabledModules = d
 	cfg.Logger.Debug("SetProperFeaturePairs Disabled features", "features", cfg.DisabledModules)
 }
@@ -143,6 +171,33 @@ func (cfg *NewChainConfig) Validate() error {
 		return types.ErrExpectedRange(types.ErrCfgHomeDirTooShort, minHomeLen, len(cfg.HomeDir))
 	}
 
+	profile, err := cfg.Profile()
+	if err != nil {
+		return err
+	}
+	if _, err := profile.Versions(); err != nil {
+		return err
+	}
+	cfg.VersionProfile = profile.Name
+
+	switch cfg.Wiring {
+	case "":
+		cfg.Wiring = WiringManual
+	case WiringManual, WiringDepinject:
+	default:
+		return fmt.Errorf("%w: %s (available: %s)", types.ErrCfgUnknownWiring, cfg.Wiring, strings.Join(Wirings, ", "))
+	}
+
+	if cfg.Wiring == WiringDepinject && cfg.IsFeatureEnabled(InterchainSecurity) {
+		return fmt.Errorf("%w: %s with %s wiring", types.ErrCfgWiringUnsupported, InterchainSecurity, cfg.Wiring)
+	}
+
+	if len(cfg.MsgFilter.MsgTypeURLs) > 0 {
+		if err := cfg.MsgFilter.Validate(); err != nil {
+			return err
+		}
+	}
+
 	if cfg.Logger == nil {
 		cfg.Logger = slog.Default()
 	}
@@ -174,7 +229,7 @@ func (cfg *NewChainConfig) CreateNewChain() error {
 	// Set proper pairings for modules to be disabled if others are enabled
 	cfg.SetProperFeaturePairs()
 
-	logger.Info("Spawning new app", "name", NewDirName)
+	logger.Info("Spawning new app", "name", NewDirName, "version-profile", cfg.VersionProfile, "wiring", cfg.Wiring)
 	logger.Debug("NewChain Disabled features", "features", cfg.DisabledModules)
 
 	if err := os.MkdirAll(NewDirName, 0755); err != nil {
@@ -188,6 +243,13 @@ func (cfg *NewChainConfig) CreateNewChain() error {
 		return fmt.Errorf("error setting up main chain app: %w", err)
 	}
 
+	if len(cfg.MsgFilter.MsgTypeURLs) > 0 {
+		logger.Info("Adding the message filter to the ante handler", "mode", cfg.MsgFilter.Mode(), "msgs", cfg.MsgFilter.MsgTypeURLs)
+		if err := AddAnteMsgFilter(NewDirName, cfg.MsgFilter); err != nil {
+			return fmt.Errorf("error adding the message filter: %w", err)
+		}
+	}
+
 	logger.Info("Setting up interchain test", "name", NewDirName, "note", "this may take a minute while it downloads dependencies")
 	if err := cfg.SetupInterchainTest(); err != nil {
 		logger.Error("Error setting up interchain test", "err", err, "file", debugErrorFile(logger, NewDirName))
@@ -195,33 +257,45 @@ func (cfg *NewChainConfig) CreateNewChain() error {
 	}
 
 	logger.Info("Setting up chain metadata")
-	cfg.MetadataFile().SaveJSON(fmt.Sprintf("%s/chain_metadata.json", NewDirName))
-	cfg.ChainRegistryFile().SaveJSON(fmt.Sprintf("%s/chain_registry.json", NewDirName))
-	cfg.ChainRegistryAssetsFile().SaveJSON(fmt.Sprintf("%s/chain_registry_assets.json", NewDirName))
+	cfg.MetadataFile().SaveJSON(path.Join(NewDirName, ChainMetadataFileName))
+	cfg.ChainRegistryFile().SaveJSON(path.Join(NewDirName, ChainRegistryFileName))
+	cfg.ChainRegistryAssetsFile().SaveJSON(path.Join(NewDirName, ChainRegistryAssetsFileName))
+	if err := ValidateChainRegistryDir(NewDirName); err != nil {
+		return fmt.Errorf("error validating chain registry files: %w", err)
+	}
 
 	// setup local-interchain testnets
 	// *testnet.json (chains/ directory)
 	logger.Info("Setting up local interchain JSON")
 	cfg.SetupLocalInterchainJSON()
 
+	if cfg.IsFeatureEnabled(BlockExplorer) {
+		logger.Info("Setting up the ping.pub explorer config")
+		if err := cfg.NewPingPubExplorer(); err != nil {
+			logger.Error("Error setting up the explorer", "err", err)
+			return fmt.Errorf("error setting up the explorer: %w", err)
+		}
+	}
+
 	cfg.MakeModTidy()
 
 	if !cfg.IgnoreGitInit {
 		cfg.GitInitNewProjectRepo()
 	}
 
-	if cfg.IsFeatureEnabled("block-explorer") {
-		cfg.NewPingPubExplorer()
-	}
-
 	return nil
 }
 
 func (cfg *NewChainConfig) SetupMainChainApp() error {
 	newDirName := cfg.ProjectName
 
-	simappFS := simapp.SimAppFS
-	return fs.WalkDir(simappFS, ".", func(relPath string, d fs.DirEntry, e error) error {
+	profile, err := cfg.Profile()
+	if err != nil {
+		return err
+	}
+
+	simappFS := profile.AppFS
+	err = fs.WalkDir(simappFS, ".", func(relPath string, d fs.DirEntry, e error) error {
 		newPath := path.Join(newDirName, relPath)
 		fc, err := GetFileContent(cfg.Logger, newPath, simappFS, relPath, d)
 		if err != nil {
@@ -230,40 +304,71 @@ func (cfg *NewChainConfig) SetupMainChainApp() error {
 			return nil
 		}
 
-		// .github/workflows/interchaintest-e2e.yml (required to replace docker image in workflow)
-		fc.ReplaceGithubActionWorkflows(cfg)
-		// Dockerfile
-		fc.ReplaceDockerFile(cfg)
-		// scripts/test_node.sh
-		fc.ReplaceTestNodeScript(cfg)
-		// app/app.go
-		fc.ReplaceApp(cfg)
-		// Makefile
-		fc.ReplaceMakeFile(cfg)
-		// *All Files
-		fc.ReplaceEverywhere(cfg)
-		// Removes any modules we care nothing about
-		fc.RemoveDisabledFeatures(cfg)
+		return cfg.saveAppFile(fc)
+	})
+	if err != nil || cfg.Wiring != WiringDepinject {
+		return err
+	}
 
-		errFileText = fc.Contents
-		if err := fc.FormatGoFile(); err != nil {
-			return err
+	// depinject replaces the app/ files of the manually wired app.
+	wiringFS := profile.WiringFS
+	wiringDir := path.Join(WiringDir, cfg.Wiring)
+	return fs.WalkDir(wiringFS, wiringDir, func(relPath string, d fs.DirEntry, e error) error {
+		if e != nil {
+			return e
+		} else if d.IsDir() {
+			return nil
 		}
 
-		if err := fc.Save(); err != nil {
+		appRelPath := strings.TrimSuffix(strings.TrimPrefix(relPath, wiringDir+"/"), ".tmpl")
+		fc := NewFileContent(cfg.Logger, appRelPath, path.Join(newDirName, appRelPath))
+
+		bz, err := fs.ReadFile(wiringFS, relPath)
+		if err != nil {
 			return err
 		}
+		fc.Contents = string(bz)
 
-		return nil
+		return cfg.saveAppFile(fc)
 	})
 }
 
+// saveAppFile replaces the template values of an app file, removes the disabled features & saves it.
+func (cfg *NewChainConfig) saveAppFile(fc *FileContent) error {
+	// .github/workflows/interchaintest-e2e.yml (required to replace docker image in workflow)
+	fc.ReplaceGithubActionWorkflows(cfg)
+	// Dockerfile
+	fc.ReplaceDockerFile(cfg)
+	// scripts/test_node.sh
+	fc.ReplaceTestNodeScript(cfg)
+	// app/app.go
+	fc.ReplaceApp(cfg)
+	// Makefile
+	fc.ReplaceMakeFile(cfg)
+	// *All Files
+	fc.ReplaceEverywhere(cfg)
+	// Removes any modules we care nothing about
+	fc.RemoveDisabledFeatures(cfg)
+
+	errFileText = fc.Contents
+	if err := fc.FormatGoFile(); err != nil {
+		return err
+	}
+
+	return fc.Save()
+}
+
 func (cfg *NewChainConfig) SetupInterchainTest() error {
 	newDirName := cfg.ProjectName
 
 	// Interchaintest e2e is a nested submodule. go.mod is renamed to go.mod_ to avoid conflicts
 	// It will be unwound during unpacking to properly nest it.
-	ictestFS := simapp.ICTestFS
+	profile, err := cfg.Profile()
+	if err != nil {
+		return err
+	}
+
+	ictestFS := profile.ICTestFS
 	return fs.WalkDir(ictestFS, ".", func(relPath string, d fs.DirEntry, e error) error {
 		newPath := path.Join(newDirName, relPath)
 
@@ -410,7 +515,7 @@ func RemoveDuplicates(disabled []string) []string {
 	return newDisabled
 }
 
-func GetFileContent(logger *slog.Logger, newFilePath string, fs embed.FS, relPath string, d fs.DirEntry) (*FileContent, error) {
+func GetFileContent(logger *slog.Logger, newFilePath string, fsys fs.FS, relPath string, d fs.DirEntry) (*FileContent, error) {
 	if relPath == "." {
 		return nil, nil
 	}
@@ -427,7 +532,7 @@ func GetFileContent(logger *slog.Logger, newFilePath string, fs embed.FS, relPat
 	}
 
 	// Read the file contents from the embedded FS
-	if fileContent, err := fs.ReadFile(relPath); err != nil {
+	if fileContent, err := fs.ReadFile(fsys, relPath); err != nil {
 		return nil, err
 	} else {
 		fc.Contents = string(fileContent)
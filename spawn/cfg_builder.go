@@ -29,3 +29,18 @@ func (s NewChainConfig) WithBinDaemon(bin string) NewChainConfig {
 	s.BinDaemon = bin
 	return s
 }
+
+func (s NewChainConfig) WithVersionProfile(profile string) NewChainConfig {
+	s.VersionProfile = profile
+	return s
+}
+
+func (s NewChainConfig) WithWiring(wiring string) NewChainConfig {
+	s.Wiring = wiring
+	return s
+}
+
+func (s NewChainConfig) WithDisabledModules(disabled ...string) NewChainConfig {
+	s.DisabledModules = disabled
+	return s
+}
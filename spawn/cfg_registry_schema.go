@@ -21,20 +21,22 @@ const (
 	DefaultNetworkType               = "testnet" // or mainnet
 	DefaultSlip44CoinType            = 118
 	DefaultChainRegistrySchema       = "https://raw.githubusercontent.com/cosmos/chain-registry/master/chain.schema.json"
-	DefaultChainRegistryAssetsSchema = "https://github.com/cosmos/chain-registry/blob/master/assetlist.schema.json"
+	DefaultChainRegistryAssetsSchema = "https://raw.githubusercontent.com/cosmos/chain-registry/master/assetlist.schema.json"
 	DefaultThemeHexColor             = "#FF2D00"
 )
 
 var caser = cases.Title(language.English)
 
 func (cfg NewChainConfig) ChainRegistryFile() types.ChainRegistryFormat {
-	// TODO: update as needed
-	DefaultSDKVersion := "0.50"
-	DefaultTendermintVersion := "0.38"
-	DefaultIBCGoVersion := "8"
-	DefaultCosmWasmVersion := ""
+	// versions are from the version profile templates, which is checked on Validate.
+	versions, err := cfg.StackVersions()
+	if err != nil {
+		cfg.Logger.Error("Error reading version profile", "profile", cfg.VersionProfile, "err", err)
+	}
+
+	cosmWasmVersion := ""
 	if cfg.IsFeatureEnabled(CosmWasm) {
-		DefaultCosmWasmVersion = "0.50"
+		cosmWasmVersion = versions.CosmWasm
 	}
 	DefaultConsensus := "tendermint" // TODO: gordian in the future on gen
 
@@ -67,14 +69,14 @@ func (cfg NewChainConfig) ChainRegistryFile() types.ChainRegistryFormat {
 			GitRepo:            "https://" + cfg.GithubPath(),
 			RecommendedVersion: "v1.0.0",
 			CompatibleVersions: []string{"v0.9.0"},
-			CosmosSdkVersion:   DefaultSDKVersion,
+			CosmosSdkVersion:   versions.CosmosSDK,
 			Consensus: types.Consensus{
 				Type:    DefaultConsensus,
-				Version: DefaultTendermintVersion,
+				Version: versions.CometBFT,
 			},
-			CosmwasmVersion: DefaultCosmWasmVersion,
+			CosmwasmVersion: cosmWasmVersion,
 			CosmwasmEnabled: cfg.IsFeatureEnabled(CosmWasm),
-			IbcGoVersion:    DefaultIBCGoVersion,
+			IbcGoVersion:    versions.IBCGo,
 			IcsEnabled:      []string{"ics20-1"},
 			Genesis: types.Genesis{
 				Name:       "v1",
@@ -150,14 +152,15 @@ func (cfg NewChainConfig) ChainRegistryAssetsFile() types.ChainRegistryAssetsLis
 						Exponent: 0,
 					},
 					{
-						Denom:    display, // TOKEN
+						Denom:    strings.ToLower(display), // token
 						Exponent: 6,
 					},
 				},
-				Base:    cfg.Denom, // utoken
-				Name:    fmt.Sprintf("%s %s", cfg.ProjectName, display),
-				Display: strings.ToLower(display), // token
-				Symbol:  display,                  // TOKEN
+				TypeAsset: "sdk.coin",
+				Base:      cfg.Denom, // utoken
+				Name:      fmt.Sprintf("%s %s", cfg.ProjectName, display),
+				Display:   strings.ToLower(display), // token
+				Symbol:    display,                  // TOKEN
 				LogoURIs: types.LogoURIs{
 					Png: DefaultLogoPNG,
 					Svg: DefaultLogoSVG,
@@ -226,6 +226,12 @@ func TestBadConfigInputs(t *testing.T) {
 		NewCfgCase("bech32 not alpha", goodCfg().WithBech32Prefix("1"), types.ErrCfgBech32Alpha),
 		NewCfgCase("bech32 not alpha", goodCfg().WithBech32Prefix("---"), types.ErrCfgBech32Alpha),
 		NewCfgCase("success: bech32 prefix", goodCfg().WithBech32Prefix("c"), nil),
+		NewCfgCase("unknown version profile", goodCfg().WithVersionProfile("v0.1"), types.ErrCfgUnknownVersionProfile),
+		NewCfgCase("success: version profile alias", goodCfg().WithVersionProfile("0.50"), nil),
+		NewCfgCase("unknown wiring", goodCfg().WithWiring("wire"), types.ErrCfgUnknownWiring),
+		NewCfgCase("depinject with ics", goodCfg().WithWiring(spawn.WiringDepinject), types.ErrCfgWiringUnsupported),
+		NewCfgCase("success: depinject wiring", goodCfg().WithWiring(spawn.WiringDepinject).WithDisabledModules(spawn.InterchainSecurity), nil),
+		NewCfgCase("success: manual wiring", goodCfg().WithWiring(spawn.WiringManual), nil),
 	}
 
 	for _, c := range chainCases {
@@ -251,4 +257,10 @@ func TestChainRegistry(t *testing.T) {
 	require.Equal(t, bech, cr.Bech32Prefix)
 	require.Equal(t, bin, cr.DaemonName)
 	require.Equal(t, denom, cr.Fees.FeeTokens[0].Denom)
+
+	versions, err := cfg.StackVersions()
+	require.NoError(t, err)
+	require.Equal(t, versions.CosmosSDK, cr.Codebase.CosmosSdkVersion)
+	require.Equal(t, versions.IBCGo, cr.Codebase.IbcGoVersion)
+	require.Equal(t, versions.CometBFT, cr.Codebase.Consensus.Version)
 }
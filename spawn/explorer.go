@@ -5,9 +5,14 @@ import (
 	"fmt"
 	"os"
 	"path"
-	"strings"
+	"strconv"
+
+	"github.com/rollchains/spawn/spawn/types"
 )
 
+// DefaultMinTxFee is the ping.pub min_tx_fee of generated chains.
+const DefaultMinTxFee = "800"
+
 type (
 	ChainExplorerAsset struct {
 		Base        string `json:"base"`
@@ -36,22 +41,6 @@ type (
 	}
 )
 
-// hacky: pingpub does not have a docker file for some reason...
-const dockerFile = `# docker build . -t pingpub:latest
-
-FROM node:20-alpine
-
-RUN apk add --no-cache yarn npm
-
-WORKDIR /app
-
-COPY . .
-
-# install node_modules to the image
-RUN yarn --ignore-engines
-
-CMD [ "yarn", "--ignore-engines", "serve", "--host", "0.0.0.0" ]`
-
 func NewEndpoint(provider, address string) Endpoint {
 	return Endpoint{
 		Provider: provider,
@@ -59,72 +48,88 @@ func NewEndpoint(provider, address string) Endpoint {
 	}
 }
 
+// NewPingPubExplorer writes the ping.pub chain config of the new chain. The explorer itself
+// is the pingpub service of docker-compose.yml, nothing is downloaded at generation time.
 func (cfg NewChainConfig) NewPingPubExplorer() error {
-	if err := os.Chdir(cfg.ProjectName); err != nil {
-		cfg.Logger.Error("chdir", "err", err)
-	}
-	if err := ExecCommand("git", "clone", "https://github.com/ping-pub/explorer.git", "--depth", "1"); err != nil {
-		cfg.Logger.Error("git clone", "err", err)
-	}
-	if err := os.Chdir(".."); err != nil {
-		cfg.Logger.Error("chdir", "err", err)
-	}
+	_, err := WriteExplorerConfig(cfg.ProjectName)
+	return err
+}
 
-	mainnet := path.Join(cfg.ProjectName, "explorer", "chains", "mainnet")
-	cfg.clearDir(mainnet)
-	cfg.clearDir(path.Join(cfg.ProjectName, "explorer", "chains", "testnet"))
+// ExplorerChainConfigPath is the ping.pub chain config of a chain generated with the block-explorer feature.
+func ExplorerChainConfigPath(homeDir, chainName string) string {
+	return path.Join(homeDir, "explorer", "chains", "mainnet", fmt.Sprintf("%s.json", chainName))
+}
 
-	// Create JSON config file for explorer
-	explorer := cfg.NewChainExplorerConfig()
-	bz, err := json.MarshalIndent(explorer, "", "  ")
-	if err != nil {
-		cfg.Logger.Error("Error marshalling chain explorer config", "err", err)
+// WriteExplorerConfig (re)generates the ping.pub chain config of the chain in homeDir from its
+// chain registry files and returns the path written.
+func WriteExplorerConfig(homeDir string) (string, error) {
+	var chain types.ChainRegistryFormat
+	if err := readJSONFile(path.Join(homeDir, ChainRegistryFileName), &chain); err != nil {
+		return "", err
 	}
 
-	err = os.WriteFile(path.Join(mainnet, fmt.Sprintf("%s.json", cfg.ProjectName)), bz, 0644)
-	if err != nil {
-		cfg.Logger.Error("Error writing chain explorer config", "err", err)
+	var assets types.ChainRegistryAssetsList
+	if err := readJSONFile(path.Join(homeDir, ChainRegistryAssetsFileName), &assets); err != nil {
+		return "", err
 	}
 
-	err = os.WriteFile(path.Join(cfg.ProjectName, "explorer", "Dockerfile"), []byte(dockerFile), 0644)
+	bz, err := json.MarshalIndent(NewChainExplorer(chain, assets), "", "  ")
 	if err != nil {
-		cfg.Logger.Error("Error writing docker file", "err", err)
+		return "", err
 	}
 
-	return nil
+	loc := ExplorerChainConfigPath(homeDir, chain.ChainName)
+	if err := os.MkdirAll(path.Dir(loc), 0755); err != nil {
+		return "", err
+	}
+
+	return loc, os.WriteFile(loc, append(bz, '\n'), 0644)
 }
 
 func (cfg NewChainConfig) NewChainExplorerConfig() ChainExplorer {
-	logo := "https://img.freepik.com/free-vector/letter-s-box-logo-design-template_474888-3345.jpg?size=338&ext=jpg&ga=GA1.1.2008272138.1721260800&semt=ais_user"
-	return ChainExplorer{
-		ChainName:  cfg.ProjectName,
-		Api:        []Endpoint{NewEndpoint("api.localhost", "http://127.0.0.1:1317")},
-		Rpc:        []Endpoint{NewEndpoint("rpc.localhost", "http://127.0.0.1:26657")},
-		SdkVersion: "0.50",
-		CoinType:   "118",
-		MinTxFee:   "800",
-		AddrPrefix: cfg.Bech32Prefix,
-		Logo:       logo,
-		ThemeColor: "#001be7",
-		Assets: []ChainExplorerAsset{
-			{
-				Base:        cfg.Denom,
-				Symbol:      strings.ToUpper(cfg.Denom),
-				Exponent:    "6",
-				CoingeckoId: "",
-				Logo:        logo,
-			},
-		},
-	}
+	return NewChainExplorer(cfg.ChainRegistryFile(), cfg.ChainRegistryAssetsFile())
 }
 
-func (cfg NewChainConfig) clearDir(dirLoc ...string) {
-	dir := path.Join(dirLoc...)
+// NewChainExplorer converts the chain registry files to the ping.pub chain config.
+func NewChainExplorer(chain types.ChainRegistryFormat, assets types.ChainRegistryAssetsList) ChainExplorer {
+	explorer := ChainExplorer{
+		ChainName:  chain.ChainName,
+		Api:        make([]Endpoint, 0, len(chain.Apis.Rest)),
+		Rpc:        make([]Endpoint, 0, len(chain.Apis.RPC)),
+		SdkVersion: StackVersions{CosmosSDK: chain.Codebase.CosmosSdkVersion}.SDKMajorMinor(),
+		CoinType:   strconv.Itoa(chain.Slip44),
+		MinTxFee:   DefaultMinTxFee,
+		AddrPrefix: chain.Bech32Prefix,
+		Assets:     make([]ChainExplorerAsset, 0, len(assets.Assets)),
+	}
+
+	for _, api := range chain.Apis.Rest {
+		explorer.Api = append(explorer.Api, NewEndpoint(api.Provider, api.Address))
+	}
+	for _, rpc := range chain.Apis.RPC {
+		explorer.Rpc = append(explorer.Rpc, NewEndpoint(rpc.Provider, rpc.Address))
+	}
 
-	if err := os.RemoveAll(dir); err != nil {
-		cfg.Logger.Error("Error removing directory", "err", err)
+	if len(chain.Images) > 0 {
+		explorer.Logo = chain.Images[0].Png
+		explorer.ThemeColor = chain.Images[0].Theme.PrimaryColorHex
 	}
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		cfg.Logger.Error("Error creating directory", "err", err)
+
+	for _, asset := range assets.Assets {
+		exponent := 0
+		for _, unit := range asset.DenomUnits {
+			if unit.Denom == asset.Display {
+				exponent = unit.Exponent
+			}
+		}
+
+		explorer.Assets = append(explorer.Assets, ChainExplorerAsset{
+			Base:     asset.Base,
+			Symbol:   asset.Symbol,
+			Exponent: strconv.Itoa(exponent),
+			Logo:     asset.LogoURIs.Png,
+		})
 	}
+
+	return explorer
 }
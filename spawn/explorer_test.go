@@ -0,0 +1,45 @@
+package spawn_test
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rollchains/spawn/spawn"
+)
+
+func TestWriteExplorerConfig(t *testing.T) {
+	home := t.TempDir()
+
+	_, err := spawn.WriteExplorerConfig(home)
+	require.ErrorIs(t, err, os.ErrNotExist, "the chain registry files are required")
+
+	saveRegistryFiles(t, home, goodCfg())
+
+	loc, err := spawn.WriteExplorerConfig(home)
+	require.NoError(t, err)
+	require.Equal(t, spawn.ExplorerChainConfigPath(home, proj), loc)
+
+	var explorer spawn.ChainExplorer
+	readJSON(t, loc, &explorer)
+	require.Equal(t, proj, explorer.ChainName)
+	require.Equal(t, bech, explorer.AddrPrefix)
+	require.Equal(t, "118", explorer.CoinType)
+	require.Equal(t, spawn.DefaultThemeHexColor, explorer.ThemeColor)
+	require.Equal(t, []spawn.Endpoint{spawn.NewEndpoint("localhost", "http://127.0.0.1:1317")}, explorer.Api)
+	require.Equal(t, []spawn.Endpoint{spawn.NewEndpoint("localhost", "http://127.0.0.1:26657")}, explorer.Rpc)
+	require.Len(t, explorer.Assets, 1)
+	require.Equal(t, denom, explorer.Assets[0].Base)
+	require.Equal(t, "6", explorer.Assets[0].Exponent)
+	require.Equal(t, spawn.DefaultLogoPNG, explorer.Assets[0].Logo)
+
+	// regenerating overwrites the config in place
+	require.NoError(t, os.WriteFile(loc, []byte("{}"), 0644))
+	_, err = spawn.WriteExplorerConfig(home)
+	require.NoError(t, err)
+	readJSON(t, loc, &explorer)
+	require.Equal(t, proj, explorer.ChainName)
+	require.NoFileExists(t, path.Join(home, "explorer", "Dockerfile"), "only the chain config is written")
+}
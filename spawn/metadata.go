@@ -5,6 +5,9 @@ import (
 	"os"
 )
 
+// ChainMetadataFileName is the display metadata of the generated chain.
+const ChainMetadataFileName = "chain_metadata.json"
+
 type (
 	MetadataFile struct {
 		Display Display `json:"display"`
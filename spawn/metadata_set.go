@@ -0,0 +1,576 @@
+package spawn
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	localictypes "github.com/strangelove-ventures/interchaintest/local-interchain/interchain/types"
+
+	"github.com/rollchains/spawn/spawn/types"
+)
+
+// MetadataKey is a key accepted by SetChainMetadata.
+type MetadataKey struct {
+	Name        string
+	Description string
+	set         func(f *chainMetadataFiles, value string) error
+}
+
+var (
+	pngMagic      = []byte("\x89PNG\r\n\x1a\n")
+	hexColorRegex = regexp.MustCompile(`^#([0-9a-fA-F]{6}|[0-9a-fA-F]{3})$`)
+
+	metadataLinks = []string{"website", "discord", "email", "github", "telegram", "twitter", "whitepaper"}
+)
+
+// MetadataKeys returns the keys that can be set on a chain, in display order.
+func MetadataKeys() []MetadataKey {
+	keys := []MetadataKey{
+		{Name: "name", Description: "display & pretty name", set: setMetadataName},
+		{Name: "description", Description: "short description of the chain", set: setMetadataDescription},
+		{Name: "chain_id", Description: "chain-id of the network (also renamed in the chains/ testnets)", set: setMetadataChainID},
+		{Name: "network_type", Description: "mainnet, testnet or devnet (moves the registry image URLs)", set: setMetadataNetworkType},
+		{Name: "status", Description: "live, upcoming or killed", set: setMetadataStatus},
+		{Name: "theme_color", Description: "primary hex color of the logo & explorer, e.g. #FF2D00", set: setMetadataThemeColor},
+		{Name: "logo", Description: "local .png or .svg file, copied to images/", set: setMetadataLogo},
+	}
+
+	for _, link := range metadataLinks {
+		keys = append(keys, MetadataKey{
+			Name:        "links." + link,
+			Description: link + " link",
+			set:         metadataLinkSetter(link),
+		})
+	}
+
+	return keys
+}
+
+// SetChainMetadata updates chain_metadata.json, the chain registry files and the explorer config (if present) of
+// the chain in homeDir with the key values. Everything is validated before any file is written.
+func SetChainMetadata(homeDir string, values map[string]string) error {
+	setters := make(map[string]MetadataKey)
+	for _, k := range MetadataKeys() {
+		setters[k.Name] = k
+	}
+	setters["website"] = setters["links.website"]
+	setters["links.logo"] = setters["logo"]
+
+	f, err := loadChainMetadataFiles(homeDir)
+	if err != nil {
+		return err
+	}
+
+	// network_type first so image URLs are generated for the new location.
+	order := make([]string, 0, len(values))
+	if _, ok := values["network_type"]; ok {
+		order = append(order, "network_type")
+	}
+	for k := range values {
+		if k != "network_type" {
+			order = append(order, k)
+		}
+	}
+
+	for _, k := range order {
+		s, ok := setters[k]
+		if !ok {
+			return fmt.Errorf("%w: %s", types.ErrMetadataUnknownKey, k)
+		}
+
+		if err := s.set(f, strings.TrimSpace(values[k])); err != nil {
+			return fmt.Errorf("%s: %w", k, err)
+		}
+	}
+
+	return f.save()
+}
+
+// chainMetadataFiles are the decoded metadata files of a chain. Unknown fields are kept as is.
+type chainMetadataFiles struct {
+	homeDir     string
+	metadata    map[string]any
+	chain       map[string]any
+	assets      map[string]any
+	explorer    map[string]any
+	explorerLoc string
+	images      map[string][]byte
+
+	// testnets are the local-interchain configs of chains/, only the changed ones are saved
+	testnets        map[string]*localictypes.ChainsConfig
+	testnetsChanged map[string]bool
+}
+
+func loadChainMetadataFiles(homeDir string) (*chainMetadataFiles, error) {
+	f := &chainMetadataFiles{
+		homeDir:         homeDir,
+		images:          make(map[string][]byte),
+		testnets:        make(map[string]*localictypes.ChainsConfig),
+		testnetsChanged: make(map[string]bool),
+	}
+
+	var err error
+	if f.metadata, err = readJSONMap(path.Join(homeDir, ChainMetadataFileName)); err != nil {
+		return nil, err
+	}
+	if f.chain, err = readJSONMap(path.Join(homeDir, ChainRegistryFileName)); err != nil {
+		return nil, err
+	}
+	if f.assets, err = readJSONMap(path.Join(homeDir, ChainRegistryAssetsFileName)); err != nil {
+		return nil, err
+	}
+
+	// the explorer is optional (block-explorer feature)
+	f.explorerLoc = ExplorerChainConfigPath(homeDir, f.chainName())
+	if _, err := os.Stat(f.explorerLoc); err == nil {
+		if f.explorer, err = readJSONMap(f.explorerLoc); err != nil {
+			return nil, err
+		}
+	}
+
+	testnets, err := filepath.Glob(path.Join(homeDir, "chains", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, loc := range testnets {
+		// read as is, LoadTestnetConfig applies the local-interchain defaults
+		cfg := &localictypes.ChainsConfig{}
+		if err := readJSONFile(loc, cfg); err != nil {
+			return nil, err
+		}
+		f.testnets[loc] = cfg
+	}
+
+	return f, nil
+}
+
+func (f *chainMetadataFiles) save() error {
+	chain, err := json.MarshalIndent(f.chain, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ValidateChainRegistry(chain); err != nil {
+		return fmt.Errorf("%s: %w", ChainRegistryFileName, err)
+	}
+
+	assets, err := json.MarshalIndent(f.assets, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ValidateChainRegistryAssets(assets); err != nil {
+		return fmt.Errorf("%s: %w", ChainRegistryAssetsFileName, err)
+	}
+
+	metadata, err := json.MarshalIndent(f.metadata, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	files := map[string][]byte{
+		path.Join(f.homeDir, ChainMetadataFileName):       append(metadata, '\n'),
+		path.Join(f.homeDir, ChainRegistryFileName):       append(chain, '\n'),
+		path.Join(f.homeDir, ChainRegistryAssetsFileName): append(assets, '\n'),
+	}
+
+	if f.explorer != nil {
+		explorer, err := json.MarshalIndent(f.explorer, "", "  ")
+		if err != nil {
+			return err
+		}
+		files[f.explorerLoc] = append(explorer, '\n')
+	}
+
+	for loc, cfg := range f.testnets {
+		if !f.testnetsChanged[loc] {
+			continue
+		}
+
+		// same format as local-interchain SaveJSON
+		bz, err := json.MarshalIndent(cfg, "", "    ")
+		if err != nil {
+			return err
+		}
+		files[loc] = bz
+	}
+
+	for name, bz := range f.images {
+		files[path.Join(f.homeDir, ChainRegistryImagesDir, name)] = bz
+	}
+
+	return writeFilesAtomic(files)
+}
+
+// writeFilesAtomic writes every file to a temporary file next to it, then renames them all into place. Nothing is
+// replaced if a write fails.
+func writeFilesAtomic(files map[string][]byte) error {
+	tmpFiles := make(map[string]string, len(files))
+	cleanup := func() {
+		for _, tmp := range tmpFiles {
+			os.Remove(tmp)
+		}
+	}
+
+	for loc, bz := range files {
+		if err := os.MkdirAll(path.Dir(loc), 0755); err != nil {
+			cleanup()
+			return err
+		}
+
+		tmp, err := os.CreateTemp(path.Dir(loc), "."+path.Base(loc)+".tmp-*")
+		if err != nil {
+			cleanup()
+			return err
+		}
+		tmpFiles[loc] = tmp.Name()
+
+		_, err = tmp.Write(bz)
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Chmod(tmp.Name(), 0644)
+		}
+		if err != nil {
+			cleanup()
+			return fmt.Errorf("error writing %s: %w", loc, err)
+		}
+	}
+
+	for loc, tmp := range tmpFiles {
+		if err := os.Rename(tmp, loc); err != nil {
+			cleanup()
+			return fmt.Errorf("error replacing %s: %w", loc, err)
+		}
+		delete(tmpFiles, loc)
+	}
+
+	return nil
+}
+
+func (f *chainMetadataFiles) chainName() string {
+	s, _ := f.chain["chain_name"].(string)
+	return s
+}
+
+// nativeAssets are the assets of the chain's staking denom, the first asset if none match.
+func (f *chainMetadataFiles) nativeAssets() []map[string]any {
+	assets := jsonObjects(f.assets["assets"])
+
+	denom := ""
+	if staking, ok := f.chain["staking"].(map[string]any); ok {
+		if tokens := jsonObjects(staking["staking_tokens"]); len(tokens) > 0 {
+			denom, _ = tokens[0]["denom"].(string)
+		}
+	}
+
+	var native []map[string]any
+	for _, a := range assets {
+		if a["base"] == denom {
+			native = append(native, a)
+		}
+	}
+
+	if len(native) == 0 && len(assets) > 0 {
+		native = assets[:1]
+	}
+
+	return native
+}
+
+// explorerNativeAssets are the explorer assets matching the registry native assets.
+func (f *chainMetadataFiles) explorerNativeAssets() []map[string]any {
+	if f.explorer == nil {
+		return nil
+	}
+
+	bases := make(map[any]bool)
+	for _, a := range f.nativeAssets() {
+		bases[a["base"]] = true
+	}
+
+	var assets []map[string]any
+	for _, a := range jsonObjects(f.explorer["assets"]) {
+		if bases[a["base"]] {
+			assets = append(assets, a)
+		}
+	}
+
+	return assets
+}
+
+// imageURL is where the chain-registry will host the image once the chain is submitted.
+func (f *chainMetadataFiles) imageURL(name string) string {
+	networkType, _ := f.chain["network_type"].(string)
+	return chainRegistryRawURL + registryChainDir(f.chainName(), networkType) + "/images/" + name
+}
+
+func (f *chainMetadataFiles) setExplorer(key string, value any) {
+	if f.explorer != nil {
+		f.explorer[key] = value
+	}
+}
+
+func setMetadataName(f *chainMetadataFiles, value string) error {
+	if value == "" {
+		return fmt.Errorf("%w: name can not be empty", types.ErrMetadataInvalidValue)
+	}
+
+	setJSONPath(f.metadata, value, "display", "name")
+	if widget, ok := jsonPath(f.metadata, "display", "widget").(map[string]any); ok {
+		widget["title"] = value
+	}
+	f.chain["pretty_name"] = value
+
+	return nil
+}
+
+func setMetadataDescription(f *chainMetadataFiles, value string) error {
+	setJSONPath(f.metadata, value, "display", "description")
+	if widget, ok := jsonPath(f.metadata, "display", "widget").(map[string]any); ok {
+		widget["description"] = value
+	}
+	f.chain["description"] = value
+
+	return nil
+}
+
+func setMetadataChainID(f *chainMetadataFiles, value string) error {
+	if value == "" || strings.ContainsAny(value, " \t") {
+		return fmt.Errorf("%w: invalid chain-id %q", types.ErrMetadataInvalidValue, value)
+	}
+
+	prev, _ := f.chain["chain_id"].(string)
+	f.chain["chain_id"] = value
+
+	if prev == "" || prev == value {
+		return nil
+	}
+
+	// the testnets of the chain & the IBC paths to it use the chain-id too
+	for loc, cfg := range f.testnets {
+		for i := range cfg.Chains {
+			c := &cfg.Chains[i]
+			if c.ChainID == prev {
+				c.ChainID = value
+				f.testnetsChanged[loc] = true
+			}
+
+			for j, p := range c.IBCPaths {
+				ids := strings.Split(p, "_")
+				for k, id := range ids {
+					if id == prev {
+						ids[k] = value
+						f.testnetsChanged[loc] = true
+					}
+				}
+				c.IBCPaths[j] = strings.Join(ids, "_")
+			}
+		}
+	}
+
+	return nil
+}
+
+func setMetadataStatus(f *chainMetadataFiles, value string) error {
+	switch value {
+	case "live", "upcoming", "killed":
+		f.chain["status"] = value
+		return nil
+	}
+
+	return fmt.Errorf("%w: status must be live, upcoming or killed", types.ErrMetadataInvalidValue)
+}
+
+func setMetadataNetworkType(f *chainMetadataFiles, value string) error {
+	switch value {
+	case "mainnet", "testnet", "devnet":
+	default:
+		return fmt.Errorf("%w: network_type must be mainnet, testnet or devnet", types.ErrMetadataInvalidValue)
+	}
+	f.chain["network_type"] = value
+
+	// images of this chain move between <chain>/ and testnets/<chain>/ in the registry.
+	own := chainImageRegexp(f.chainName())
+	rewrite := func(s string) string {
+		if m := own.FindStringSubmatch(s); m != nil {
+			return f.imageURL(m[1])
+		}
+		return s
+	}
+
+	for _, m := range []map[string]any{f.metadata, f.chain, f.assets, f.explorer} {
+		if m != nil {
+			rewriteJSONStrings(m, rewrite)
+		}
+	}
+
+	return nil
+}
+
+func setMetadataThemeColor(f *chainMetadataFiles, value string) error {
+	if !hexColorRegex.MatchString(value) {
+		return fmt.Errorf("%w: theme_color must be a hex color like #FF2D00", types.ErrMetadataInvalidValue)
+	}
+
+	for _, img := range jsonObjects(f.chain["images"]) {
+		setJSONPath(img, value, "theme", "primary_color_hex")
+	}
+	for _, a := range f.nativeAssets() {
+		for _, img := range jsonObjects(a["images"]) {
+			setJSONPath(img, value, "theme", "primary_color_hex")
+		}
+	}
+	f.setExplorer("theme_color", value)
+
+	return nil
+}
+
+func setMetadataLogo(f *chainMetadataFiles, value string) error {
+	bz, err := os.ReadFile(value)
+	if err != nil {
+		return err
+	}
+
+	format := strings.TrimPrefix(strings.ToLower(path.Ext(value)), ".")
+	switch {
+	case format == "png" && bytes.HasPrefix(bz, pngMagic):
+	case format == "svg" && bytes.Contains(bz, []byte("<svg")):
+	default:
+		return fmt.Errorf("%w: logo must be a .png or .svg image", types.ErrMetadataInvalidValue)
+	}
+
+	name := f.chainName() + "." + format
+	f.images[name] = bz
+	uri := f.imageURL(name)
+
+	// other formats still pointing to an image not owned by the chain (e.g. the template logo) are removed.
+	own := chainImageRegexp(f.chainName())
+	setImage := func(img map[string]any) {
+		img[format] = uri
+		for _, other := range []string{"png", "svg"} {
+			if s, _ := img[other].(string); other != format && s != "" && !own.MatchString(s) {
+				delete(img, other)
+			}
+		}
+	}
+
+	images := jsonObjects(f.chain["images"])
+	if len(images) == 0 {
+		images = []map[string]any{{}}
+		f.chain["images"] = []any{images[0]}
+	}
+	setImage(images[0])
+
+	logoURIs, _ := f.chain["logo_URIs"].(map[string]any)
+	if logoURIs == nil {
+		logoURIs = make(map[string]any)
+		f.chain["logo_URIs"] = logoURIs
+	}
+	setImage(logoURIs)
+
+	for _, a := range f.nativeAssets() {
+		if uris, ok := a["logo_URIs"].(map[string]any); ok {
+			setImage(uris)
+		} else {
+			a["logo_URIs"] = map[string]any{format: uri}
+		}
+
+		assetImages := jsonObjects(a["images"])
+		if len(assetImages) == 0 {
+			assetImages = []map[string]any{{}}
+			a["images"] = []any{assetImages[0]}
+		}
+		setImage(assetImages[0])
+	}
+
+	// single logo fields prefer the png.
+	logo, _ := images[0]["png"].(string)
+	if logo == "" {
+		logo = uri
+	}
+
+	setJSONPath(f.metadata, logo, "display", "links", "logo")
+	f.setExplorer("logo", logo)
+	for _, a := range f.explorerNativeAssets() {
+		a["logo"] = logo
+	}
+
+	return nil
+}
+
+func metadataLinkSetter(link string) func(f *chainMetadataFiles, value string) error {
+	return func(f *chainMetadataFiles, value string) error {
+		if link == "email" {
+			if _, err := mail.ParseAddress(value); err != nil {
+				return fmt.Errorf("%w: %w", types.ErrMetadataInvalidValue, err)
+			}
+		} else if u, err := url.Parse(value); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w: %s must be an http(s) URL", types.ErrMetadataInvalidValue, link)
+		}
+
+		setJSONPath(f.metadata, value, "display", "links", link)
+
+		switch link {
+		case "website":
+			f.chain["website"] = value
+			for _, a := range f.nativeAssets() {
+				setJSONPath(a, value, "socials", "website")
+			}
+		case "twitter":
+			for _, a := range f.nativeAssets() {
+				setJSONPath(a, value, "socials", "twitter")
+			}
+		case "github":
+			setJSONPath(f.chain, value, "codebase", "git_repo")
+		}
+
+		return nil
+	}
+}
+
+// jsonPath returns the value at keys within decoded JSON, nil if not found.
+func jsonPath(m map[string]any, keys ...string) any {
+	var v any = m
+	for _, k := range keys {
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = obj[k]
+	}
+	return v
+}
+
+// setJSONPath sets value at keys within decoded JSON, creating missing objects.
+func setJSONPath(m map[string]any, value any, keys ...string) {
+	for _, k := range keys[:len(keys)-1] {
+		child, ok := m[k].(map[string]any)
+		if !ok {
+			child = make(map[string]any)
+			m[k] = child
+		}
+		m = child
+	}
+	m[keys[len(keys)-1]] = value
+}
+
+// jsonObjects returns the objects of a decoded JSON array.
+func jsonObjects(v any) []map[string]any {
+	arr, _ := v.([]any)
+
+	objs := make([]map[string]any, 0, len(arr))
+	for _, e := range arr {
+		if obj, ok := e.(map[string]any); ok {
+			objs = append(objs, obj)
+		}
+	}
+	return objs
+}
@@ -0,0 +1,152 @@
+package spawn_test
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"testing"
+
+	localictypes "github.com/strangelove-ventures/interchaintest/local-interchain/interchain/types"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rollchains/spawn/spawn"
+	"github.com/rollchains/spawn/spawn/types"
+)
+
+func TestSetChainMetadata(t *testing.T) {
+	home := t.TempDir()
+
+	cfg := goodCfg()
+	saveRegistryFiles(t, home, cfg)
+	require.NoError(t, cfg.MetadataFile().SaveJSON(path.Join(home, spawn.ChainMetadataFileName)))
+
+	explorerLoc, err := spawn.WriteExplorerConfig(home)
+	require.NoError(t, err)
+
+	logo := path.Join(t.TempDir(), "logo.png")
+	require.NoError(t, os.WriteFile(logo, []byte("\x89PNG\r\n\x1a\nimage"), 0644))
+
+	require.NoError(t, spawn.SetChainMetadata(home, map[string]string{
+		"links.website": "https://mychain.xyz",
+		"logo":          logo,
+		"theme_color":   "#1A2B3C",
+	}))
+
+	pngURL := "https://raw.githubusercontent.com/cosmos/chain-registry/master/testnets/" + proj + "/images/" + proj + ".png"
+	require.FileExists(t, path.Join(home, spawn.ChainRegistryImagesDir, proj+".png"))
+
+	var metadata spawn.MetadataFile
+	readJSON(t, path.Join(home, spawn.ChainMetadataFileName), &metadata)
+	require.Equal(t, "https://mychain.xyz", metadata.Display.Links.Website)
+	require.Equal(t, pngURL, metadata.Display.Links.Logo)
+
+	var chain types.ChainRegistryFormat
+	readJSON(t, path.Join(home, spawn.ChainRegistryFileName), &chain)
+	require.Equal(t, "https://mychain.xyz", chain.Website)
+	require.Equal(t, pngURL, chain.Images[0].Png)
+	require.Equal(t, "#1A2B3C", chain.Images[0].Theme.PrimaryColorHex)
+
+	var assets types.ChainRegistryAssetsList
+	readJSON(t, path.Join(home, spawn.ChainRegistryAssetsFileName), &assets)
+	require.Equal(t, pngURL, assets.Assets[0].LogoURIs.Png)
+	require.Empty(t, assets.Assets[0].LogoURIs.Svg, "template svg must not be mixed with the new logo")
+	require.Equal(t, "https://mychain.xyz", assets.Assets[0].Socials.Website)
+
+	var explorer spawn.ChainExplorer
+	readJSON(t, explorerLoc, &explorer)
+	require.Equal(t, pngURL, explorer.Logo)
+	require.Equal(t, pngURL, explorer.Assets[0].Logo)
+	require.Equal(t, "#1A2B3C", explorer.ThemeColor)
+
+	// mainnets move the images out of testnets/
+	require.NoError(t, spawn.SetChainMetadata(home, map[string]string{"network_type": "mainnet"}))
+	readJSON(t, path.Join(home, spawn.ChainMetadataFileName), &metadata)
+	require.Equal(t, "https://raw.githubusercontent.com/cosmos/chain-registry/master/"+proj+"/images/"+proj+".png", metadata.Display.Links.Logo)
+	require.NoError(t, spawn.ValidateChainRegistryDir(home))
+}
+
+func TestSetChainMetadataInvalid(t *testing.T) {
+	home := t.TempDir()
+
+	cfg := goodCfg()
+	saveRegistryFiles(t, home, cfg)
+	require.NoError(t, cfg.MetadataFile().SaveJSON(path.Join(home, spawn.ChainMetadataFileName)))
+
+	before, err := os.ReadFile(path.Join(home, spawn.ChainRegistryFileName))
+	require.NoError(t, err)
+
+	require.ErrorIs(t, spawn.SetChainMetadata(home, map[string]string{"links.unknown": "x"}), types.ErrMetadataUnknownKey)
+	require.ErrorIs(t, spawn.SetChainMetadata(home, map[string]string{"theme_color": "red"}), types.ErrMetadataInvalidValue)
+	require.ErrorIs(t, spawn.SetChainMetadata(home, map[string]string{"links.website": "mychain.xyz"}), types.ErrMetadataInvalidValue)
+
+	notPNG := path.Join(t.TempDir(), "logo.png")
+	require.NoError(t, os.WriteFile(notPNG, []byte("<svg></svg>"), 0644))
+	require.ErrorIs(t, spawn.SetChainMetadata(home, map[string]string{"logo": notPNG}), types.ErrMetadataInvalidValue)
+
+	// nothing is written when a value is invalid
+	after, err := os.ReadFile(path.Join(home, spawn.ChainRegistryFileName))
+	require.NoError(t, err)
+	require.Equal(t, before, after)
+
+	// or when one of the files can not be written (images/ is a file)
+	png := path.Join(t.TempDir(), "logo.png")
+	require.NoError(t, os.WriteFile(png, []byte("\x89PNG\r\n\x1a\nimage"), 0644))
+	require.NoError(t, os.WriteFile(path.Join(home, spawn.ChainRegistryImagesDir), nil, 0644))
+	require.Error(t, spawn.SetChainMetadata(home, map[string]string{"logo": png, "links.website": "https://mychain.xyz"}))
+
+	after, err = os.ReadFile(path.Join(home, spawn.ChainRegistryFileName))
+	require.NoError(t, err)
+	require.Equal(t, before, after)
+
+	entries, err := os.ReadDir(home)
+	require.NoError(t, err)
+	for _, e := range entries {
+		require.NotContains(t, e.Name(), ".tmp-", "temporary files are removed")
+	}
+}
+
+func TestSetChainMetadataChainID(t *testing.T) {
+	home := t.TempDir()
+
+	cfg := goodCfg()
+	saveRegistryFiles(t, home, cfg)
+	require.NoError(t, cfg.MetadataFile().SaveJSON(path.Join(home, spawn.ChainMetadataFileName)))
+
+	self := testnetChain(spawn.DefaultChainID)
+	other := testnetChain("localchain-2")
+	self.SetAppendedIBCPathLink(other)
+
+	testnetLoc := path.Join(home, "chains", "self-ibc.json")
+	unrelatedLoc := path.Join(home, "chains", "unrelated.json")
+	require.NoError(t, localictypes.NewChainsConfig(self, other).SaveJSON(testnetLoc))
+	require.NoError(t, localictypes.NewChainsConfig(testnetChain("otherchain-1")).SaveJSON(unrelatedLoc))
+
+	unrelated, err := os.ReadFile(unrelatedLoc)
+	require.NoError(t, err)
+
+	require.NoError(t, spawn.SetChainMetadata(home, map[string]string{"chain_id": "mychain-1"}))
+
+	var chain types.ChainRegistryFormat
+	readJSON(t, path.Join(home, spawn.ChainRegistryFileName), &chain)
+	require.Equal(t, "mychain-1", chain.ChainID)
+
+	testnet, err := spawn.LoadTestnetConfig(testnetLoc)
+	require.NoError(t, err)
+	require.Equal(t, "mychain-1", testnet.Chains[0].ChainID)
+	require.Equal(t, "localchain-2", testnet.Chains[1].ChainID)
+	require.Equal(t, []string{"mychain-1_localchain-2"}, testnet.Chains[0].IBCPaths)
+	require.Equal(t, []string{"mychain-1_localchain-2"}, testnet.Chains[1].IBCPaths)
+	require.NoError(t, spawn.ValidateTestnetConfig(testnet))
+
+	after, err := os.ReadFile(unrelatedLoc)
+	require.NoError(t, err)
+	require.Equal(t, unrelated, after, "testnets of other chains are not rewritten")
+}
+
+func readJSON(t *testing.T, loc string, v any) {
+	t.Helper()
+
+	bz, err := os.ReadFile(loc)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(bz, v))
+}
@@ -0,0 +1,506 @@
+package spawn
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	gotypes "go/types"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rollchains/spawn/spawn/types"
+)
+
+// KeeperDep is the keeper of another module a module keeper can depend on, through an expected keeper interface.
+type KeeperDep struct {
+	Name string
+	// Interface is the expected keeper interface of types/expected_keepers.go
+	Interface string
+	Methods   []string
+	// Imports of the methods (path;alias)
+	Imports []string
+	// Field is the Keeper field & NewKeeper parameter
+	Field string
+	// App is the keeper passed to NewKeeper in app.go, Test the one of the keeper test fixture
+	App  string
+	Test string
+	// Mock adds the testutil mock of the interface to the keeper test fixture, for the keepers it does not create
+	Mock bool
+}
+
+// KeeperDeps are the keepers modules can depend on.
+var KeeperDeps = []KeeperDep{
+	{
+		Name:      "account",
+		Interface: "AccountKeeper",
+		Methods: []string{
+			"GetAccount(ctx context.Context, addr sdk.AccAddress) sdk.AccountI",
+			"GetModuleAddress(moduleName string) sdk.AccAddress",
+			"GetModuleAccount(ctx context.Context, moduleName string) sdk.ModuleAccountI",
+		},
+		Imports: []string{"context", "github.com/cosmos/cosmos-sdk/types;sdk"},
+		Field:   "accountKeeper",
+		App:     "app.AccountKeeper",
+		Test:    "f.accountkeeper",
+	},
+	{
+		Name:      "bank",
+		Interface: "BankKeeper",
+		Methods: []string{
+			"GetBalance(ctx context.Context, addr sdk.AccAddress, denom string) sdk.Coin",
+			"GetAllBalances(ctx context.Context, addr sdk.AccAddress) sdk.Coins",
+			"SpendableCoins(ctx context.Context, addr sdk.AccAddress) sdk.Coins",
+			"SendCoins(ctx context.Context, fromAddr, toAddr sdk.AccAddress, amt sdk.Coins) error",
+			"SendCoinsFromAccountToModule(ctx context.Context, senderAddr sdk.AccAddress, recipientModule string, amt sdk.Coins) error",
+			"SendCoinsFromModuleToAccount(ctx context.Context, senderModule string, recipientAddr sdk.AccAddress, amt sdk.Coins) error",
+		},
+		Imports: []string{"context", "github.com/cosmos/cosmos-sdk/types;sdk"},
+		Field:   "bankKeeper",
+		App:     "app.BankKeeper",
+		Test:    "f.bankkeeper",
+	},
+	{
+		Name:      "staking",
+		Interface: "StakingKeeper",
+		Methods: []string{
+			"BondDenom(ctx context.Context) (string, error)",
+			"GetValidator(ctx context.Context, addr sdk.ValAddress) (stakingtypes.Validator, error)",
+			"GetDelegation(ctx context.Context, delAddr sdk.AccAddress, valAddr sdk.ValAddress) (stakingtypes.Delegation, error)",
+			"TotalBondedTokens(ctx context.Context) (math.Int, error)",
+		},
+		Imports: []string{
+			"context",
+			"cosmossdk.io/math",
+			"github.com/cosmos/cosmos-sdk/types;sdk",
+			"github.com/cosmos/cosmos-sdk/x/staking/types;stakingtypes",
+		},
+		Field: "stakingKeeper",
+		App:   "app.StakingKeeper",
+		Test:  "f.stakingKeeper",
+	},
+	{
+		Name:      "icacontroller",
+		Interface: "ICAControllerKeeper",
+		Methods: []string{
+			"RegisterInterchainAccount(ctx sdk.Context, connectionID, owner, version string) error",
+			"GetInterchainAccountAddress(ctx sdk.Context, connectionID, portID string) (string, bool)",
+			"SendTx(ctx sdk.Context, chanCap *capabilitytypes.Capability, connectionID, portID string, icaPacketData icatypes.InterchainAccountPacketData, timeoutTimestamp uint64) (uint64, error)",
+		},
+		Imports: []string{
+			"github.com/cosmos/cosmos-sdk/types;sdk",
+			"github.com/cosmos/ibc-go/modules/capability/types;capabilitytypes",
+			"github.com/cosmos/ibc-go/v8/modules/apps/27-interchain-accounts/types;icatypes",
+		},
+		Field: "icaControllerKeeper",
+		// the ICA controller keeper is created after the module keepers
+		App:  "&app.ICAControllerKeeper",
+		Test: "f.icaControllerKeeper",
+		Mock: true,
+	},
+}
+
+// KeeperDepNames returns the name of every keeper modules can depend on.
+func KeeperDepNames() []string {
+	names := make([]string, len(KeeperDeps))
+	for i, d := range KeeperDeps {
+		names[i] = d.Name
+	}
+	return names
+}
+
+// GetKeeperDeps returns the keeper dependencies by name.
+func GetKeeperDeps(names []string) ([]KeeperDep, error) {
+	deps := make([]KeeperDep, 0, len(names))
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		i := slices.IndexFunc(KeeperDeps, func(d KeeperDep) bool { return d.Name == name })
+		if i < 0 {
+			return nil, fmt.Errorf("%w: %q, expected one of %s", types.ErrKeeperDepUnknown, name, strings.Join(KeeperDepNames(), ", "))
+		}
+		if !slices.ContainsFunc(deps, func(d KeeperDep) bool { return d.Name == name }) {
+			deps = append(deps, KeeperDeps[i])
+		}
+	}
+	return deps, nil
+}
+
+// AddModuleKeeperDeps makes the keeper of a module depend on other keepers. The expected keeper interfaces are added
+// to types/expected_keepers.go, the Keeper gets a field for each and NewKeeper a parameter before the authority.
+// The NewKeeper calls of depinject.go & the keeper tests pass the new keepers, app.go is left to the caller. The
+// testutil mocks of the expected keepers are regenerated, the test fixture uses them for the keepers it does not create.
+func AddModuleKeeperDeps(cwd, module string, deps []KeeperDep) error {
+	cfg, err := LoadStubGenConfig(cwd)
+	if err != nil {
+		return err
+	}
+	moduleDir := cfg.ModuleDir(cwd, module)
+	keeperDir := path.Join(moduleDir, "keeper")
+
+	if err := addExpectedKeepers(path.Join(moduleDir, "types", "expected_keepers.go"), deps); err != nil {
+		return err
+	}
+
+	keeper, err := readKeeperStruct(keeperDir)
+	if err != nil {
+		return err
+	}
+	typesImport, err := keeperTypesImport(keeper.loc)
+	if err != nil {
+		return err
+	}
+
+	deps = slices.DeleteFunc(slices.Clone(deps), func(d KeeperDep) bool { return slices.Contains(keeper.fields, d.Field) })
+	if len(deps) == 0 {
+		return WriteKeeperMocks(moduleDir, typesImport)
+	}
+
+	if err := editGoFile(keeper.loc, func(fset *token.FileSet, f *ast.File, src []byte) []sourceEdit {
+		return addKeeperDepFields(fset, f, src, deps)
+	}); err != nil {
+		return err
+	}
+
+	if err := editGoFunc(keeperDir, "NewKeeper", func(fset *token.FileSet, f *ast.File, src []byte, fn *ast.FuncDecl) ([]sourceEdit, error) {
+		return addNewKeeperParams(fset, src, fn, deps)
+	}); err != nil {
+		return err
+	}
+
+	// depinject.go provides the keeper with the module inputs.
+	if _, err := findGoDecl(moduleDir, "ProvideModule"); err == nil {
+		if err := addDepinjectInputs(moduleDir, typesImport, deps); err != nil {
+			return err
+		}
+	}
+
+	if err := addFixtureMocks(moduleDir, typesImport, deps); err != nil {
+		return err
+	}
+
+	args := make([]string, len(deps))
+	for i, d := range deps {
+		args[i] = d.Test
+	}
+	if err := editKeeperTests(moduleDir, func(fset *token.FileSet, f *ast.File, src []byte) []sourceEdit {
+		return insertNewKeeperArgs(fset, f, args)
+	}); err != nil {
+		return err
+	}
+
+	return WriteKeeperMocks(moduleDir, typesImport)
+}
+
+// addFixtureMocks adds the testutil mock of the Mock dependencies to the keeper test fixture, created before the keeper.
+func addFixtureMocks(moduleDir, typesImport string, deps []KeeperDep) error {
+	deps = slices.DeleteFunc(slices.Clone(deps), func(d KeeperDep) bool { return !d.Mock })
+	if len(deps) == 0 {
+		return nil
+	}
+
+	loc, src, err := findKeeperTestFixture(path.Join(moduleDir, "keeper"))
+	if err != nil || loc == "" {
+		return err
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, loc, src, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+
+	st := fixtureStructType(f)
+	newKeeper := findNewKeeperStmt(f)
+	if newKeeper == nil {
+		return fmt.Errorf("%s: the test fixture must create the keeper with keeper.NewKeeper", loc)
+	}
+
+	var fields, mocks strings.Builder
+	for _, d := range deps {
+		fmt.Fprintf(&fields, "%s *testutil.Mock%s\n", d.Field, d.Interface)
+		fmt.Fprintf(&mocks, "%s = new(testutil.Mock%s)\n", d.Test, d.Interface)
+	}
+
+	// next to the keepers of the other modules
+	fieldsEdit := lineStartEdit(src, fset.Position(st.Fields.Closing).Offset, "\n"+fields.String())
+	for _, field := range st.Fields.List {
+		if len(field.Names) > 0 && strings.HasSuffix(strings.ToLower(field.Names[0].Name), "keeper") {
+			fieldsEdit = sourceEdit{offset: fset.Position(field.End()).Offset, text: "\n" + strings.TrimSuffix(fields.String(), "\n")}
+		}
+	}
+
+	out, _, err := editSource(src, []sourceEdit{
+		fieldsEdit,
+		lineStartEdit(src, fset.Position(newKeeper.Pos()).Offset, mocks.String()),
+	})
+	if err != nil {
+		return err
+	}
+
+	out, err = addGoImports(loc, out, []string{path.Join(path.Dir(typesImport), "testutil")})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(loc, out, 0644)
+}
+
+// findKeeperTestFixture returns the keeper test file declaring the testFixture, empty when there is none.
+func findKeeperTestFixture(keeperDir string) (string, []byte, error) {
+	entries, err := os.ReadDir(keeperDir)
+	if err != nil {
+		return "", nil, err
+	}
+
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), "_test.go") {
+			continue
+		}
+
+		loc := path.Join(keeperDir, e.Name())
+		src, err := os.ReadFile(loc)
+		if err != nil {
+			return "", nil, err
+		}
+
+		f, err := parser.ParseFile(token.NewFileSet(), loc, src, 0)
+		if err != nil {
+			return "", nil, err
+		}
+		if fixtureStructType(f) != nil {
+			return loc, src, nil
+		}
+	}
+	return "", nil, nil
+}
+
+// fixtureStructType returns the testFixture struct of the keeper tests.
+func fixtureStructType(f *ast.File) *ast.StructType {
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			if ts := spec.(*ast.TypeSpec); ts.Name.Name == "testFixture" {
+				st, _ := ts.Type.(*ast.StructType)
+				return st
+			}
+		}
+	}
+	return nil
+}
+
+// findNewKeeperStmt returns the statement creating the keeper with keeper.NewKeeper.
+func findNewKeeperStmt(f *ast.File) ast.Stmt {
+	var stmt ast.Stmt
+	ast.Inspect(f, func(n ast.Node) bool {
+		if assign, ok := n.(*ast.AssignStmt); ok && stmt == nil {
+			for _, rhs := range assign.Rhs {
+				if call, ok := rhs.(*ast.CallExpr); ok && gotypes.ExprString(call.Fun) == "keeper.NewKeeper" {
+					stmt = assign
+				}
+			}
+		}
+		return stmt == nil
+	})
+	return stmt
+}
+
+// addExpectedKeepers declares the expected keeper interfaces which are not declared yet, creating the file if needed.
+func addExpectedKeepers(loc string, deps []KeeperDep) error {
+	src, err := os.ReadFile(loc)
+	if os.IsNotExist(err) {
+		src, err = []byte("package types\n"), nil
+	}
+	if err != nil {
+		return err
+	}
+
+	f, err := parser.ParseFile(token.NewFileSet(), loc, src, 0)
+	if err != nil {
+		return err
+	}
+
+	var code strings.Builder
+	var imports []string
+	for _, d := range deps {
+		if declaresType(f, d.Interface) {
+			continue
+		}
+
+		fmt.Fprintf(&code, "\n// %s defines the expected %s keeper used by the module.\ntype %s interface {\n\t%s\n}\n",
+			d.Interface, d.Name, d.Interface, strings.Join(d.Methods, "\n\t"))
+		imports = append(imports, d.Imports...)
+	}
+	if code.Len() == 0 {
+		return nil
+	}
+
+	out, err := addGoImports(loc, append(src, code.String()...), imports)
+	if err != nil {
+		return err
+	}
+
+	formatted, err := format.Source(out)
+	if err != nil {
+		return fmt.Errorf("formatting %s: %w", loc, err)
+	}
+	if err := os.MkdirAll(path.Dir(loc), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(loc, formatted, 0644)
+}
+
+// keeperTypesImport returns the import path of the module types package the keeper uses.
+func keeperTypesImport(keeperLoc string) (string, error) {
+	f, err := parser.ParseFile(token.NewFileSet(), keeperLoc, nil, parser.ImportsOnly)
+	if err != nil {
+		return "", err
+	}
+
+	for _, imp := range f.Imports {
+		importPath, _ := strconv.Unquote(imp.Path.Value)
+		if (imp.Name == nil && path.Base(importPath) == "types") || (imp.Name != nil && imp.Name.Name == "types") {
+			return importPath, nil
+		}
+	}
+	return "", fmt.Errorf("%s does not import the module types", keeperLoc)
+}
+
+// addKeeperDepFields adds the dependency fields after the other expected keepers of the Keeper, or at its end.
+func addKeeperDepFields(fset *token.FileSet, f *ast.File, src []byte, deps []KeeperDep) []sourceEdit {
+	fields := make([]string, len(deps))
+	for i, d := range deps {
+		fields[i] = fmt.Sprintf("%s types.%s", d.Field, d.Interface)
+	}
+	code := strings.Join(fields, "\n")
+
+	st := keeperStructType(f)
+	var last *ast.Field
+	for _, field := range st.Fields.List {
+		if t := gotypes.ExprString(field.Type); strings.HasPrefix(t, "types.") && strings.HasSuffix(t, "Keeper") {
+			last = field
+		}
+	}
+
+	if last == nil {
+		return []sourceEdit{lineStartEdit(src, fset.Position(st.Fields.Closing).Offset, "\n"+code+"\n")}
+	}
+	return []sourceEdit{{offset: fset.Position(last.End()).Offset, text: "\n" + code}}
+}
+
+// addNewKeeperParams adds the dependencies as NewKeeper parameters, before the authority, and sets the Keeper fields.
+func addNewKeeperParams(fset *token.FileSet, src []byte, fn *ast.FuncDecl, deps []KeeperDep) ([]sourceEdit, error) {
+	var keeper *ast.CompositeLit
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		if lit, ok := n.(*ast.CompositeLit); ok && gotypes.ExprString(lit.Type) == "Keeper" {
+			keeper = lit
+		}
+		return true
+	})
+	if keeper == nil {
+		return nil, fmt.Errorf("NewKeeper must create the Keeper{}")
+	}
+
+	var params strings.Builder
+	edits := make([]sourceEdit, 0, len(deps)+1)
+	for _, d := range deps {
+		fmt.Fprintf(&params, "%s types.%s,\n", d.Field, d.Interface)
+		edits = append(edits, appendCompositeElt(fset, src, keeper, d.Field+": "+d.Field))
+	}
+
+	list := fn.Type.Params.List
+	for _, p := range list {
+		if len(p.Names) > 0 && p.Names[0].Name == "authority" {
+			return append(edits, lineStartEdit(src, fset.Position(p.Pos()).Offset, params.String())), nil
+		}
+	}
+
+	if len(list) == 0 {
+		return append(edits, sourceEdit{offset: fset.Position(fn.Type.Params.Closing).Offset, text: params.String()}), nil
+	}
+	return append(edits, sourceEdit{offset: fset.Position(list[len(list)-1].End()).Offset, text: ",\n" + strings.TrimSuffix(params.String(), ",\n")}), nil
+}
+
+// addDepinjectInputs adds the dependencies to the ModuleInputs of depinject.go & passes them to NewKeeper.
+func addDepinjectInputs(moduleDir, typesImport string, deps []KeeperDep) error {
+	loc, err := findGoDecl(moduleDir, "ProvideModule")
+	if err != nil {
+		return err
+	}
+
+	err = editGoFile(loc, func(fset *token.FileSet, f *ast.File, src []byte) []sourceEdit {
+		var edits []sourceEdit
+		args := make([]string, len(deps))
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				st, ok := ts.Type.(*ast.StructType)
+				if !ok || ts.Name.Name != "ModuleInputs" {
+					continue
+				}
+
+				existing := make(map[string]bool)
+				for _, field := range st.Fields.List {
+					for _, n := range field.Names {
+						existing[n.Name] = true
+					}
+				}
+
+				var fields strings.Builder
+				for i, d := range deps {
+					// inputs are resolved by type, the concrete keepers may already be a field
+					name := d.Interface
+					if existing[name] {
+						name = "Expected" + name
+					}
+					args[i] = "in." + name
+					fmt.Fprintf(&fields, "%s types.%s\n", name, d.Interface)
+				}
+				edits = append(edits, lineStartEdit(src, fset.Position(st.Fields.Closing).Offset, "\n"+fields.String()))
+			}
+		}
+		return append(edits, insertNewKeeperArgs(fset, f, args)...)
+	})
+	if err != nil {
+		return err
+	}
+
+	src, err := os.ReadFile(loc)
+	if err != nil {
+		return err
+	}
+	out, err := addGoImports(loc, src, []string{typesImport + ";types"})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(loc, out, 0644)
+}
+
+// insertNewKeeperArgs passes the args to the keeper.NewKeeper calls of the file, before the last (authority) argument.
+func insertNewKeeperArgs(fset *token.FileSet, f *ast.File, args []string) []sourceEdit {
+	var edits []sourceEdit
+	ast.Inspect(f, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || gotypes.ExprString(call.Fun) != "keeper.NewKeeper" {
+			return true
+		}
+
+		if len(call.Args) == 0 {
+			edits = append(edits, sourceEdit{offset: fset.Position(call.Rparen).Offset, text: strings.Join(args, ", ")})
+		} else {
+			last := call.Args[len(call.Args)-1]
+			edits = append(edits, sourceEdit{offset: fset.Position(last.Pos()).Offset, text: strings.Join(args, ", ") + ", "})
+		}
+		return true
+	})
+	return edits
+}
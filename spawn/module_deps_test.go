@@ -0,0 +1,71 @@
+package spawn
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rollchains/spawn/spawn/types"
+)
+
+func TestAddModuleKeeperDeps(t *testing.T) {
+	cwd := setupExampleModule(t)
+
+	_, err := GetKeeperDeps([]string{"bank", "oracle"})
+	require.ErrorIs(t, err, types.ErrKeeperDepUnknown)
+
+	deps, err := GetKeeperDeps([]string{"account", "bank"})
+	require.NoError(t, err)
+	require.NoError(t, AddModuleKeeperDeps(cwd, "example", deps))
+
+	expected := readTestFile(t, cwd, "x/example/types/expected_keepers.go")
+	require.Contains(t, expected, "type AccountKeeper interface {")
+	require.Contains(t, expected, "type BankKeeper interface {")
+
+	keeper := readTestFile(t, cwd, "x/example/keeper/keeper.go")
+	require.Contains(t, keeper, "accountKeeper types.AccountKeeper")
+	require.Contains(t, keeper, "bankKeeper    types.BankKeeper")
+
+	require.Contains(t, readTestFile(t, cwd, "x/example/depinject.go"), "in.BankKeeper")
+	require.Contains(t, readTestFile(t, cwd, "x/example/keeper/keeper_test.go"), "f.accountkeeper, f.bankkeeper, f.govModAddr)")
+
+	mocks := readTestFile(t, cwd, "x/example/"+KeeperMocksFile)
+	require.Contains(t, mocks, "var _ types.BankKeeper = (*MockBankKeeper)(nil)")
+	require.Contains(t, mocks, `func (_m *MockBankKeeper) SendCoins(ctx context.Context, fromAddr sdk.AccAddress, toAddr sdk.AccAddress, amt sdk.Coins) error {
+	ret := _m.Called(ctx, fromAddr, toAddr, amt)
+
+	return ret.Error(0)
+}`)
+
+	// existing dependencies are skipped
+	require.NoError(t, AddModuleKeeperDeps(cwd, "example", deps))
+	require.Equal(t, keeper, readTestFile(t, cwd, "x/example/keeper/keeper.go"))
+
+	// the concrete staking keeper input of depinject.go is kept
+	deps, err = GetKeeperDeps([]string{"staking"})
+	require.NoError(t, err)
+	require.NoError(t, AddModuleKeeperDeps(cwd, "example", deps))
+
+	depinject := readTestFile(t, cwd, "x/example/depinject.go")
+	require.Contains(t, depinject, "ExpectedStakingKeeper types.StakingKeeper")
+	require.Contains(t, depinject, "in.BankKeeper, in.ExpectedStakingKeeper, govAddr)")
+
+	mocks = readTestFile(t, cwd, "x/example/"+KeeperMocksFile)
+	require.Contains(t, mocks, `func (_m *MockStakingKeeper) BondDenom(ctx context.Context) (string, error) {
+	ret := _m.Called(ctx)
+
+	r0, _ := ret.Get(0).(string)
+	return r0, ret.Error(1)
+}`)
+
+	// the fixture has no ICA controller keeper, its mock is passed instead
+	deps, err = GetKeeperDeps([]string{"icacontroller"})
+	require.NoError(t, err)
+	require.NoError(t, AddModuleKeeperDeps(cwd, "example", deps))
+
+	fixture := readTestFile(t, cwd, "x/example/keeper/keeper_test.go")
+	require.Contains(t, fixture, "icaControllerKeeper *testutil.MockICAControllerKeeper")
+	require.Contains(t, fixture, `"github.com/rollchains/spawn/simapp/x/example/testutil"`)
+	require.Contains(t, fixture, "f.icaControllerKeeper = new(testutil.MockICAControllerKeeper)\n\tf.k = keeper.NewKeeper(")
+	require.Contains(t, fixture, "f.stakingKeeper, f.icaControllerKeeper, f.govModAddr)")
+}
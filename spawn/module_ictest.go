@@ -0,0 +1,633 @@
+package spawn
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/rollchains/spawn/spawn/types"
+)
+
+const (
+	ictestDir      = "interchaintest"
+	ictestWorkflow = ".github/workflows/interchaintest-e2e.yml"
+)
+
+var (
+	ictestMakeTargetRegex = regexp.MustCompile(`(?m)^ictest-[\w-]+:`)
+	ictestMatrixRegex     = regexp.MustCompile(`(?m)^[ \t]+- "ictest-[\w-]+"$`)
+)
+
+// AddModuleInterchainTest creates the interchaintest/<module>_test.go e2e test of a module, querying its Params &
+// updating them with the UpdateParams tx through the CLI. The test runs with `make ictest-<module>`, a Makefile
+// target added to the matrix of the e2e workflow.
+func AddModuleInterchainTest(logger *slog.Logger, cwd, module string) error {
+	ics, err := isICSInterchainTest(cwd)
+	if err != nil {
+		return err
+	}
+
+	query, err := findModuleRPC(logger, cwd, module, Query, "Params")
+	if err != nil {
+		return err
+	}
+	tx, err := findModuleRPC(logger, cwd, module, Tx, "UpdateParams")
+	if err != nil {
+		return err
+	}
+
+	return saveModuleICTest(logger, cwd, module, moduleICTestSource(module, query, tx), ics)
+}
+
+// AddIBCInterchainTest creates the interchaintest/<module>_test.go two chain e2e test of an IBC module or middleware,
+// relaying a packet which is acknowledged & one which times out. The module opens a channel between its ports and
+// sends the packets with its example tx, the middleware wraps the transfer stack and relays ICS-20 transfers.
+func AddIBCInterchainTest(logger *slog.Logger, cwd, module string, middleware bool) error {
+	ics, err := isICSInterchainTest(cwd)
+	if err != nil {
+		return err
+	}
+
+	if middleware {
+		return saveModuleICTest(logger, cwd, module, ibcMiddlewareICTestSource(module), ics)
+	}
+
+	// the counterparty of a consumer chain is the provider, which does not run the module
+	if ics {
+		return fmt.Errorf("%w: IBC module %s needs a second chain running it, interchain-security chains connect to the provider", types.ErrICTestInvalid, module)
+	}
+	if _, err := findModuleRPC(logger, cwd, module, Tx, "SendExampleTx"); err != nil {
+		return err
+	}
+
+	return saveModuleICTest(logger, cwd, module, ibcModuleICTestSource(module), ics)
+}
+
+// isICSInterchainTest returns true if the interchaintest of the chain runs it as a consumer of the provider chain.
+func isICSInterchainTest(cwd string) (bool, error) {
+	setup, err := os.ReadFile(path.Join(cwd, ictestDir, "setup.go"))
+	if err != nil {
+		return false, fmt.Errorf("%w: %w", types.ErrICTestInvalid, err)
+	}
+	return strings.Contains(string(setup), "ProviderChain"), nil
+}
+
+// findModuleRPC returns the RPC of the module proto service.
+func findModuleRPC(logger *slog.Logger, cwd, module string, ftype FileType, name string) (*ProtoRPC, error) {
+	modules, err := GetCurrentModuleRPCsFromProto(logger, path.Join(cwd, "proto"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rpc := range modules[module] {
+		if rpc.FType == ftype && rpc.Name == name {
+			return rpc, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: module %s has no %s %s rpc", types.ErrICTestInvalid, module, name, ftype)
+}
+
+// saveModuleICTest saves the test with the interchain-security parts of the chain & adds its make target.
+func saveModuleICTest(logger *slog.Logger, cwd, module, src string, ics bool) error {
+	testLoc := path.Join(cwd, ictestDir, module+"_test.go")
+	if _, err := os.Stat(testLoc); err == nil {
+		return fmt.Errorf("%w: %s", types.ErrICTestExists, testLoc)
+	}
+
+	fc := NewFileContent(logger, path.Join(ictestDir, module+"_test.go"), testLoc)
+	fc.Contents = src
+
+	// consumer chains run with the provider, their governance only allows the whitelisted proposals
+	if ics {
+		fc.RemoveTaggedLines("not-ics", true)
+	} else {
+		fc.RemoveTaggedLines("ics", true)
+	}
+	fc.RemoveTaggedLines("", false)
+
+	if err := fc.FormatGoFile(); err != nil {
+		return err
+	}
+	if err := fc.Save(); err != nil {
+		return err
+	}
+
+	if err := addICTestMakeTarget(path.Join(cwd, "Makefile"), module); err != nil {
+		return err
+	}
+	return addICTestWorkflowMatrix(path.Join(cwd, ictestWorkflow), module)
+}
+
+// addICTestMakeTarget adds the ictest-<module> target after the other e2e targets of the Makefile.
+func addICTestMakeTarget(loc, module string) error {
+	bz, err := os.ReadFile(loc)
+	if err != nil {
+		return err
+	}
+
+	src := string(bz)
+	target := fmt.Sprintf("ictest-%s:", module)
+	if strings.Contains(src, "\n"+target) {
+		return nil
+	}
+
+	block := fmt.Sprintf("%s\n\t@echo \"Running %s e2e test\"\n\t@cd interchaintest && go test -race -v -run Test%s .\n",
+		target, module, goCamelCase(module))
+
+	targets := ictestMakeTargetRegex.FindAllStringIndex(src, -1)
+	if len(targets) == 0 {
+		return os.WriteFile(loc, []byte(strings.TrimRight(src, "\n")+"\n\n"+block), 0644)
+	}
+
+	// end of the last target, its recipe stops at the first blank line
+	end := targets[len(targets)-1][1]
+	if i := strings.Index(src[end:], "\n\n"); i >= 0 {
+		end += i + 1
+	} else {
+		src = strings.TrimRight(src, "\n") + "\n"
+		end = len(src)
+	}
+
+	return os.WriteFile(loc, []byte(src[:end]+"\n"+block+src[end:]), 0644)
+}
+
+// addICTestWorkflowMatrix runs the ictest-<module> target in the e2e workflow, after the other tests of its matrix.
+func addICTestWorkflowMatrix(loc, module string) error {
+	bz, err := os.ReadFile(loc)
+	if err != nil {
+		return err
+	}
+
+	src := string(bz)
+	entry := fmt.Sprintf(`- "ictest-%s"`, module)
+	if strings.Contains(src, entry) {
+		return nil
+	}
+
+	tests := ictestMatrixRegex.FindAllStringIndex(src, -1)
+	if len(tests) == 0 {
+		return fmt.Errorf("%w: no ictest matrix in %s", types.ErrICTestInvalid, loc)
+	}
+
+	last := src[tests[len(tests)-1][0]:tests[len(tests)-1][1]]
+	indent := last[:strings.Index(last, "-")]
+	end := tests[len(tests)-1][1]
+
+	return os.WriteFile(loc, []byte(src[:end]+"\n"+indent+entry+src[end:]), 0644)
+}
+
+func moduleICTestSource(module string, query, tx *ProtoRPC) string {
+	name := goCamelCase(module)
+
+	return fmt.Sprintf(`package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	govv1 "github.com/cosmos/cosmos-sdk/x/gov/types/v1"
+	"github.com/strangelove-ventures/interchaintest/v8"
+	"github.com/strangelove-ventures/interchaintest/v8/chain/cosmos"
+	"github.com/strangelove-ventures/interchaintest/v8/ibc"
+	"github.com/strangelove-ventures/interchaintest/v8/relayer"
+	"github.com/strangelove-ventures/interchaintest/v8/testreporter"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func Test%[1]s(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping in short mode")
+	}
+
+	ctx := context.Background()
+	rep := testreporter.NewNopReporter()
+	eRep := rep.RelayerExecReporter(t)
+	client, network := interchaintest.DockerSetup(t)
+
+	cf := interchaintest.NewBuiltinChainFactory(zaptest.NewLogger(t), []*interchaintest.ChainSpec{
+		&DefaultChainSpec,
+		&ProviderChain, // spawntag:ics
+	})
+
+	chains, err := cf.Chains(t.Name())
+	require.NoError(t, err)
+
+	chain := chains[0].(*cosmos.CosmosChain)
+	provider := chains[1].(*cosmos.CosmosChain) // spawntag:ics
+
+	ic := interchaintest.NewInterchain().AddChain(chain)
+
+	// <spawntag:ics
+	r := interchaintest.NewBuiltinRelayerFactory(
+		ibc.CosmosRly,
+		zaptest.NewLogger(t),
+		relayer.CustomDockerImage(RelayerRepo, RelayerVersion, "100:1000"),
+		relayer.StartupFlags("--block-history", "200"),
+	).Build(t, client, network)
+
+	ic = ic.AddChain(provider).
+		AddRelayer(r, "relayer").
+		AddProviderConsumerLink(interchaintest.ProviderConsumerLink{
+			Provider: provider,
+			Consumer: chain,
+			Relayer:  r,
+			Path:     ibcPath,
+		})
+	// spawntag:ics>
+
+	require.NoError(t, ic.Build(ctx, eRep, interchaintest.InterchainBuildOptions{
+		TestName:         t.Name(),
+		Client:           client,
+		NetworkID:        network,
+		SkipPathCreation: false,
+	}))
+	t.Cleanup(func() {
+		_ = ic.Close()
+	})
+
+	require.NoError(t, provider.FinishICSProviderSetup(ctx, r, eRep, ibcPath)) // spawntag:ics
+
+	users := interchaintest.GetAndFundTestUsers(t, ctx, "default", GenesisFundsAmount, chain)
+	user := users[0]
+
+	// params set by the transactions, change them to your own values to test the update
+	var params json.RawMessage
+
+	t.Run("query params", func(t *testing.T) {
+		params = query%[1]sParams(t, ctx, chain)
+	})
+
+	// <spawntag:not-ics
+	t.Run("update params with governance", func(t *testing.T) {
+		govAddr, err := chain.GetGovernanceAddress(ctx)
+		require.NoError(t, err)
+
+		msg, err := json.Marshal(map[string]any{
+			"@type":     %[4]q,
+			"authority": govAddr,
+			"params":    params,
+		})
+		require.NoError(t, err)
+
+		height, err := chain.Height(ctx)
+		require.NoError(t, err)
+
+		prop, err := chain.SubmitProposal(ctx, user.KeyName(), cosmos.TxProposalv1{
+			Messages: []json.RawMessage{msg},
+			Deposit:  "1" + chain.Config().Denom,
+			Title:    "Update %[2]s params",
+			Summary:  "Update the params of the %[2]s module",
+		})
+		require.NoError(t, err)
+
+		proposalID, err := strconv.ParseUint(prop.ProposalID, 10, 64)
+		require.NoError(t, err)
+
+		require.NoError(t, chain.VoteOnProposalAllValidators(ctx, proposalID, cosmos.ProposalVoteYes))
+		_, err = cosmos.PollForProposalStatusV1(ctx, chain, height, height+30, proposalID, govv1.StatusPassed)
+		require.NoError(t, err)
+
+		require.JSONEq(t, string(params), string(query%[1]sParams(t, ctx, chain)))
+	})
+	// spawntag:not-ics>
+
+	t.Run("update params requires the authority", func(t *testing.T) {
+		cmd := TxCommandBuilder(ctx, chain, []string{"tx", %[2]q, %[3]q, "--params", string(params)}, user.KeyName())
+		res, err := ExecuteTransaction(ctx, chain, cmd)
+		require.NoError(t, err)
+
+		// the user signs as the authority, the module rejects the message
+		tx, err := chain.GetTransaction(res.TxHash)
+		require.NoError(t, err)
+		require.NotZero(t, tx.Code, tx.RawLog)
+	})
+}
+
+// query%[1]sParams returns the JSON of the %[2]s module params.
+func query%[1]sParams(t *testing.T, ctx context.Context, chain *cosmos.CosmosChain) json.RawMessage {
+	var res struct {
+		Params json.RawMessage `+"`json:\"params\"`"+`
+	}
+	ExecuteQuery(ctx, chain, []string{"query", %[2]q, %[5]q}, &res)
+	require.NotEmpty(t, res.Params)
+
+	return res.Params
+}
+`, name, module, toKebabCase(tx.Name), "/"+tx.ReqType, toKebabCase(query.Name))
+}
+
+func ibcModuleICTestSource(module string) string {
+	name := goCamelCase(module)
+
+	return fmt.Sprintf(`package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	channeltypes "github.com/cosmos/ibc-go/v8/modules/core/04-channel/types"
+	"github.com/strangelove-ventures/interchaintest/v8"
+	"github.com/strangelove-ventures/interchaintest/v8/chain/cosmos"
+	"github.com/strangelove-ventures/interchaintest/v8/ibc"
+	"github.com/strangelove-ventures/interchaintest/v8/relayer"
+	"github.com/strangelove-ventures/interchaintest/v8/testreporter"
+	"github.com/strangelove-ventures/interchaintest/v8/testutil"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func Test%[1]s(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping in short mode")
+	}
+
+	ctx := context.Background()
+	rep := testreporter.NewNopReporter()
+	eRep := rep.RelayerExecReporter(t)
+	client, network := interchaintest.DockerSetup(t)
+
+	cf := interchaintest.NewBuiltinChainFactory(zaptest.NewLogger(t), []*interchaintest.ChainSpec{
+		&DefaultChainSpec,
+		&SecondDefaultChainSpec,
+	})
+
+	chains, err := cf.Chains(t.Name())
+	require.NoError(t, err)
+
+	chainA, chainB := chains[0].(*cosmos.CosmosChain), chains[1].(*cosmos.CosmosChain)
+
+	r := interchaintest.NewBuiltinRelayerFactory(
+		ibc.CosmosRly,
+		zaptest.NewLogger(t),
+		relayer.CustomDockerImage(RelayerRepo, RelayerVersion, "100:1000"),
+		relayer.StartupFlags("--block-history", "200"),
+	).Build(t, client, network)
+
+	ic := interchaintest.NewInterchain().
+		AddChain(chainA).
+		AddChain(chainB).
+		AddRelayer(r, "relayer").
+		AddLink(interchaintest.InterchainLink{
+			Chain1:  chainA,
+			Chain2:  chainB,
+			Relayer: r,
+			Path:    ibcPath,
+		})
+
+	require.NoError(t, ic.Build(ctx, eRep, interchaintest.InterchainBuildOptions{
+		TestName:         t.Name(),
+		Client:           client,
+		NetworkID:        network,
+		SkipPathCreation: false,
+	}))
+	t.Cleanup(func() {
+		_ = ic.Close()
+	})
+
+	// open a channel between the %[2]s ports, on the connection of the transfer channel
+	require.NoError(t, r.CreateChannel(ctx, eRep, ibcPath, ibc.CreateChannelOptions{
+		SourcePortName: %[2]q,
+		DestPortName:   %[2]q,
+		Order:          ibc.Unordered,
+		Version:        %[3]q,
+	}))
+
+	aChannelID := get%[1]sChannel(t, ctx, r, eRep, chainA)
+	bChannelID := get%[1]sChannel(t, ctx, r, eRep, chainB)
+
+	users := interchaintest.GetAndFundTestUsers(t, ctx, "default", GenesisFundsAmount, chainA)
+	user := users[0]
+
+	t.Run("acknowledgement", func(t *testing.T) {
+		cmd := TxCommandBuilder(ctx, chainA, []string{"tx", %[2]q, "example-tx", %[2]q, aChannelID, "some data"}, user.KeyName())
+		res, err := ExecuteTransaction(ctx, chainA, cmd)
+		require.NoError(t, err)
+		require.Zero(t, res.Code, res.RawLog)
+
+		require.NoError(t, r.Flush(ctx, eRep, ibcPath, aChannelID))
+
+		// chainB received the packet & wrote the result acknowledgement of OnRecvPacket
+		var ack struct {
+			Acknowledgement []byte `+"`json:\"acknowledgement\"`"+`
+		}
+		ExecuteQuery(ctx, chainB, []string{"query", "ibc", "channel", "packet-ack", %[2]q, bChannelID, "1"}, &ack)
+		expected := channeltypes.CommitAcknowledgement(channeltypes.NewResultAcknowledgement([]byte{byte(1)}).Acknowledgement())
+		require.Equal(t, expected, ack.Acknowledgement)
+
+		// chainA handled the acknowledgement
+		require.Empty(t, query%[1]sPacketCommitments(ctx, chainA, aChannelID))
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		timeout := strconv.FormatUint(uint64(time.Second.Nanoseconds()), 10)
+		cmd := TxCommandBuilder(ctx, chainA, []string{"tx", %[2]q, "example-tx", %[2]q, aChannelID, "some data", "--packet-timeout-timestamp", timeout}, user.KeyName())
+		res, err := ExecuteTransaction(ctx, chainA, cmd)
+		require.NoError(t, err)
+		require.Zero(t, res.Code, res.RawLog)
+
+		// the packet times out on chainB before it is relayed
+		require.NoError(t, testutil.WaitForBlocks(ctx, 5, chainB))
+		require.NoError(t, r.Flush(ctx, eRep, ibcPath, aChannelID))
+
+		var receipt struct {
+			Received bool `+"`json:\"received\"`"+`
+		}
+		ExecuteQuery(ctx, chainB, []string{"query", "ibc", "channel", "packet-receipt", %[2]q, bChannelID, "2"}, &receipt)
+		require.False(t, receipt.Received)
+
+		// chainA handled the timeout
+		require.Empty(t, query%[1]sPacketCommitments(ctx, chainA, aChannelID))
+	})
+}
+
+// get%[1]sChannel returns the channel of the %[2]s port on the chain.
+func get%[1]sChannel(t *testing.T, ctx context.Context, r ibc.Relayer, eRep ibc.RelayerExecReporter, chain *cosmos.CosmosChain) string {
+	channels, err := r.GetChannels(ctx, eRep, chain.Config().ChainID)
+	require.NoError(t, err)
+
+	for _, channel := range channels {
+		if channel.PortID == %[2]q {
+			return channel.ChannelID
+		}
+	}
+
+	require.FailNow(t, "no %[2]s channel", channels)
+	return ""
+}
+
+// query%[1]sPacketCommitments returns the packets sent on the channel which are not acknowledged or timed out.
+func query%[1]sPacketCommitments(ctx context.Context, chain *cosmos.CosmosChain, channelID string) []json.RawMessage {
+	var res struct {
+		Commitments []json.RawMessage `+"`json:\"commitments\"`"+`
+	}
+	ExecuteQuery(ctx, chain, []string{"query", "ibc", "channel", "packet-commitments", %[2]q, channelID}, &res)
+
+	return res.Commitments
+}
+`, name, module, module+"-1")
+}
+
+func ibcMiddlewareICTestSource(module string) string {
+	name := goCamelCase(module)
+
+	return fmt.Sprintf(`package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"cosmossdk.io/math"
+	transfertypes "github.com/cosmos/ibc-go/v8/modules/apps/transfer/types"
+	"github.com/strangelove-ventures/interchaintest/v8"
+	"github.com/strangelove-ventures/interchaintest/v8/chain/cosmos"
+	"github.com/strangelove-ventures/interchaintest/v8/ibc"
+	"github.com/strangelove-ventures/interchaintest/v8/relayer"
+	"github.com/strangelove-ventures/interchaintest/v8/testreporter"
+	"github.com/strangelove-ventures/interchaintest/v8/testutil"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func Test%[1]s(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping in short mode")
+	}
+
+	ctx := context.Background()
+	rep := testreporter.NewNopReporter()
+	eRep := rep.RelayerExecReporter(t)
+	client, network := interchaintest.DockerSetup(t)
+
+	cf := interchaintest.NewBuiltinChainFactory(zaptest.NewLogger(t), []*interchaintest.ChainSpec{
+		&DefaultChainSpec,
+		&ProviderChain,          // spawntag:ics
+		&SecondDefaultChainSpec, // spawntag:not-ics
+	})
+
+	chains, err := cf.Chains(t.Name())
+	require.NoError(t, err)
+
+	chainA, chainB := chains[0].(*cosmos.CosmosChain), chains[1].(*cosmos.CosmosChain)
+
+	r := interchaintest.NewBuiltinRelayerFactory(
+		ibc.CosmosRly,
+		zaptest.NewLogger(t),
+		relayer.CustomDockerImage(RelayerRepo, RelayerVersion, "100:1000"),
+		relayer.StartupFlags("--block-history", "200"),
+	).Build(t, client, network)
+
+	ic := interchaintest.NewInterchain().
+		AddChain(chainA).
+		AddChain(chainB).
+		AddRelayer(r, "relayer")
+
+	// <spawntag:not-ics
+	ic = ic.AddLink(interchaintest.InterchainLink{
+		Chain1:  chainA,
+		Chain2:  chainB,
+		Relayer: r,
+		Path:    ibcPath,
+	})
+	// spawntag:not-ics>
+	// <spawntag:ics
+	ic = ic.AddProviderConsumerLink(interchaintest.ProviderConsumerLink{
+		Consumer: chainA,
+		Provider: chainB,
+		Relayer:  r,
+		Path:     ibcPath,
+	})
+	// spawntag:ics>
+
+	require.NoError(t, ic.Build(ctx, eRep, interchaintest.InterchainBuildOptions{
+		TestName:         t.Name(),
+		Client:           client,
+		NetworkID:        network,
+		SkipPathCreation: false,
+	}))
+	t.Cleanup(func() {
+		_ = ic.Close()
+	})
+
+	require.NoError(t, chainB.FinishICSProviderSetup(ctx, r, eRep, ibcPath)) // spawntag:ics
+
+	users := interchaintest.GetAndFundTestUsers(t, ctx, "default", GenesisFundsAmount, chainA, chainB)
+	userA, userB := users[0], users[1]
+
+	aInfo, err := r.GetChannels(ctx, eRep, chainA.Config().ChainID)
+	require.NoError(t, err)
+	aChannelID, err := getTransferChannel(aInfo)
+	require.NoError(t, err)
+
+	bInfo, err := r.GetChannels(ctx, eRep, chainB.Config().ChainID)
+	require.NoError(t, err)
+	bChannelID, err := getTransferChannel(bInfo)
+	require.NoError(t, err)
+
+	// the %[2]s middleware wraps the transfer stack, the ICS-20 packets go through its callbacks
+	amount := math.NewInt(1_000_000)
+	transfer := ibc.WalletAmount{
+		Address: userB.FormattedAddress(),
+		Denom:   chainA.Config().Denom,
+		Amount:  amount,
+	}
+
+	t.Run("acknowledgement", func(t *testing.T) {
+		_, err := chainA.SendIBCTransfer(ctx, aChannelID, userA.KeyName(), transfer, ibc.TransferOptions{})
+		require.NoError(t, err)
+
+		require.NoError(t, r.Flush(ctx, eRep, ibcPath, aChannelID))
+
+		// chainB received the transfer
+		ibcDenom := transfertypes.ParseDenomTrace(transfertypes.GetPrefixedDenom("transfer", bChannelID, chainA.Config().Denom)).IBCDenom()
+		bal, err := chainB.GetBalance(ctx, userB.FormattedAddress(), ibcDenom)
+		require.NoError(t, err)
+		require.True(t, bal.Equal(amount), bal)
+
+		// chainA handled the acknowledgement
+		require.Empty(t, query%[1]sPacketCommitments(ctx, chainA, aChannelID))
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		before, err := chainA.GetBalance(ctx, userA.FormattedAddress(), chainA.Config().Denom)
+		require.NoError(t, err)
+
+		_, err = chainA.SendIBCTransfer(ctx, aChannelID, userA.KeyName(), transfer, ibc.TransferOptions{
+			Timeout: &ibc.IBCTimeout{NanoSeconds: uint64(time.Second.Nanoseconds())},
+		})
+		require.NoError(t, err)
+
+		// the packet times out on chainB before it is relayed
+		require.NoError(t, testutil.WaitForBlocks(ctx, 5, chainB))
+		require.NoError(t, r.Flush(ctx, eRep, ibcPath, aChannelID))
+
+		// chainA handled the timeout & refunded the transfer
+		require.Empty(t, query%[1]sPacketCommitments(ctx, chainA, aChannelID))
+
+		after, err := chainA.GetBalance(ctx, userA.FormattedAddress(), chainA.Config().Denom)
+		require.NoError(t, err)
+		require.True(t, after.Equal(before), after)
+	})
+}
+
+// query%[1]sPacketCommitments returns the transfers sent on the channel which are not acknowledged or timed out.
+func query%[1]sPacketCommitments(ctx context.Context, chain *cosmos.CosmosChain, channelID string) []json.RawMessage {
+	var res struct {
+		Commitments []json.RawMessage `+"`json:\"commitments\"`"+`
+	}
+	ExecuteQuery(ctx, chain, []string{"query", "ibc", "channel", "packet-commitments", transfertypes.PortID, channelID}, &res)
+
+	return res.Commitments
+}
+`, name, module)
+}
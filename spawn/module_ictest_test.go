@@ -0,0 +1,144 @@
+package spawn
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rollchains/spawn/simapp"
+	"github.com/rollchains/spawn/spawn/types"
+)
+
+// setupICTestChain creates a chain with the x/example module, the interchaintest setup & the e2e targets.
+func setupICTestChain(t *testing.T, ics bool) string {
+	t.Helper()
+
+	cwd := setupExampleModule(t)
+	for embedded, files := range map[fs.FS][]string{
+		simapp.ICTestFS: {"interchaintest/setup.go"},
+		simapp.SimAppFS: {"Makefile", ictestWorkflow},
+	} {
+		for _, relPath := range files {
+			bz, err := fs.ReadFile(embedded, relPath)
+			require.NoError(t, err)
+
+			if !ics {
+				fc := &FileContent{Contents: string(bz), Logger: logger}
+				fc.RemoveTaggedLines("ics", true)
+				bz = []byte(fc.Contents)
+			}
+
+			loc := path.Join(cwd, relPath)
+			require.NoError(t, os.MkdirAll(path.Dir(loc), 0755))
+			require.NoError(t, os.WriteFile(loc, bz, 0644))
+		}
+	}
+
+	return cwd
+}
+
+func TestAddModuleInterchainTest(t *testing.T) {
+	cwd := setupICTestChain(t, false)
+
+	require.NoError(t, AddModuleInterchainTest(logger, cwd, "example"))
+
+	test := readTestFile(t, cwd, "interchaintest/example_test.go")
+	require.Contains(t, test, "func TestExample(t *testing.T) {")
+	require.Contains(t, test, `[]string{"query", "example", "params"}`)
+	require.Contains(t, test, `[]string{"tx", "example", "update-params", "--params", string(params)}`)
+	require.Contains(t, test, `"@type":     "/example.v1.MsgUpdateParams",`)
+	require.NotContains(t, test, "provider")
+	require.NotContains(t, test, "spawntag")
+
+	makefile := readTestFile(t, cwd, "Makefile")
+	require.Contains(t, makefile, "TestIBCRateLimit .\n\nictest-example:\n\t@echo \"Running example e2e test\"\n\t@cd interchaintest && go test -race -v -run TestExample .\n\n")
+
+	workflow := readTestFile(t, cwd, ictestWorkflow)
+	require.Contains(t, workflow, "          - \"ictest-ratelimit\"\n          - \"ictest-example\"\n")
+
+	// the test exists, the targets are not added twice
+	require.ErrorIs(t, AddModuleInterchainTest(logger, cwd, "example"), types.ErrICTestExists)
+	require.NoError(t, os.Remove(path.Join(cwd, "interchaintest", "example_test.go")))
+	require.NoError(t, AddModuleInterchainTest(logger, cwd, "example"))
+	require.Equal(t, makefile, readTestFile(t, cwd, "Makefile"))
+	require.Equal(t, workflow, readTestFile(t, cwd, ictestWorkflow))
+}
+
+func TestAddModuleInterchainTestICS(t *testing.T) {
+	cwd := setupICTestChain(t, true)
+
+	require.NoError(t, AddModuleInterchainTest(logger, cwd, "example"))
+
+	test := readTestFile(t, cwd, "interchaintest/example_test.go")
+	require.Contains(t, test, "AddProviderConsumerLink")
+	require.Contains(t, test, "provider.FinishICSProviderSetup(ctx, r, eRep, ibcPath)")
+	require.NotContains(t, test, "SubmitProposal", "consumer governance only allows whitelisted proposals")
+	require.NotContains(t, test, "govv1")
+	require.NotContains(t, test, "spawntag")
+}
+
+func TestAddModuleInterchainTestInvalid(t *testing.T) {
+	cwd := setupICTestChain(t, false)
+
+	require.ErrorIs(t, AddModuleInterchainTest(logger, cwd, "other"), types.ErrICTestInvalid)
+	require.ErrorIs(t, AddModuleInterchainTest(logger, t.TempDir(), "example"), types.ErrICTestInvalid)
+	require.NoFileExists(t, path.Join(cwd, "interchaintest", "other_test.go"))
+	require.NotContains(t, readTestFile(t, cwd, "Makefile"), "ictest-other")
+}
+
+func TestAddIBCInterchainTest(t *testing.T) {
+	cwd := setupICTestChain(t, false)
+	require.NoError(t, fs.WalkDir(simapp.ProtoModuleFS, "proto/ibcmodule", func(relPath string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		bz, err := fs.ReadFile(simapp.ProtoModuleFS, relPath)
+		if err != nil {
+			return err
+		}
+
+		loc := path.Join(cwd, relPath)
+		if err := os.MkdirAll(path.Dir(loc), 0755); err != nil {
+			return err
+		}
+		return os.WriteFile(loc, bz, 0644)
+	}))
+
+	require.NoError(t, AddIBCInterchainTest(logger, cwd, "ibcmodule", false))
+
+	test := readTestFile(t, cwd, "interchaintest/ibcmodule_test.go")
+	require.Contains(t, test, "func TestIbcmodule(t *testing.T) {")
+	require.Contains(t, test, `Version:        "ibcmodule-1",`)
+	require.Contains(t, test, `[]string{"tx", "ibcmodule", "example-tx", "ibcmodule", aChannelID, "some data"}`)
+	require.Contains(t, test, "&SecondDefaultChainSpec,")
+	require.NotContains(t, test, "spawntag")
+	require.Contains(t, readTestFile(t, cwd, "Makefile"), "\t@cd interchaintest && go test -race -v -run TestIbcmodule .\n")
+	require.Contains(t, readTestFile(t, cwd, ictestWorkflow), `- "ictest-ibcmodule"`)
+
+	// the middleware relays transfers, it has no tx to find
+	require.NoError(t, AddIBCInterchainTest(logger, cwd, "mw", true))
+	test = readTestFile(t, cwd, "interchaintest/mw_test.go")
+	require.Contains(t, test, "chainA.SendIBCTransfer(ctx, aChannelID, userA.KeyName(), transfer, ibc.TransferOptions{})")
+	require.Contains(t, test, "func queryMwPacketCommitments(")
+	require.NotContains(t, test, "ProviderChain")
+
+	// no ibc module tx
+	require.ErrorIs(t, AddIBCInterchainTest(logger, cwd, "example", false), types.ErrICTestInvalid)
+}
+
+func TestAddIBCInterchainTestICS(t *testing.T) {
+	cwd := setupICTestChain(t, true)
+
+	require.ErrorIs(t, AddIBCInterchainTest(logger, cwd, "ibcmodule", false), types.ErrICTestInvalid)
+
+	require.NoError(t, AddIBCInterchainTest(logger, cwd, "mw", true))
+	test := readTestFile(t, cwd, "interchaintest/mw_test.go")
+	require.Contains(t, test, "AddProviderConsumerLink")
+	require.Contains(t, test, "&ProviderChain,")
+	require.NotContains(t, test, "SecondDefaultChainSpec")
+	require.NotContains(t, test, "spawntag")
+}
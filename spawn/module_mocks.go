@@ -0,0 +1,238 @@
+package spawn
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	gotypes "go/types"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+const (
+	// KeeperMocksFile is the file of the expected keeper mocks, relative to the module directory.
+	KeeperMocksFile = "testutil/expected_keepers_mocks.go"
+
+	testifyMockImport = "github.com/stretchr/testify/mock"
+)
+
+// WriteKeeperMocks generates a testify mock for every interface of the module types/expected_keepers.go, so the
+// keeper can be unit tested without the keepers of other modules. The file is regenerated from the interfaces each
+// time, typesImport is the import path of the module types package.
+func WriteKeeperMocks(moduleDir, typesImport string) error {
+	expectedLoc := path.Join(moduleDir, "types", "expected_keepers.go")
+	f, err := parser.ParseFile(token.NewFileSet(), expectedLoc, nil, 0)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var mocks strings.Builder
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			ts := spec.(*ast.TypeSpec)
+			iface, ok := ts.Type.(*ast.InterfaceType)
+			if !ok || !ts.Name.IsExported() || ts.TypeParams != nil {
+				continue
+			}
+			writeInterfaceMock(&mocks, ts.Name.Name, iface)
+		}
+	}
+	if mocks.Len() == 0 {
+		return nil
+	}
+
+	var src strings.Builder
+	src.WriteString(`// Code generated by spawn from types/expected_keepers.go. DO NOT EDIT.
+
+// Package testutil has mocks of the module expected keepers, to unit test the keeper without the other modules:
+//
+//	bankKeeper := new(testutil.MockBankKeeper)
+//	bankKeeper.On("SendCoins", mock.Anything, from, to, coins).Return(nil)
+package testutil
+
+import (
+`)
+	// standard library imports first, then the others
+	var std, others strings.Builder
+	fmt.Fprintf(&others, "\t%q\n\t%q\n", testifyMockImport, typesImport)
+	for _, imp := range f.Imports {
+		importPath, _ := strconv.Unquote(imp.Path.Value)
+		b := &others
+		if !strings.Contains(strings.Split(importPath, "/")[0], ".") {
+			b = &std
+		}
+
+		if imp.Name != nil {
+			fmt.Fprintf(b, "\t%s %s\n", imp.Name.Name, imp.Path.Value)
+		} else {
+			fmt.Fprintf(b, "\t%s\n", imp.Path.Value)
+		}
+	}
+	fmt.Fprintf(&src, "%s\n%s)\n", std.String(), others.String())
+	src.WriteString(mocks.String())
+
+	loc := path.Join(moduleDir, KeeperMocksFile)
+	out, err := removeUnusedImports(loc, []byte(src.String()))
+	if err != nil {
+		return err
+	}
+
+	formatted, err := format.Source(out)
+	if err != nil {
+		return fmt.Errorf("formatting %s: %w", loc, err)
+	}
+	if err := os.MkdirAll(path.Dir(loc), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(loc, formatted, 0644)
+}
+
+// writeInterfaceMock writes the Mock<Name> type of an expected keeper interface & its methods.
+func writeInterfaceMock(b *strings.Builder, name string, iface *ast.InterfaceType) {
+	mockName := "Mock" + name
+	fmt.Fprintf(b, "\n// %s is a mock of the types.%s expected keeper.\ntype %s struct {\n\tmock.Mock\n}\n\n", mockName, name, mockName)
+	fmt.Fprintf(b, "var _ types.%s = (*%s)(nil)\n", name, mockName)
+
+	for _, method := range iface.Methods.List {
+		fn, ok := method.Type.(*ast.FuncType)
+		if !ok || len(method.Names) == 0 {
+			// embedded interfaces are not mocked
+			continue
+		}
+
+		var params, args []string
+		for _, field := range fieldList(fn.Params) {
+			typ := gotypes.ExprString(qualifyTypesIdents(field.Type))
+			names := field.Names
+			if len(names) == 0 {
+				names = []*ast.Ident{ast.NewIdent("_")}
+			}
+			for _, n := range names {
+				argName := n.Name
+				if argName == "_" {
+					argName = fmt.Sprintf("p%d", len(args))
+				}
+				params = append(params, argName+" "+typ)
+				args = append(args, argName)
+			}
+		}
+
+		var results []string
+		for _, field := range fieldList(fn.Results) {
+			typ := gotypes.ExprString(qualifyTypesIdents(field.Type))
+			for range max(1, len(field.Names)) {
+				results = append(results, typ)
+			}
+		}
+
+		resultsDecl := strings.Join(results, ", ")
+		if len(results) > 1 {
+			resultsDecl = "(" + resultsDecl + ")"
+		}
+
+		methodName := method.Names[0].Name
+		fmt.Fprintf(b, "\n// %s mocks types.%s.%s.\n", methodName, name, methodName)
+		fmt.Fprintf(b, "func (_m *%s) %s(%s) %s {\n", mockName, methodName, strings.Join(params, ", "), resultsDecl)
+		if len(results) == 0 {
+			fmt.Fprintf(b, "\t_m.Called(%s)\n}\n", strings.Join(args, ", "))
+			continue
+		}
+
+		fmt.Fprintf(b, "\tret := _m.Called(%s)\n\n", strings.Join(args, ", "))
+		rets := make([]string, len(results))
+		for i, typ := range results {
+			if typ == "error" {
+				rets[i] = fmt.Sprintf("ret.Error(%d)", i)
+				continue
+			}
+			rets[i] = fmt.Sprintf("r%d", i)
+			fmt.Fprintf(b, "\tr%d, _ := ret.Get(%d).(%s)\n", i, i, typ)
+		}
+		fmt.Fprintf(b, "\treturn %s\n}\n", strings.Join(rets, ", "))
+	}
+}
+
+func fieldList(fl *ast.FieldList) []*ast.Field {
+	if fl == nil {
+		return nil
+	}
+	return fl.List
+}
+
+// qualifyTypesIdents qualifies the types declared in the module types package with the types import.
+func qualifyTypesIdents(expr ast.Expr) ast.Expr {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if gotypes.Universe.Lookup(e.Name) == nil {
+			return &ast.SelectorExpr{X: ast.NewIdent("types"), Sel: e}
+		}
+	case *ast.StarExpr:
+		return &ast.StarExpr{X: qualifyTypesIdents(e.X)}
+	case *ast.ArrayType:
+		return &ast.ArrayType{Len: e.Len, Elt: qualifyTypesIdents(e.Elt)}
+	case *ast.MapType:
+		return &ast.MapType{Key: qualifyTypesIdents(e.Key), Value: qualifyTypesIdents(e.Value)}
+	case *ast.ChanType:
+		return &ast.ChanType{Dir: e.Dir, Value: qualifyTypesIdents(e.Value)}
+	case *ast.Ellipsis:
+		return &ast.Ellipsis{Elt: qualifyTypesIdents(e.Elt)}
+	case *ast.IndexExpr:
+		return &ast.IndexExpr{X: qualifyTypesIdents(e.X), Index: qualifyTypesIdents(e.Index)}
+	case *ast.FuncType:
+		return &ast.FuncType{Params: qualifyFieldList(e.Params), Results: qualifyFieldList(e.Results)}
+	}
+	return expr
+}
+
+func qualifyFieldList(fl *ast.FieldList) *ast.FieldList {
+	if fl == nil {
+		return nil
+	}
+	out := &ast.FieldList{}
+	for _, field := range fl.List {
+		out.List = append(out.List, &ast.Field{Names: field.Names, Type: qualifyTypesIdents(field.Type)})
+	}
+	return out
+}
+
+// removeUnusedImports drops the imports the source does not use.
+func removeUnusedImports(fileLoc string, src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, fileLoc, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, imp := range slices.Clone(f.Imports) {
+		importPath, _ := strconv.Unquote(imp.Path.Value)
+		if astutil.UsesImport(f, importPath) {
+			continue
+		}
+
+		name := ""
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		astutil.DeleteNamedImport(fset, f, name, importPath)
+	}
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, f); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,282 @@
+package spawn
+
+import (
+	"fmt"
+	"log/slog"
+	"path"
+	"slices"
+	"strings"
+
+	protoast "github.com/bufbuild/protocompile/ast"
+
+	"github.com/rollchains/spawn/spawn/types"
+)
+
+const coinProtoType = "cosmos.base.v1beta1.Coin"
+
+// ModuleRPC is a new Msg or Query RPC of a module, see AddModuleRPC.
+type ModuleRPC struct {
+	Module string
+	// The RPC name (CreatePost), the request & response are Msg<Name> or Query<Name>Request
+	Name  string
+	FType FileType
+	// Fields of the request & response messages
+	Fields    []ProtoField
+	ResFields []ProtoField
+	// The address field which signs the Msg, added when not one of the fields
+	Signer string
+	// Impl is the body of the server method, a TODO stub when empty
+	Impl string
+}
+
+// ParseRPCFields parses name:type field arguments, see protoFieldTypes. The type defaults to string.
+func ParseRPCFields(args []string) ([]ProtoField, error) {
+	fields := make([]ProtoField, 0, len(args))
+	for _, arg := range args {
+		field, err := parseField(arg, types.ErrRPCInvalid)
+		if err != nil {
+			return nil, err
+		}
+		if slices.ContainsFunc(fields, func(f ProtoField) bool { return f.Name == field.Name }) {
+			return nil, fmt.Errorf("%w: duplicate field %s", types.ErrRPCInvalid, field.Name)
+		}
+
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+func (r ModuleRPC) req() string {
+	if r.FType == Tx {
+		return "Msg" + r.Name
+	}
+	return "Query" + r.Name + "Request"
+}
+
+func (r ModuleRPC) res() string {
+	if r.FType == Tx {
+		return "Msg" + r.Name + "Response"
+	}
+	return "Query" + r.Name + "Response"
+}
+
+// reqFields are the request fields, with the signer first when it is not one of them.
+func (r ModuleRPC) reqFields() []ProtoField {
+	if r.FType != Tx || slices.ContainsFunc(r.Fields, func(f ProtoField) bool { return f.Name == r.Signer }) {
+		return r.Fields
+	}
+	return append([]ProtoField{{Name: r.Signer, Type: "string", Scalar: addressScalar}}, r.Fields...)
+}
+
+// Validate checks the RPC can be generated.
+func (r ModuleRPC) Validate() error {
+	if !stateNameRegex.MatchString(r.Name) {
+		return fmt.Errorf("%w: name %q must be CamelCase", types.ErrRPCInvalid, r.Name)
+	}
+	if r.FType != Tx && r.FType != Query {
+		return fmt.Errorf("%w: only Msg & Query RPCs can be added", types.ErrRPCInvalid)
+	}
+
+	if r.FType == Tx {
+		if !stateFieldNameRegex.MatchString(r.Signer) {
+			return fmt.Errorf("%w: signer %q must be a snake_case field", types.ErrRPCInvalid, r.Signer)
+		}
+		for _, f := range r.Fields {
+			if f.Name == r.Signer && (f.Type != "string" || f.Repeated) {
+				return fmt.Errorf("%w: signer %s must be an address", types.ErrRPCInvalid, f.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// AddModuleRPC adds an RPC to the Msg or Query service of a module with its request & response messages. Msgs are
+// signed by their Signer field and have an amino name. The server stub is then generated as with stub-gen.
+func AddModuleRPC(logger *slog.Logger, cwd string, r ModuleRPC) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+
+	if err := addModuleRPCProto(logger, cwd, r); err != nil {
+		return err
+	}
+
+	var impl func(rpc *ProtoRPC)
+	if r.Impl != "" {
+		impl = func(rpc *ProtoRPC) { rpc.Impl = r.Impl }
+	}
+	return scaffoldRPCs(logger, cwd, r.Module, r.FType, []string{r.Name}, impl)
+}
+
+// addModuleRPCProto adds the RPC to the proto service of the module, with its request & response messages.
+func addModuleRPCProto(logger *slog.Logger, cwd string, r ModuleRPC) error {
+	files, err := parseProtoDir(logger, path.Join(cwd, "proto"))
+	if err != nil {
+		return err
+	}
+
+	var file *ProtoFile
+	for _, pf := range files {
+		if pf.Module() != r.Module {
+			continue
+		}
+
+		for _, rpc := range pf.RPCs {
+			if rpc.Name == r.Name && rpc.FType == r.FType {
+				return fmt.Errorf("%w: %s is already defined at %s", types.ErrRPCExists, r.Name, rpc.ProtoLoc)
+			}
+			if rpc.FType == r.FType && rpc.Service == r.service() {
+				file = pf
+			}
+		}
+		for _, m := range pf.Messages {
+			if name := protoBaseName(m.FullName); name == r.req() || name == r.res() {
+				return fmt.Errorf("%w: %s is already defined at %s", types.ErrRPCExists, m.FullName, m.ProtoLoc)
+			}
+		}
+	}
+
+	if file == nil {
+		return fmt.Errorf("proto/%s has no %s service", r.Module, r.service())
+	}
+
+	return editProtoFile(file.Loc, func(node *protoast.FileNode, src []byte) ([]sourceEdit, error) {
+		svc := protoService(node, r.service())
+		if svc == nil {
+			return nil, fmt.Errorf("%s: %s service not found", file.Loc, r.service())
+		}
+
+		return []sourceEdit{
+			protoImportEdit(node, r.protoImports()...),
+			protoInsertBeforeBrace(node, src, svc.CloseBrace, r.rpcProto(file.Package)),
+			protoAppendEdit(src, r.msgsProto()),
+		}, nil
+	})
+}
+
+func (r ModuleRPC) service() string {
+	if r.FType == Tx {
+		return "Msg"
+	}
+	return "Query"
+}
+
+func (r ModuleRPC) protoImports() []string {
+	var imports []string
+	if r.FType == Tx {
+		imports = append(imports, "cosmos/msg/v1/msg.proto", "amino/amino.proto")
+	} else {
+		imports = append(imports, "google/api/annotations.proto")
+	}
+
+	return append(imports, protoFieldImports(append(r.reqFields(), r.ResFields...))...)
+}
+
+// protoFieldImports are the proto imports the field declarations of protoFieldsDecl need.
+func protoFieldImports(fields []ProtoField) []string {
+	var imports []string
+	for _, f := range fields {
+		switch {
+		case f.Scalar != "":
+			imports = append(imports, "cosmos_proto/cosmos.proto")
+		case f.Type == coinProtoType:
+			imports = append(imports, "cosmos/base/v1beta1/coin.proto", "gogoproto/gogo.proto", "amino/amino.proto")
+		}
+	}
+	return imports
+}
+
+func (r ModuleRPC) rpcProto(pkg string) string {
+	if r.FType == Tx {
+		return fmt.Sprintf("\n  // %s defines the %s transaction.\n  rpc %s(%s) returns (%s);\n", r.Name, r.req(), r.Name, r.req(), r.res())
+	}
+
+	return fmt.Sprintf(`
+  // %s defines the %s query.
+  rpc %s(%s) returns (%s) {
+    option (google.api.http).get = "/%s/%s";
+  }
+`, r.Name, r.Name, r.Name, r.req(), r.res(), strings.ReplaceAll(pkg, ".", "/"), toSnakeCase(r.Name))
+}
+
+func (r ModuleRPC) msgsProto() string {
+	var b strings.Builder
+	if r.FType == Tx {
+		fmt.Fprintf(&b, "// %s is the Msg/%s request type.\nmessage %s {\n", r.req(), r.Name, r.req())
+		fmt.Fprintf(&b, "  option (cosmos.msg.v1.signer) = %q;\n  option (amino.name) = %q;\n\n", r.Signer, r.Module+"/"+r.req())
+	} else {
+		fmt.Fprintf(&b, "// %s is the request type for the Query/%s RPC method.\nmessage %s {\n", r.req(), r.Name, r.req())
+	}
+	b.WriteString(protoFieldsDecl(r.reqFields()))
+	b.WriteString("}\n\n")
+
+	if r.FType == Tx {
+		fmt.Fprintf(&b, "// %s is the Msg/%s response type.\n", r.res(), r.Name)
+	} else {
+		fmt.Fprintf(&b, "// %s is the response type for the Query/%s RPC method.\n", r.res(), r.Name)
+	}
+	if len(r.ResFields) == 0 {
+		fmt.Fprintf(&b, "message %s {}\n", r.res())
+	} else {
+		fmt.Fprintf(&b, "message %s {\n%s}\n", r.res(), protoFieldsDecl(r.ResFields))
+	}
+
+	return strings.ReplaceAll(b.String(), "{\n}", "{}")
+}
+
+// protoFieldsDecl returns the field declarations of a message body, numbered in order.
+func protoFieldsDecl(fields []ProtoField) string {
+	var b strings.Builder
+	for i, f := range fields {
+		var opts []string
+		if f.Scalar != "" {
+			opts = append(opts, fmt.Sprintf("(cosmos_proto.scalar) = %q", f.Scalar))
+		}
+		if f.Type == coinProtoType {
+			opts = append(opts, "(gogoproto.nullable) = false", "(amino.dont_omitempty) = true")
+			if f.Repeated {
+				opts = append(opts, `(amino.encoding) = "legacy_coins"`, `(gogoproto.castrepeated) = "github.com/cosmos/cosmos-sdk/types.Coins"`)
+			}
+		}
+
+		b.WriteString("  ")
+		if f.Repeated {
+			b.WriteString("repeated ")
+		}
+		fmt.Fprintf(&b, "%s %s = %d", f.Type, f.Name, i+1)
+		if len(opts) > 0 {
+			fmt.Fprintf(&b, " [%s]", strings.Join(opts, ", "))
+		}
+		b.WriteString(";\n")
+	}
+	return b.String()
+}
+
+// scaffoldRPCs applies the server stubs of the named Msg or Query RPCs of a module when they are not implemented yet,
+// and wires them into the module as stub-gen does. impl may set the implementation of each stub.
+func scaffoldRPCs(logger *slog.Logger, cwd, module string, ftype FileType, names []string, impl func(rpc *ProtoRPC)) error {
+	missing, err := GetMissingRPCMethodsFromModuleProto(logger, cwd)
+	if err != nil {
+		return err
+	}
+
+	var rpcs []*ProtoRPC
+	for _, rpc := range missing[module] {
+		// a Msg & Query may share a name
+		if rpc.FType != ftype || !slices.Contains(names, rpc.Name) {
+			continue
+		}
+
+		if impl != nil {
+			impl(rpc)
+		}
+		rpcs = append(rpcs, rpc)
+	}
+
+	mapping := ModuleMapping{module: rpcs}
+	if err := ApplyMissingRPCMethodsToGoSourceFiles(logger, mapping); err != nil {
+		return err
+	}
+	return ScaffoldMissingRPCs(logger, cwd, mapping)
+}
@@ -0,0 +1,72 @@
+package spawn
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rollchains/spawn/spawn/types"
+)
+
+func TestParseRPCFields(t *testing.T) {
+	fields, err := ParseRPCFields([]string{"title", "to:address", "amount:coins"})
+	require.NoError(t, err)
+	require.Equal(t, []ProtoField{
+		{Name: "title", Type: "string"},
+		{Name: "to", Type: "string", Scalar: addressScalar},
+		{Name: "amount", Type: coinProtoType, Repeated: true},
+	}, fields)
+
+	_, err = ParseRPCFields([]string{"amount:decimal"})
+	require.ErrorIs(t, err, types.ErrRPCInvalid)
+}
+
+func TestAddModuleRPC(t *testing.T) {
+	cwd := setupExampleModule(t)
+
+	fields, err := ParseRPCFields([]string{"title", "amount:coin"})
+	require.NoError(t, err)
+
+	msg := ModuleRPC{Module: "example", Name: "CreatePost", FType: Tx, Fields: fields, Signer: "sender"}
+	require.NoError(t, AddModuleRPC(logger, cwd, msg))
+
+	tx := readTestFile(t, cwd, "proto/example/v1/tx.proto")
+	require.Contains(t, tx, `import "amino/amino.proto";`)
+	require.Contains(t, tx, `import "cosmos/base/v1beta1/coin.proto";`)
+	require.Contains(t, tx, "  rpc CreatePost(MsgCreatePost) returns (MsgCreatePostResponse);\n}")
+	require.Contains(t, tx, `message MsgCreatePost {
+  option (cosmos.msg.v1.signer) = "sender";
+  option (amino.name) = "example/MsgCreatePost";
+
+  string sender = 1 [(cosmos_proto.scalar) = "cosmos.AddressString"];
+  string title = 2;
+  cosmos.base.v1beta1.Coin amount = 3 [(gogoproto.nullable) = false, (amino.dont_omitempty) = true];
+}`)
+	require.Contains(t, tx, "message MsgCreatePostResponse {}")
+
+	require.Contains(t, readTestFile(t, cwd, "x/example/keeper/msg_server.go"), "func (ms msgServer) CreatePost(ctx context.Context, msg *types.MsgCreatePost)")
+	require.Contains(t, readTestFile(t, cwd, "x/example/types/codec.go"), `ModuleName+"/MsgCreatePost"`)
+	require.Contains(t, readTestFile(t, cwd, "x/example/autocli.go"), `Use:            "create-post [title] [amount]",`)
+
+	require.ErrorIs(t, AddModuleRPC(logger, cwd, msg), types.ErrRPCExists)
+
+	require.NoError(t, AddModuleRPC(logger, cwd, ModuleRPC{
+		Module:    "example",
+		Name:      "Post",
+		FType:     Query,
+		Fields:    []ProtoField{{Name: "id", Type: "uint64"}},
+		ResFields: []ProtoField{{Name: "title", Type: "string"}},
+	}))
+
+	query := readTestFile(t, cwd, "proto/example/v1/query.proto")
+	require.Contains(t, query, "rpc Post(QueryPostRequest) returns (QueryPostResponse) {\n    option (google.api.http).get = \"/example/v1/post\";")
+	require.Contains(t, query, "message QueryPostRequest {\n  uint64 id = 1;\n}")
+	require.Contains(t, query, "message QueryPostResponse {\n  string title = 1;\n}")
+
+	_, err = ParseProtoFile("query.proto", []byte(query))
+	require.NoError(t, err, "generated proto must be valid")
+
+	require.Contains(t, readTestFile(t, cwd, "x/example/keeper/query_server.go"), "func (k Querier) Post(goCtx context.Context, req *types.QueryPostRequest)")
+
+	require.ErrorIs(t, AddModuleRPC(logger, cwd, ModuleRPC{Module: "example", Name: "Swap", FType: Tx, Signer: "Sender"}), types.ErrRPCInvalid)
+}
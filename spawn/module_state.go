@@ -0,0 +1,1050 @@
+package spawn
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	gotypes "go/types"
+	"log/slog"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	protoast "github.com/bufbuild/protocompile/ast"
+
+	"github.com/rollchains/spawn/spawn/types"
+)
+
+// StateStorage is how a module state type is kept in the store.
+type StateStorage string
+
+const (
+	// StateCollections stores the entries in a collections.Map of the keeper.
+	StateCollections StateStorage = "collections"
+	// StateORM stores the entries in a cosmos-orm table of the keeper's OrmDB.
+	StateORM StateStorage = "orm"
+)
+
+// protoFieldType is a field type of new Msgs, Queries & state types.
+type protoFieldType struct {
+	proto  ProtoField
+	goType string
+	// the collections key codec, empty if the type can not be a primary key
+	keyCodec string
+	// the import the value uses, if any
+	goImport string
+	// returns a distinct Go value for tests
+	value func(field string, i int) string
+}
+
+// protoFieldTypes are the name:type field types of `module msg`, `module query` and `module state add`.
+var protoFieldTypes = map[string]protoFieldType{
+	"string": {ProtoField{Type: "string"}, "string", "collections.StringKey", "", func(f string, i int) string { return strconv.Quote(fmt.Sprintf("%s%d", f, i)) }},
+	"bytes":  {ProtoField{Type: "bytes"}, "[]byte", "collections.BytesKey", "", func(f string, i int) string { return fmt.Sprintf("[]byte(%q)", fmt.Sprintf("%s%d", f, i)) }},
+	"bool":   {ProtoField{Type: "bool"}, "bool", "collections.BoolKey", "", func(_ string, i int) string { return strconv.FormatBool(i == 0) }},
+	"uint64": {ProtoField{Type: "uint64"}, "uint64", "collections.Uint64Key", "", func(_ string, i int) string { return strconv.Itoa(i + 1) }},
+	"uint32": {ProtoField{Type: "uint32"}, "uint32", "collections.Uint32Key", "", func(_ string, i int) string { return strconv.Itoa(i + 1) }},
+	"int64":  {ProtoField{Type: "int64"}, "int64", "collections.Int64Key", "", func(_ string, i int) string { return strconv.Itoa(i + 1) }},
+	"int32":  {ProtoField{Type: "int32"}, "int32", "collections.Int32Key", "", func(_ string, i int) string { return strconv.Itoa(i + 1) }},
+	// the keeper test fixture has 3 addresses
+	"address": {ProtoField{Type: "string", Scalar: addressScalar}, "string", "collections.StringKey", "", func(_ string, i int) string { return fmt.Sprintf("f.addrs[%d].String()", i) }},
+	"coin":    {ProtoField{Type: coinProtoType}, "", "", sdkTypesImport, func(_ string, i int) string { return fmt.Sprintf("sdk.NewInt64Coin(\"token\", %d)", i+1) }},
+	"coins":   {ProtoField{Type: coinProtoType, Repeated: true}, "", "", sdkTypesImport, func(_ string, i int) string { return fmt.Sprintf("sdk.NewCoins(sdk.NewInt64Coin(\"token\", %d))", i+1) }},
+}
+
+const sdkTypesImport = "github.com/cosmos/cosmos-sdk/types;sdk"
+
+// parseField parses a name:type field argument. The type defaults to string.
+func parseField(arg string, invalid error) (ProtoField, error) {
+	name, typeName, ok := strings.Cut(arg, ":")
+	if !ok {
+		typeName = "string"
+	}
+
+	if !stateFieldNameRegex.MatchString(name) {
+		return ProtoField{}, fmt.Errorf("%w: field %q must be snake_case", invalid, name)
+	}
+
+	ft, ok := protoFieldTypes[typeName]
+	if !ok {
+		return ProtoField{}, fmt.Errorf("%w: field %s has unsupported type %q, expected one of %s", invalid, name, typeName, strings.Join(sortedKeys(protoFieldTypes), ", "))
+	}
+
+	field := ft.proto
+	field.Name = name
+	return field, nil
+}
+
+// protoFieldTypeOf returns the type of a parsed field.
+func protoFieldTypeOf(f ProtoField) (protoFieldType, bool) {
+	for _, ft := range protoFieldTypes {
+		if ft.proto.Type == f.Type && ft.proto.Scalar == f.Scalar && ft.proto.Repeated == f.Repeated {
+			return ft, true
+		}
+	}
+	return protoFieldType{}, false
+}
+
+// stateMsgSigner signs the Set & Delete msgs of a state type, it must be the module authority.
+const stateMsgSigner = "authority"
+
+var (
+	stateNameRegex      = regexp.MustCompile(`^[A-Z][A-Za-z0-9]*$`)
+	stateFieldNameRegex = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+	ormTableIDRegex     = regexp.MustCompile(`\(cosmos\.orm\.v1\.(?:table|singleton)\)\s*=\s*\{[^}]*?\bid:\s*(\d+)`)
+)
+
+// ModuleState is a new type kept in the state of a module, see AddModuleState.
+type ModuleState struct {
+	Module string
+	// The proto message & keeper field name (Pool)
+	Name   string
+	Fields []ProtoField
+	// The field the entries are keyed by
+	PrimaryKey string
+	Storage    StateStorage
+
+	// the package name of the api types of the keeper's OrmDB (apiv1)
+	apiPkg string
+}
+
+// ParseStateFields parses name:type field arguments, see protoFieldTypes. The type defaults to string.
+func ParseStateFields(args []string) ([]ProtoField, error) {
+	fields := make([]ProtoField, 0, len(args))
+	for _, arg := range args {
+		field, err := parseField(arg, types.ErrStateInvalid)
+		if err != nil {
+			return nil, err
+		}
+		if slices.ContainsFunc(fields, func(f ProtoField) bool { return f.Name == field.Name }) {
+			return nil, fmt.Errorf("%w: duplicate field %s", types.ErrStateInvalid, field.Name)
+		}
+
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// Validate checks the state can be generated.
+func (s ModuleState) Validate() error {
+	if !stateNameRegex.MatchString(s.Name) {
+		return fmt.Errorf("%w: name %q must be CamelCase", types.ErrStateInvalid, s.Name)
+	}
+	if len(s.Fields) == 0 {
+		return fmt.Errorf("%w: no fields", types.ErrStateInvalid)
+	}
+	if s.Storage != StateCollections && s.Storage != StateORM {
+		return fmt.Errorf("%w: unknown storage %q", types.ErrStateInvalid, s.Storage)
+	}
+
+	for _, f := range s.Fields {
+		if _, ok := protoFieldTypeOf(f); !ok {
+			return fmt.Errorf("%w: field %s has unsupported type %q", types.ErrStateInvalid, f.Name, f.Type)
+		}
+		if f.Type == coinProtoType && s.Storage == StateORM {
+			return fmt.Errorf("%w: field %s, coins are not supported by ORM tables", types.ErrStateInvalid, f.Name)
+		}
+		if f.Name == stateMsgSigner {
+			return fmt.Errorf("%w: field %s is the signer of the Set & Delete msgs", types.ErrStateInvalid, f.Name)
+		}
+	}
+
+	pk, ok := s.primaryKey()
+	if !ok {
+		return fmt.Errorf("%w: primary key %q is not a field", types.ErrStateInvalid, s.PrimaryKey)
+	}
+	if ft, _ := protoFieldTypeOf(pk); ft.keyCodec == "" {
+		return fmt.Errorf("%w: primary key %s can not be a %s", types.ErrStateInvalid, pk.Name, pk.Type)
+	}
+
+	return nil
+}
+
+// keyType is the type of the primary key.
+func (s ModuleState) keyType() protoFieldType {
+	pk, _ := s.primaryKey()
+	ft, _ := protoFieldTypeOf(pk)
+	return ft
+}
+
+func (s ModuleState) primaryKey() (ProtoField, bool) {
+	for _, f := range s.Fields {
+		if f.Name == s.PrimaryKey {
+			return f, true
+		}
+	}
+	return ProtoField{}, false
+}
+
+// plural is the name of a list of entries (Pools).
+func (s ModuleState) plural() string {
+	return pluralize(s.Name)
+}
+
+// AddModuleState adds a new type to the state of a module:
+//   - the message to state.proto, as a cosmos-orm table or a plain message stored in a collections.Map
+//   - a repeated field to the GenesisState, imported & exported by the keeper's InitGenesis & ExportGenesis
+//   - <Name> & <Names> RPCs to the Query service, implemented by the module's querier
+//   - Set<Name> & Delete<Name> RPCs to the Msg service, gated by the module authority
+//   - the collections.Map to the Keeper & NewKeeper
+//   - a keeper test going through genesis, the queries & msgs
+//
+// The generated Go code is only valid once the proto files are generated again (make proto-gen).
+func AddModuleState(logger *slog.Logger, cwd string, s ModuleState) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+
+	files, err := parseProtoDir(logger, path.Join(cwd, "proto"))
+	if err != nil {
+		return err
+	}
+
+	var genesis, query, tx, state *ProtoFile
+	for _, pf := range files {
+		if pf.Module() != s.Module {
+			continue
+		}
+
+		for _, m := range pf.Messages {
+			switch protoBaseName(m.FullName) {
+			case s.Name, "Query" + s.Name + "Request", "Query" + s.plural() + "Request", "MsgSet" + s.Name, "MsgDelete" + s.Name:
+				return fmt.Errorf("%w: %s is already defined at %s", types.ErrStateExists, m.FullName, m.ProtoLoc)
+			case "GenesisState":
+				genesis = pf
+			}
+		}
+		for _, rpc := range pf.RPCs {
+			switch {
+			case rpc.FType == Query && rpc.Service == "Query":
+				query = pf
+			case rpc.FType == Tx && rpc.Service == "Msg":
+				tx = pf
+			}
+		}
+		if path.Base(pf.Name) == "state.proto" {
+			state = pf
+		}
+	}
+
+	if genesis == nil || query == nil || tx == nil {
+		return fmt.Errorf("proto/%s must define a GenesisState message, a Query and a Msg service", s.Module)
+	}
+
+	cfg, err := LoadStubGenConfig(cwd)
+	if err != nil {
+		return err
+	}
+	moduleDir := cfg.ModuleDir(cwd, s.Module)
+	keeperDir := path.Join(moduleDir, "keeper")
+
+	keeper, err := readKeeperStruct(keeperDir)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(keeper.fields, s.Name) {
+		return fmt.Errorf("%w: the Keeper already has a %s field", types.ErrStateExists, s.Name)
+	}
+	if !slices.Contains(keeper.fields, "authority") {
+		return fmt.Errorf("%w: the Keeper has no authority field to gate the Set & Delete msgs", types.ErrStateInvalid)
+	}
+	if s.Storage == StateORM {
+		if keeper.ormImport == "" {
+			return fmt.Errorf("%w: the Keeper has no OrmDB StateStore, use collections instead", types.ErrStateInvalid)
+		}
+		s.apiPkg = goImportAlias(keeper.ormImport)
+	}
+
+	if state == nil {
+		if s.Storage == StateORM {
+			return fmt.Errorf("%w: proto/%s has no state.proto with the module's ORM tables", types.ErrStateInvalid, s.Module)
+		}
+
+		state = &ProtoFile{
+			Loc:       path.Join(path.Dir(genesis.Loc), "state.proto"),
+			Name:      path.Join(path.Dir(genesis.Name), "state.proto"),
+			Package:   genesis.Package,
+			GoPackage: genesis.GoPackage,
+		}
+		content := fmt.Sprintf("syntax = \"proto3\";\npackage %s;\n\noption go_package = %q;\n", state.Package, state.GoPackage)
+		if err := os.WriteFile(state.Loc, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+
+	// proto
+	if err := editProtoFile(state.Loc, func(node *protoast.FileNode, src []byte) ([]sourceEdit, error) {
+		imports := protoFieldImports(s.Fields)
+		if s.Storage == StateORM {
+			imports = append(imports, "cosmos/orm/v1/orm.proto")
+		}
+		return []sourceEdit{protoImportEdit(node, imports...), protoAppendEdit(src, s.stateProto(src))}, nil
+	}); err != nil {
+		return err
+	}
+
+	if err := editProtoFile(genesis.Loc, func(node *protoast.FileNode, src []byte) ([]sourceEdit, error) {
+		m := protoMessage(node, "GenesisState")
+		if m == nil {
+			return nil, fmt.Errorf("%s: GenesisState must be a top level message", genesis.Loc)
+		}
+
+		field := fmt.Sprintf("\n  // %s defines the %s entries of the module state.\n  repeated %s %s = %d [(gogoproto.nullable) = false];\n",
+			toSnakeCase(s.plural()), s.Name, s.Name, toSnakeCase(s.plural()), protoNextFieldTag(m))
+
+		return []sourceEdit{
+			protoImportEdit(node, "gogoproto/gogo.proto", state.Name),
+			protoInsertBeforeBrace(node, src, m.CloseBrace, field),
+		}, nil
+	}); err != nil {
+		return err
+	}
+
+	if err := editProtoFile(query.Loc, func(node *protoast.FileNode, src []byte) ([]sourceEdit, error) {
+		svc := protoService(node, "Query")
+		if svc == nil {
+			return nil, fmt.Errorf("%s: Query service not found", query.Loc)
+		}
+
+		imports := []string{"google/api/annotations.proto", state.Name}
+		if s.Storage == StateCollections {
+			imports = append(imports, "cosmos/base/query/v1beta1/pagination.proto")
+		}
+
+		return []sourceEdit{
+			protoImportEdit(node, imports...),
+			protoInsertBeforeBrace(node, src, svc.CloseBrace, s.queryRPCsProto(query.Package)),
+			protoAppendEdit(src, s.queryMsgsProto()),
+		}, nil
+	}); err != nil {
+		return err
+	}
+
+	// keeper
+	if s.Storage == StateCollections {
+		if err := editGoFile(path.Join(moduleDir, "types", "keys.go"), s.addCollectionsPrefix); err != nil {
+			return err
+		}
+		if err := editGoFile(keeper.loc, s.addKeeperMap); err != nil {
+			return err
+		}
+	}
+
+	for _, fn := range []struct {
+		name string
+		edit func(fset *token.FileSet, f *ast.File, src []byte, fn *ast.FuncDecl) ([]sourceEdit, error)
+	}{
+		{"NewKeeper", s.initKeeperMap},
+		{"InitGenesis", s.importGenesis},
+		{"ExportGenesis", s.exportGenesis},
+	} {
+		if fn.name == "NewKeeper" && s.Storage == StateORM {
+			// the OrmDB StateStore includes every table of state.proto
+			continue
+		}
+
+		if err := editGoFunc(keeperDir, fn.name, fn.edit); err != nil {
+			return err
+		}
+	}
+
+	if err := s.addQueryServerMethods(logger, cwd, keeper); err != nil {
+		return err
+	}
+
+	if err := s.addMsgServerMethods(logger, cwd, keeper); err != nil {
+		return err
+	}
+
+	return s.addKeeperTest(logger, keeperDir, goImportPath(genesis.GoPackage))
+}
+
+// stateProto is the message stored in state.proto.
+func (s ModuleState) stateProto(src []byte) string {
+	var b strings.Builder
+	if s.Storage == StateORM {
+		id := 0
+		for _, m := range ormTableIDRegex.FindAllSubmatch(src, -1) {
+			n, _ := strconv.Atoi(string(m[1]))
+			id = max(id, n)
+		}
+
+		fmt.Fprintf(&b, "// %s is stored in the %sTable of the keeper's OrmDB, by %s.\nmessage %s {\n", s.Name, s.Name, s.PrimaryKey, s.Name)
+		fmt.Fprintf(&b, "  option (cosmos.orm.v1.table) = {\n    id: %d;\n    primary_key: { fields: %q }\n  };\n\n", id+1, s.PrimaryKey)
+	} else {
+		fmt.Fprintf(&b, "// %s is stored in the keeper's %s map, by %s.\nmessage %s {\n", s.Name, s.Name, s.PrimaryKey, s.Name)
+	}
+
+	b.WriteString(protoFieldsDecl(s.Fields))
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func (s ModuleState) queryRPCsProto(pkg string) string {
+	route := "/" + strings.ReplaceAll(pkg, ".", "/")
+
+	return fmt.Sprintf(`
+  // %[1]s returns the %[1]s of the given %[3]s.
+  rpc %[1]s(Query%[1]sRequest) returns (Query%[1]sResponse) {
+    option (google.api.http).get = "%[4]s/%[5]s/{%[3]s}";
+  }
+
+  // %[2]s queries all %[1]s entries.
+  rpc %[2]s(Query%[2]sRequest) returns (Query%[2]sResponse) {
+    option (google.api.http).get = "%[4]s/%[6]s";
+  }
+`, s.Name, s.plural(), s.PrimaryKey, route, toSnakeCase(s.Name), toSnakeCase(s.plural()))
+}
+
+func (s ModuleState) queryMsgsProto() string {
+	pk, _ := s.primaryKey()
+
+	listReq, listRes := "{}", "}"
+	if s.Storage == StateCollections {
+		listReq = "{\n  cosmos.base.query.v1beta1.PageRequest pagination = 1;\n}"
+		listRes = "  cosmos.base.query.v1beta1.PageResponse pagination = 2;\n}"
+	}
+
+	return fmt.Sprintf(`// Query%[1]sRequest is the request type for the Query/%[1]s RPC method.
+message Query%[1]sRequest {
+  %[3]s %[4]s = 1;
+}
+
+// Query%[1]sResponse is the response type for the Query/%[1]s RPC method.
+message Query%[1]sResponse {
+  %[1]s %[5]s = 1;
+}
+
+// Query%[2]sRequest is the request type for the Query/%[2]s RPC method.
+message Query%[2]sRequest %[7]s
+
+// Query%[2]sResponse is the response type for the Query/%[2]s RPC method.
+message Query%[2]sResponse {
+  repeated %[1]s %[6]s = 1;
+%[8]s
+`, s.Name, s.plural(), pk.Type, pk.Name, toSnakeCase(s.Name), toSnakeCase(s.plural()), listReq, listRes)
+}
+
+// keeperStruct is the Keeper type of a module.
+type keeperStruct struct {
+	loc    string
+	fields []string
+	// the import of the OrmDB StateStore (github.com/aaa/bbb/api/amm/v1;apiv1), empty without ORM
+	ormImport string
+}
+
+func readKeeperStruct(keeperDir string) (keeperStruct, error) {
+	loc, err := findGoDecl(keeperDir, "Keeper")
+	if err != nil {
+		return keeperStruct{}, err
+	}
+
+	f, err := parser.ParseFile(token.NewFileSet(), loc, nil, 0)
+	if err != nil {
+		return keeperStruct{}, err
+	}
+
+	k := keeperStruct{loc: loc}
+	st := keeperStructType(f)
+	if st == nil {
+		return keeperStruct{}, fmt.Errorf("%s: Keeper is not a struct", loc)
+	}
+	for _, field := range st.Fields.List {
+		for _, name := range field.Names {
+			k.fields = append(k.fields, name.Name)
+
+			sel, ok := field.Type.(*ast.SelectorExpr)
+			if name.Name != "OrmDB" || !ok {
+				continue
+			}
+			if pkg, ok := sel.X.(*ast.Ident); ok {
+				for _, imp := range f.Imports {
+					importPath, _ := strconv.Unquote(imp.Path.Value)
+					if (imp.Name != nil && imp.Name.Name == pkg.Name) || (imp.Name == nil && path.Base(importPath) == pkg.Name) {
+						k.ormImport = importPath + ";" + pkg.Name
+					}
+				}
+			}
+		}
+	}
+	return k, nil
+}
+
+func keeperStructType(f *ast.File) *ast.StructType {
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			if ts := spec.(*ast.TypeSpec); ts.Name.Name == "Keeper" {
+				if st, ok := ts.Type.(*ast.StructType); ok {
+					return st
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// findGoDecl returns the file of a package directory which declares the type or function (methods included).
+func findGoDecl(dir, name string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".go") || strings.HasSuffix(e.Name(), "_test.go") {
+			continue
+		}
+
+		loc := path.Join(dir, e.Name())
+		f, err := parser.ParseFile(token.NewFileSet(), loc, nil, 0)
+		if err != nil {
+			return "", err
+		}
+
+		if declaresType(f, name) || findFunc(f, name) != nil {
+			return loc, nil
+		}
+	}
+
+	return "", fmt.Errorf("%s is not declared in %s", name, dir)
+}
+
+func findFunc(f *ast.File, name string) *ast.FuncDecl {
+	for _, decl := range f.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Name.Name == name && fn.Body != nil {
+			return fn
+		}
+	}
+	return nil
+}
+
+// editGoFunc edits the file of a package directory which declares the function.
+func editGoFunc(dir, name string, edit func(fset *token.FileSet, f *ast.File, src []byte, fn *ast.FuncDecl) ([]sourceEdit, error)) error {
+	loc, err := findGoDecl(dir, name)
+	if err != nil {
+		return err
+	}
+
+	var editErr error
+	err = editGoFile(loc, func(fset *token.FileSet, f *ast.File, src []byte) []sourceEdit {
+		var edits []sourceEdit
+		edits, editErr = edit(fset, f, src, findFunc(f, name))
+		return edits
+	})
+	if editErr != nil {
+		return fmt.Errorf("%s: %w", loc, editErr)
+	}
+	return err
+}
+
+// addCollectionsPrefix adds the <Name>Key prefix next to the other collections prefixes of types/keys.go.
+func (s ModuleState) addCollectionsPrefix(fset *token.FileSet, f *ast.File, src []byte) []sourceEdit {
+	var last *ast.ValueSpec
+	prefix := -1
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.VAR {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			vs := spec.(*ast.ValueSpec)
+			for _, v := range vs.Values {
+				call, ok := v.(*ast.CallExpr)
+				if !ok || gotypes.ExprString(call.Fun) != "collections.NewPrefix" || len(call.Args) != 1 {
+					continue
+				}
+
+				last = vs
+				if lit, ok := call.Args[0].(*ast.BasicLit); ok && lit.Kind == token.INT {
+					n, _ := strconv.Atoi(lit.Value)
+					prefix = max(prefix, n)
+				}
+			}
+		}
+	}
+
+	code := fmt.Sprintf("// %sKey saves the %s entries, by %s.\n%sKey = collections.NewPrefix(%d)", s.Name, s.Name, s.PrimaryKey, s.Name, prefix+1)
+	if last == nil {
+		return []sourceEdit{{offset: len(src), text: fmt.Sprintf("\nvar (\n%s\n)\n", code)}}
+	}
+	return []sourceEdit{{offset: fset.Position(last.End()).Offset, text: "\n\n" + code}}
+}
+
+// addKeeperMap adds the collections.Map to the Keeper, after the other collections.
+func (s ModuleState) addKeeperMap(fset *token.FileSet, f *ast.File, src []byte) []sourceEdit {
+	code := fmt.Sprintf("%s collections.Map[%s, types.%s]", s.Name, s.keyType().goType, s.Name)
+
+	st := keeperStructType(f)
+	var last *ast.Field
+	for _, field := range st.Fields.List {
+		if strings.HasPrefix(gotypes.ExprString(field.Type), "collections.") {
+			last = field
+		}
+	}
+
+	if last == nil {
+		return []sourceEdit{lineStartEdit(src, fset.Position(st.Fields.Closing).Offset, code+"\n")}
+	}
+	return []sourceEdit{{offset: fset.Position(last.End()).Offset, text: "\n" + code}}
+}
+
+// initKeeperMap creates the collections.Map in NewKeeper, with the schema builder of the other collections.
+func (s ModuleState) initKeeperMap(fset *token.FileSet, f *ast.File, src []byte, fn *ast.FuncDecl) ([]sourceEdit, error) {
+	sb, cdc := "", ""
+	for _, param := range fn.Type.Params.List {
+		if t := gotypes.ExprString(param.Type); (t == "codec.BinaryCodec" || t == "codec.Codec") && len(param.Names) > 0 {
+			cdc = param.Names[0].Name
+		}
+	}
+
+	var keeper *ast.CompositeLit
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			if call, ok := n.Rhs[0].(*ast.CallExpr); ok && gotypes.ExprString(call.Fun) == "collections.NewSchemaBuilder" {
+				sb = gotypes.ExprString(n.Lhs[0])
+			}
+		case *ast.CompositeLit:
+			if gotypes.ExprString(n.Type) == "Keeper" {
+				keeper = n
+			}
+		}
+		return true
+	})
+
+	if sb == "" || cdc == "" || keeper == nil {
+		return nil, fmt.Errorf("NewKeeper must create a collections.NewSchemaBuilder and the Keeper{} with a codec")
+	}
+
+	code := fmt.Sprintf("%s: collections.NewMap(%s, types.%sKey, %q, %s, codec.CollValue[types.%s](%s))",
+		s.Name, sb, s.Name, toSnakeCase(s.Name), s.keyType().keyCodec, s.Name, cdc)
+
+	var last ast.Expr
+	for _, elt := range keeper.Elts {
+		if kv, ok := elt.(*ast.KeyValueExpr); ok && strings.HasPrefix(gotypes.ExprString(kv.Value), "collections.New") {
+			last = elt
+		}
+	}
+
+	if last == nil {
+		return []sourceEdit{appendCompositeElt(fset, src, keeper, code)}, nil
+	}
+
+	end := fset.Position(last.End()).Offset
+	if hasTrailingComma(src, end) {
+		end = bytes.IndexByte(src[end:], ',') + end + 1
+		return []sourceEdit{{offset: end, text: "\n" + code + ","}}, nil
+	}
+	return []sourceEdit{{offset: end, text: ",\n" + code}}, nil
+}
+
+// importGenesis sets the genesis entries in InitGenesis.
+func (s ModuleState) importGenesis(fset *token.FileSet, f *ast.File, src []byte, fn *ast.FuncDecl) ([]sourceEdit, error) {
+	params := funcParamNames(fn)
+	if len(params) < 2 || fn.Recv == nil {
+		return nil, fmt.Errorf("InitGenesis must be a keeper method with a context and genesis state")
+	}
+	k, ctx, data := recvName(fn), params[0], params[len(params)-1]
+
+	pk := goCamelCase(s.PrimaryKey)
+	var set string
+	if s.Storage == StateORM {
+		set = fmt.Sprintf("%s.OrmDB.%sTable().Insert(%s, %s)", k, s.Name, ctx, s.ormValue("&", "v"))
+	} else {
+		set = fmt.Sprintf("%s.%s.Set(%s, v.%s, v)", k, s.Name, ctx, pk)
+	}
+
+	code := fmt.Sprintf(`for _, v := range %s.%s {
+	if err := %s; err != nil {
+		return err
+	}
+}
+
+`, data, s.plural(), set)
+
+	return []sourceEdit{beforeLastStmtEdit(fset, fn, code)}, nil
+}
+
+// exportGenesis reads all entries in ExportGenesis and sets them on the returned genesis state.
+func (s ModuleState) exportGenesis(fset *token.FileSet, f *ast.File, src []byte, fn *ast.FuncDecl) ([]sourceEdit, error) {
+	params := funcParamNames(fn)
+	if len(params) < 1 || fn.Recv == nil || len(fn.Body.List) == 0 {
+		return nil, fmt.Errorf("ExportGenesis must be a keeper method with a context")
+	}
+	k, ctx := recvName(fn), params[0]
+
+	var genesis *ast.CompositeLit
+	if ret, ok := fn.Body.List[len(fn.Body.List)-1].(*ast.ReturnStmt); ok && len(ret.Results) == 2 {
+		if u, ok := ret.Results[0].(*ast.UnaryExpr); ok {
+			genesis, _ = u.X.(*ast.CompositeLit)
+		}
+	}
+	if genesis == nil {
+		return nil, fmt.Errorf("ExportGenesis must return (*types.GenesisState, error) as &types.GenesisState{...}, nil")
+	}
+
+	list := lowerFirst(s.plural())
+
+	var code string
+	if s.Storage == StateORM {
+		code = fmt.Sprintf(`%[1]sIt, err := %[2]s.OrmDB.%[3]sTable().List(%[4]s, %[5]s.%[3]sPrimaryKey{})
+if err != nil {
+	return nil, err
+}
+defer %[1]sIt.Close()
+
+var %[1]s []types.%[3]s
+for %[1]sIt.Next() {
+	v, err := %[1]sIt.Value()
+	if err != nil {
+		return nil, err
+	}
+	%[1]s = append(%[1]s, %[6]s)
+}
+
+`, list, k, s.Name, ctx, s.apiPkg, s.typesValue("", "v"))
+	} else {
+		code = fmt.Sprintf(`var %[1]s []types.%[3]s
+if err := %[2]s.%[3]s.Walk(%[4]s, nil, func(_ %[5]s, v types.%[3]s) (bool, error) {
+	%[1]s = append(%[1]s, v)
+	return false, nil
+}); err != nil {
+	return nil, err
+}
+
+`, list, k, s.Name, ctx, s.keyType().goType)
+	}
+
+	return []sourceEdit{
+		beforeLastStmtEdit(fset, fn, code),
+		setCompositeField(fset, src, genesis, s.plural(), list),
+	}, nil
+}
+
+// ormValue converts the gogoproto value v into the api type of the ORM table.
+func (s ModuleState) ormValue(ref, v string) string {
+	return fmt.Sprintf("%s%s.%s{%s}", ref, s.apiPkg, s.Name, s.copyFields(v))
+}
+
+// typesValue converts the api value v of the ORM table into the gogoproto type.
+func (s ModuleState) typesValue(ref, v string) string {
+	return fmt.Sprintf("%stypes.%s{%s}", ref, s.Name, s.copyFields(v))
+}
+
+func (s ModuleState) copyFields(v string) string {
+	fields := make([]string, 0, len(s.Fields))
+	for _, f := range s.Fields {
+		fields = append(fields, fmt.Sprintf("%s: %s.%s", goCamelCase(f.Name), v, goCamelCase(f.Name)))
+	}
+	return strings.Join(fields, ", ")
+}
+
+// addQueryServerMethods implements the new Query RPCs in the module's querier, and adds their autocli commands.
+func (s ModuleState) addQueryServerMethods(logger *slog.Logger, cwd string, keeper keeperStruct) error {
+	return scaffoldRPCs(logger, cwd, s.Module, Query, []string{s.Name, s.plural()}, func(rpc *ProtoRPC) {
+		recv := "k"
+		if rpc.Recv != "" {
+			recv = strings.Fields(rpc.Recv)[0]
+		}
+		if rpc.Name == s.Name {
+			rpc.Impl = s.getImpl(recv + ".Keeper")
+		} else {
+			rpc.Impl = s.listImpl(recv + ".Keeper")
+		}
+
+		switch {
+		case s.Storage == StateORM:
+			rpc.ImplImports = []string{keeper.ormImport}
+		case rpc.Name == s.plural():
+			rpc.ImplImports = []string{"github.com/cosmos/cosmos-sdk/types/query"}
+		default:
+			rpc.ImplImports = []string{"cosmossdk.io/collections", "cosmossdk.io/errors"}
+		}
+		if rpc.Name == s.Name {
+			rpc.ImplImports = append(rpc.ImplImports, "google.golang.org/grpc/codes", "google.golang.org/grpc/status")
+			if s.Storage == StateORM {
+				rpc.ImplImports = append(rpc.ImplImports, "cosmossdk.io/orm/types/ormerrors")
+			}
+		}
+	})
+}
+
+// addMsgServerMethods adds the Set<Name> & Delete<Name> Msgs and implements them in the module's msg server.
+func (s ModuleState) addMsgServerMethods(logger *slog.Logger, cwd string, keeper keeperStruct) error {
+	pk, _ := s.primaryKey()
+	msgs := []ModuleRPC{
+		{Module: s.Module, Name: "Set" + s.Name, FType: Tx, Fields: s.Fields, Signer: stateMsgSigner},
+		{Module: s.Module, Name: "Delete" + s.Name, FType: Tx, Fields: []ProtoField{pk}, Signer: stateMsgSigner},
+	}
+	for _, r := range msgs {
+		if err := addModuleRPCProto(logger, cwd, r); err != nil {
+			return err
+		}
+	}
+
+	return scaffoldRPCs(logger, cwd, s.Module, Tx, []string{msgs[0].Name, msgs[1].Name}, func(rpc *ProtoRPC) {
+		recv := "ms"
+		if rpc.Recv != "" {
+			recv = strings.Fields(rpc.Recv)[0]
+		}
+		rpc.Impl = s.msgImpl(recv+".k", rpc.Name, rpc.Res)
+
+		rpc.ImplImports = []string{"cosmossdk.io/errors", "github.com/cosmos/cosmos-sdk/x/gov/types;govtypes"}
+		if s.Storage == StateORM {
+			rpc.ImplImports = append(rpc.ImplImports, keeper.ormImport)
+		}
+	})
+}
+
+// msgImpl checks the authority of a Set or Delete msg and writes the entry.
+func (s ModuleState) msgImpl(k, name, res string) string {
+	pk := goCamelCase(s.PrimaryKey)
+
+	var write string
+	switch {
+	case name == "Set"+s.Name && s.Storage == StateORM:
+		write = fmt.Sprintf("%s.OrmDB.%sTable().Save(ctx, %s)", k, s.Name, s.ormValue("&", "msg"))
+	case name == "Set"+s.Name:
+		write = fmt.Sprintf("%s.%s.Set(ctx, msg.%s, %s)", k, s.Name, pk, s.typesValue("", "msg"))
+	case s.Storage == StateORM:
+		write = fmt.Sprintf("%s.OrmDB.%sTable().Delete(ctx, &%s.%s{%s: msg.%s})", k, s.Name, s.apiPkg, s.Name, pk, pk)
+	default:
+		write = fmt.Sprintf("%s.%s.Remove(ctx, msg.%s)", k, s.Name, pk)
+	}
+
+	return fmt.Sprintf(`	if err := msg.Validate(); err != nil {
+		return nil, err
+	}
+
+	if %[1]s.authority != msg.Authority {
+		return nil, errors.Wrapf(govtypes.ErrInvalidSigner, "invalid authority; expected %%s, got %%s", %[1]s.authority, msg.Authority)
+	}
+
+	if err := %[2]s; err != nil {
+		return nil, err
+	}
+
+	return &types.%[3]s{}, nil
+`, k, write, res)
+}
+
+func (s ModuleState) getImpl(k string) string {
+	pk := goCamelCase(s.PrimaryKey)
+
+	if s.Storage == StateORM {
+		return fmt.Sprintf(`	v, err := %[1]s.OrmDB.%[2]sTable().Get(goCtx, req.%[3]s)
+	if ormerrors.IsNotFound(err) {
+		return nil, status.Errorf(codes.NotFound, "%[5]s %%v not found", req.%[3]s)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &types.Query%[2]sResponse{%[2]s: %[4]s}, nil
+`, k, s.Name, pk, s.typesValue("&", "v"), toSnakeCase(s.Name))
+	}
+
+	return fmt.Sprintf(`	v, err := %[1]s.%[2]s.Get(goCtx, req.%[3]s)
+	if errors.IsOf(err, collections.ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, "%[4]s %%v not found", req.%[3]s)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &types.Query%[2]sResponse{%[2]s: &v}, nil
+`, k, s.Name, pk, toSnakeCase(s.Name))
+}
+
+func (s ModuleState) listImpl(k string) string {
+	list := lowerFirst(s.plural())
+
+	if s.Storage == StateORM {
+		return fmt.Sprintf(`	it, err := %[1]s.OrmDB.%[2]sTable().List(goCtx, %[6]s.%[2]sPrimaryKey{})
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	var %[3]s []*types.%[2]s
+	for it.Next() {
+		v, err := it.Value()
+		if err != nil {
+			return nil, err
+		}
+		%[3]s = append(%[3]s, %[5]s)
+	}
+
+	return &types.Query%[4]sResponse{%[4]s: %[3]s}, nil
+`, k, s.Name, list, s.plural(), s.typesValue("&", "v"), s.apiPkg)
+	}
+
+	return fmt.Sprintf(`	%[3]s, pageRes, err := query.CollectionPaginate(goCtx, %[1]s.%[2]s, req.Pagination, func(_ %[5]s, v types.%[2]s) (*types.%[2]s, error) {
+		return &v, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.Query%[4]sResponse{%[4]s: %[3]s, Pagination: pageRes}, nil
+`, k, s.Name, list, s.plural(), s.keyType().goType)
+}
+
+// addKeeperTest creates keeper/<name>_test.go, which imports the entries through genesis, reads them back with the
+// queries and changes them with the msgs. It uses the SetupTest fixture of keeper_test.go.
+func (s ModuleState) addKeeperTest(logger *slog.Logger, keeperDir, typesImport string) error {
+	fixture, err := os.ReadFile(path.Join(keeperDir, "keeper_test.go"))
+	if err != nil || !bytes.Contains(fixture, []byte("func SetupTest(")) {
+		logger.Warn("keeper_test.go SetupTest fixture not found, skipping the keeper test", "dir", keeperDir)
+		return nil
+	}
+
+	loc := path.Join(keeperDir, toSnakeCase(s.Name)+"_test.go")
+	if _, err := os.Stat(loc); err == nil {
+		logger.Warn("Test file already exists, skipping", "file", loc)
+		return nil
+	}
+
+	var imports string
+	value := func(i int) string {
+		fields := make([]string, 0, len(s.Fields))
+		for _, f := range s.Fields {
+			ft, _ := protoFieldTypeOf(f)
+			if spec := "\n\t" + goImportSpec(ft.goImport); ft.goImport != "" && !strings.Contains(imports, spec) {
+				imports += spec
+			}
+
+			v := ft.value(f.Name, 0)
+			if f.Name == s.PrimaryKey {
+				v = ft.value(f.Name, i)
+			}
+			fields = append(fields, fmt.Sprintf("%s: %s", goCamelCase(f.Name), v))
+		}
+		return strings.Join(fields, ", ")
+	}
+	first, second := value(0), value(1)
+
+	pk, _ := s.primaryKey()
+	pkName := goCamelCase(pk.Name)
+
+	code := fmt.Sprintf(`package keeper_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"%[8]s
+
+	%[6]q
+)
+
+func Test%[1]sState(t *testing.T) {
+	f := SetupTest(t)
+	require := require.New(t)
+
+	genesis := types.DefaultGenesis()
+	genesis.%[2]s = []types.%[1]s{
+		{%[3]s},
+	}
+	require.NoError(f.k.InitGenesis(f.ctx, genesis))
+
+	res, err := f.queryServer.%[1]s(f.ctx, &types.Query%[1]sRequest{%[4]s: genesis.%[2]s[0].%[4]s})
+	require.NoError(err)
+	require.Equal(genesis.%[2]s[0], *res.%[1]s)
+
+	_, err = f.queryServer.%[1]s(f.ctx, &types.Query%[1]sRequest{%[4]s: %[5]s})
+	require.Equal(codes.NotFound, status.Code(err))
+
+	all, err := f.queryServer.%[2]s(f.ctx, &types.Query%[2]sRequest{})
+	require.NoError(err)
+	require.Len(all.%[2]s, 1)
+
+	exported, err := f.k.ExportGenesis(f.ctx)
+	require.NoError(err)
+	require.Equal(genesis.%[2]s, exported.%[2]s)
+
+	// only the authority sets & deletes entries
+	_, err = f.msgServer.Set%[1]s(f.ctx, &types.MsgSet%[1]s{Authority: f.addrs[0].String(), %[7]s})
+	require.Error(err)
+
+	_, err = f.msgServer.Set%[1]s(f.ctx, &types.MsgSet%[1]s{Authority: f.govModAddr, %[7]s})
+	require.NoError(err)
+
+	_, err = f.msgServer.Delete%[1]s(f.ctx, &types.MsgDelete%[1]s{Authority: f.govModAddr, %[4]s: genesis.%[2]s[0].%[4]s})
+	require.NoError(err)
+
+	all, err = f.queryServer.%[2]s(f.ctx, &types.Query%[2]sRequest{})
+	require.NoError(err)
+	require.Len(all.%[2]s, 1)
+	require.EqualValues(%[5]s, all.%[2]s[0].%[4]s)
+}
+`, s.Name, s.plural(), first, pkName, s.keyType().value(pk.Name, 1), typesImport, second, imports)
+
+	return applySourceEdits(loc, nil, []sourceEdit{{offset: 0, text: code}})
+}
+
+// beforeLastStmtEdit inserts code before the final return of a function, or at the end of its body.
+func beforeLastStmtEdit(fset *token.FileSet, fn *ast.FuncDecl, code string) sourceEdit {
+	if n := len(fn.Body.List); n > 0 {
+		if ret, ok := fn.Body.List[n-1].(*ast.ReturnStmt); ok {
+			return sourceEdit{offset: fset.Position(ret.Pos()).Offset, text: code}
+		}
+	}
+	return sourceEdit{offset: fset.Position(fn.Body.Rbrace).Offset, text: "\n" + code}
+}
+
+// recvName returns the name of a method receiver.
+func recvName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 || len(fn.Recv.List[0].Names) == 0 {
+		return "_"
+	}
+	return fn.Recv.List[0].Names[0].Name
+}
+
+// funcParamNames returns the names of the function parameters, _ for unnamed ones.
+func funcParamNames(fn *ast.FuncDecl) []string {
+	var names []string
+	for _, p := range fn.Type.Params.List {
+		if len(p.Names) == 0 {
+			names = append(names, "_")
+		}
+		for _, n := range p.Names {
+			names = append(names, n.Name)
+		}
+	}
+	return names
+}
+
+// lineStartEdit inserts lines before the line of the offset.
+func lineStartEdit(src []byte, offset int, text string) sourceEdit {
+	return sourceEdit{offset: bytes.LastIndexByte(src[:offset], '\n') + 1, text: text}
+}
+
+// pluralize returns the plural of a type name (Pool -> Pools, Entry -> Entries).
+func pluralize(s string) string {
+	lower := strings.ToLower(s)
+	for _, suffix := range []string{"s", "x", "z", "ch", "sh"} {
+		if strings.HasSuffix(lower, suffix) {
+			return s + "es"
+		}
+	}
+	if n := len(lower); n > 1 && lower[n-1] == 'y' && !strings.ContainsRune("aeiou", rune(lower[n-2])) {
+		return s[:len(s)-1] + "ies"
+	}
+	return s + "s"
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
@@ -0,0 +1,227 @@
+package spawn
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/protocompile/parser"
+	"github.com/bufbuild/protocompile/reporter"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rollchains/spawn/simapp"
+	"github.com/rollchains/spawn/spawn/types"
+)
+
+func TestParseStateFields(t *testing.T) {
+	fields, err := ParseStateFields([]string{"id:uint64", "denom", "owner:bytes"})
+	require.NoError(t, err)
+	require.Equal(t, []ProtoField{{Name: "id", Type: "uint64"}, {Name: "denom", Type: "string"}, {Name: "owner", Type: "bytes"}}, fields)
+
+	fields, err = ParseStateFields([]string{"owner:address", "amount:coin"})
+	require.NoError(t, err)
+	require.Equal(t, []ProtoField{{Name: "owner", Type: "string", Scalar: addressScalar}, {Name: "amount", Type: coinProtoType}}, fields)
+
+	for _, args := range [][]string{{"id:float"}, {"Id:uint64"}, {"id", "id:uint64"}} {
+		_, err := ParseStateFields(args)
+		require.ErrorIs(t, err, types.ErrStateInvalid, args)
+	}
+
+	s := ModuleState{Name: "Pool", Fields: []ProtoField{{Name: "authority", Type: "string"}}, PrimaryKey: "authority", Storage: StateCollections}
+	require.ErrorIs(t, s.Validate(), types.ErrStateInvalid, "authority signs the msgs")
+
+	s = ModuleState{Name: "Pool", Fields: fields, PrimaryKey: "amount", Storage: StateCollections}
+	require.ErrorIs(t, s.Validate(), types.ErrStateInvalid, "coins are not keys")
+
+	s.PrimaryKey, s.Storage = "owner", StateORM
+	require.ErrorIs(t, s.Validate(), types.ErrStateInvalid, "coins are not in ORM tables")
+
+	s.Storage = StateCollections
+	require.NoError(t, s.Validate())
+}
+
+func TestProtoNextFieldTag(t *testing.T) {
+	node, err := parser.Parse("genesis.proto", strings.NewReader(`syntax = "proto3";
+package example.v1;
+message GenesisState {
+  string a = 1;
+  oneof kind {
+    string b = 4;
+  }
+  map<string, string> c = 3;
+  reserved 2, 5 to 7, 9;
+}`), reporter.NewHandler(nil))
+	require.NoError(t, err)
+
+	require.EqualValues(t, 8, protoNextFieldTag(protoMessage(node, "GenesisState")))
+}
+
+func TestAddModuleStateCollections(t *testing.T) {
+	cwd := setupExampleModule(t)
+
+	state := ModuleState{
+		Module:     "example",
+		Name:       "Pool",
+		Fields:     []ProtoField{{Name: "id", Type: "uint64"}, {Name: "denom", Type: "string"}},
+		PrimaryKey: "id",
+		Storage:    StateCollections,
+	}
+	require.NoError(t, AddModuleState(logger, cwd, state))
+
+	stateProto := readTestFile(t, cwd, "proto/example/v1/state.proto")
+	require.Contains(t, stateProto, "message Pool {\n  uint64 id = 1;\n  string denom = 2;\n}")
+
+	genesis := readTestFile(t, cwd, "proto/example/v1/genesis.proto")
+	require.Contains(t, genesis, `import "example/v1/state.proto";`)
+	require.Contains(t, genesis, "  repeated Pool pools = 2 [(gogoproto.nullable) = false];\n}")
+
+	query := readTestFile(t, cwd, "proto/example/v1/query.proto")
+	require.Contains(t, query, `import "cosmos/base/query/v1beta1/pagination.proto";`)
+	require.Contains(t, query, "rpc Pool(QueryPoolRequest) returns (QueryPoolResponse) {\n    option (google.api.http).get = \"/example/v1/pool/{id}\";")
+	require.Contains(t, query, "rpc Pools(QueryPoolsRequest) returns (QueryPoolsResponse) {")
+	require.Contains(t, query, "message QueryPoolsResponse {\n  repeated Pool pools = 1;\n  cosmos.base.query.v1beta1.PageResponse pagination = 2;\n}")
+
+	_, err := ParseProtoFile("query.proto", []byte(query))
+	require.NoError(t, err, "generated proto must be valid")
+
+	keys := readTestFile(t, cwd, "x/example/types/keys.go")
+	require.Contains(t, keys, "PoolKey = collections.NewPrefix(1)")
+
+	keeper := readTestFile(t, cwd, "x/example/keeper/keeper.go")
+	require.Contains(t, keeper, "Pool   collections.Map[uint64, types.Pool]")
+	require.Contains(t, keeper, `Pool:   collections.NewMap(sb, types.PoolKey, "pool", collections.Uint64Key, codec.CollValue[types.Pool](cdc)),`)
+	require.Contains(t, keeper, "if err := k.Pool.Set(ctx, v.Id, v); err != nil {")
+	require.Contains(t, keeper, "if err := k.Pool.Walk(ctx, nil, func(_ uint64, v types.Pool) (bool, error) {")
+	require.Contains(t, keeper, "}); err != nil {\n\t\treturn nil, err\n\t}")
+	require.Contains(t, keeper, "Pools:  pools,")
+
+	tx := readTestFile(t, cwd, "proto/example/v1/tx.proto")
+	require.Contains(t, tx, "rpc SetPool(MsgSetPool) returns (MsgSetPoolResponse);")
+	require.Contains(t, tx, "rpc DeletePool(MsgDeletePool) returns (MsgDeletePoolResponse);")
+	require.Contains(t, tx, "message MsgDeletePool {\n  option (cosmos.msg.v1.signer) = \"authority\";")
+
+	msgServer := readTestFile(t, cwd, "x/example/keeper/msg_server.go")
+	require.Contains(t, msgServer, "if ms.k.authority != msg.Authority {")
+	require.Contains(t, msgServer, "if err := ms.k.Pool.Set(ctx, msg.Id, types.Pool{Id: msg.Id, Denom: msg.Denom}); err != nil {")
+	require.Contains(t, msgServer, "if err := ms.k.Pool.Remove(ctx, msg.Id); err != nil {")
+	require.Contains(t, readTestFile(t, cwd, "x/example/keeper/msg_server_test.go"), "Authority: f.govModAddr,")
+
+	querier := readTestFile(t, cwd, "x/example/keeper/query_server.go")
+	require.Contains(t, querier, "v, err := k.Keeper.Pool.Get(goCtx, req.Id)")
+	require.Contains(t, querier, "if errors.IsOf(err, collections.ErrNotFound) {\n\t\treturn nil, status.Errorf(codes.NotFound, \"pool %v not found\", req.Id)")
+	require.Contains(t, querier, "query.CollectionPaginate(goCtx, k.Keeper.Pool, req.Pagination,")
+	require.Contains(t, querier, `"github.com/cosmos/cosmos-sdk/types/query"`)
+
+	require.Contains(t, readTestFile(t, cwd, "x/example/autocli.go"), `Use:            "pool [id]",`)
+
+	test := readTestFile(t, cwd, "x/example/keeper/pool_test.go")
+	require.Contains(t, test, "func TestPoolState(t *testing.T) {")
+	require.Contains(t, test, "{Id: 1, Denom: \"denom0\"},")
+	require.Contains(t, test, "&types.QueryPoolRequest{Id: 2}")
+	require.Contains(t, test, "require.Equal(codes.NotFound, status.Code(err))")
+	require.Contains(t, test, "&types.MsgSetPool{Authority: f.govModAddr, Id: 2, Denom: \"denom0\"}")
+
+	require.ErrorIs(t, AddModuleState(logger, cwd, state), types.ErrStateExists)
+}
+
+func TestAddModuleStateAddressCoin(t *testing.T) {
+	cwd := setupExampleModule(t)
+
+	fields, err := ParseStateFields([]string{"owner:address", "amount:coin"})
+	require.NoError(t, err)
+
+	require.NoError(t, AddModuleState(logger, cwd, ModuleState{Module: "example", Name: "Deposit", Fields: fields, PrimaryKey: "owner", Storage: StateCollections}))
+
+	stateProto := readTestFile(t, cwd, "proto/example/v1/state.proto")
+	require.Contains(t, stateProto, `import "cosmos/base/v1beta1/coin.proto";`)
+	require.Contains(t, stateProto, `string owner = 1 [(cosmos_proto.scalar) = "cosmos.AddressString"];`)
+	require.Contains(t, stateProto, "cosmos.base.v1beta1.Coin amount = 2 [(gogoproto.nullable) = false, (amino.dont_omitempty) = true];")
+	_, err = ParseProtoFile("state.proto", []byte(stateProto))
+	require.NoError(t, err)
+
+	require.Contains(t, readTestFile(t, cwd, "x/example/keeper/keeper.go"), "Deposit collections.Map[string, types.Deposit]")
+
+	test := readTestFile(t, cwd, "x/example/keeper/deposit_test.go")
+	require.Contains(t, test, `sdk "github.com/cosmos/cosmos-sdk/types"`)
+	require.Contains(t, test, `{Owner: f.addrs[0].String(), Amount: sdk.NewInt64Coin("token", 1)},`)
+	require.Contains(t, test, "&types.QueryDepositRequest{Owner: f.addrs[1].String()}")
+}
+
+func TestAddModuleStateSharedRPCName(t *testing.T) {
+	cwd := setupExampleModule(t)
+
+	// an unimplemented Msg with the name of the state query
+	require.NoError(t, addModuleRPCProto(logger, cwd, ModuleRPC{Module: "example", Name: "Pool", FType: Tx, Signer: "sender"}))
+
+	require.NoError(t, AddModuleState(logger, cwd, ModuleState{
+		Module:     "example",
+		Name:       "Pool",
+		Fields:     []ProtoField{{Name: "id", Type: "uint64"}},
+		PrimaryKey: "id",
+		Storage:    StateCollections,
+	}))
+
+	require.Contains(t, readTestFile(t, cwd, "x/example/keeper/query_server.go"), "func (k Querier) Pool(")
+	require.NotContains(t, readTestFile(t, cwd, "x/example/keeper/msg_server.go"), ") Pool(", "the Msg is not given the query implementation")
+}
+
+func TestAddModuleStateORM(t *testing.T) {
+	cwd := setupExampleModule(t)
+
+	require.NoError(t, AddModuleState(logger, cwd, ModuleState{
+		Module:     "example",
+		Name:       "Entry",
+		Fields:     []ProtoField{{Name: "account", Type: "bytes"}, {Name: "amount", Type: "uint64"}},
+		PrimaryKey: "account",
+		Storage:    StateORM,
+	}))
+
+	stateProto := readTestFile(t, cwd, "proto/example/v1/state.proto")
+	require.Contains(t, stateProto, "id: 2;\n    primary_key: { fields: \"account\" }")
+
+	require.Contains(t, readTestFile(t, cwd, "proto/example/v1/query.proto"), "message QueryEntriesRequest {}")
+
+	keeper := readTestFile(t, cwd, "x/example/keeper/keeper.go")
+	require.NotContains(t, keeper, "collections.Map", "orm tables are part of the OrmDB")
+	require.Contains(t, keeper, "k.OrmDB.EntryTable().Insert(ctx, &apiv1.Entry{Account: v.Account, Amount: v.Amount})")
+	require.Contains(t, keeper, "entriesIt, err := k.OrmDB.EntryTable().List(ctx, apiv1.EntryPrimaryKey{})")
+
+	querier := readTestFile(t, cwd, "x/example/keeper/query_server.go")
+	require.Contains(t, querier, "it, err := k.Keeper.OrmDB.EntryTable().List(goCtx, apiv1.EntryPrimaryKey{})")
+	require.Contains(t, querier, "if ormerrors.IsNotFound(err) {")
+
+	msgServer := readTestFile(t, cwd, "x/example/keeper/msg_server.go")
+	require.Contains(t, msgServer, "ms.k.OrmDB.EntryTable().Save(ctx, &apiv1.Entry{Account: msg.Account, Amount: msg.Amount})")
+	require.Contains(t, msgServer, "ms.k.OrmDB.EntryTable().Delete(ctx, &apiv1.Entry{Account: msg.Account})")
+	require.Contains(t, querier, `apiv1 "github.com/rollchains/spawn/simapp/api/example/v1"`)
+}
+
+// setupExampleModule creates a chain with the x/example module & its proto files.
+func setupExampleModule(t *testing.T) string {
+	t.Helper()
+
+	cwd := t.TempDir()
+	for _, embedded := range []fs.FS{simapp.ExtensionFS, simapp.ProtoModuleFS} {
+		err := fs.WalkDir(embedded, ".", func(relPath string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !strings.HasPrefix(relPath, "x/example/") && !strings.HasPrefix(relPath, "proto/example/") {
+				return err
+			}
+
+			bz, err := fs.ReadFile(embedded, relPath)
+			if err != nil {
+				return err
+			}
+
+			loc := path.Join(cwd, relPath)
+			if err := os.MkdirAll(path.Dir(loc), 0755); err != nil {
+				return err
+			}
+			return os.WriteFile(loc, bz, 0644)
+		})
+		require.NoError(t, err)
+	}
+
+	return cwd
+}
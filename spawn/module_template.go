@@ -0,0 +1,274 @@
+package spawn
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+
+	"github.com/rollchains/spawn/spawn/types"
+)
+
+// ModuleTemplatePlaceholder is the module name of a custom template directory, replaced by the new module name as in
+// the embedded example module.
+const ModuleTemplatePlaceholder = "example"
+
+// ModuleTemplatesDir is the directory of the version profile ModuleTemplateFS with the files of each template.
+const ModuleTemplatesDir = "templates/modules"
+
+// SimappGoModule is the go module path of the embedded modules & templates, replaced by the one of the chain.
+const SimappGoModule = "github.com/rollchains/spawn/simapp"
+
+// ModuleTemplate is a module `spawn module new --template` can generate. Templates start from one of the embedded
+// x/ & proto/ modules (the base), the files of the template are then added on top of it and the keeper gets the
+// template dependencies & RPCs.
+type ModuleTemplate struct {
+	Name        string
+	Aliases     []string
+	Description string
+
+	// Base is the embedded module the template starts from (example, ibcmodule or ibcmiddleware).
+	Base string
+	// Deps are the keepers the module keeper depends on, see KeeperDeps.
+	Deps []string
+	// RPCs are the Msgs & Queries added to the base module, with their implementation.
+	RPCs []ModuleRPC
+
+	// ModuleAccount gives the module an account in app.go to hold coins.
+	ModuleAccount bool
+	// SendRestriction registers the keeper SendRestriction method as a bank send hook in app.go.
+	SendRestriction bool
+}
+
+// ModuleTemplates are all the modules spawn can generate.
+var ModuleTemplates = []ModuleTemplate{
+	{
+		Name:        "example",
+		Aliases:     []string{"standard", "default"},
+		Description: "standard module with params, genesis & an ORM store",
+		Base:        "example",
+	},
+	{
+		Name:        "ibcmodule",
+		Aliases:     []string{"ibc-module", "ibc"},
+		Description: "IBC application sending & receiving its own packets",
+		Base:        "ibcmodule",
+	},
+	{
+		Name:        "ibcmiddleware",
+		Aliases:     []string{"ibc-middleware", "middleware"},
+		Description: "IBC middleware wrapping the transfer stack",
+		Base:        "ibcmiddleware",
+	},
+	{
+		Name:        "abci",
+		Aliases:     []string{"blocker", "begin-end-block", "hooks"},
+		Description: "standard module running logic in BeginBlock & EndBlock",
+		Base:        "example",
+	},
+	{
+		Name:            "escrow",
+		Description:     "standard module holding coins in its own module account, guarded by a bank send hook",
+		Base:            "example",
+		Deps:            []string{"account", "bank"},
+		ModuleAccount:   true,
+		SendRestriction: true,
+	},
+	{
+		Name:        "icacontroller",
+		Aliases:     []string{"ica-controller", "ica"},
+		Description: "standard module registering & controlling interchain accounts on other chains",
+		Base:        "example",
+		Deps:        []string{"icacontroller"},
+		RPCs: []ModuleRPC{
+			{
+				Name:  "RegisterInterchainAccount",
+				FType: Tx,
+				Fields: []ProtoField{
+					{Name: "owner", Type: "string", Scalar: addressScalar},
+					{Name: "connection_id", Type: "string"},
+					{Name: "version", Type: "string"},
+				},
+				Signer: "owner",
+				Impl: `	if err := ms.k.RegisterInterchainAccount(ctx, msg.ConnectionId, msg.Owner, msg.Version); err != nil {
+		return nil, err
+	}
+
+	return &types.MsgRegisterInterchainAccountResponse{}, nil
+`,
+			},
+			{
+				Name:  "InterchainAccount",
+				FType: Query,
+				Fields: []ProtoField{
+					{Name: "owner", Type: "string", Scalar: addressScalar},
+					{Name: "connection_id", Type: "string"},
+				},
+				ResFields: []ProtoField{{Name: "address", Type: "string"}},
+				Impl: `	addr, err := k.Keeper.InterchainAccountAddress(goCtx, req.ConnectionId, req.Owner)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.QueryInterchainAccountResponse{Address: addr}, nil
+`,
+			},
+		},
+	},
+	{
+		Name:        "icq",
+		Aliases:     []string{"interchain-query", "interchainquery"},
+		Description: "IBC application querying the state of other chains through their async-icq host",
+		Base:        "ibcmodule",
+	},
+}
+
+// ModuleTemplateNames returns the name of every module template.
+func ModuleTemplateNames() []string {
+	names := make([]string, len(ModuleTemplates))
+	for i, t := range ModuleTemplates {
+		names[i] = t.Name
+	}
+	return names
+}
+
+// GetModuleTemplate returns the module template by name or alias. An empty name returns the example module.
+func GetModuleTemplate(name string) (ModuleTemplate, error) {
+	if name == "" {
+		name = "example"
+	}
+
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, t := range ModuleTemplates {
+		if t.Name == name || slices.Contains(t.Aliases, name) {
+			return t, nil
+		}
+	}
+
+	return ModuleTemplate{}, fmt.Errorf("%w: %s, available templates: %s", types.ErrModuleTemplateNotFound, name, strings.Join(ModuleTemplateNames(), ", "))
+}
+
+// IsIBC returns true when the module is an IBC application or middleware.
+func (t ModuleTemplate) IsIBC() bool {
+	return t.Base == "ibcmodule" || t.Base == "ibcmiddleware"
+}
+
+// KeeperDeps returns the keepers the module keeper depends on.
+func (t ModuleTemplate) KeeperDeps() ([]KeeperDep, error) {
+	return GetKeeperDeps(t.Deps)
+}
+
+// Files returns the files the template adds to its base module. Paths are relative to the chain root & still use
+// the base module name, `.tmpl` Go sources keep the templates out of the simapp build.
+func (t ModuleTemplate) Files(templateFS fs.FS) (map[string][]byte, error) {
+	root := path.Join(ModuleTemplatesDir, t.Name)
+	files := make(map[string][]byte)
+
+	err := fs.WalkDir(templateFS, root, func(relPath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if relPath == root && errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		bz, err := fs.ReadFile(templateFS, relPath)
+		if err != nil {
+			return err
+		}
+
+		files[strings.TrimSuffix(strings.TrimPrefix(relPath, root+"/"), ".tmpl")] = bz
+		return nil
+	})
+
+	return files, err
+}
+
+// ApplyModuleTemplate wires the template dependencies & RPCs into a module generated from the template base.
+func ApplyModuleTemplate(logger *slog.Logger, cwd, module string, t ModuleTemplate) error {
+	deps, err := t.KeeperDeps()
+	if err != nil {
+		return err
+	}
+
+	if len(deps) > 0 {
+		if err := AddModuleKeeperDeps(cwd, module, deps); err != nil {
+			return fmt.Errorf("adding the %s keeper dependencies: %w", t.Name, err)
+		}
+	}
+
+	for _, rpc := range t.RPCs {
+		rpc.Module = module
+		if err := AddModuleRPC(logger, cwd, rpc); err != nil {
+			return fmt.Errorf("adding the %s rpc: %w", rpc.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// IsGitURL returns true when the template source is a git repository to clone rather than a local directory.
+func IsGitURL(src string) bool {
+	for _, prefix := range []string{"https://", "http://", "git@", "ssh://", "git://", "file://"} {
+		if strings.HasPrefix(src, prefix) {
+			return true
+		}
+	}
+	return strings.HasSuffix(src, ".git")
+}
+
+// LoadModuleTemplateDir returns the files of a custom module template, a local directory or a git repository cloned
+// into a temporary directory. The template has the x/example & optional proto/example directories of a module named
+// with the ModuleTemplatePlaceholder. cleanup removes the clone.
+func LoadModuleTemplateDir(src string) (templateFS fs.FS, cleanup func(), err error) {
+	cleanup = func() {}
+
+	dir := src
+	if IsGitURL(src) {
+		dir, err = os.MkdirTemp("", "spawn-module-template-")
+		if err != nil {
+			return nil, cleanup, err
+		}
+		cleanup = func() { os.RemoveAll(dir) }
+
+		if out, err := ExecCommandWithOutput("git", "clone", "--depth", "1", "--quiet", src, dir); err != nil {
+			cleanup()
+			return nil, func() {}, fmt.Errorf("%w: cloning %s: %s", types.ErrModuleTemplateDir, src, strings.TrimSpace(string(out)))
+		}
+	}
+
+	templateFS = os.DirFS(dir)
+	if info, err := fs.Stat(templateFS, path.Join("x", ModuleTemplatePlaceholder)); err != nil || !info.IsDir() {
+		cleanup()
+		return nil, func() {}, fmt.Errorf("%w: %s has no x/%s module", types.ErrModuleTemplateDir, src, ModuleTemplatePlaceholder)
+	}
+
+	return templateFS, cleanup, nil
+}
+
+// ModuleTemplateGoModule returns the go module path the imports of a custom module template use, the one of its
+// go.mod or SimappGoModule without one. A module path without a "/" can not be told apart from the code of the
+// module when it is replaced, it is rejected.
+func ModuleTemplateGoModule(templateFS fs.FS) (string, error) {
+	bz, err := fs.ReadFile(templateFS, "go.mod")
+	if errors.Is(err, fs.ErrNotExist) {
+		return SimappGoModule, nil
+	} else if err != nil {
+		return "", err
+	}
+
+	modPath := modfile.ModulePath(bz)
+	if !strings.Contains(modPath, "/") {
+		return "", fmt.Errorf("%w: go.mod module %q must be a full path like github.com/myorg/module-template", types.ErrModuleTemplateDir, modPath)
+	}
+	return modPath, nil
+}
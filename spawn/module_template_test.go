@@ -0,0 +1,88 @@
+package spawn
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rollchains/spawn/simapp"
+	"github.com/rollchains/spawn/spawn/types"
+)
+
+func TestGetModuleTemplate(t *testing.T) {
+	tmpl, err := GetModuleTemplate("")
+	require.NoError(t, err)
+	require.Equal(t, "example", tmpl.Name)
+
+	tmpl, err = GetModuleTemplate("ICA")
+	require.NoError(t, err)
+	require.Equal(t, "icacontroller", tmpl.Name)
+	require.False(t, tmpl.IsIBC())
+
+	tmpl, err = GetModuleTemplate("icq")
+	require.NoError(t, err)
+	require.True(t, tmpl.IsIBC())
+
+	_, err = GetModuleTemplate("oracle")
+	require.ErrorIs(t, err, types.ErrModuleTemplateNotFound)
+
+	for _, tmpl := range ModuleTemplates {
+		_, err := tmpl.KeeperDeps()
+		require.NoError(t, err, tmpl.Name)
+	}
+}
+
+func TestModuleTemplateFiles(t *testing.T) {
+	escrow, err := GetModuleTemplate("escrow")
+	require.NoError(t, err)
+
+	files, err := escrow.Files(simapp.ModuleTemplateFS)
+	require.NoError(t, err)
+	require.Contains(t, files, "x/example/keeper/escrow.go")
+	require.Contains(t, files, "x/example/keeper/escrow_test.go")
+
+	example, err := GetModuleTemplate("example")
+	require.NoError(t, err)
+
+	files, err = example.Files(simapp.ModuleTemplateFS)
+	require.NoError(t, err)
+	require.Empty(t, files)
+}
+
+func TestLoadModuleTemplateDir(t *testing.T) {
+	require.True(t, IsGitURL("https://github.com/myorg/module-template"))
+	require.True(t, IsGitURL("git@github.com:myorg/module-template.git"))
+	require.False(t, IsGitURL("./templates/mymodule"))
+
+	dir := setupExampleModule(t)
+	require.NoError(t, os.WriteFile(path.Join(dir, "README.md"), []byte("# template"), 0644))
+
+	templateFS, cleanup, err := LoadModuleTemplateDir(dir)
+	require.NoError(t, err)
+	defer cleanup()
+
+	_, err = fs.Stat(templateFS, "x/example/keeper/keeper.go")
+	require.NoError(t, err)
+
+	modPath, err := ModuleTemplateGoModule(templateFS)
+	require.NoError(t, err)
+	require.Equal(t, SimappGoModule, modPath)
+
+	require.NoError(t, os.WriteFile(path.Join(dir, "go.mod"), []byte("module github.com/myorg/module-template\n"), 0644))
+	modPath, err = ModuleTemplateGoModule(templateFS)
+	require.NoError(t, err)
+	require.Equal(t, "github.com/myorg/module-template", modPath)
+
+	require.NoError(t, os.WriteFile(path.Join(dir, "go.mod"), []byte("module mytemplate\n"), 0644))
+	_, err = ModuleTemplateGoModule(templateFS)
+	require.ErrorIs(t, err, types.ErrModuleTemplateDir)
+
+	_, _, err = LoadModuleTemplateDir(path.Join(dir, "x"))
+	require.ErrorIs(t, err, types.ErrModuleTemplateDir)
+
+	_, _, err = LoadModuleTemplateDir("file://" + path.Join(dir, "missing.git"))
+	require.ErrorIs(t, err, types.ErrModuleTemplateDir)
+}
@@ -0,0 +1,409 @@
+package spawn
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/bufbuild/protocompile/parser"
+	"github.com/bufbuild/protocompile/reporter"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// ProtoFile is a parsed .proto file.
+type ProtoFile struct {
+	// Location of the file on disk
+	Loc string
+	// The import path of the file, relative to the proto/ directory (amm/v1/tx.proto)
+	Name string
+	// The proto package (amm.v1)
+	Package string
+	// The go_package option (github.com/aaa/bbb/x/amm/types)
+	GoPackage string
+	// Files imported by this file
+	Imports []string
+	// All messages defined within the file, including nested messages
+	Messages []*ProtoMessage
+	// RPC methods of the Msg & Query services within the file
+	RPCs []*ProtoRPC
+}
+
+// Module is the module the file belongs to, the first directory of its import path (proto/<module>).
+func (pf *ProtoFile) Module() string {
+	return strings.Split(path.Dir(pf.Name), "/")[0]
+}
+
+// ProtoMessage is a message defined in a .proto file.
+type ProtoMessage struct {
+	// Fully qualified name (amm.v1.MsgSwap)
+	FullName string
+	// Fields from the (cosmos.msg.v1.signer) option
+	Signers []string
+	Fields  []ProtoField
+	// Where the message is defined (file:line)
+	ProtoLoc string
+}
+
+// ProtoField is a field of a proto message.
+type ProtoField struct {
+	Name string
+	// The type as written (string, uint64, cosmos.base.v1beta1.Coin)
+	Type     string
+	Repeated bool
+	// The (cosmos_proto.scalar) option of the field (cosmos.AddressString)
+	Scalar string
+}
+
+// Field returns the field by name.
+func (m *ProtoMessage) Field(name string) (ProtoField, bool) {
+	for _, f := range m.Fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return ProtoField{}, false
+}
+
+// ParseProtoFile parses a .proto file into its services and messages. Syntax errors are returned as file:line:col.
+// Request & response types are left as written, see ResolveProtoTypes.
+func ParseProtoFile(loc string, content []byte) (*ProtoFile, error) {
+	handler := reporter.NewHandler(nil)
+
+	node, err := parser.Parse(loc, bytes.NewReader(content), handler)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := parser.ResultFromAST(node, true, handler)
+	if err != nil {
+		return nil, err
+	}
+
+	fd := res.FileDescriptorProto()
+	pf := &ProtoFile{
+		Loc:       loc,
+		Name:      protoImportName(loc),
+		Package:   fd.GetPackage(),
+		GoPackage: protoGoPackage(fd),
+		Imports:   fd.GetDependency(),
+	}
+
+	var addMessages func(prefix string, msgs []*descriptorpb.DescriptorProto)
+	addMessages = func(prefix string, msgs []*descriptorpb.DescriptorProto) {
+		for _, m := range msgs {
+			name := protoJoin(prefix, m.GetName())
+			pos := node.NodeInfo(res.MessageNode(m)).Start()
+
+			msg := &ProtoMessage{
+				FullName: name,
+				Signers:  protoOptionStrings(m.GetOptions().GetUninterpretedOption(), "cosmos.msg.v1.signer"),
+				ProtoLoc: fmt.Sprintf("%s:%d", pos.Filename, pos.Line),
+			}
+			for _, f := range m.GetField() {
+				field := ProtoField{
+					Name:     f.GetName(),
+					Type:     f.GetTypeName(),
+					Repeated: f.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_REPEATED,
+				}
+				if field.Type == "" {
+					// scalar types are only set as the descriptor type (TYPE_STRING -> string)
+					field.Type = strings.ToLower(strings.TrimPrefix(f.GetType().String(), "TYPE_"))
+				}
+				if scalar := protoOptionStrings(f.GetOptions().GetUninterpretedOption(), "cosmos_proto.scalar"); len(scalar) > 0 {
+					field.Scalar = scalar[0]
+				}
+				msg.Fields = append(msg.Fields, field)
+			}
+
+			pf.Messages = append(pf.Messages, msg)
+			addMessages(name, m.GetNestedType())
+		}
+	}
+	addMessages(pf.Package, fd.GetMessageType())
+
+	module := strings.Split(pf.Package, ".")[0]
+	for _, svc := range fd.GetService() {
+		ft := protoServiceFileType(svc)
+		if ft == None {
+			continue
+		}
+
+		for _, m := range svc.GetMethod() {
+			pos := node.NodeInfo(res.MethodNode(m)).Start()
+
+			pf.RPCs = append(pf.RPCs, &ProtoRPC{
+				Name:            m.GetName(),
+				Req:             protoBaseName(m.GetInputType()),
+				Res:             protoBaseName(m.GetOutputType()),
+				Module:          module,
+				FType:           ft,
+				FileLoc:         loc,
+				Service:         svc.GetName(),
+				ReqType:         m.GetInputType(),
+				ResType:         m.GetOutputType(),
+				ClientStreaming: m.GetClientStreaming(),
+				ServerStreaming: m.GetServerStreaming(),
+				ProtoLoc:        fmt.Sprintf("%s:%d", pos.Filename, pos.Line),
+				GoPackage:       pf.GoPackage,
+			})
+		}
+	}
+
+	return pf, nil
+}
+
+// protoImportName returns the import path of a .proto file, relative to the last proto/ directory of its location.
+// i.e. /home/user/chain/proto/amm/v1/tx.proto -> amm/v1/tx.proto
+func protoImportName(loc string) string {
+	dirs := strings.Split(path.Dir(path.Clean(loc)), "/")
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if dirs[i] == "proto" {
+			return path.Join(append(dirs[i+1:], path.Base(loc))...)
+		}
+	}
+
+	// already relative to the proto root (amm/v1/tx.proto)
+	if !path.IsAbs(loc) {
+		return path.Clean(loc)
+	}
+
+	return path.Base(loc)
+}
+
+// ResolveProtoTypes resolves the request & response types of every RPC to their fully qualified proto name and Go
+// type, following the protobuf scoping rules across the given files. Types defined in another file must be imported.
+// RPCs with a type that can not be resolved are logged and removed from their file.
+func ResolveProtoTypes(logger *slog.Logger, files []*ProtoFile) {
+	defs := make(map[string]*ProtoFile)
+	msgs := make(map[string]*ProtoMessage)
+	for _, f := range files {
+		for _, m := range f.Messages {
+			defs[m.FullName] = f
+			msgs[m.FullName] = m
+		}
+	}
+
+	for _, f := range files {
+		rpcs := make([]*ProtoRPC, 0, len(f.RPCs))
+		for _, rpc := range f.RPCs {
+			var err error
+
+			rpc.ReqType, rpc.Req, rpc.ReqGoImport, err = resolveProtoType(defs, f, rpc, rpc.ReqType)
+			if err == nil {
+				rpc.ResType, rpc.Res, rpc.ResGoImport, err = resolveProtoType(defs, f, rpc, rpc.ResType)
+			}
+			if err != nil {
+				logger.Warn("Skipping RPC", "rpc", rpc.Name, "module", rpc.Module, "err", err)
+				continue
+			}
+
+			rpc.ReqMsg = msgs[rpc.ReqType]
+			rpcs = append(rpcs, rpc)
+		}
+		f.RPCs = rpcs
+	}
+}
+
+// resolveProtoType returns the fully qualified name, Go type name and Go import (if not the module's types package)
+// of a message referenced by an RPC.
+func resolveProtoType(defs map[string]*ProtoFile, f *ProtoFile, rpc *ProtoRPC, name string) (string, string, string, error) {
+	var candidates []string
+	if strings.HasPrefix(name, ".") {
+		candidates = append(candidates, strings.TrimPrefix(name, "."))
+	} else {
+		// innermost scope first: pkg.Service.Name, pkg.Name, parent pkgs, then the name alone
+		scope := protoJoin(f.Package, rpc.Service)
+		for scope != "" {
+			candidates = append(candidates, protoJoin(scope, name))
+			if i := strings.LastIndex(scope, "."); i >= 0 {
+				scope = scope[:i]
+			} else {
+				scope = ""
+			}
+		}
+		candidates = append(candidates, name)
+	}
+
+	for _, c := range candidates {
+		def, ok := defs[c]
+		if !ok {
+			continue
+		}
+
+		if def != f && !slices.Contains(f.Imports, def.Name) {
+			return "", "", "", fmt.Errorf("%s: %s is defined in %s which is not imported", rpc.ProtoLoc, c, def.Name)
+		}
+
+		goImport := ""
+		if def.GoPackage != "" && goImportPath(def.GoPackage) != goImportPath(f.GoPackage) {
+			goImport = def.GoPackage
+		}
+
+		return c, protoGoName(def.Package, c), goImport, nil
+	}
+
+	// not defined within the parsed files. Assume the file's own package, as long as the name allows for it.
+	full := strings.TrimPrefix(name, ".")
+	switch {
+	case f.Package != "" && strings.HasPrefix(full, f.Package+"."):
+		return full, protoGoName(f.Package, full), "", nil
+	case !strings.HasPrefix(name, ".") && !strings.Contains(name, "."):
+		return protoJoin(f.Package, name), name, "", nil
+	}
+
+	// buf dependencies are not within proto/, use the Go package they are generated into.
+	if pkg, goPackage, ok := protoDependencyGoPackage(full); ok {
+		return full, protoGoName(pkg, full), goPackage, nil
+	}
+
+	return "", "", "", fmt.Errorf("%s: unable to resolve %s, it must be defined in a .proto file within the proto/ directory or a known buf dependency", rpc.ProtoLoc, name)
+}
+
+// protoDependencyGoPackages are the Go packages of the buf dependency protos (buf.yaml deps), by proto package or
+// fully qualified message when a package is split across Go packages.
+var protoDependencyGoPackages = map[string]string{
+	"cosmos.base.v1beta1":          "github.com/cosmos/cosmos-sdk/types;sdk",
+	"cosmos.base.query.v1beta1":    "github.com/cosmos/cosmos-sdk/types/query",
+	"cosmos.auth.v1beta1":          "github.com/cosmos/cosmos-sdk/x/auth/types;authtypes",
+	"cosmos.bank.v1beta1":          "github.com/cosmos/cosmos-sdk/x/bank/types;banktypes",
+	"cosmos.staking.v1beta1":       "github.com/cosmos/cosmos-sdk/x/staking/types;stakingtypes",
+	"cosmos.distribution.v1beta1":  "github.com/cosmos/cosmos-sdk/x/distribution/types;distrtypes",
+	"cosmos.gov.v1":                "github.com/cosmos/cosmos-sdk/x/gov/types/v1;govv1",
+	"cosmos.gov.v1beta1":           "github.com/cosmos/cosmos-sdk/x/gov/types/v1beta1;govv1beta1",
+	"cosmos.upgrade.v1beta1":       "cosmossdk.io/x/upgrade/types;upgradetypes",
+	"ibc.core.client.v1":           "github.com/cosmos/ibc-go/v8/modules/core/02-client/types;clienttypes",
+	"ibc.core.channel.v1":          "github.com/cosmos/ibc-go/v8/modules/core/04-channel/types;channeltypes",
+	"ibc.applications.transfer.v1": "github.com/cosmos/ibc-go/v8/modules/apps/transfer/types;transfertypes",
+	"google.protobuf.Any":          "github.com/cosmos/cosmos-sdk/codec/types;codectypes",
+	"google.protobuf":              "github.com/cosmos/gogoproto/types;gogotypes",
+}
+
+// protoDependencyGoPackage returns the proto package & go_package of a fully qualified buf dependency message.
+func protoDependencyGoPackage(fullName string) (string, string, bool) {
+	for scope := fullName; strings.Contains(scope, "."); scope = scope[:strings.LastIndex(scope, ".")] {
+		goPackage, ok := protoDependencyGoPackages[scope]
+		if !ok {
+			continue
+		}
+
+		pkg := scope
+		if scope == fullName {
+			pkg = fullName[:strings.LastIndex(fullName, ".")]
+		}
+		return pkg, goPackage, true
+	}
+
+	return "", "", false
+}
+
+// protoServiceFileType returns the type of a service from its name or the cosmos.msg.v1.service option.
+func protoServiceFileType(svc *descriptorpb.ServiceDescriptorProto) FileType {
+	for _, opt := range svc.GetOptions().GetUninterpretedOption() {
+		for _, part := range opt.GetName() {
+			if part.GetNamePart() == "cosmos.msg.v1.service" && opt.GetIdentifierValue() == "true" {
+				return Tx
+			}
+		}
+	}
+
+	switch svc.GetName() {
+	case "Msg", "Tx":
+		return Tx
+	case "Query":
+		return Query
+	}
+
+	return None
+}
+
+// protoOptionStrings returns the string values of an option, which may be set multiple times.
+func protoOptionStrings(opts []*descriptorpb.UninterpretedOption, name string) []string {
+	var values []string
+	for _, opt := range opts {
+		parts := make([]string, 0, len(opt.GetName()))
+		for _, part := range opt.GetName() {
+			parts = append(parts, part.GetNamePart())
+		}
+
+		if strings.Join(parts, ".") == name && opt.StringValue != nil {
+			values = append(values, string(opt.GetStringValue()))
+		}
+	}
+	return values
+}
+
+// protoGoPackage returns the go_package option of a file, empty if not set.
+func protoGoPackage(fd *descriptorpb.FileDescriptorProto) string {
+	for _, opt := range fd.GetOptions().GetUninterpretedOption() {
+		if len(opt.GetName()) == 1 && opt.GetName()[0].GetNamePart() == "go_package" {
+			return string(opt.GetStringValue())
+		}
+	}
+	return ""
+}
+
+// protoGoName is the Go type generated for a fully qualified message (nested messages are joined by _).
+func protoGoName(pkg, fullName string) string {
+	if pkg != "" {
+		fullName = strings.TrimPrefix(fullName, pkg+".")
+	}
+	return strings.ReplaceAll(fullName, ".", "_")
+}
+
+func protoBaseName(name string) string {
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+func protoJoin(scope, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
+
+// goImportPath returns the import path of a go_package option (github.com/aaa/bbb/types;alias).
+func goImportPath(goPackage string) string {
+	p, _, _ := strings.Cut(goPackage, ";")
+	return p
+}
+
+// goImportSpec returns the Go import of a go_package option, with its alias if set (sdk "github.com/aaa/bbb/types").
+func goImportSpec(goPackage string) string {
+	p, alias, _ := strings.Cut(goPackage, ";")
+	if alias == "" {
+		return strconv.Quote(p)
+	}
+	return alias + " " + strconv.Quote(p)
+}
+
+// goImportAlias returns the package name to reference a go_package option with. `types` packages are prefixed with
+// their parent directory so they do not collide with the module's own types import.
+func goImportAlias(goPackage string) string {
+	p, alias, ok := strings.Cut(goPackage, ";")
+	if ok && alias != "" {
+		return alias
+	}
+
+	alias = path.Base(p)
+	if alias == "types" {
+		alias = path.Base(path.Dir(p)) + "types"
+	}
+
+	// import paths may contain characters Go identifiers can not (cosmos-sdk, 02-client)
+	alias = strings.Map(func(r rune) rune {
+		if r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, alias)
+	if alias == "" || unicode.IsDigit(rune(alias[0])) {
+		alias = "pkg" + alias
+	}
+
+	return alias
+}
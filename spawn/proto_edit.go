@@ -0,0 +1,180 @@
+package spawn
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	protoast "github.com/bufbuild/protocompile/ast"
+	"github.com/bufbuild/protocompile/parser"
+	"github.com/bufbuild/protocompile/reporter"
+)
+
+// editProtoFile applies the edits of fn to a .proto file. Proto files are not formatted, inserted text must be
+// indented already.
+func editProtoFile(loc string, fn func(node *protoast.FileNode, src []byte) ([]sourceEdit, error)) error {
+	src, err := os.ReadFile(loc)
+	if err != nil {
+		return err
+	}
+
+	node, err := parser.Parse(loc, bytes.NewReader(src), reporter.NewHandler(nil))
+	if err != nil {
+		return err
+	}
+
+	edits, err := fn(node, src)
+	if err != nil {
+		return err
+	}
+
+	out, changed := spliceEdits(src, edits)
+	if !changed {
+		return nil
+	}
+
+	return os.WriteFile(loc, out, 0644)
+}
+
+// protoImportEdit adds the imports the file does not import yet after its last import, or after the package
+// statement when there are none.
+func protoImportEdit(node *protoast.FileNode, imports ...string) sourceEdit {
+	var existing []string
+	var after protoast.Node
+	for _, decl := range node.Decls {
+		switch d := decl.(type) {
+		case *protoast.ImportNode:
+			existing = append(existing, d.Name.AsString())
+			after = d
+		case *protoast.PackageNode:
+			if after == nil {
+				after = d
+			}
+		}
+	}
+
+	var text strings.Builder
+	for _, imp := range imports {
+		if !slices.Contains(existing, imp) {
+			fmt.Fprintf(&text, "import %q;\n", imp)
+			existing = append(existing, imp)
+		}
+	}
+
+	if text.Len() == 0 || after == nil {
+		return sourceEdit{}
+	}
+
+	offset := node.NodeInfo(after).End().Offset + 1
+	if _, ok := after.(*protoast.PackageNode); ok {
+		return sourceEdit{offset: offset, text: "\n\n" + strings.TrimSuffix(text.String(), "\n")}
+	}
+	return sourceEdit{offset: offset, text: "\n" + strings.TrimSuffix(text.String(), "\n")}
+}
+
+// protoMessage returns a top level message of the file.
+func protoMessage(node *protoast.FileNode, name string) *protoast.MessageNode {
+	for _, decl := range node.Decls {
+		if m, ok := decl.(*protoast.MessageNode); ok && m.Name.Val == name {
+			return m
+		}
+	}
+	return nil
+}
+
+// protoService returns a service of the file.
+func protoService(node *protoast.FileNode, name string) *protoast.ServiceNode {
+	for _, decl := range node.Decls {
+		if s, ok := decl.(*protoast.ServiceNode); ok && s.Name.Val == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// protoMaxFieldTag is the largest field number of a message (2^29 - 1), the end of `reserved N to max`.
+const protoMaxFieldTag = 536870911
+
+// protoNextFieldTag returns the tag to use for a new field of the message, after the fields (including oneof and
+// map fields) and outside of the reserved ranges.
+func protoNextFieldTag(m *protoast.MessageNode) uint64 {
+	var (
+		tag      uint64
+		reserved [][2]uint64
+	)
+
+	addTag := func(t *protoast.UintLiteralNode) {
+		if t != nil {
+			tag = max(tag, t.Val)
+		}
+	}
+
+	for _, decl := range m.Decls {
+		switch d := decl.(type) {
+		case *protoast.FieldNode:
+			addTag(d.Tag)
+		case *protoast.MapFieldNode:
+			addTag(d.Tag)
+		case *protoast.GroupNode:
+			addTag(d.Tag)
+		case *protoast.OneofNode:
+			for _, od := range d.Decls {
+				switch f := od.(type) {
+				case *protoast.FieldNode:
+					addTag(f.Tag)
+				case *protoast.GroupNode:
+					addTag(f.Tag)
+				}
+			}
+		case *protoast.ReservedNode:
+			for _, r := range d.Ranges {
+				start, ok := r.StartValueAsInt32(1, protoMaxFieldTag)
+				if !ok {
+					continue
+				}
+				end, ok := r.EndValueAsInt32(1, protoMaxFieldTag)
+				if !ok {
+					continue
+				}
+				reserved = append(reserved, [2]uint64{uint64(start), uint64(end)})
+			}
+		}
+	}
+
+	next := tag + 1
+	for moved := true; moved; {
+		moved = false
+		for _, r := range reserved {
+			if next >= r[0] && next <= r[1] {
+				next = r[1] + 1
+				moved = true
+			}
+		}
+	}
+
+	return next
+}
+
+// protoInsertBeforeBrace inserts lines before the closing brace of a message or service body.
+func protoInsertBeforeBrace(node *protoast.FileNode, src []byte, brace *protoast.RuneNode, text string) sourceEdit {
+	offset := node.NodeInfo(brace).Start().Offset
+
+	lineStart := bytes.LastIndexByte(src[:offset], '\n') + 1
+	if len(bytes.TrimSpace(src[lineStart:offset])) == 0 {
+		return sourceEdit{offset: lineStart, text: text}
+	}
+
+	// `message Name {}`, the body moves to its own lines
+	return sourceEdit{offset: offset, text: "\n" + text}
+}
+
+// protoAppendEdit adds text at the end of the file, separated by an empty line.
+func protoAppendEdit(src []byte, text string) sourceEdit {
+	prefix := "\n"
+	if !bytes.HasSuffix(src, []byte("\n")) {
+		prefix = "\n\n"
+	}
+	return sourceEdit{offset: len(src), text: prefix + text}
+}
@@ -0,0 +1,589 @@
+package spawn
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"log/slog"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OrphanedRPC is a msgServer or Querier method without an RPC in the module's proto services.
+type OrphanedRPC struct {
+	Name   string
+	Module string
+	FType  FileType
+	// The Go file the method is implemented in
+	FileLoc string
+	// The Go request & response type names (MsgSwap, MsgSwapResponse), empty for streaming methods
+	Req string
+	Res string
+	// The request message is no longer defined in the module's proto files
+	ReqRemoved bool
+	// The missing RPC with the same request & response types, set when the RPC was renamed
+	RenamedTo *ProtoRPC
+
+	// The server type the method is on
+	recv string
+}
+
+// serverMethod is a Go method with the signature of a gRPC server method.
+type serverMethod struct {
+	name    string
+	recv    string
+	req     string
+	res     string
+	fileLoc string
+}
+
+// GetOrphanedRPCMethods returns the server methods of each module that are no longer an RPC in proto/. An orphan is
+// matched as a rename when exactly one missing RPC has the same request & response types.
+func GetOrphanedRPCMethods(logger *slog.Logger, cwd string, missing ModuleMapping) ([]*OrphanedRPC, error) {
+	files, err := parseProtoDir(logger, path.Join(cwd, "proto"))
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := LoadStubGenConfig(cwd)
+	if err != nil {
+		return nil, err
+	}
+
+	rpcs := make(ModuleMapping)
+	msgs := make(map[string][]string)
+	for _, pf := range files {
+		name := pf.Module()
+		rpcs[name] = append(rpcs[name], pf.RPCs...)
+		for _, msg := range pf.Messages {
+			msgs[name] = append(msgs[name], protoGoName(pf.Package, msg.FullName))
+		}
+	}
+
+	var orphans []*OrphanedRPC
+	for _, name := range sortedKeys(rpcs) {
+		if len(rpcs[name]) == 0 {
+			continue
+		}
+
+		servers, err := findModuleServers(cwd, cfg, name, rpcs[name])
+		if err != nil {
+			return nil, err
+		}
+
+		var moduleOrphans []*OrphanedRPC
+		for _, service := range sortedKeys(servers) {
+			server := servers[service]
+			if server.isNew {
+				continue
+			}
+
+			var ft FileType
+			var serviceRPCs []string
+			for _, rpc := range rpcs[name] {
+				if rpc.serviceName() == service {
+					ft = rpc.FType
+					serviceRPCs = append(serviceRPCs, rpc.Name)
+				}
+			}
+
+			for _, m := range server.methods {
+				if slices.Contains(serviceRPCs, m.name) {
+					continue
+				}
+
+				logger.Debug("orphaned rpc", "module", name, "name", m.name, "server", server.typeName, "file", m.fileLoc)
+
+				moduleOrphans = append(moduleOrphans, &OrphanedRPC{
+					Name:       m.name,
+					Module:     name,
+					FType:      ft,
+					FileLoc:    m.fileLoc,
+					Req:        m.req,
+					Res:        m.res,
+					ReqRemoved: m.req != "" && !slices.Contains(msgs[name], m.req),
+					recv:       server.typeName,
+				})
+			}
+		}
+
+		matchRenamedRPCs(moduleOrphans, missing[name])
+		orphans = append(orphans, moduleOrphans...)
+	}
+
+	return orphans, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// matchRenamedRPCs sets RenamedTo for orphans that share their request & response types with exactly one missing RPC,
+// which no other orphan shares.
+func matchRenamedRPCs(orphans []*OrphanedRPC, missing []*ProtoRPC) {
+	matches := make(map[*OrphanedRPC]*ProtoRPC)
+	count := make(map[*ProtoRPC]int)
+
+	for _, o := range orphans {
+		if o.Req == "" {
+			continue
+		}
+
+		var found []*ProtoRPC
+		for _, rpc := range missing {
+			if rpc.FType == o.FType && !rpc.ClientStreaming && !rpc.ServerStreaming && rpc.Req == o.Req && rpc.Res == o.Res {
+				found = append(found, rpc)
+			}
+		}
+
+		if len(found) == 1 {
+			matches[o] = found[0]
+			count[found[0]]++
+		}
+	}
+
+	for o, rpc := range matches {
+		if count[rpc] == 1 {
+			o.RenamedTo = rpc
+		}
+	}
+}
+
+// newServerMethod returns the method if it has the signature of a unary or streaming gRPC server method.
+func newServerMethod(fn *ast.FuncDecl) (serverMethod, bool) {
+	m := serverMethod{name: fn.Name.Name, recv: goTypeName(fn.Recv.List[0].Type)}
+
+	params, results := fieldTypes(fn.Type.Params), fieldTypes(fn.Type.Results)
+	switch {
+	// (ctx context.Context, req *types.Req) (*types.Res, error)
+	case len(params) == 2 && len(results) == 2 && types.ExprString(params[0]) == "context.Context" && types.ExprString(results[1]) == "error":
+		m.req, m.res = goTypeName(params[1]), goTypeName(results[0])
+	// ([req *types.Req,] stream types.Msg_NameServer) error
+	case len(params) >= 1 && len(params) <= 2 && len(results) == 1 && types.ExprString(results[0]) == "error" &&
+		strings.HasSuffix(types.ExprString(params[len(params)-1]), "Server"):
+	default:
+		return serverMethod{}, false
+	}
+
+	return m, true
+}
+
+// fieldTypes returns the type of each parameter or result, expanding grouped names (a, b string).
+func fieldTypes(fl *ast.FieldList) []ast.Expr {
+	if fl == nil {
+		return nil
+	}
+
+	var exprs []ast.Expr
+	for _, field := range fl.List {
+		n := max(len(field.Names), 1)
+		for i := 0; i < n; i++ {
+			exprs = append(exprs, field.Type)
+		}
+	}
+	return exprs
+}
+
+// goTypeName returns the type name without pointer or package (*types.MsgSwap -> MsgSwap).
+func goTypeName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return goTypeName(e.X)
+	case *ast.SelectorExpr:
+		return e.Sel.Name
+	case *ast.Ident:
+		return e.Name
+	}
+	return ""
+}
+
+// ApplyRenamedRPCMethods renames orphaned methods matched to a new RPC, keeping their implementation. The autocli
+// command and keeper tests are renamed with them. Renamed RPCs are removed from missing so no stub is generated.
+func ApplyRenamedRPCMethods(logger *slog.Logger, cwd string, orphans []*OrphanedRPC, missing ModuleMapping) error {
+	cfg, err := LoadStubGenConfig(cwd)
+	if err != nil {
+		return err
+	}
+
+	for _, o := range orphans {
+		if o.RenamedTo == nil {
+			continue
+		}
+
+		oldName, newName := o.Name, o.RenamedTo.Name
+		moduleDir := cfg.ModuleDir(cwd, o.Module)
+
+		if err := editGoFile(o.FileLoc, func(fset *token.FileSet, f *ast.File, src []byte) []sourceEdit {
+			fn := findServerMethod(f, o.recv, oldName)
+			if fn == nil {
+				return nil
+			}
+
+			edits := []sourceEdit{renameEdit(fset, fn.Name, newName)}
+			if fn.Doc != nil {
+				for _, c := range fn.Doc.List {
+					if strings.HasPrefix(c.Text, "// "+oldName+" ") {
+						edits = append(edits, sourceEdit{offset: fset.Position(c.Pos()).Offset + 3, remove: len(oldName), text: newName})
+					}
+				}
+			}
+			return edits
+		}); err != nil {
+			return fmt.Errorf("%s: %w", o.FileLoc, err)
+		}
+
+		autocli := path.Join(moduleDir, "autocli.go")
+		if err := editGoFile(autocli, func(fset *token.FileSet, f *ast.File, src []byte) []sourceEdit {
+			return renameAutoCLIOption(fset, f, o.FType, oldName, newName)
+		}); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("%s: %w", autocli, err)
+		}
+
+		if err := editKeeperTests(moduleDir, func(fset *token.FileSet, f *ast.File, src []byte) []sourceEdit {
+			return renameKeeperTests(fset, f, oldName, newName)
+		}); err != nil {
+			return err
+		}
+
+		missing[o.Module] = slices.DeleteFunc(missing[o.Module], func(rpc *ProtoRPC) bool { return rpc == o.RenamedTo })
+
+		logger.Debug("renamed rpc", "module", o.Module, "from", oldName, "to", newName, "file", o.FileLoc)
+	}
+
+	return nil
+}
+
+// PruneOrphanedRPCMethods removes orphaned (not renamed) methods with their autocli command and keeper test. Msgs that
+// are no longer defined in proto/ are removed from types/codec.go, types/msgs.go and simulation/operations.go.
+func PruneOrphanedRPCMethods(logger *slog.Logger, cwd string, orphans []*OrphanedRPC) error {
+	cfg, err := LoadStubGenConfig(cwd)
+	if err != nil {
+		return err
+	}
+
+	for _, o := range orphans {
+		if o.RenamedTo != nil {
+			continue
+		}
+
+		name := o.Name
+		moduleDir := cfg.ModuleDir(cwd, o.Module)
+
+		if err := editGoFile(o.FileLoc, func(fset *token.FileSet, f *ast.File, src []byte) []sourceEdit {
+			if fn := findServerMethod(f, o.recv, name); fn != nil {
+				return []sourceEdit{removeDeclEdit(fset, src, fn)}
+			}
+			return nil
+		}); err != nil {
+			return fmt.Errorf("%s: %w", o.FileLoc, err)
+		}
+
+		edits := map[string]func(fset *token.FileSet, f *ast.File, src []byte) []sourceEdit{
+			path.Join(moduleDir, "autocli.go"): func(fset *token.FileSet, f *ast.File, src []byte) []sourceEdit {
+				if cmds, ok := autoCLIOptions(f)[o.FType]; ok {
+					if opt := autoCLIOption(cmds, name); opt != nil {
+						return []sourceEdit{removeEdit(src, fset.Position(opt.Pos()).Offset, fset.Position(opt.End()).Offset)}
+					}
+				}
+				return nil
+			},
+		}
+		if o.FType == Tx && o.ReqRemoved {
+			edits[path.Join(moduleDir, "types", "codec.go")] = func(fset *token.FileSet, f *ast.File, src []byte) []sourceEdit {
+				return removeMsgFromCodec(fset, f, src, o.Req)
+			}
+			edits[path.Join(moduleDir, "types", "msgs.go")] = func(fset *token.FileSet, f *ast.File, src []byte) []sourceEdit {
+				return removeMsgFromMsgsFile(fset, f, src, o.Req)
+			}
+			edits[path.Join(moduleDir, "simulation", "operations.go")] = func(fset *token.FileSet, f *ast.File, src []byte) []sourceEdit {
+				return removeMsgFromSimulation(fset, f, src, o.Req)
+			}
+		}
+
+		for loc, fn := range edits {
+			if err := editGoFile(loc, fn); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("%s: %w", loc, err)
+			}
+		}
+
+		if err := editKeeperTests(moduleDir, func(fset *token.FileSet, f *ast.File, src []byte) []sourceEdit {
+			for _, decl := range f.Decls {
+				if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil && fn.Name.Name == "Test"+name {
+					return []sourceEdit{removeDeclEdit(fset, src, fn)}
+				}
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		logger.Debug("pruned rpc", "module", o.Module, "name", name, "file", o.FileLoc)
+	}
+
+	return nil
+}
+
+// findServerMethod returns the method of the receiver type with a server signature by name.
+func findServerMethod(f *ast.File, recv, name string) *ast.FuncDecl {
+	for _, decl := range f.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv != nil && fn.Name.Name == name && goTypeName(fn.Recv.List[0].Type) == recv {
+			if _, ok := newServerMethod(fn); ok {
+				return fn
+			}
+		}
+	}
+	return nil
+}
+
+// renameAutoCLIOption renames the RpcMethod of the command, and its Use when it was generated from the method name.
+func renameAutoCLIOption(fset *token.FileSet, f *ast.File, ft FileType, oldName, newName string) []sourceEdit {
+	cmds, ok := autoCLIOptions(f)[ft]
+	if !ok {
+		return nil
+	}
+
+	opt := autoCLIOption(cmds, oldName)
+	if opt == nil {
+		return nil
+	}
+
+	var edits []sourceEdit
+	for _, elt := range opt.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			continue
+		}
+
+		switch v := types.ExprString(kv.Value); {
+		case types.ExprString(kv.Key) == "RpcMethod":
+			edits = append(edits, replaceEdit(fset, kv.Value, strconv.Quote(newName)))
+		case types.ExprString(kv.Key) == "Use" && v == strconv.Quote(toKebabCase(oldName)):
+			edits = append(edits, replaceEdit(fset, kv.Value, strconv.Quote(toKebabCase(newName))))
+		}
+	}
+	return edits
+}
+
+// renameKeeperTests renames the Test<Name> function and calls to the method through the msgServer or queryServer.
+func renameKeeperTests(fset *token.FileSet, f *ast.File, oldName, newName string) []sourceEdit {
+	var edits []sourceEdit
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			if n.Recv == nil && n.Name.Name == "Test"+oldName {
+				edits = append(edits, renameEdit(fset, n.Name, "Test"+newName))
+			}
+		case *ast.SelectorExpr:
+			if n.Sel.Name != oldName {
+				return true
+			}
+			if server := goTypeName(n.X); server == "msgServer" || server == "queryServer" {
+				edits = append(edits, renameEdit(fset, n.Sel, newName))
+			}
+		}
+		return true
+	})
+	return edits
+}
+
+// removeMsgFromCodec removes the amino registration and sdk.Msg implementation of the msg.
+func removeMsgFromCodec(fset *token.FileSet, f *ast.File, src []byte, msg string) []sourceEdit {
+	var edits []sourceEdit
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.ExprStmt:
+			call, ok := n.X.(*ast.CallExpr)
+			if ok && goTypeName(call.Fun) == "RegisterConcrete" && callHasCompositeArg(call, msg) {
+				edits = append(edits, removeEdit(src, fset.Position(n.Pos()).Offset, fset.Position(n.End()).Offset))
+				return false
+			}
+		case *ast.CallExpr:
+			if goTypeName(n.Fun) != "RegisterImplementations" {
+				return true
+			}
+			for _, arg := range n.Args {
+				if isCompositeAddr(arg, msg) {
+					edits = append(edits, removeEdit(src, fset.Position(arg.Pos()).Offset, fset.Position(arg.End()).Offset))
+				}
+			}
+		}
+		return true
+	})
+	return edits
+}
+
+// removeMsgFromMsgsFile removes the sdk.Msg assertion, constructor and methods of the msg.
+func removeMsgFromMsgsFile(fset *token.FileSet, f *ast.File, src []byte, msg string) []sourceEdit {
+	var edits []sourceEdit
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if (d.Recv != nil && goTypeName(d.Recv.List[0].Type) == msg) || (d.Recv == nil && d.Name.Name == "New"+msg) {
+				edits = append(edits, removeDeclEdit(fset, src, d))
+			}
+		case *ast.GenDecl:
+			if d.Tok != token.VAR {
+				continue
+			}
+			for _, spec := range d.Specs {
+				vs, ok := spec.(*ast.ValueSpec)
+				if !ok || len(vs.Values) != 1 {
+					continue
+				}
+				if isCompositeAddr(vs.Values[0], msg) {
+					edits = append(edits, removeEdit(src, fset.Position(vs.Pos()).Offset, fset.Position(vs.End()).Offset))
+				}
+			}
+		}
+	}
+	return edits
+}
+
+// removeMsgFromSimulation removes the weighted operation of the msg, with its weight constants and Simulate function.
+func removeMsgFromSimulation(fset *token.FileSet, f *ast.File, src []byte, msg string) []sourceEdit {
+	var edits []sourceEdit
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv == nil && d.Name.Name == "Simulate"+msg {
+				edits = append(edits, removeDeclEdit(fset, src, d))
+			}
+		case *ast.GenDecl:
+			if d.Tok != token.CONST {
+				continue
+			}
+
+			weights := []string{"OpWeight" + msg, "DefaultWeight" + msg}
+			var specs []ast.Spec
+			for _, spec := range d.Specs {
+				if vs, ok := spec.(*ast.ValueSpec); ok && len(vs.Names) == 1 && slices.Contains(weights, vs.Names[0].Name) {
+					specs = append(specs, spec)
+				}
+			}
+
+			if len(specs) == len(d.Specs) && len(specs) > 0 {
+				edits = append(edits, removeEdit(src, fset.Position(d.Pos()).Offset, fset.Position(d.End()).Offset))
+				continue
+			}
+			for _, spec := range specs {
+				edits = append(edits, removeEdit(src, fset.Position(spec.Pos()).Offset, fset.Position(spec.End()).Offset))
+			}
+		}
+	}
+
+	// the statements of WeightedOperations using the weight of the msg
+	if fn := findFunc(f, "WeightedOperations"); fn != nil {
+		for _, stmt := range fn.Body.List {
+			uses := false
+			ast.Inspect(stmt, func(n ast.Node) bool {
+				if ident, ok := n.(*ast.Ident); ok && ident.Name == "weight"+msg {
+					uses = true
+				}
+				return !uses
+			})
+
+			if uses {
+				edits = append(edits, removeEdit(src, fset.Position(stmt.Pos()).Offset, fset.Position(stmt.End()).Offset))
+			}
+		}
+	}
+
+	return edits
+}
+
+// editGoFile parses the file and applies the edits returned by fn.
+func editGoFile(loc string, fn func(fset *token.FileSet, f *ast.File, src []byte) []sourceEdit) error {
+	src, err := os.ReadFile(loc)
+	if err != nil {
+		return err
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, loc, src, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+
+	return applySourceEdits(loc, src, fn(fset, f, src))
+}
+
+// editKeeperTests applies fn to every test file of the module's keeper.
+func editKeeperTests(moduleDir string, fn func(fset *token.FileSet, f *ast.File, src []byte) []sourceEdit) error {
+	keeperDir := path.Join(moduleDir, "keeper")
+
+	entries, err := os.ReadDir(keeperDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), "_test.go") {
+			continue
+		}
+
+		loc := path.Join(keeperDir, e.Name())
+		if err := editGoFile(loc, fn); err != nil {
+			return fmt.Errorf("%s: %w", loc, err)
+		}
+	}
+
+	return nil
+}
+
+func renameEdit(fset *token.FileSet, ident *ast.Ident, name string) sourceEdit {
+	return sourceEdit{offset: fset.Position(ident.Pos()).Offset, remove: len(ident.Name), text: name}
+}
+
+func replaceEdit(fset *token.FileSet, expr ast.Expr, text string) sourceEdit {
+	start, end := fset.Position(expr.Pos()).Offset, fset.Position(expr.End()).Offset
+	return sourceEdit{offset: start, remove: end - start, text: text}
+}
+
+// removeDeclEdit removes a declaration with its doc comment.
+func removeDeclEdit(fset *token.FileSet, src []byte, fn *ast.FuncDecl) sourceEdit {
+	start := fn.Pos()
+	if fn.Doc != nil {
+		start = fn.Doc.Pos()
+	}
+	return removeEdit(src, fset.Position(start).Offset, fset.Position(fn.End()).Offset)
+}
+
+// removeEdit removes the source between the offsets, including a trailing comma of list elements. When nothing else
+// is on the lines, the lines are removed as a whole.
+func removeEdit(src []byte, start, end int) sourceEdit {
+	if rest := bytes.TrimLeft(src[end:], " \t"); len(rest) > 0 && rest[0] == ',' {
+		end = len(src) - len(rest) + 1
+	}
+
+	lineStart := bytes.LastIndexByte(src[:start], '\n') + 1
+	lineEnd := len(src)
+	if i := bytes.IndexByte(src[end:], '\n'); i >= 0 {
+		lineEnd = end + i + 1
+	}
+
+	if len(bytes.TrimSpace(src[lineStart:start])) == 0 && len(bytes.TrimSpace(src[end:lineEnd])) == 0 {
+		start, end = lineStart, lineEnd
+	}
+
+	return sourceEdit{offset: start, remove: end - start}
+}
+
+// toKebabCase converts an RPC name into the autocli command name (UpdateParams -> update-params).
+func toKebabCase(s string) string {
+	return strings.ReplaceAll(toSnakeCase(s), "_", "-")
+}
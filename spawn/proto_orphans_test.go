@@ -0,0 +1,116 @@
+package spawn
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const orphansTxProto = `syntax = "proto3";
+package amm.v1;
+
+import "cosmos/msg/v1/msg.proto";
+
+option go_package = "github.com/rollchains/mychain/x/amm/types";
+
+service Msg {
+  option (cosmos.msg.v1.service) = true;
+
+  rpc UpdateParams(MsgUpdateParams) returns (MsgUpdateParamsResponse);
+  RPCS
+}
+
+message MsgUpdateParams {
+  option (cosmos.msg.v1.signer) = "authority";
+  string authority = 1;
+}
+message MsgUpdateParamsResponse {}
+
+message MsgSwap {
+  option (cosmos.msg.v1.signer) = "sender";
+  string sender = 1;
+}
+message MsgSwapResponse {}
+MSGS`
+
+const burnMsg = `
+message MsgBurn {
+  option (cosmos.msg.v1.signer) = "sender";
+  string sender = 1;
+}
+message MsgBurnResponse {}
+`
+
+func TestOrphanedRPCMethods(t *testing.T) {
+	before := strings.NewReplacer(
+		"RPCS", "rpc Swap(MsgSwap) returns (MsgSwapResponse);\n  rpc Burn(MsgBurn) returns (MsgBurnResponse);",
+		"MSGS", burnMsg,
+	).Replace(orphansTxProto)
+
+	cwd := setupStubGenModule(t, before)
+	runStubGen(t, cwd)
+	require.Contains(t, readModuleFile(t, cwd, "keeper/msg_server.go"), "func (ms msgServer) Burn(")
+	require.Contains(t, readModuleFile(t, cwd, "simulation/operations.go"), "func SimulateMsgBurn(")
+
+	// Swap is renamed to Exchange and Burn is removed with its msg
+	writeTxProto(t, cwd, strings.NewReplacer(
+		"RPCS", "rpc Exchange(MsgSwap) returns (MsgSwapResponse);",
+		"MSGS", "",
+	).Replace(orphansTxProto))
+
+	missing, err := GetMissingRPCMethodsFromModuleProto(logger, cwd)
+	require.NoError(t, err)
+	require.Len(t, missing["amm"], 1)
+
+	orphans, err := GetOrphanedRPCMethods(logger, cwd, missing)
+	require.NoError(t, err)
+	require.Len(t, orphans, 2)
+
+	byName := make(map[string]*OrphanedRPC)
+	for _, o := range orphans {
+		byName[o.Name] = o
+	}
+	require.Equal(t, "Exchange", byName["Swap"].RenamedTo.Name)
+	require.False(t, byName["Swap"].ReqRemoved)
+	require.Nil(t, byName["Burn"].RenamedTo)
+	require.True(t, byName["Burn"].ReqRemoved)
+
+	require.NoError(t, ApplyRenamedRPCMethods(logger, cwd, orphans, missing))
+	require.Empty(t, missing["amm"], "renamed rpcs do not get a new stub")
+
+	msgServer := readModuleFile(t, cwd, "keeper/msg_server.go")
+	require.Contains(t, msgServer, "// Exchange implements types.MsgServer.\nfunc (ms msgServer) Exchange(")
+	require.NotContains(t, msgServer, ") Swap(")
+	require.Contains(t, msgServer, ") Burn(", "orphans are kept without --prune")
+
+	tests := readModuleFile(t, cwd, "keeper/msg_server_test.go")
+	require.Contains(t, tests, "func TestExchange(t *testing.T) {")
+	require.Contains(t, tests, "f.msgServer.Exchange(f.ctx, tc.request)")
+
+	autocli := readModuleFile(t, cwd, "autocli.go")
+	require.Contains(t, autocli, `RpcMethod: "Exchange",`)
+	require.Contains(t, autocli, `Use:       "exchange",`)
+
+	require.NoError(t, PruneOrphanedRPCMethods(logger, cwd, orphans))
+
+	msgServer = readModuleFile(t, cwd, "keeper/msg_server.go")
+	require.NotContains(t, msgServer, "Burn")
+	require.Contains(t, msgServer, ") Exchange(")
+	require.Contains(t, msgServer, ") UpdateParams(")
+
+	for _, f := range []string{"keeper/msg_server_test.go", "autocli.go", "types/codec.go", "types/msgs.go", "simulation/operations.go"} {
+		require.NotContains(t, readModuleFile(t, cwd, f), "Burn", f)
+	}
+	require.Contains(t, readModuleFile(t, cwd, "types/codec.go"), "&MsgSwap{}", "msgs still in proto are kept")
+	require.Contains(t, readModuleFile(t, cwd, "simulation/operations.go"), "SimulateMsgSwap(txGen, k)")
+
+	// nothing is left to do
+	missing, err = GetMissingRPCMethodsFromModuleProto(logger, cwd)
+	require.NoError(t, err)
+	require.Empty(t, missing["amm"])
+
+	orphans, err = GetOrphanedRPCMethods(logger, cwd, missing)
+	require.NoError(t, err)
+	require.Empty(t, orphans)
+}
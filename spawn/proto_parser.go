@@ -1,12 +1,19 @@
 package spawn
 
 import (
+	"bytes"
 	"fmt"
+	"go/format"
+	"go/parser"
+	"go/token"
 	"io/fs"
 	"log/slog"
 	"os"
 	"path"
+	"slices"
 	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
 )
 
 /// --- types ---
@@ -54,6 +61,31 @@ type ProtoRPC struct {
 	FType FileType
 	// Where there Query/Msg Server is located (querier.go, msgserver.gom, etc.)
 	FileLoc string
+	// The receiver of the server methods (ms msgServer), the template's when empty
+	Recv string
+	// The server type does not exist yet, it is created in FileLoc with the first stub
+	NewServer bool
+
+	// The proto service the RPC is in (Msg, Query)
+	Service string
+	// The fully qualified proto request & response types (amm.v1.MsgUpdateParams)
+	ReqType string
+	ResType string
+	// The go_package of the request & response when it is not the module's types package
+	ReqGoImport string
+	ResGoImport string
+	// Streaming RPCs (rpc Name(stream Req) returns (stream Res))
+	ClientStreaming bool
+	ServerStreaming bool
+	// Where the RPC is defined (file:line)
+	ProtoLoc string
+	// The request message, nil if it is not defined within the proto/ directory
+	ReqMsg *ProtoMessage
+	// The go_package of the proto file
+	GoPackage string
+	// The body of the generated server method and the go packages it uses, a TODO stub when empty
+	Impl        string
+	ImplImports []string
 }
 
 func (pr *ProtoRPC) String() string {
@@ -67,64 +99,115 @@ func (pr *ProtoRPC) String() string {
 
 // BuildProtoInterfaceStub returns the string to save to the file for the msgServer or Querier.
 func (pr ProtoRPC) BuildProtoInterfaceStub() string {
-	if pr.FType == Tx {
-		return fmt.Sprintf(`// %s implements types.MsgServer.
-func (ms msgServer) %s(ctx context.Context, msg *types.%s) (*types.%s, error) {
-	// ctx := sdk.UnwrapSDKContext(goCtx)
-	panic("%s is unimplemented")
-	return &types.%s{}, nil
-}
-`, pr.Name, pr.Name, pr.Req, pr.Res, pr.Name, pr.Res)
-	} else if pr.FType == Query {
-		return fmt.Sprintf(`// %s implements types.QueryServer.
-func (k Querier) %s(goCtx context.Context, req *types.%s) (*types.%s, error) {
-	// ctx := sdk.UnwrapSDKContext(goCtx)
-	panic("%s is unimplemented")
-	return &types.%s{}, nil
-}
-`, pr.Name, pr.Name, pr.Req, pr.Res, pr.Name, pr.Res)
-	} else {
+	var recv, ctxName, argName string
+	switch pr.FType {
+	case Tx:
+		recv, ctxName, argName = "ms msgServer", "ctx", "msg"
+	case Query:
+		recv, ctxName, argName = "k Querier", "goCtx", "req"
+	default:
 		panic("Unknown FileType for: " + pr.Name)
 	}
-}
+	if pr.Recv != "" {
+		recv = pr.Recv
+	}
 
-// ProtoServiceParser parses out a proto file content and returns all the RPC services within it.
-func ProtoServiceParser(logger *slog.Logger, content []byte, ft FileType, fileLoc string) []*ProtoRPC {
-	pRPCs := make([]*ProtoRPC, 0)
-	c := strings.Split(string(content), "\n")
+	service := pr.serviceName()
+	req := pr.goType(pr.Req, pr.ReqGoImport)
+	res := pr.goType(pr.Res, pr.ResGoImport)
 
-	moduleName := GetProtoPackageName(content)
+	if pr.ClientStreaming || pr.ServerStreaming {
+		// gRPC streams are passed as the generated <Service>_<Name>Server.
+		params := fmt.Sprintf("stream types.%s_%sServer", service, pr.Name)
+		if !pr.ClientStreaming {
+			params = fmt.Sprintf("%s *%s, %s", argName, req, params)
+		}
 
-	for idx, line := range c {
-		line = strings.TrimLeft(line, " ")
-		line = strings.TrimLeft(line, "\t")
+		return fmt.Sprintf(`// %s implements types.%sServer.
+func (%s) %s(%s) error {
+	// TODO: implement %s
+	return status.Error(codes.Unimplemented, "%s is not implemented")
+}
+`, pr.Name, service, recv, pr.Name, params, pr.Name, pr.Name)
+	}
 
-		if strings.HasPrefix(line, "rpc ") {
-			line = strings.Trim(line, " ")
-			logger.Debug("proto file", "rpc line", line)
+	// msgs.go Validate is generated by stub-gen for the module's own messages.
+	validate := ""
+	if pr.FType == Tx && pr.ReqGoImport == "" {
+		validate = fmt.Sprintf(`if err := %s.Validate(); err != nil {
+		return nil, err
+	}
 
-			// if line does not end with {, we also need to load the next line (multi line proto from linting)
-			if !strings.HasSuffix(line, "{") {
-				line = line + c[idx+1]
-			}
+	`, argName)
+	}
 
-			line = strings.Trim(line, " ")
+	if pr.Impl != "" {
+		return fmt.Sprintf(`// %s implements types.%sServer.
+func (%s) %s(%s context.Context, %s *%s) (*%s, error) {
+%s}
+`, pr.Name, service, recv, pr.Name, ctxName, argName, req, res, pr.Impl)
+	}
+
+	return fmt.Sprintf(`// %s implements types.%sServer.
+func (%s) %s(%s context.Context, %s *%s) (*%s, error) {
+	%s// sdkCtx := sdk.UnwrapSDKContext(%s)
+
+	// TODO: implement %s
+	return nil, status.Error(codes.Unimplemented, "%s is not implemented")
+}
+`, pr.Name, service, recv, pr.Name, ctxName, argName, req, res, validate, ctxName, pr.Name, pr.Name)
+}
 
-			line = strings.NewReplacer("rpc", "", "returns", "", "(", " ", ")", " ", "{", "", "}", "").Replace(line)
-
-			words := strings.Fields(line)
-			pRPCs = append(pRPCs, &ProtoRPC{
-				Name:    words[0],
-				Req:     words[1],
-				Res:     words[2],
-				FType:   ft,
-				Module:  moduleName,
-				FileLoc: fileLoc,
-			})
+// GoImports returns the go packages the interface stub uses besides the module's types.
+func (pr ProtoRPC) GoImports() []string {
+	var imports []string
+	if !pr.ClientStreaming && !pr.ServerStreaming {
+		imports = append(imports, "context")
+	}
+	// stubs without an implementation return codes.Unimplemented
+	extra := pr.ImplImports
+	if pr.Impl == "" {
+		extra = []string{"google.golang.org/grpc/codes", "google.golang.org/grpc/status"}
+	}
+	for _, imp := range append([]string{pr.ReqGoImport, pr.ResGoImport}, extra...) {
+		if imp != "" && !slices.Contains(imports, imp) {
+			imports = append(imports, imp)
 		}
 	}
+	return imports
+}
+
+func (pr ProtoRPC) serviceName() string {
+	if pr.Service != "" {
+		return pr.Service
+	}
+	if pr.FType == Tx {
+		return "Msg"
+	}
+	return "Query"
+}
+
+func (pr ProtoRPC) goType(name, goImport string) string {
+	if goImport == "" {
+		return "types." + name
+	}
+	return goImportAlias(goImport) + "." + name
+}
+
+// ProtoServiceParser parses out a proto file content and returns all the RPC methods of its Msg & Query services.
+func ProtoServiceParser(logger *slog.Logger, content []byte, fileLoc string) ([]*ProtoRPC, error) {
+	pf, err := ParseProtoFile(fileLoc, content)
+	if err != nil {
+		return nil, err
+	}
+
+	ResolveProtoTypes(logger, []*ProtoFile{pf})
+
+	for _, rpc := range pf.RPCs {
+		logger.Debug("proto file", "rpc", rpc.Name, "req", rpc.ReqType, "res", rpc.ResType, "loc", rpc.ProtoLoc)
+	}
 
-	return pRPCs
+	return pf.RPCs, nil
 }
 
 // GetProtoPackageName inputs proto file content, then parse out the package (cosmos module) name
@@ -151,11 +234,36 @@ func GetProtoPackageName(content []byte) string {
 	return ""
 }
 
-// Converts .proto files into a mapping depending on the type.
-func GetCurrentModuleRPCsFromProto(logger *slog.Logger, absProtoPath string) ModuleMapping {
+// Converts .proto files into a mapping of the module (proto/<module>) to its RPCs.
+func GetCurrentModuleRPCsFromProto(logger *slog.Logger, absProtoPath string) (ModuleMapping, error) {
 	modules := make(ModuleMapping)
 
+	files, err := parseProtoDir(logger, absProtoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pf := range files {
+		if len(pf.RPCs) == 0 {
+			continue
+		}
+
+		modules[pf.Module()] = append(modules[pf.Module()], pf.RPCs...)
+	}
+
+	modules.Print(logger)
+
+	return modules, nil
+}
+
+// parseProtoDir parses every .proto file within the directory and resolves their RPC types.
+func parseProtoDir(logger *slog.Logger, absProtoPath string) ([]*ProtoFile, error) {
+	var files []*ProtoFile
 	err := fs.WalkDir(os.DirFS(absProtoPath), ".", func(relPath string, d fs.DirEntry, e error) error {
+		if e != nil {
+			return e
+		}
+
 		if !strings.HasSuffix(relPath, ".proto") {
 			return nil
 		}
@@ -164,35 +272,25 @@ func GetCurrentModuleRPCsFromProto(logger *slog.Logger, absProtoPath string) Mod
 
 		content, err := os.ReadFile(loc)
 		if err != nil {
-			logger.Error("Error", "error", err)
-		}
-
-		fileType := FileTypeFromProtoContent(content)
-		if fileType == None {
-			return nil
+			return err
 		}
 
-		rpcs := ProtoServiceParser(logger, content, fileType, loc)
-
-		parent := path.Dir(relPath)
-		parent = strings.Split(parent, "/")[0]
-
-		if _, ok := modules[parent]; !ok {
-			modules[parent] = make([]*ProtoRPC, 0)
+		pf, err := ParseProtoFile(loc, content)
+		if err != nil {
+			return err
 		}
 
-		modules[parent] = append(modules[parent], rpcs...)
-
+		files = append(files, pf)
 		return nil
 	})
 	if err != nil {
-		logger.Error("Error", "error", err)
-		panic(err)
+		return nil, err
 	}
 
-	modules.Print(logger)
+	// types are resolved across all files, so imports of other modules work.
+	ResolveProtoTypes(logger, files)
 
-	return modules
+	return files, nil
 }
 
 // returns "tx" or "query" depending on the content of the file
@@ -212,127 +310,47 @@ func FileTypeFromProtoContent(bz []byte) FileType {
 	return None
 }
 
+// GetMissingRPCMethodsFromModuleProto returns the RPCs of each module that are not implemented by its server types
+// yet. The server of a service is the Go type implementing types.<Service>Server, see findServerImpl.
 func GetMissingRPCMethodsFromModuleProto(logger *slog.Logger, cwd string) (ModuleMapping, error) {
 	protoPath := path.Join(cwd, "proto")
-	modules := GetCurrentModuleRPCsFromProto(logger, protoPath)
+	modules, err := GetCurrentModuleRPCsFromProto(logger, protoPath)
+	if err != nil {
+		return nil, err
+	}
 
-	missing := make(ModuleMapping, 0)
+	cfg, err := LoadStubGenConfig(cwd)
+	if err != nil {
+		return nil, err
+	}
 
-	txMethods := make(map[string][]string)
-	queryMethods := make(map[string][]string)
+	missing := make(ModuleMapping, 0)
 
 	for name, rpcMethods := range modules {
+		moduleDir := cfg.ModuleDir(cwd, name)
+		if _, err := os.Stat(moduleDir); os.IsNotExist(err) {
+			logger.Warn("Module directory not found, skipping", "module", name, "dir", moduleDir)
+			continue
+		}
 
-		// hardcode for keeper is less than ideal, but will do for now
-		modulePath := path.Join(cwd, "x", name, "keeper")
+		servers, err := findModuleServers(cwd, cfg, name, rpcMethods)
+		if err != nil {
+			return nil, err
+		}
 
-		logger.Debug("module", "module", name, "modulePath", modulePath)
+		missing[name] = make([]*ProtoRPC, 0)
 
 		for _, rpc := range rpcMethods {
-			rpc := rpc
 			rpc.Module = name
 
-			logger.Debug("rpc", "rpc", rpc)
-
-			files, err := os.ReadDir(modulePath)
-			if err != nil {
-				return nil, err
-			}
-
-			for _, f := range files {
-				if strings.HasSuffix(f.Name(), "_test.go") {
-					continue
-				}
-
-				content, err := os.ReadFile(path.Join(modulePath, f.Name()))
-				if err != nil {
-					logger.Error("error", "err", err)
-					return nil, err
-				}
-
-				// if the file type is not the expected, continue
-				// if the content of this file is not the same as the RPC type we are tying to use, continue
-				if rpc.FType != getFileType(content) {
-					continue
-				}
-
-				logger.Debug(" = file", "file", f.Name())
-
-				// Set the file type tot his file
-				rpc.FileLoc = path.Join(modulePath, f.Name())
-
-				// find any line with `func ` in it
-				lines := strings.Split(string(content), "\n")
-				for _, line := range lines {
-					// receiver func
-					if strings.Contains(line, "func (") {
-						if strings.Contains(line, rpc.Name+"(") {
-							switch rpc.FType {
-							case Tx:
-								txMethods[name] = append(txMethods[name], parseReceiverMethodName(line))
-							case Query:
-								queryMethods[name] = append(queryMethods[name], parseReceiverMethodName(line))
-							default:
-								logger.Error("error", "err", "Unknown FileType")
-								return nil, fmt.Errorf("unknown file type")
-							}
-						}
-					}
-				}
-
-			}
-		}
+			server := servers[rpc.serviceName()]
+			rpc.FileLoc, rpc.Recv, rpc.NewServer = server.fileLoc, server.receiver(), server.isNew
 
-		logger.Debug("Current Tx Methods: ", "txMethods", txMethods)
-		logger.Debug("Current Query Methods: ", "queryMethods", queryMethods)
-	}
-
-	// iterate services again and apply any missing methods to the file
-	for name, rpcs := range modules {
-		rpcs := rpcs
-
-		if _, ok := missing[name]; !ok {
-			missing[name] = make([]*ProtoRPC, 0)
-		}
+			logger.Debug("rpc", "rpc", rpc.Name, "server", server.typeName, "file", server.fileLoc)
 
-		for _, rpc := range rpcs {
-			rpc := rpc
-
-			var current []string
-			switch rpc.FType {
-			case Tx:
-				current = txMethods[name]
-			case Query:
-				current = queryMethods[name]
-			default:
-				logger.Error("error", "err", "Unknown FileType")
-				return nil, fmt.Errorf("unknown file type")
-			}
-
-			logger.Debug("    Current: ", "current", current)
-
-			found := false
-			for _, method := range current {
-				method := method
-				if method == rpc.Name {
-					found = true
-					break
-				}
-			}
-
-			if found {
-				continue
-			}
-			logger.Debug("  - Not Found: ", "rpc", rpc.Name)
-
-			alreadyIncluded := false
-			for _, m := range missing[name] {
-				if m.Name == rpc.Name {
-					alreadyIncluded = true
-					break
-				}
-			}
-			if alreadyIncluded {
+			if server.hasMethod(rpc.Name) || slices.ContainsFunc(missing[name], func(m *ProtoRPC) bool {
+				return m.Name == rpc.Name && m.serviceName() == rpc.serviceName()
+			}) {
 				continue
 			}
 
@@ -346,40 +364,6 @@ func GetMissingRPCMethodsFromModuleProto(logger *slog.Logger, cwd string) (Modul
 	return missing, nil
 }
 
-// given a string of text like `func (k Querier) Params(c context.Context, req *types.QueryParamsRequest) (*types.QueryParamsResponse, error) {`
-// parse out Params, req and the response
-func parseReceiverMethodName(f string) string {
-	name := ""
-
-	// f = func (k Querier) Params(c context.Context, req *types.QueryParamsRequest) (*types.QueryParamsResponse, error) {
-
-	// k Querier) Params(c context.Context, req *types.QueryParamsRequest) (*types.QueryParamsResponse, error) {
-	f = strings.ReplaceAll(f, "func (", "")
-
-	// [`k Querier` , `Params(c context.Context, req *types.QueryParamsRequest`, `(*types.QueryParamsResponse, error) {` ]
-	parts := strings.Split(f, ") ")
-
-	// [`Params`, `c context.Context, req *types.QueryParamsRequest`]
-	name = strings.Split(parts[1], "(")[0]
-
-	// `Params`
-	return strings.Trim(name, " ")
-}
-
-func getFileType(bz []byte) FileType {
-	s := strings.ToLower(string(bz))
-
-	if strings.Contains(s, "queryserver") || strings.Contains(s, "querier") {
-		return Query
-	}
-
-	if strings.Contains(s, "msgserver") || strings.Contains(s, "msgservice") {
-		return Tx
-	}
-
-	return None
-}
-
 // ApplyMissingRPCMethodsToGoSourceFiles builds the proto interface stubs and appends them to the file for missing methods.
 // If .proto file contained an rpc method for `Params` and `Other` but only `Params` is found in the querier, then `Other` is generated, appended, and saved.
 func ApplyMissingRPCMethodsToGoSourceFiles(logger *slog.Logger, missingRPCMethods ModuleMapping) error {
@@ -390,7 +374,7 @@ func ApplyMissingRPCMethodsToGoSourceFiles(logger *slog.Logger, missingRPCMethod
 
 			logger.Debug("rpc info", "module", miss.Module, "fileLoc", fileLoc, "name", miss.Name, "ftype", miss.FType.String())
 
-			content, err := os.ReadFile(fileLoc)
+			content, err := readServerFile(fileLoc, miss)
 			if err != nil {
 				return fmt.Errorf("error: %s, file: %s", err.Error(), fileLoc)
 			}
@@ -405,6 +389,11 @@ func ApplyMissingRPCMethodsToGoSourceFiles(logger *slog.Logger, missingRPCMethod
 			// append to the file content after a new line at the end
 			content = append(content, []byte("\n"+code)...)
 
+			content, err = addGoImports(fileLoc, content, miss.GoImports())
+			if err != nil {
+				return fmt.Errorf("error: %s, file: %s", err.Error(), fileLoc)
+			}
+
 			if err := os.WriteFile(fileLoc, content, 0644); err != nil {
 				logger.Error("error", "err", err)
 				return err
@@ -414,3 +403,67 @@ func ApplyMissingRPCMethodsToGoSourceFiles(logger *slog.Logger, missingRPCMethod
 
 	return nil
 }
+
+// readServerFile returns the content of the server file, creating the file & server type when they do not exist.
+func readServerFile(fileLoc string, rpc *ProtoRPC) ([]byte, error) {
+	content, err := os.ReadFile(fileLoc)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+
+		if err := os.MkdirAll(path.Dir(fileLoc), 0755); err != nil {
+			return nil, err
+		}
+		content = []byte(newServerFile(fileLoc, rpc))
+	}
+
+	if rpc.NewServer {
+		f, err := parser.ParseFile(token.NewFileSet(), fileLoc, content, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		if !declaresType(f, newServerImpl(rpc.serviceName()).typeName) {
+			content = append(content, []byte("\n"+newServerCode(rpc))...)
+		}
+	}
+
+	return content, nil
+}
+
+// addGoImports adds the go_package imports to the Go source if they are not already imported.
+func addGoImports(fileLoc string, src []byte, goPackages []string) ([]byte, error) {
+	if len(goPackages) == 0 {
+		return src, nil
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, fileLoc, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	added := false
+	for _, goPkg := range goPackages {
+		importPath, alias := goImportPath(goPkg), goImportAlias(goPkg)
+		if alias == path.Base(importPath) {
+			alias = ""
+		}
+
+		if astutil.AddNamedImport(fset, f, alias, importPath) {
+			added = true
+		}
+	}
+
+	if !added {
+		return src, nil
+	}
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, f); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -21,14 +21,16 @@ func TestParser(t *testing.T) {
 		modPkg       string
 		protoContent string
 		ft           FileType
+		fileLoc      string
 		expected     []*ProtoRPC
 	}
 
 	tests := []tcase{
 		{
-			name:   "query, multi line rpc",
-			modPkg: "github.com/orgName/chainName",
-			ft:     Query,
+			name:    "query, multi line rpc",
+			modPkg:  "github.com/orgName/chainName",
+			ft:      Query,
+			fileLoc: "cnd/v1/query.proto",
 			protoContent: `syntax = "proto3";
 			package cnd.v1;
 			import "google/api/annotations.proto";
@@ -50,27 +52,38 @@ func TestParser(t *testing.T) {
 			}`,
 			expected: []*ProtoRPC{
 				{
-					Name:    "Params",
-					Req:     "QueryParamsRequest",
-					Res:     "QueryParamsResponse",
-					Module:  "cnd",
-					FType:   Query,
-					FileLoc: "",
+					Name:      "Params",
+					Req:       "QueryParamsRequest",
+					Res:       "QueryParamsResponse",
+					Module:    "cnd",
+					FType:     Query,
+					FileLoc:   "cnd/v1/query.proto",
+					Service:   "Query",
+					ReqType:   "cnd.v1.QueryParamsRequest",
+					ResType:   "cnd.v1.QueryParamsResponse",
+					ProtoLoc:  "cnd/v1/query.proto:10",
+					GoPackage: "github.com/orgName/chainName/x/cnd/types",
 				},
 				{
-					Name:    "FeeShare",
-					Req:     "QueryFeeShareRequest",
-					Res:     "QueryFeeShareResponse",
-					Module:  "cnd",
-					FType:   Query,
-					FileLoc: "",
+					Name:      "FeeShare",
+					Req:       "QueryFeeShareRequest",
+					Res:       "QueryFeeShareResponse",
+					Module:    "cnd",
+					FType:     Query,
+					FileLoc:   "cnd/v1/query.proto",
+					Service:   "Query",
+					ReqType:   "cnd.v1.QueryFeeShareRequest",
+					ResType:   "cnd.v1.QueryFeeShareResponse",
+					ProtoLoc:  "cnd/v1/query.proto:15",
+					GoPackage: "github.com/orgName/chainName/x/cnd/types",
 				},
 			},
 		},
 		{
-			name:   "tx, multiple msgs",
-			modPkg: "github.com/aaa/bbb",
-			ft:     Tx,
+			name:    "tx, multiple msgs",
+			modPkg:  "github.com/aaa/bbb",
+			ft:      Tx,
+			fileLoc: "amm/v1/tx.proto",
 			protoContent: `syntax = "proto3";
 		package amm.v1;
 		import "cosmos/msg/v1/msg.proto";
@@ -87,20 +100,30 @@ func TestParser(t *testing.T) {
 		}`,
 			expected: []*ProtoRPC{
 				{
-					Name:    "UpdateParams",
-					Req:     "MsgUpdateParams",
-					Res:     "MsgUpdateParamsResponse",
-					Module:  "amm",
-					FType:   Tx,
-					FileLoc: "",
+					Name:      "UpdateParams",
+					Req:       "MsgUpdateParams",
+					Res:       "MsgUpdateParamsResponse",
+					Module:    "amm",
+					FType:     Tx,
+					FileLoc:   "amm/v1/tx.proto",
+					Service:   "Msg",
+					ReqType:   "amm.v1.MsgUpdateParams",
+					ResType:   "amm.v1.MsgUpdateParamsResponse",
+					ProtoLoc:  "amm/v1/tx.proto:11",
+					GoPackage: "github.com/aaa/bbb/x/amm/nested/types",
 				},
 				{
-					Name:    "UpdateParams2",
-					Req:     "MsgUpdateParams2",
-					Res:     "MsgUpdateParamsResponse2",
-					Module:  "amm",
-					FType:   Tx,
-					FileLoc: "",
+					Name:      "UpdateParams2",
+					Req:       "MsgUpdateParams2",
+					Res:       "MsgUpdateParamsResponse2",
+					Module:    "amm",
+					FType:     Tx,
+					FileLoc:   "amm/v1/tx.proto",
+					Service:   "Msg",
+					ReqType:   "amm.v1.MsgUpdateParams2",
+					ResType:   "amm.v1.MsgUpdateParamsResponse2",
+					ProtoLoc:  "amm/v1/tx.proto:13",
+					GoPackage: "github.com/aaa/bbb/x/amm/nested/types",
 				},
 			},
 		},
@@ -115,7 +138,8 @@ func TestParser(t *testing.T) {
 
 		buildMockGoMod(t, tc.modPkg)
 
-		r := ProtoServiceParser(logger, content, tc.ft, "")
+		r, err := ProtoServiceParser(logger, content, tc.fileLoc)
+		require.NoError(t, err, tc.name)
 
 		require.Equal(t, len(tc.expected), len(r), tc.name, *r[0])
 
@@ -143,9 +167,10 @@ func TestBuildProtoInterfaceStub(t *testing.T) {
 			},
 			expected: `// RPCMethodName implements types.QueryServer.
 func (k Querier) RPCMethodName(goCtx context.Context, req *types.Query...Request) (*types.Query...Response, error) {
-	// ctx := sdk.UnwrapSDKContext(goCtx)
-	panic("RPCMethodName is unimplemented")
-	return &types.Query...Response{}, nil
+	// sdkCtx := sdk.UnwrapSDKContext(goCtx)
+
+	// TODO: implement RPCMethodName
+	return nil, status.Error(codes.Unimplemented, "RPCMethodName is not implemented")
 }
 `,
 		},
@@ -159,9 +184,14 @@ func (k Querier) RPCMethodName(goCtx context.Context, req *types.Query...Request
 			},
 			expected: `// UpdateParams implements types.MsgServer.
 func (ms msgServer) UpdateParams(ctx context.Context, msg *types.MsgUpdateParams) (*types.MsgUpdateParamsResponse, error) {
-	// ctx := sdk.UnwrapSDKContext(goCtx)
-	panic("UpdateParams is unimplemented")
-	return &types.MsgUpdateParamsResponse{}, nil
+	if err := msg.Validate(); err != nil {
+		return nil, err
+	}
+
+	// sdkCtx := sdk.UnwrapSDKContext(ctx)
+
+	// TODO: implement UpdateParams
+	return nil, status.Error(codes.Unimplemented, "UpdateParams is not implemented")
 }
 `,
 		},
@@ -259,3 +289,139 @@ func buildMockGoMod(t *testing.T, moduleName string) {
 	_, err = f.WriteString(fmt.Sprintf("module %s", moduleName))
 	require.NoError(t, err)
 }
+
+func TestParserGrammar(t *testing.T) {
+	content := `syntax = "proto3";
+package amm.v1;
+
+option go_package = "github.com/aaa/bbb/x/amm/types";
+
+// service Query { rpc Fake(A) returns (B); }
+service Query {
+  /* rpc Commented(QueryA) returns (QueryB); */
+  rpc Pools(
+    amm.v1.QueryPoolsRequest
+  )
+    returns (stream .amm.v1.QueryPoolsResponse) {
+    option (google.api.http) = {
+      get: "/amm/v1/pools"
+      additional_bindings { get: "/amm/v1/pools/{id}" }
+    };
+  }
+
+  rpc Nested(QueryNested.Request) returns (QueryNested.Response);
+}
+
+message QueryPoolsRequest {}
+message QueryPoolsResponse {}
+message QueryNested {
+  message Request {}
+  message Response {}
+}`
+
+	r, err := ProtoServiceParser(logger, []byte(content), "amm/v1/query.proto")
+	require.NoError(t, err)
+	require.Len(t, r, 2)
+
+	require.Equal(t, "Pools", r[0].Name)
+	require.Equal(t, "QueryPoolsRequest", r[0].Req)
+	require.Equal(t, "QueryPoolsResponse", r[0].Res)
+	require.Equal(t, "amm.v1.QueryPoolsResponse", r[0].ResType)
+	require.False(t, r[0].ClientStreaming)
+	require.True(t, r[0].ServerStreaming)
+	require.Equal(t, "amm/v1/query.proto:9", r[0].ProtoLoc)
+
+	require.Equal(t, "QueryNested_Request", r[1].Req)
+	require.Equal(t, "QueryNested_Response", r[1].Res)
+	require.Equal(t, "amm.v1.QueryNested.Request", r[1].ReqType)
+
+	_, err = ProtoServiceParser(logger, []byte("syntax = \"proto3\";\npackage amm.v1;\nservice Msg {\n  rpc Bad(MsgBad) returns MsgBadResponse;\n}"), "amm/v1/tx.proto")
+	require.ErrorContains(t, err, "amm/v1/tx.proto:4:")
+}
+
+func TestResolveProtoTypesAcrossFiles(t *testing.T) {
+	parse := func(name, content string) *ProtoFile {
+		pf, err := ParseProtoFile(path.Join("/home/user/chain/proto", name), []byte(content))
+		require.NoError(t, err)
+		require.Equal(t, name, pf.Name)
+		return pf
+	}
+
+	other := parse("other/v1/types.proto", `syntax = "proto3";
+package other.v1;
+option go_package = "github.com/aaa/bbb/x/other/types";
+message Shared {}`)
+
+	tx := parse("amm/v1/tx.proto", `syntax = "proto3";
+package amm.v1;
+import "other/v1/types.proto";
+option go_package = "github.com/aaa/bbb/x/amm/types";
+service Msg {
+  rpc Share(other.v1.Shared) returns (MsgShareResponse);
+}
+message MsgShareResponse {}`)
+
+	rpc := tx.RPCs[0]
+	ResolveProtoTypes(logger, []*ProtoFile{other, tx})
+	require.Equal(t, "other.v1.Shared", rpc.ReqType)
+	require.Equal(t, "github.com/aaa/bbb/x/other/types", rpc.ReqGoImport)
+	require.Empty(t, rpc.ResGoImport)
+	require.Contains(t, rpc.BuildProtoInterfaceStub(), "msg *othertypes.Shared) (*types.MsgShareResponse, error)")
+
+	// must be imported, the RPC is skipped otherwise
+	tx.Imports = nil
+	rpc.ReqType = "other.v1.Shared"
+	ResolveProtoTypes(logger, []*ProtoFile{other, tx})
+	require.Empty(t, tx.RPCs)
+
+	// buf dependencies resolve to their Go packages
+	tx.RPCs = []*ProtoRPC{rpc}
+	rpc.ReqType = "cosmos.base.v1beta1.Coin"
+	rpc.ResType = "google.protobuf.Any"
+	ResolveProtoTypes(logger, []*ProtoFile{tx})
+	require.Len(t, tx.RPCs, 1)
+	require.Equal(t, "cosmos.base.v1beta1.Coin", rpc.ReqType)
+	require.Equal(t, "Coin", rpc.Req)
+	require.Equal(t, "github.com/cosmos/cosmos-sdk/types;sdk", rpc.ReqGoImport)
+	require.Equal(t, "Any", rpc.Res)
+	require.Equal(t, "github.com/cosmos/cosmos-sdk/codec/types;codectypes", rpc.ResGoImport)
+	require.Contains(t, rpc.BuildProtoInterfaceStub(), "msg *sdk.Coin) (*codectypes.Any, error)")
+
+	// unknown packages are not guessed, only that RPC is skipped
+	other.RPCs = []*ProtoRPC{{Name: "Other", Module: "other", ReqType: "Shared", ResType: "Shared"}}
+	rpc.ReqType = "foo.v1.Bar"
+	ResolveProtoTypes(logger, []*ProtoFile{other, tx})
+	require.Empty(t, tx.RPCs)
+	require.Len(t, other.RPCs, 1)
+	require.Equal(t, "other.v1.Shared", other.RPCs[0].ReqType)
+}
+
+func TestGoImportAlias(t *testing.T) {
+	require.Equal(t, "sdk", goImportAlias("github.com/cosmos/cosmos-sdk/types;sdk"))
+	require.Equal(t, "cosmossdktypes", goImportAlias("github.com/cosmos/cosmos-sdk/types"))
+	require.Equal(t, "othertypes", goImportAlias("github.com/aaa/bbb/x/other/types"))
+	require.Equal(t, "pkg02client", goImportAlias("github.com/cosmos/ibc-go/v8/modules/core/02-client"))
+	require.Equal(t, "v1", goImportAlias("github.com/cosmos/cosmos-sdk/x/gov/types/v1"))
+}
+
+func TestBuildProtoInterfaceStreamingStub(t *testing.T) {
+	pr := ProtoRPC{
+		Name:            "Pools",
+		Req:             "QueryPoolsRequest",
+		Res:             "QueryPoolsResponse",
+		FType:           Query,
+		Service:         "Query",
+		ServerStreaming: true,
+	}
+
+	require.Equal(t, `// Pools implements types.QueryServer.
+func (k Querier) Pools(req *types.QueryPoolsRequest, stream types.Query_PoolsServer) error {
+	// TODO: implement Pools
+	return status.Error(codes.Unimplemented, "Pools is not implemented")
+}
+`, pr.BuildProtoInterfaceStub())
+	require.Equal(t, []string{"google.golang.org/grpc/codes", "google.golang.org/grpc/status"}, pr.GoImports())
+
+	pr.ClientStreaming = true
+	require.Contains(t, pr.BuildProtoInterfaceStub(), "func (k Querier) Pools(stream types.Query_PoolsServer) error {")
+}
@@ -0,0 +1,704 @@
+package spawn
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"log/slog"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/tools/imports"
+)
+
+const addressScalar = "cosmos.AddressString"
+
+// ScaffoldMissingRPCs wires new RPCs into the rest of the module after their server stubs are applied. Msgs are
+// registered in types/codec.go, get their sdk.Msg methods in types/msgs.go, a test case in keeper/msg_server_test.go
+// and a simulation operation in simulation/operations.go. Every unary RPC is added to autocli.go.
+func ScaffoldMissingRPCs(logger *slog.Logger, cwd string, missing ModuleMapping) error {
+	cfg, err := LoadStubGenConfig(cwd)
+	if err != nil {
+		return err
+	}
+
+	for name, rpcs := range missing {
+		if len(rpcs) == 0 {
+			continue
+		}
+
+		moduleDir := cfg.ModuleDir(cwd, name)
+
+		// msgs of other go packages are registered by their own module.
+		var msgs []*ProtoRPC
+		for _, rpc := range rpcs {
+			if rpc.FType != Tx || rpc.ClientStreaming || rpc.ServerStreaming || rpc.ReqGoImport != "" {
+				continue
+			}
+
+			checkMsgSigners(logger, rpc)
+			msgs = append(msgs, rpc)
+		}
+
+		steps := []struct {
+			file string
+			fn   func(loc string) error
+		}{
+			{path.Join(moduleDir, "types", "codec.go"), func(loc string) error { return addMsgsToCodec(loc, msgs) }},
+			{path.Join(moduleDir, "types", "msgs.go"), func(loc string) error { return addMsgsToMsgsFile(loc, msgs) }},
+			{path.Join(moduleDir, "autocli.go"), func(loc string) error { return addRPCsToAutoCLI(loc, rpcs) }},
+			{path.Join(moduleDir, "keeper", "msg_server_test.go"), func(loc string) error { return addMsgServerTests(loc, msgs) }},
+			{path.Join(moduleDir, "simulation", "operations.go"), func(loc string) error { return addMsgsToSimulation(loc, msgs) }},
+		}
+
+		for _, step := range steps {
+			if err := step.fn(step.file); err != nil {
+				if os.IsNotExist(err) {
+					logger.Warn("File not found, skipping", "module", name, "file", step.file)
+					continue
+				}
+				return fmt.Errorf("%s: %w", step.file, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkMsgSigners warns when a Msg can not be signed, the SDK rejects these when the app starts.
+func checkMsgSigners(logger *slog.Logger, rpc *ProtoRPC) {
+	if rpc.ReqMsg == nil {
+		return
+	}
+
+	if len(rpc.ReqMsg.Signers) == 0 {
+		logger.Warn("Msg is missing the (cosmos.msg.v1.signer) option", "msg", rpc.ReqType, "loc", rpc.ReqMsg.ProtoLoc)
+		return
+	}
+
+	for _, signer := range rpc.ReqMsg.Signers {
+		if _, ok := rpc.ReqMsg.Field(signer); !ok {
+			logger.Warn("Msg signer field does not exist", "msg", rpc.ReqType, "signer", signer, "loc", rpc.ReqMsg.ProtoLoc)
+		}
+	}
+}
+
+// addMsgsToCodec registers the msgs with the amino codec and as sdk.Msg implementations.
+func addMsgsToCodec(loc string, msgs []*ProtoRPC) error {
+	src, err := os.ReadFile(loc)
+	if err != nil {
+		return err
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, loc, src, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+
+	var aminoBody *ast.BlockStmt
+	var impls *ast.CallExpr
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			if n.Name.Name == "RegisterLegacyAminoCodec" {
+				aminoBody = n.Body
+			}
+		case *ast.CallExpr:
+			if sel, ok := n.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "RegisterImplementations" &&
+				len(n.Args) > 0 && types.ExprString(n.Args[0]) == "(*sdk.Msg)(nil)" {
+				impls = n
+			}
+		}
+		return true
+	})
+
+	var edits []sourceEdit
+	for _, msg := range msgs {
+		if aminoBody != nil && !bytes.Contains(src, []byte(fmt.Sprintf("&%s{}, ModuleName", msg.Req))) {
+			edits = append(edits, sourceEdit{
+				offset: fset.Position(aminoBody.Rbrace).Offset,
+				text:   fmt.Sprintf("\tcdc.RegisterConcrete(&%s{}, ModuleName+\"/%s\", nil)\n", msg.Req, msg.Req),
+			})
+		}
+
+		if impls != nil && !callHasCompositeArg(impls, msg.Req) {
+			edits = append(edits, sourceEdit{
+				offset: fset.Position(impls.Args[len(impls.Args)-1].End()).Offset,
+				text:   fmt.Sprintf(",\n&%s{}", msg.Req),
+			})
+		}
+	}
+
+	return applySourceEdits(loc, src, edits)
+}
+
+// addMsgsToMsgsFile adds the sdk.Msg assertion, legacy methods and a Validate to each msg. Existing methods are kept.
+func addMsgsToMsgsFile(loc string, msgs []*ProtoRPC) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	src, err := os.ReadFile(loc)
+	if os.IsNotExist(err) {
+		src = []byte("package types\n")
+	} else if err != nil {
+		return err
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, loc, src, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+
+	methods := make(map[string]bool)
+	var lastAssertion *ast.ValueSpec
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv != nil && len(d.Recv.List) == 1 {
+				recv := strings.TrimPrefix(types.ExprString(d.Recv.List[0].Type), "*")
+				methods[recv+"."+d.Name.Name] = true
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				if vs, ok := spec.(*ast.ValueSpec); ok && vs.Type != nil && types.ExprString(vs.Type) == "sdk.Msg" {
+					lastAssertion = vs
+				}
+			}
+		}
+	}
+
+	var edits []sourceEdit
+	var code strings.Builder
+	for _, msg := range msgs {
+		assertion := fmt.Sprintf("_ sdk.Msg = &%s{}", msg.Req)
+		if !bytes.Contains(src, []byte(assertion)) {
+			if lastAssertion != nil {
+				edits = append(edits, sourceEdit{offset: fset.Position(lastAssertion.End()).Offset, text: "\n" + assertion})
+			} else {
+				code.WriteString(fmt.Sprintf("\nvar %s\n", assertion))
+			}
+		}
+
+		for _, m := range msgMethods(msg) {
+			if !methods[msg.Req+"."+m.name] {
+				code.WriteString("\n" + m.code)
+			}
+		}
+	}
+	edits = append(edits, sourceEdit{offset: len(src), text: code.String()})
+
+	out, changed, err := editSource(src, edits)
+	if err != nil || !changed {
+		return err
+	}
+
+	out, err = addGoImports(loc, out, []string{"cosmossdk.io/errors", "github.com/cosmos/cosmos-sdk/types;sdk"})
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(loc, out, 0644)
+}
+
+type msgMethod struct {
+	name string
+	code string
+}
+
+// msgMethods returns the methods the msgs.go template has for MsgUpdateParams.
+func msgMethods(msg *ProtoRPC) []msgMethod {
+	t := msg.Req
+	methods := []msgMethod{
+		{"Route", fmt.Sprintf(`// Route returns the name of the module
+func (msg %s) Route() string { return ModuleName }
+`, t)},
+		{"Type", fmt.Sprintf(`// Type returns the the action
+func (msg %s) Type() string { return "%s" }
+`, t, toSnakeCase(msg.Name))},
+		{"GetSignBytes", fmt.Sprintf(`// GetSignBytes implements the LegacyMsg interface.
+func (msg %s) GetSignBytes() []byte {
+	return sdk.MustSortJSON(AminoCdc.MustMarshalJSON(&msg))
+}
+`, t)},
+	}
+
+	signers := msgSignerFields(msg)
+	if len(signers) > 0 {
+		var body strings.Builder
+		addrs := make([]string, len(signers))
+		for i, s := range signers {
+			addrs[i] = "addr"
+			if len(signers) > 1 {
+				addrs[i] = fmt.Sprintf("addr%d", i)
+			}
+			fmt.Fprintf(&body, "\t%s, _ := sdk.AccAddressFromBech32(msg.%s)\n", addrs[i], goCamelCase(s))
+		}
+
+		methods = append(methods, msgMethod{"GetSigners", fmt.Sprintf(`// GetSigners returns the expected signers for a %s message.
+func (msg *%s) GetSigners() []sdk.AccAddress {
+%s	return []sdk.AccAddress{%s}
+}
+`, t, t, body.String(), strings.Join(addrs, ", "))})
+	}
+
+	var checks strings.Builder
+	for _, field := range msgAddressFields(msg) {
+		fmt.Fprintf(&checks, `	if _, err := sdk.AccAddressFromBech32(msg.%s); err != nil {
+		return errors.Wrap(err, "invalid %s")
+	}
+
+`, goCamelCase(field), field)
+	}
+
+	methods = append(methods, msgMethod{"Validate", fmt.Sprintf(`// Validate does a sanity check on the provided data.
+func (msg *%s) Validate() error {
+%s	return nil
+}
+`, t, checks.String())})
+
+	return methods
+}
+
+// msgSignerFields are the string signer fields of the msg.
+func msgSignerFields(msg *ProtoRPC) []string {
+	if msg.ReqMsg == nil {
+		return nil
+	}
+
+	var signers []string
+	for _, s := range msg.ReqMsg.Signers {
+		if f, ok := msg.ReqMsg.Field(s); ok && f.Type == "string" && !f.Repeated {
+			signers = append(signers, s)
+		}
+	}
+	return signers
+}
+
+// msgAddressFields are the signers followed by all other address fields of the msg.
+func msgAddressFields(msg *ProtoRPC) []string {
+	fields := msgSignerFields(msg)
+	if msg.ReqMsg == nil {
+		return fields
+	}
+
+	for _, f := range msg.ReqMsg.Fields {
+		if f.Scalar == addressScalar && f.Type == "string" && !f.Repeated && !slices.Contains(fields, f.Name) {
+			fields = append(fields, f.Name)
+		}
+	}
+	return fields
+}
+
+// addRPCsToAutoCLI adds a command for each unary RPC to the Tx or Query service descriptor.
+func addRPCsToAutoCLI(loc string, rpcs []*ProtoRPC) error {
+	src, err := os.ReadFile(loc)
+	if err != nil {
+		return err
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, loc, src, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+
+	options := autoCLIOptions(f)
+
+	var edits []sourceEdit
+	for _, rpc := range rpcs {
+		cmds, ok := options[rpc.FType]
+		if !ok || rpc.ClientStreaming || rpc.ServerStreaming || autoCLIOption(cmds, rpc.Name) != nil {
+			continue
+		}
+
+		edits = append(edits, appendCompositeElt(fset, src, cmds, autoCLIEntry(rpc)))
+	}
+
+	return applySourceEdits(loc, src, edits)
+}
+
+// autoCLIOptions returns the RpcCommandOptions of the Tx & Query service descriptors.
+func autoCLIOptions(f *ast.File) map[FileType]*ast.CompositeLit {
+	// Tx: &autocliv1.ServiceCommandDescriptor{RpcCommandOptions: []*autocliv1.RpcCommandOptions{...}}
+	options := make(map[FileType]*ast.CompositeLit)
+	ast.Inspect(f, func(n ast.Node) bool {
+		kv, ok := n.(*ast.KeyValueExpr)
+		if !ok {
+			return true
+		}
+
+		key := types.ExprString(kv.Key)
+		if key != "Tx" && key != "Query" {
+			return true
+		}
+
+		desc, ok := ast.Unparen(kv.Value).(*ast.UnaryExpr)
+		if !ok {
+			return true
+		}
+		lit, ok := desc.X.(*ast.CompositeLit)
+		if !ok {
+			return true
+		}
+
+		for _, elt := range lit.Elts {
+			if field, ok := elt.(*ast.KeyValueExpr); ok && types.ExprString(field.Key) == "RpcCommandOptions" {
+				if cmds, ok := field.Value.(*ast.CompositeLit); ok {
+					options[map[string]FileType{"Tx": Tx, "Query": Query}[key]] = cmds
+				}
+			}
+		}
+		return true
+	})
+
+	return options
+}
+
+// autoCLIOption returns the RpcCommandOptions entry of the method, nil if there is none.
+func autoCLIOption(cmds *ast.CompositeLit, method string) *ast.CompositeLit {
+	for _, elt := range cmds.Elts {
+		lit, ok := elt.(*ast.CompositeLit)
+		if ok && compositeField(lit, "RpcMethod") == strconv.Quote(method) {
+			return lit
+		}
+	}
+	return nil
+}
+
+// compositeField returns the value of a key in a composite literal as written, empty if not set.
+func compositeField(lit *ast.CompositeLit, key string) string {
+	for _, elt := range lit.Elts {
+		if kv, ok := elt.(*ast.KeyValueExpr); ok && types.ExprString(kv.Key) == key {
+			return types.ExprString(kv.Value)
+		}
+	}
+	return ""
+}
+
+// addMsgServerTests appends a table driven test for each msg, using the keeper_test SetupTest fixture.
+func addMsgServerTests(loc string, msgs []*ProtoRPC) error {
+	src, err := os.ReadFile(loc)
+	if err != nil {
+		return err
+	}
+
+	var code strings.Builder
+	var goImports []string
+	for _, msg := range msgs {
+		if bytes.Contains(src, []byte(fmt.Sprintf("func Test%s(", msg.Name))) {
+			continue
+		}
+
+		// stubs return codes.Unimplemented, their success case is skipped until implemented
+		unimplemented := ""
+		if msg.Impl == "" {
+			unimplemented = fmt.Sprintf(`
+			if status.Code(err) == codes.Unimplemented {
+				t.Skip("%s is not implemented")
+			}
+`, msg.Name)
+			goImports = []string{"google.golang.org/grpc/codes", "google.golang.org/grpc/status"}
+		}
+
+		addrs := msgAddressFields(msg)
+		request := func(invalid string) string {
+			var b strings.Builder
+			fmt.Fprintf(&b, "&types.%s{", msg.Req)
+			for _, a := range addrs {
+				value := "f.addrs[0].String()"
+				switch a {
+				case invalid:
+					value = `"invalid"`
+				case "authority":
+					// authority msgs are gated by the governance module account
+					value = "f.govModAddr"
+				}
+				fmt.Fprintf(&b, "\n\t\t\t\t%s: %s,", goCamelCase(a), value)
+			}
+			if len(addrs) > 0 {
+				b.WriteString("\n\t\t\t")
+			}
+			b.WriteString("}")
+			return b.String()
+		}
+
+		var cases strings.Builder
+		if len(addrs) > 0 {
+			fmt.Fprintf(&cases, `		{
+			name:    "fail; invalid %s",
+			request: %s,
+			err:     true,
+		},
+`, strings.ReplaceAll(addrs[0], "_", " "), request(addrs[0]))
+		}
+		fmt.Fprintf(&cases, `		{
+			name:    "success",
+			request: %s,
+			err:     false,
+		},
+`, request(""))
+
+		fmt.Fprintf(&code, `
+func Test%s(t *testing.T) {
+	f := SetupTest(t)
+	require := require.New(t)
+
+	testCases := []struct {
+		name    string
+		request *types.%s
+		err     bool
+	}{
+%s	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := f.msgServer.%s(f.ctx, tc.request)
+%s
+			if tc.err {
+				require.Error(err)
+			} else {
+				require.NoError(err)
+			}
+		})
+	}
+}
+`, msg.Name, msg.Req, cases.String(), msg.Name, unimplemented)
+	}
+
+	out, changed, err := editSource(src, []sourceEdit{{offset: len(src), text: code.String()}})
+	if err != nil || !changed {
+		return err
+	}
+
+	out, err = addGoImports(loc, out, goImports)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(loc, out, 0644)
+}
+
+// addMsgsToSimulation adds a weighted operation stub for each msg to the WeightedOperations of the simulation package.
+func addMsgsToSimulation(loc string, msgs []*ProtoRPC) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	src, err := os.ReadFile(loc)
+	if err != nil {
+		return err
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, loc, src, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+
+	// operations are appended to the slice returned by WeightedOperations
+	ret := weightedOperationsReturn(f)
+	if ret == nil {
+		return fmt.Errorf("WeightedOperations does not return a simulation.WeightedOperations variable")
+	}
+	operations := types.ExprString(ret.Results[0])
+
+	var edits []sourceEdit
+	var code strings.Builder
+	var typesImports []string
+	for _, msg := range msgs {
+		if findFunc(f, "Simulate"+msg.Req) != nil {
+			continue
+		}
+
+		weight := "weight" + msg.Req
+		edits = append(edits, lineStartEdit(src, fset.Position(ret.Pos()).Offset, fmt.Sprintf(`	var %s int
+	appParams.GetOrGenerate(OpWeight%s, &%s, nil, func(_ *rand.Rand) {
+		%s = DefaultWeight%s
+	})
+	%s = append(%s, simulation.NewWeightedOperation(%s, Simulate%s(txGen, k)))
+
+`, weight, msg.Req, weight, weight, msg.Req, operations, operations, weight, msg.Req)))
+
+		// the signers are random accounts
+		var account string
+		var fields strings.Builder
+		if signers := msgSignerFields(msg); len(signers) > 0 {
+			account = "simAccount, _ := simtypes.RandomAcc(r, accs)\n\t\t"
+			for _, s := range signers {
+				fmt.Fprintf(&fields, "\n\t\t\t%s: simAccount.Address.String(),", goCamelCase(s))
+			}
+			fields.WriteString("\n\t\t")
+		}
+
+		fmt.Fprintf(&code, `
+const (
+	OpWeight%s      = "op_weight_%s"
+	DefaultWeight%s = 100
+)
+
+// Simulate%s returns an operation delivering a random %s.
+func Simulate%s(txGen client.TxConfig, k keeper.Keeper) simtypes.Operation {
+	return func(r *rand.Rand, app *baseapp.BaseApp, ctx sdk.Context, accs []simtypes.Account, chainID string) (simtypes.OperationMsg, []simtypes.FutureOperation, error) {
+		%smsg := &types.%s{%s}
+
+		// TODO: randomize %s & deliver it with simulation.GenAndDeliverTxWithRandFees
+		return simtypes.NoOpMsg(types.ModuleName, sdk.MsgTypeURL(msg), "not implemented"), nil, nil
+	}
+}
+`, msg.Req, toSnakeCase(msg.Req), msg.Req, msg.Req, msg.Req, msg.Req, account, msg.Req, fields.String(), msg.Req)
+
+		if typesImport := goImportPath(msg.GoPackage) + ";types"; msg.GoPackage != "" && !slices.Contains(typesImports, typesImport) {
+			typesImports = append(typesImports, typesImport)
+		}
+	}
+	edits = append(edits, sourceEdit{offset: len(src), text: code.String()})
+
+	out, changed, err := editSource(src, edits)
+	if err != nil || !changed {
+		return err
+	}
+
+	out, err = addGoImports(loc, out, append([]string{
+		"math/rand",
+		"github.com/cosmos/cosmos-sdk/baseapp",
+		"github.com/cosmos/cosmos-sdk/types;sdk",
+	}, typesImports...))
+	if err != nil {
+		return err
+	}
+
+	// math/rand goes into its own group, the imports are only sorted & grouped, not resolved
+	out, err = imports.Process(loc, out, &imports.Options{FormatOnly: true, Comments: true, TabIndent: true, TabWidth: 8})
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(loc, out, 0644)
+}
+
+// weightedOperationsReturn returns the return statement of WeightedOperations, when it returns a variable.
+func weightedOperationsReturn(f *ast.File) *ast.ReturnStmt {
+	fn := findFunc(f, "WeightedOperations")
+	if fn == nil || len(fn.Body.List) == 0 {
+		return nil
+	}
+
+	ret, ok := fn.Body.List[len(fn.Body.List)-1].(*ast.ReturnStmt)
+	if !ok || len(ret.Results) != 1 {
+		return nil
+	}
+	if _, ok := ret.Results[0].(*ast.Ident); !ok {
+		return nil
+	}
+	return ret
+}
+
+func callHasCompositeArg(call *ast.CallExpr, typeName string) bool {
+	return slices.ContainsFunc(call.Args, func(arg ast.Expr) bool { return isCompositeAddr(arg, typeName) })
+}
+
+// isCompositeAddr reports whether the expression is &typeName{...}.
+func isCompositeAddr(expr ast.Expr, typeName string) bool {
+	if u, ok := expr.(*ast.UnaryExpr); ok && u.Op == token.AND {
+		if lit, ok := u.X.(*ast.CompositeLit); ok && types.ExprString(lit.Type) == typeName {
+			return true
+		}
+	}
+	return false
+}
+
+// sourceEdit replaces the `remove` bytes at the offset of a file with text.
+type sourceEdit struct {
+	offset int
+	remove int
+	text   string
+}
+
+// applySourceEdits applies the edits, formats and saves the file. Nothing is written without edits.
+func applySourceEdits(loc string, src []byte, edits []sourceEdit) error {
+	out, changed, err := editSource(src, edits)
+	if err != nil || !changed {
+		return err
+	}
+
+	return os.WriteFile(loc, out, 0644)
+}
+
+// editSource applies the edits and formats the result.
+func editSource(src []byte, edits []sourceEdit) ([]byte, bool, error) {
+	out, changed := spliceEdits(src, edits)
+	if !changed {
+		return src, false, nil
+	}
+
+	formatted, err := format.Source(out)
+	if err != nil {
+		return nil, false, fmt.Errorf("formatting generated code: %w", err)
+	}
+
+	return formatted, true, nil
+}
+
+// spliceEdits applies the edits, last offset first so earlier offsets stay valid. Edits at the same offset keep their
+// order.
+func spliceEdits(src []byte, edits []sourceEdit) ([]byte, bool) {
+	edits = slices.DeleteFunc(slices.Clone(edits), func(e sourceEdit) bool { return e.text == "" && e.remove == 0 })
+	if len(edits) == 0 {
+		return src, false
+	}
+
+	slices.Reverse(edits)
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].offset > edits[j].offset })
+
+	out := append([]byte(nil), src...)
+	for _, e := range edits {
+		out = append(out[:e.offset], append([]byte(e.text), out[e.offset+e.remove:]...)...)
+	}
+	return out, true
+}
+
+// goCamelCase converts a proto field name into the generated Go field name (to_address -> ToAddress).
+func goCamelCase(s string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range s {
+		if r == '_' {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// toSnakeCase converts an RPC name into snake case (UpdateParams -> update_params).
+func toSnakeCase(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,145 @@
+package spawn
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rollchains/spawn/simapp"
+)
+
+func TestScaffoldMissingRPCs(t *testing.T) {
+	cwd := setupStubGenModule(t, `syntax = "proto3";
+package amm.v1;
+
+import "cosmos/msg/v1/msg.proto";
+import "cosmos_proto/cosmos.proto";
+
+option go_package = "github.com/rollchains/mychain/x/amm/types";
+
+service Msg {
+  option (cosmos.msg.v1.service) = true;
+
+  rpc UpdateParams(MsgUpdateParams) returns (MsgUpdateParamsResponse);
+  rpc Swap(MsgSwap) returns (MsgSwapResponse);
+}
+
+message MsgUpdateParams {
+  option (cosmos.msg.v1.signer) = "authority";
+  string authority = 1 [(cosmos_proto.scalar) = "cosmos.AddressString"];
+}
+message MsgUpdateParamsResponse {}
+
+message MsgSwap {
+  option (cosmos.msg.v1.signer) = "sender";
+
+  string sender = 1 [(cosmos_proto.scalar) = "cosmos.AddressString"];
+  string to_address = 2 [(cosmos_proto.scalar) = "cosmos.AddressString"];
+  uint64 amount = 3;
+}
+message MsgSwapResponse {}
+`)
+
+	stubGen := func() { runStubGen(t, cwd) }
+	stubGen()
+
+	read := func(f string) string { return readModuleFile(t, cwd, f) }
+
+	codec := read("types/codec.go")
+	require.Contains(t, codec, `cdc.RegisterConcrete(&MsgSwap{}, ModuleName+"/MsgSwap", nil)`)
+	require.Contains(t, codec, "&MsgSwap{},")
+
+	msgs := read("types/msgs.go")
+	require.Contains(t, msgs, "_ sdk.Msg = &MsgSwap{}")
+	require.Contains(t, msgs, `func (msg MsgSwap) Type() string { return "swap" }`)
+	require.Contains(t, msgs, "addr, _ := sdk.AccAddressFromBech32(msg.Sender)")
+	require.Contains(t, msgs, "sdk.AccAddressFromBech32(msg.ToAddress)")
+	require.Contains(t, msgs, `"cosmossdk.io/errors"`)
+	require.Equal(t, 1, strings.Count(msgs, "MsgUpdateParams) Type()"), "existing msgs are left as is")
+
+	autocli := read("autocli.go")
+	require.Contains(t, autocli, `RpcMethod:      "Swap",`)
+	require.Contains(t, autocli, `Use:            "swap [to_address] [amount]",`)
+
+	tests := read("keeper/msg_server_test.go")
+	require.Contains(t, tests, "func TestSwap(t *testing.T) {")
+	require.Contains(t, tests, `name: "fail; invalid sender",`)
+	require.Contains(t, tests, "f.msgServer.Swap(f.ctx, tc.request)")
+	require.Contains(t, tests, "if status.Code(err) == codes.Unimplemented {\n\t\t\t\tt.Skip(\"Swap is not implemented\")")
+	require.Contains(t, tests, `"google.golang.org/grpc/codes"`)
+
+	msgServer := read("keeper/msg_server.go")
+	require.Contains(t, msgServer, "if err := msg.Validate(); err != nil {")
+
+	ops := read("simulation/operations.go")
+	require.Contains(t, ops, "operations = append(operations, simulation.NewWeightedOperation(weightMsgSwap, SimulateMsgSwap(txGen, k)))\n\n\treturn operations\n}")
+	require.Contains(t, ops, `OpWeightMsgSwap      = "op_weight_msg_swap"`)
+	require.Contains(t, ops, "func SimulateMsgSwap(txGen client.TxConfig, k keeper.Keeper) simtypes.Operation {")
+	require.Contains(t, ops, "msg := &types.MsgSwap{\n\t\t\tSender: simAccount.Address.String(),\n\t\t}")
+	require.Contains(t, ops, `"github.com/rollchains/mychain/x/amm/types"`)
+	require.Contains(t, ops, "import (\n\t\"math/rand\"\n\n")
+	require.NotContains(t, ops, "SimulateMsgUpdateParams", "authority msgs are simulated as proposals")
+
+	// running again does not duplicate anything
+	stubGen()
+	require.Equal(t, codec, read("types/codec.go"))
+	require.Equal(t, msgs, read("types/msgs.go"))
+	require.Equal(t, autocli, read("autocli.go"))
+	require.Equal(t, tests, read("keeper/msg_server_test.go"))
+	require.Equal(t, msgServer, read("keeper/msg_server.go"))
+	require.Equal(t, ops, read("simulation/operations.go"))
+}
+
+// setupStubGenModule creates a chain with the x/amm module from the x/example template and the tx.proto.
+func setupStubGenModule(t *testing.T, txProto string) string {
+	t.Helper()
+
+	cwd := t.TempDir()
+	writeTxProto(t, cwd, txProto)
+
+	for _, f := range []string{"autocli.go", "types/codec.go", "types/msgs.go", "keeper/msg_server.go", "keeper/msg_server_test.go", "simulation/operations.go"} {
+		bz, err := simapp.ExtensionFS.ReadFile(path.Join("x", "example", f))
+		require.NoError(t, err)
+
+		loc := path.Join(cwd, "x", "amm", f)
+		require.NoError(t, os.MkdirAll(path.Dir(loc), 0755))
+		require.NoError(t, os.WriteFile(loc, bz, 0644))
+	}
+
+	return cwd
+}
+
+func writeTxProto(t *testing.T, cwd, txProto string) {
+	t.Helper()
+
+	protoDir := path.Join(cwd, "proto", "amm", "v1")
+	require.NoError(t, os.MkdirAll(protoDir, 0755))
+	require.NoError(t, os.WriteFile(path.Join(protoDir, "tx.proto"), []byte(txProto), 0644))
+}
+
+func runStubGen(t *testing.T, cwd string) {
+	t.Helper()
+
+	missing, err := GetMissingRPCMethodsFromModuleProto(logger, cwd)
+	require.NoError(t, err)
+	require.NoError(t, ApplyMissingRPCMethodsToGoSourceFiles(logger, missing))
+	require.NoError(t, ScaffoldMissingRPCs(logger, cwd, missing))
+}
+
+// readModuleFile returns a file of x/amm, which must be valid Go.
+func readModuleFile(t *testing.T, cwd, f string) string {
+	t.Helper()
+
+	loc := path.Join(cwd, "x", "amm", f)
+	_, err := parser.ParseFile(token.NewFileSet(), loc, nil, parser.AllErrors)
+	require.NoError(t, err, "generated Go must be valid")
+
+	bz, err := os.ReadFile(loc)
+	require.NoError(t, err)
+	return string(bz)
+}
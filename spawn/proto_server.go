@@ -0,0 +1,427 @@
+package spawn
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// StubGenConfigFileName is the optional stub-gen config in the root of the chain.
+const StubGenConfigFileName = "stub-gen.json"
+
+// StubGenConfig tells stub-gen where the Go code of a module is, when it does not follow the x/<module> layout.
+//
+//	{"modules": {"amm": {"dir": "x/amm", "services": {"Query": "x/amm/keeper/grpc_query.go"}}}}
+type StubGenConfig struct {
+	Modules map[string]StubGenModuleConfig `json:"modules"`
+}
+
+// StubGenModuleConfig overrides the locations of a module's Go code. Paths are relative to the root of the chain.
+type StubGenModuleConfig struct {
+	// The module's Go code, x/<module> by default
+	Dir string `json:"dir,omitempty"`
+	// The file to add the stubs of a proto service (Msg, Query) to
+	Services map[string]string `json:"services,omitempty"`
+}
+
+// LoadStubGenConfig reads the stub-gen config of the chain, empty if there is none.
+func LoadStubGenConfig(cwd string) (StubGenConfig, error) {
+	var cfg StubGenConfig
+
+	bz, err := os.ReadFile(path.Join(cwd, StubGenConfigFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return cfg, err
+	}
+
+	if err := json.Unmarshal(bz, &cfg); err != nil {
+		return cfg, fmt.Errorf("%s: %w", StubGenConfigFileName, err)
+	}
+
+	return cfg, nil
+}
+
+// ModuleDir returns the directory of the module's Go code.
+func (c StubGenConfig) ModuleDir(cwd, module string) string {
+	if dir := c.Modules[module].Dir; dir != "" {
+		return path.Join(cwd, dir)
+	}
+	return path.Join(cwd, "x", module)
+}
+
+// serverImpl is the Go type implementing a proto service (types.MsgServer, types.QueryServer).
+type serverImpl struct {
+	typeName string
+	// The receiver of its methods (ms, *msgServer)
+	recvName string
+	pointer  bool
+	// The file new methods are added to
+	fileLoc string
+	// Exported methods with a gRPC server signature
+	methods []serverMethod
+	// The type does not exist yet and is created with the first stub
+	isNew bool
+}
+
+// receiver returns the receiver for new methods (ms msgServer).
+func (s *serverImpl) receiver() string {
+	if s.pointer {
+		return s.recvName + " *" + s.typeName
+	}
+	return s.recvName + " " + s.typeName
+}
+
+func (s *serverImpl) hasMethod(name string) bool {
+	return slices.ContainsFunc(s.methods, func(m serverMethod) bool { return m.name == name })
+}
+
+// goPackage is the syntax of a Go package, enough to find the implementations of a service without type checking.
+// Packages with missing server methods do not compile, so go/types can not be relied on.
+type goPackage struct {
+	dir  string
+	name string
+	// Type declarations and the file they are declared in
+	types map[string]string
+	// Methods of each receiver type
+	methods map[string][]serverMethod
+	// The receiver name and whether it is a pointer, of each receiver type
+	recvs map[string]receiverInfo
+	// Types asserted or returned as an interface (MsgServer -> msgServer)
+	impls map[string][]string
+}
+
+type receiverInfo struct {
+	name    string
+	pointer bool
+}
+
+// findModuleServers returns the implementation of each proto service of the module's RPCs. Services without one get
+// a new type in the configured file, or keeper/<msg|query>_server.go.
+func findModuleServers(cwd string, cfg StubGenConfig, module string, rpcs []*ProtoRPC) (map[string]*serverImpl, error) {
+	pkgs, err := parseGoPackages(cfg.ModuleDir(cwd, module))
+	if err != nil {
+		return nil, err
+	}
+
+	services := make(map[string][]string)
+	for _, rpc := range rpcs {
+		services[rpc.serviceName()] = append(services[rpc.serviceName()], rpc.Name)
+	}
+
+	servers := make(map[string]*serverImpl, len(services))
+	for service, names := range services {
+		target := ""
+		if loc := cfg.Modules[module].Services[service]; loc != "" {
+			target = path.Join(cwd, loc)
+		}
+
+		s := findServerImpl(pkgs, service, names)
+		switch {
+		case s == nil:
+			s = newServerImpl(service)
+			s.fileLoc = target
+			if s.fileLoc == "" {
+				s.fileLoc = path.Join(cfg.ModuleDir(cwd, module), "keeper", toSnakeCase(service)+"_server.go")
+			}
+		case target != "":
+			s.fileLoc = target
+		}
+
+		servers[service] = s
+	}
+
+	return servers, nil
+}
+
+// findServerImpl returns the type asserted or returned as the <service>Server interface. Otherwise the type with the
+// most methods of the service is used.
+func findServerImpl(pkgs []*goPackage, service string, rpcNames []string) *serverImpl {
+	for _, pkg := range pkgs {
+		for _, typeName := range pkg.impls[service+"Server"] {
+			if _, ok := pkg.types[typeName]; ok {
+				return pkg.serverImpl(typeName)
+			}
+		}
+	}
+
+	var best *serverImpl
+	most := 0
+	for _, pkg := range pkgs {
+		for typeName, methods := range pkg.methods {
+			n := 0
+			for _, m := range methods {
+				if slices.Contains(rpcNames, m.name) {
+					n++
+				}
+			}
+
+			if n > most || (n == most && best != nil && typeName < best.typeName) {
+				best, most = pkg.serverImpl(typeName), n
+			}
+		}
+	}
+
+	return best
+}
+
+// serverImpl returns the implementation by the type, new methods are added to the file with most of its methods.
+func (pkg *goPackage) serverImpl(typeName string) *serverImpl {
+	recv, ok := pkg.recvs[typeName]
+	if !ok {
+		recv.name = strings.ToLower(typeName[:1])
+	}
+
+	s := &serverImpl{
+		typeName: typeName,
+		recvName: recv.name,
+		pointer:  recv.pointer,
+		fileLoc:  pkg.types[typeName],
+		methods:  pkg.methods[typeName],
+	}
+
+	count := make(map[string]int)
+	for _, m := range s.methods {
+		count[m.fileLoc]++
+		if count[m.fileLoc] > count[s.fileLoc] {
+			s.fileLoc = m.fileLoc
+		}
+	}
+
+	return s
+}
+
+// newServerImpl returns the server type for a service without one, named like the module template.
+func newServerImpl(service string) *serverImpl {
+	switch service {
+	case "Msg":
+		return &serverImpl{typeName: "msgServer", recvName: "ms", isNew: true}
+	case "Query":
+		return &serverImpl{typeName: "Querier", recvName: "k", isNew: true}
+	}
+
+	return &serverImpl{typeName: strings.ToLower(service[:1]) + service[1:] + "Server", recvName: "s", isNew: true}
+}
+
+// newServerCode returns the declaration of a new server type and its constructor.
+func newServerCode(rpc *ProtoRPC) string {
+	service := rpc.serviceName()
+	if service == "Query" {
+		return `var _ types.QueryServer = Querier{}
+
+type Querier struct {
+	Keeper
+}
+
+func NewQuerier(keeper Keeper) Querier {
+	return Querier{Keeper: keeper}
+}
+`
+	}
+
+	s, constructor := newServerImpl(service), "New"+service+"ServerImpl"
+	return fmt.Sprintf(`type %s struct {
+	k Keeper
+}
+
+var _ types.%sServer = %s{}
+
+// %s returns an implementation of the module %sServer interface.
+func %s(keeper Keeper) types.%sServer {
+	return &%s{k: keeper}
+}
+`, s.typeName, service, s.typeName, constructor, service, constructor, service, s.typeName)
+}
+
+// newServerFile returns the package clause & types import of a new server file.
+func newServerFile(fileLoc string, rpc *ProtoRPC) string {
+	dir := path.Dir(fileLoc)
+
+	pkgName := path.Base(dir)
+	if pkgs, err := parseGoPackages(dir); err == nil {
+		for _, pkg := range pkgs {
+			if pkg.dir == dir {
+				pkgName = pkg.name
+			}
+		}
+	}
+
+	var imports []string
+	if slices.Contains(rpc.GoImports(), "context") {
+		imports = append(imports, `"context"`)
+	}
+	if rpc.GoPackage != "" {
+		imp := fmt.Sprintf("%q", goImportPath(rpc.GoPackage))
+		if path.Base(goImportPath(rpc.GoPackage)) != "types" {
+			imp = "types " + imp
+		}
+		imports = append(imports, imp)
+	}
+
+	if len(imports) == 0 {
+		return fmt.Sprintf("package %s\n", pkgName)
+	}
+	return fmt.Sprintf("package %s\n\nimport (\n\t%s\n)\n", pkgName, strings.Join(imports, "\n\n\t"))
+}
+
+// parseGoPackages parses the Go packages within the directory. Tests and generated protobuf files are skipped.
+func parseGoPackages(dir string) ([]*goPackage, error) {
+	pkgs := make(map[string]*goPackage)
+	var dirs []string
+
+	err := filepath.WalkDir(dir, func(loc string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && loc == dir {
+				return fs.SkipAll
+			}
+			return err
+		}
+
+		name := d.Name()
+		if d.IsDir() {
+			if loc != dir && (name == "testdata" || strings.HasPrefix(name, ".")) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		if !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") || strings.Contains(name, ".pb.") {
+			return nil
+		}
+
+		f, err := parser.ParseFile(token.NewFileSet(), loc, nil, 0)
+		if err != nil {
+			return err
+		}
+
+		pkg, ok := pkgs[path.Dir(loc)]
+		if !ok {
+			pkg = &goPackage{
+				dir:     path.Dir(loc),
+				name:    f.Name.Name,
+				types:   make(map[string]string),
+				methods: make(map[string][]serverMethod),
+				recvs:   make(map[string]receiverInfo),
+				impls:   make(map[string][]string),
+			}
+			pkgs[pkg.dir] = pkg
+			dirs = append(dirs, pkg.dir)
+		}
+
+		pkg.addFile(loc, f)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*goPackage, 0, len(dirs))
+	for _, d := range dirs {
+		res = append(res, pkgs[d])
+	}
+	return res, nil
+}
+
+func (pkg *goPackage) addFile(loc string, f *ast.File) {
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					pkg.types[s.Name.Name] = loc
+				case *ast.ValueSpec:
+					// var _ types.MsgServer = msgServer{}
+					if s.Type == nil || len(s.Values) != 1 || len(s.Names) != 1 || s.Names[0].Name != "_" {
+						continue
+					}
+					pkg.addImpl(goTypeName(s.Type), s.Values[0])
+				}
+			}
+		case *ast.FuncDecl:
+			if d.Recv == nil {
+				pkg.addConstructor(d)
+				continue
+			}
+
+			field := d.Recv.List[0]
+			typeName := goTypeName(field.Type)
+			if _, ok := pkg.recvs[typeName]; !ok && len(field.Names) > 0 && field.Names[0].Name != "_" {
+				_, pointer := field.Type.(*ast.StarExpr)
+				pkg.recvs[typeName] = receiverInfo{name: field.Names[0].Name, pointer: pointer}
+			}
+
+			if !d.Name.IsExported() {
+				continue
+			}
+			if m, ok := newServerMethod(d); ok {
+				m.fileLoc = loc
+				pkg.methods[typeName] = append(pkg.methods[typeName], m)
+			}
+		}
+	}
+}
+
+// addConstructor records the types returned as a server interface (func NewMsgServerImpl(k Keeper) types.MsgServer).
+func (pkg *goPackage) addConstructor(fn *ast.FuncDecl) {
+	results := fieldTypes(fn.Type.Results)
+	if len(results) != 1 || fn.Body == nil {
+		return
+	}
+
+	iface, ok := results[0].(*ast.SelectorExpr)
+	if !ok || !strings.HasSuffix(iface.Sel.Name, "Server") {
+		return
+	}
+
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		if ret, ok := n.(*ast.ReturnStmt); ok && len(ret.Results) == 1 {
+			pkg.addImpl(iface.Sel.Name, ret.Results[0])
+		}
+		return true
+	})
+}
+
+// addImpl records the type of a value (T{}, &T{}, (*T)(nil)) as an implementation of the interface.
+func (pkg *goPackage) addImpl(iface string, value ast.Expr) {
+	typeName := valueTypeName(value)
+	if typeName != "" && !slices.Contains(pkg.impls[iface], typeName) {
+		pkg.impls[iface] = append(pkg.impls[iface], typeName)
+	}
+}
+
+func declaresType(f *ast.File, name string) bool {
+	for _, decl := range f.Decls {
+		if d, ok := decl.(*ast.GenDecl); ok && d.Tok == token.TYPE {
+			for _, spec := range d.Specs {
+				if spec.(*ast.TypeSpec).Name.Name == name {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func valueTypeName(expr ast.Expr) string {
+	switch e := ast.Unparen(expr).(type) {
+	case *ast.CompositeLit:
+		return goTypeName(e.Type)
+	case *ast.UnaryExpr:
+		return valueTypeName(e.X)
+	case *ast.CallExpr:
+		if star, ok := ast.Unparen(e.Fun).(*ast.StarExpr); ok {
+			return goTypeName(star.X)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,163 @@
+package spawn
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const serverTxProto = `syntax = "proto3";
+package amm.v1;
+
+option go_package = "github.com/rollchains/mychain/x/amm/types";
+
+service Msg {
+  rpc UpdateParams(MsgUpdateParams) returns (MsgUpdateParamsResponse);
+  rpc Swap(MsgSwap) returns (MsgSwapResponse);
+}
+
+message MsgUpdateParams {}
+message MsgUpdateParamsResponse {}
+message MsgSwap {}
+message MsgSwapResponse {}
+`
+
+const serverQueryProto = `syntax = "proto3";
+package amm.v1;
+
+option go_package = "github.com/rollchains/mychain/x/amm/types";
+
+service Query {
+  rpc Params(QueryParamsRequest) returns (QueryParamsResponse);
+  rpc Pool(QueryPoolRequest) returns (QueryPoolResponse);
+}
+
+message QueryParamsRequest {}
+message QueryParamsResponse {}
+message QueryPoolRequest {}
+message QueryPoolResponse {}
+`
+
+func TestStubGenCustomServerLayout(t *testing.T) {
+	cwd := t.TempDir()
+
+	writeTestFiles(t, cwd, map[string]string{
+		"proto/amm/v1/tx.proto":    serverTxProto,
+		"proto/amm/v1/query.proto": serverQueryProto,
+		"x/amm/keeper/keeper.go": `package keeper
+
+// Keeper is used by the querier and msgserver packages.
+type Keeper struct{}
+
+func (k Keeper) Params(ctx context.Context, req *types.QueryParamsRequest) (*types.QueryParamsResponse, error) {
+	return nil, nil
+}
+`,
+		"x/amm/keeper/grpc_query.go": `package keeper
+
+type queryServer struct {
+	k Keeper
+}
+
+func NewQueryServerImpl(k Keeper) types.QueryServer {
+	return &queryServer{k: k}
+}
+
+func (s *queryServer) Params(ctx context.Context, req *types.QueryParamsRequest) (*types.QueryParamsResponse, error) {
+	return s.k.Params(ctx, req)
+}
+`,
+		"x/amm/keeper/msgserver/server.go": `package msgserver
+
+type Server struct{}
+
+var _ types.MsgServer = (*Server)(nil)
+`,
+		"x/amm/keeper/msgserver/params.go": `package msgserver
+
+func (srv *Server) UpdateParams(ctx context.Context, m *types.MsgUpdateParams) (*types.MsgUpdateParamsResponse, error) {
+	return nil, nil
+}
+`,
+	})
+
+	missing, err := GetMissingRPCMethodsFromModuleProto(logger, cwd)
+	require.NoError(t, err)
+	require.Len(t, missing["amm"], 2)
+
+	for _, rpc := range missing["amm"] {
+		switch rpc.Name {
+		case "Swap":
+			require.Equal(t, path.Join(cwd, "x/amm/keeper/msgserver/params.go"), rpc.FileLoc)
+			require.Equal(t, "srv *Server", rpc.Recv)
+		case "Pool":
+			require.Equal(t, path.Join(cwd, "x/amm/keeper/grpc_query.go"), rpc.FileLoc)
+			require.Equal(t, "s *queryServer", rpc.Recv)
+		default:
+			t.Fatalf("unexpected missing rpc %s", rpc.Name)
+		}
+		require.False(t, rpc.NewServer)
+	}
+
+	require.NoError(t, ApplyMissingRPCMethodsToGoSourceFiles(logger, missing))
+	require.Contains(t, readTestFile(t, cwd, "x/amm/keeper/msgserver/params.go"), "func (srv *Server) Swap(ctx context.Context, msg *types.MsgSwap)")
+	require.Contains(t, readTestFile(t, cwd, "x/amm/keeper/grpc_query.go"), "func (s *queryServer) Pool(goCtx context.Context, req *types.QueryPoolRequest)")
+
+	missing, err = GetMissingRPCMethodsFromModuleProto(logger, cwd)
+	require.NoError(t, err)
+	require.Empty(t, missing["amm"])
+}
+
+func TestStubGenCreatesServerFile(t *testing.T) {
+	cwd := t.TempDir()
+
+	writeTestFiles(t, cwd, map[string]string{
+		"proto/amm/v1/tx.proto":    serverTxProto,
+		"proto/amm/v1/query.proto": serverQueryProto,
+		"modules/amm/keeper/keeper.go": `package keeper
+
+type Keeper struct{}
+`,
+		StubGenConfigFileName: `{"modules": {"amm": {"dir": "modules/amm", "services": {"Query": "modules/amm/keeper/queries.go"}}}}`,
+	})
+
+	missing, err := GetMissingRPCMethodsFromModuleProto(logger, cwd)
+	require.NoError(t, err)
+	require.Len(t, missing["amm"], 4)
+	require.NoError(t, ApplyMissingRPCMethodsToGoSourceFiles(logger, missing))
+
+	msgServer := readTestFile(t, cwd, "modules/amm/keeper/msg_server.go")
+	require.Contains(t, msgServer, "package keeper")
+	require.Contains(t, msgServer, `"github.com/rollchains/mychain/x/amm/types"`)
+	require.Contains(t, msgServer, "var _ types.MsgServer = msgServer{}")
+	require.Contains(t, msgServer, "func (ms msgServer) UpdateParams(")
+	require.Contains(t, msgServer, "func (ms msgServer) Swap(")
+
+	queries := readTestFile(t, cwd, "modules/amm/keeper/queries.go")
+	require.Contains(t, queries, "var _ types.QueryServer = Querier{}")
+	require.Contains(t, queries, "func (k Querier) Pool(")
+
+	missing, err = GetMissingRPCMethodsFromModuleProto(logger, cwd)
+	require.NoError(t, err)
+	require.Empty(t, missing["amm"])
+}
+
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for f, content := range files {
+		loc := path.Join(dir, f)
+		require.NoError(t, os.MkdirAll(path.Dir(loc), 0755))
+		require.NoError(t, os.WriteFile(loc, []byte(content), 0644))
+	}
+}
+
+func readTestFile(t *testing.T, dir, f string) string {
+	t.Helper()
+
+	bz, err := os.ReadFile(path.Join(dir, f))
+	require.NoError(t, err)
+	return string(bz)
+}
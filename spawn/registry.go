@@ -0,0 +1,329 @@
+package spawn
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+
+	"github.com/rollchains/spawn/spawn/types"
+)
+
+const (
+	// ChainRegistryFileName is the chain.json of the generated chain.
+	ChainRegistryFileName = "chain_registry.json"
+	// ChainRegistryAssetsFileName is the assetlist.json of the generated chain.
+	ChainRegistryAssetsFileName = "chain_registry_assets.json"
+	// ChainRegistryImagesDir is where images referenced by the registry files are kept within the chain.
+	ChainRegistryImagesDir = "images"
+
+	chainSchema     = "chain.schema.json"
+	assetListSchema = "assetlist.schema.json"
+	ibcDataSchema   = "ibc_data.schema.json"
+
+	chainRegistryRawURL = "https://raw.githubusercontent.com/cosmos/chain-registry/master/"
+)
+
+var (
+	// vendored cosmos/chain-registry schemas. Refresh with `make update-registry-schemas`.
+	//
+	//go:embed schemas/*.json
+	registrySchemaFS embed.FS
+
+	registrySchemas   = make(map[string]*jsonschema.Schema)
+	registrySchemasMu sync.Mutex
+)
+
+// ValidateChainRegistry validates a chain.json against the chain-registry schema.
+func ValidateChainRegistry(bz []byte) error {
+	return validateRegistrySchema(chainSchema, bz)
+}
+
+// ValidateChainRegistryAssets validates an assetlist.json against the chain-registry schema.
+func ValidateChainRegistryAssets(bz []byte) error {
+	return validateRegistrySchema(assetListSchema, bz)
+}
+
+// ValidateIBCData validates an _IBC/<chain_1>-<chain_2>.json against the chain-registry schema.
+func ValidateIBCData(bz []byte) error {
+	return validateRegistrySchema(ibcDataSchema, bz)
+}
+
+// ValidateChainRegistryDir validates the chain registry and assets files within a generated chain.
+func ValidateChainRegistryDir(homeDir string) error {
+	files := map[string]func([]byte) error{
+		ChainRegistryFileName:       ValidateChainRegistry,
+		ChainRegistryAssetsFileName: ValidateChainRegistryAssets,
+	}
+
+	for _, name := range []string{ChainRegistryFileName, ChainRegistryAssetsFileName} {
+		bz, err := os.ReadFile(path.Join(homeDir, name))
+		if err != nil {
+			return err
+		}
+
+		if err := files[name](bz); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// ExportChainRegistry writes a cosmos/chain-registry ready folder to outDir from the chain's registry files.
+// The chain.json, assetlist.json, local images and IBC connection files are placed in their upstream location
+// (testnets are nested under testnets/). Returns the directory of the chain within outDir.
+func ExportChainRegistry(homeDir, outDir string, ibcFiles []string) (string, error) {
+	if err := ValidateChainRegistryDir(homeDir); err != nil {
+		return "", err
+	}
+
+	chain, err := readJSONMap(path.Join(homeDir, ChainRegistryFileName))
+	if err != nil {
+		return "", err
+	}
+
+	assets, err := readJSONMap(path.Join(homeDir, ChainRegistryAssetsFileName))
+	if err != nil {
+		return "", err
+	}
+
+	chainName, _ := chain["chain_name"].(string)
+	networkType, _ := chain["network_type"].(string)
+
+	registryDir := registryChainDir(chainName, networkType)
+	schemaPrefix := strings.Repeat("../", strings.Count(registryDir, "/")+1)
+
+	chainDir := path.Join(outDir, registryDir)
+	if err := os.MkdirAll(chainDir, 0755); err != nil {
+		return "", err
+	}
+
+	// images of this chain are copied from the local images/ directory & point to the exported location.
+	imageURL := chainImageRegexp(chainName)
+	images := make(map[string]bool)
+	rewrite := func(s string) string {
+		m := imageURL.FindStringSubmatch(s)
+		if m == nil {
+			return s
+		}
+		images[m[1]] = true
+		return chainRegistryRawURL + registryDir + "/images/" + m[1]
+	}
+
+	chain = rewriteJSONStrings(chain, rewrite).(map[string]any)
+	assets = rewriteJSONStrings(assets, rewrite).(map[string]any)
+	chain["$schema"] = schemaPrefix + chainSchema
+	assets["$schema"] = schemaPrefix + assetListSchema
+
+	if err := writeRegistryJSON(path.Join(chainDir, "chain.json"), chain, ValidateChainRegistry); err != nil {
+		return "", err
+	}
+	if err := writeRegistryJSON(path.Join(chainDir, "assetlist.json"), assets, ValidateChainRegistryAssets); err != nil {
+		return "", err
+	}
+
+	for img := range images {
+		bz, err := os.ReadFile(path.Join(homeDir, ChainRegistryImagesDir, img))
+		if err != nil {
+			return "", fmt.Errorf("image %s is referenced by the registry files but not found: %w", img, err)
+		}
+
+		dst := path.Join(chainDir, "images", img)
+		if err := os.MkdirAll(path.Dir(dst), 0755); err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(dst, bz, 0644); err != nil {
+			return "", err
+		}
+	}
+
+	for _, f := range ibcFiles {
+		if err := exportIBCData(f, chainName, path.Join(outDir, path.Dir(registryDir), "_IBC"), schemaPrefix); err != nil {
+			return "", fmt.Errorf("%s: %w", f, err)
+		}
+	}
+
+	return chainDir, nil
+}
+
+// registryChainDir is the upstream directory of a chain: <chain>/ for mainnets, testnets/<chain>/ for everything else.
+func registryChainDir(chainName, networkType string) string {
+	if networkType != "" && networkType != "mainnet" {
+		return path.Join("testnets", chainName)
+	}
+	return chainName
+}
+
+// chainImageRegexp matches the chain-registry image URLs of chainName, capturing the image file name.
+func chainImageRegexp(chainName string) *regexp.Regexp {
+	return regexp.MustCompile(`^` + regexp.QuoteMeta(chainRegistryRawURL) + `(?:testnets/)?` + regexp.QuoteMeta(chainName) + `/images/(.+)$`)
+}
+
+// exportIBCData validates an IBC connection file for the chain and saves it as _IBC/<chain_1>-<chain_2>.json.
+func exportIBCData(loc, chainName, ibcDir, schemaPrefix string) error {
+	data, err := readJSONMap(loc)
+	if err != nil {
+		return err
+	}
+	data["$schema"] = schemaPrefix + ibcDataSchema
+
+	name := func(key string) string {
+		c, _ := data[key].(map[string]any)
+		n, _ := c["chain_name"].(string)
+		return n
+	}
+
+	chain1, chain2 := name("chain_1"), name("chain_2")
+	if chain1 != chainName && chain2 != chainName {
+		return fmt.Errorf("%w: connection %s-%s does not include %s", types.ErrRegistryInvalid, chain1, chain2, chainName)
+	}
+	if chain1 > chain2 {
+		return fmt.Errorf("%w: chain_1 (%s) must be alphabetically before chain_2 (%s)", types.ErrRegistryInvalid, chain1, chain2)
+	}
+
+	if err := os.MkdirAll(ibcDir, 0755); err != nil {
+		return err
+	}
+
+	return writeRegistryJSON(path.Join(ibcDir, fmt.Sprintf("%s-%s.json", chain1, chain2)), data, ValidateIBCData)
+}
+
+func writeRegistryJSON(loc string, v any, validate func([]byte) error) error {
+	bz, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := validate(bz); err != nil {
+		return fmt.Errorf("%s: %w", path.Base(loc), err)
+	}
+
+	return os.WriteFile(loc, append(bz, '\n'), 0644)
+}
+
+func readJSONMap(loc string) (map[string]any, error) {
+	var m map[string]any
+	if err := readJSONFile(loc, &m); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func readJSONFile(loc string, v any) error {
+	bz, err := os.ReadFile(loc)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(bz, v); err != nil {
+		return fmt.Errorf("error parsing %s: %w", loc, err)
+	}
+
+	return nil
+}
+
+// rewriteJSONStrings applies fn to every string value within decoded JSON.
+func rewriteJSONStrings(v any, fn func(string) string) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, child := range t {
+			t[k] = rewriteJSONStrings(child, fn)
+		}
+	case []any:
+		for i, child := range t {
+			t[i] = rewriteJSONStrings(child, fn)
+		}
+	case string:
+		return fn(t)
+	}
+
+	return v
+}
+
+func validateRegistrySchema(name string, bz []byte) error {
+	schema, err := loadRegistrySchema(name)
+	if err != nil {
+		return err
+	}
+
+	var v any
+	if err := json.Unmarshal(bz, &v); err != nil {
+		return fmt.Errorf("%w: %w", types.ErrRegistryInvalid, err)
+	}
+
+	// generated chains reference the upstream schema URL, the registry only allows relative paths.
+	if m, ok := v.(map[string]any); ok && m["$schema"] == chainRegistryRawURL+name {
+		m["$schema"] = "../" + name
+	}
+
+	if err := schema.Validate(v); err != nil {
+		if ve, ok := err.(*jsonschema.ValidationError); ok {
+			return fmt.Errorf("%w (%s):%s", types.ErrRegistryInvalid, name, formatSchemaErrors(ve))
+		}
+		return err
+	}
+
+	return nil
+}
+
+// formatSchemaErrors returns the leaf validation errors, one per line with the JSON location.
+func formatSchemaErrors(ve *jsonschema.ValidationError) string {
+	var sb strings.Builder
+
+	var walk func(e *jsonschema.ValidationError)
+	walk = func(e *jsonschema.ValidationError) {
+		if len(e.Causes) == 0 {
+			loc := e.InstanceLocation
+			if loc == "" {
+				loc = "/"
+			}
+			fmt.Fprintf(&sb, "\n  - %s: %s", loc, e.Message)
+			return
+		}
+
+		for _, c := range e.Causes {
+			walk(c)
+		}
+	}
+	walk(ve)
+
+	return sb.String()
+}
+
+func loadRegistrySchema(name string) (*jsonschema.Schema, error) {
+	registrySchemasMu.Lock()
+	defer registrySchemasMu.Unlock()
+
+	if s, ok := registrySchemas[name]; ok {
+		return s, nil
+	}
+
+	bz, err := registrySchemaFS.ReadFile(path.Join("schemas", name))
+	if err != nil {
+		return nil, err
+	}
+
+	c := jsonschema.NewCompiler()
+	c.Draft = jsonschema.Draft7
+	c.AssertFormat = true
+	if err := c.AddResource(name, bytes.NewReader(bz)); err != nil {
+		return nil, err
+	}
+
+	s, err := c.Compile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	registrySchemas[name] = s
+	return s, nil
+}
@@ -0,0 +1,119 @@
+package spawn_test
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rollchains/spawn/spawn"
+	"github.com/rollchains/spawn/spawn/types"
+)
+
+func saveRegistryFiles(t *testing.T, dir string, cfg spawn.NewChainConfig) {
+	t.Helper()
+
+	require.NoError(t, cfg.ChainRegistryFile().SaveJSON(path.Join(dir, spawn.ChainRegistryFileName)))
+	require.NoError(t, cfg.ChainRegistryAssetsFile().SaveJSON(path.Join(dir, spawn.ChainRegistryAssetsFileName)))
+}
+
+func TestChainRegistrySchemas(t *testing.T) {
+	cfg := goodCfg()
+
+	bz, err := json.Marshal(cfg.ChainRegistryFile())
+	require.NoError(t, err)
+	require.NoError(t, spawn.ValidateChainRegistry(bz))
+
+	bz, err = json.Marshal(cfg.ChainRegistryAssetsFile())
+	require.NoError(t, err)
+	require.NoError(t, spawn.ValidateChainRegistryAssets(bz))
+
+	badChain := cfg.ChainRegistryFile()
+	badChain.Status = "running"
+	badChain.KeyAlgos = []string{"rsa"}
+	bz, err = json.Marshal(badChain)
+	require.NoError(t, err)
+
+	err = spawn.ValidateChainRegistry(bz)
+	require.ErrorIs(t, err, types.ErrRegistryInvalid)
+	require.Contains(t, err.Error(), "/status")
+	require.Contains(t, err.Error(), "/key_algos/0")
+
+	badAssets := cfg.ChainRegistryAssetsFile()
+	badAssets.Assets[0].TypeAsset = ""
+	bz, err = json.Marshal(badAssets)
+	require.NoError(t, err)
+	require.ErrorIs(t, spawn.ValidateChainRegistryAssets(bz), types.ErrRegistryInvalid)
+}
+
+func TestExportChainRegistry(t *testing.T) {
+	home := t.TempDir()
+	out := t.TempDir()
+
+	cfg := goodCfg()
+	saveRegistryFiles(t, home, cfg)
+	require.NoError(t, spawn.ValidateChainRegistryDir(home))
+
+	ibc := `{
+  "chain_1": {"chain_name": "cosmoshubtestnet", "client_id": "07-tendermint-0", "connection_id": "connection-0"},
+  "chain_2": {"chain_name": "myproject", "client_id": "07-tendermint-0", "connection_id": "connection-0"},
+  "channels": [
+    {
+      "chain_1": {"channel_id": "channel-0", "port_id": "transfer"},
+      "chain_2": {"channel_id": "channel-0", "port_id": "transfer"},
+      "ordering": "unordered",
+      "version": "ics20-1",
+      "tags": {"status": "live", "preferred": true}
+    }
+  ]
+}`
+	ibcFile := path.Join(home, "ibc.json")
+	require.NoError(t, os.WriteFile(ibcFile, []byte(ibc), 0644))
+
+	chainDir, err := spawn.ExportChainRegistry(home, out, []string{ibcFile})
+	require.NoError(t, err)
+
+	// generated chains default to testnet
+	require.Equal(t, path.Join(out, "testnets", proj), chainDir)
+	require.FileExists(t, path.Join(chainDir, "chain.json"))
+	require.FileExists(t, path.Join(chainDir, "assetlist.json"))
+	require.FileExists(t, path.Join(out, "testnets", "_IBC", "cosmoshubtestnet-myproject.json"))
+
+	bz, err := os.ReadFile(path.Join(chainDir, "chain.json"))
+	require.NoError(t, err)
+	require.Contains(t, string(bz), `"$schema": "../../chain.schema.json"`)
+
+	// connections must include the chain and be ordered alphabetically
+	require.NoError(t, os.WriteFile(ibcFile, []byte(`{
+  "chain_1": {"chain_name": "zchain", "client_id": "07-tendermint-0", "connection_id": "connection-0"},
+  "chain_2": {"chain_name": "myproject", "client_id": "07-tendermint-0", "connection_id": "connection-0"},
+  "channels": [{"chain_1": {"channel_id": "channel-0", "port_id": "transfer"}, "chain_2": {"channel_id": "channel-0", "port_id": "transfer"}, "ordering": "unordered", "version": "ics20-1"}]
+}`), 0644))
+	_, err = spawn.ExportChainRegistry(home, out, []string{ibcFile})
+	require.ErrorIs(t, err, types.ErrRegistryInvalid)
+}
+
+func TestExportChainRegistryImages(t *testing.T) {
+	home := t.TempDir()
+	out := t.TempDir()
+
+	cfg := goodCfg()
+	saveRegistryFiles(t, home, cfg)
+
+	// point the chain logo to a local image
+	chain := cfg.ChainRegistryFile()
+	chain.Images[0].Png = "https://raw.githubusercontent.com/cosmos/chain-registry/master/testnets/" + proj + "/images/logo.png"
+	require.NoError(t, chain.SaveJSON(path.Join(home, spawn.ChainRegistryFileName)))
+
+	_, err := spawn.ExportChainRegistry(home, out, nil)
+	require.Error(t, err, "local image is missing")
+
+	require.NoError(t, os.MkdirAll(path.Join(home, spawn.ChainRegistryImagesDir), 0755))
+	require.NoError(t, os.WriteFile(path.Join(home, spawn.ChainRegistryImagesDir, "logo.png"), []byte("png"), 0644))
+
+	chainDir, err := spawn.ExportChainRegistry(home, out, nil)
+	require.NoError(t, err)
+	require.FileExists(t, path.Join(chainDir, "images", "logo.png"))
+}
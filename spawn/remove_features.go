@@ -135,6 +135,7 @@ func (fc *FileContent) RemovePOA() {
 
 	fc.RemoveModuleFromText(text,
 		appGo,
+		appConfigGo,
 		appAnte,
 		path.Join("scripts", "test_node.sh"),
 		path.Join("scripts", "test_ics_node.sh"),
@@ -142,6 +143,8 @@ func (fc *FileContent) RemovePOA() {
 		path.Join("workflows", "interchaintest-e2e.yml"),
 	)
 
+	fc.RemoveModuleFromText("POAKeeper", appGo) // depinject.Inject
+
 	fc.DeleteFile(path.Join("interchaintest", "poa_test.go"))
 	fc.DeleteFile(path.Join("interchaintest", "poa.go")) // helpers
 }
@@ -234,7 +237,12 @@ func (fc *FileContent) RemoveOptimisticExecution() {
 }
 
 func (fc *FileContent) RemoveExplorer() {
-	// fc.DeleteDirectoryContents("nginx")
+	fc.DeleteFile("docker-compose.yml")
+	fc.DeleteFile(path.Join("explorer", "Dockerfile"))
+
+	if fc.ContainsPath("Makefile") {
+		fc.ReplaceAll(".PHONY: explorer\nexplorer:\n\tdocker compose up\n\n", "")
+	}
 }
 
 func (fc *FileContent) RemoveInterchainSecurity() {
@@ -288,6 +296,7 @@ func (fc *FileContent) RemoveStaking() {
 	fc.DeleteFile(path.Join("app", "test_helpers.go"))
 	fc.DeleteFile(path.Join("app", "test_support.go"))
 	fc.DeleteFile(path.Join("app", "app_test.go"))
+	fc.DeleteFile(path.Join("app", "ibc_test.go"))
 	fc.DeleteFile(path.Join("cmd", "wasmd", "testnet.go"))
 
 	// Since we will be using ICS (test_ics_node.sh)
@@ -0,0 +1,342 @@
+package spawn
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	localictypes "github.com/strangelove-ventures/interchaintest/local-interchain/interchain/types"
+	localicutil "github.com/strangelove-ventures/interchaintest/local-interchain/interchain/util"
+	"github.com/strangelove-ventures/interchaintest/v8/ibc"
+
+	"github.com/rollchains/spawn/spawn/types"
+)
+
+const (
+	// TestnetConfigDir is where local-interchain configurations are saved within a generated chain.
+	TestnetConfigDir = "chains"
+	// DefaultLocalICAPIAddress is the default host of the local-interchain REST server.
+	DefaultLocalICAPIAddress = "127.0.0.1"
+	// DefaultLocalICAPIPort is the default port of the local-interchain REST server.
+	DefaultLocalICAPIPort uint16 = 8080
+	// LocalICContainerLabel is the docker label interchaintest applies to every container it creates.
+	LocalICContainerLabel = "ibc-test"
+	// LocalImageVersion is the docker tag `make local-image` builds for the chain.
+	LocalImageVersion = "local"
+)
+
+// LocalICInstallMsg is shown when the local-ic binary can not be found.
+const LocalICInstallMsg = "local-ic not found. Install with `make get-localic` from the spawn repository"
+
+// LocalICAPIURL returns the local-interchain REST server URL for the host and port it was started with.
+func LocalICAPIURL(host string, port uint16) string {
+	return "http://" + net.JoinHostPort(host, strconv.Itoa(int(port)))
+}
+
+// TestnetConfigPath resolves a testnet name (testnet, testnet.json, chains/testnet.json) to the
+// absolute location of the configuration file within the homeDir's chains/ directory.
+func TestnetConfigPath(homeDir, name string) (string, error) {
+	name = strings.TrimPrefix(name, TestnetConfigDir+"/")
+	if path.Ext(name) == "" {
+		name += ".json"
+	}
+
+	loc := path.Join(homeDir, TestnetConfigDir, name)
+	if _, err := os.Stat(loc); err != nil {
+		return "", fmt.Errorf("%w: %s (available: %s)", types.ErrTestnetConfigNotFound, loc, strings.Join(AvailableTestnets(homeDir), ", "))
+	}
+
+	return loc, nil
+}
+
+// AvailableTestnets returns the names of all testnet configurations in the homeDir's chains/ directory.
+func AvailableTestnets(homeDir string) []string {
+	entries, err := os.ReadDir(path.Join(homeDir, TestnetConfigDir))
+	if err != nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || path.Ext(e.Name()) != ".json" {
+			continue
+		}
+		names = append(names, strings.TrimSuffix(e.Name(), ".json"))
+	}
+
+	return names
+}
+
+// TestnetName returns the name local-interchain uses for a config file. Containers are labeled with it.
+// i.e. chains/testnet.json -> testnetic
+func TestnetName(configLoc string) string {
+	base := path.Base(configLoc)
+	return strings.TrimSuffix(base, path.Ext(base)) + "ic"
+}
+
+// LoadTestnetConfig reads a local-interchain chains configuration file. Chain defaults and
+// placeholders (%DENOM%, %BIN%, %CHAIN_ID%) are applied the same as local-interchain does on start.
+func LoadTestnetConfig(loc string) (*localictypes.ChainsConfig, error) {
+	bz, err := os.ReadFile(loc)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg localictypes.ChainsConfig
+	if err := json.Unmarshal(bz, &cfg); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", loc, err)
+	}
+
+	for i := range cfg.Chains {
+		c := &cfg.Chains[i]
+		c.SetChainDefaults()
+		localicutil.ReplaceStringValues(c, "%DENOM%", c.Denom)
+		localicutil.ReplaceStringValues(c, "%BIN%", c.Binary)
+		localicutil.ReplaceStringValues(c, "%CHAIN_ID%", c.ChainID)
+	}
+
+	return &cfg, nil
+}
+
+// ValidateTestnetConfig ensures the testnet configuration can be started by local-interchain.
+// Every chain must have the required fields, chain-ids must be unique, IBC paths must link exactly
+// 2 chains, and ICS consumers must reference a provider within the same config.
+func ValidateTestnetConfig(cfg *localictypes.ChainsConfig) error {
+	if cfg == nil || len(cfg.Chains) == 0 {
+		return types.ErrTestnetNoChains
+	}
+
+	chainIDs := make(map[string]bool, len(cfg.Chains))
+	ibcPaths := make(map[string]int)
+
+	for _, c := range cfg.Chains {
+		required := map[string]string{
+			"name":          c.Name,
+			"chain_id":      c.ChainID,
+			"binary":        c.Binary,
+			"denom":         c.Denom,
+			"bech32_prefix": c.Bech32Prefix,
+			"docker_image":  c.DockerImage.Repository,
+		}
+		for field, value := range required {
+			if value == "" {
+				return fmt.Errorf("%w: chain %q is missing %s", types.ErrTestnetInvalidChain, c.ChainID, field)
+			}
+		}
+
+		if c.NumberVals < 1 {
+			return fmt.Errorf("%w: chain %q must have at least 1 validator", types.ErrTestnetInvalidChain, c.ChainID)
+		}
+
+		if chainIDs[c.ChainID] {
+			return fmt.Errorf("%w: duplicate chain_id %q", types.ErrTestnetInvalidChain, c.ChainID)
+		}
+		chainIDs[c.ChainID] = true
+
+		for _, p := range c.IBCPaths {
+			ibcPaths[p]++
+		}
+	}
+
+	for p, count := range ibcPaths {
+		if count != 2 {
+			return fmt.Errorf("%w: ibc path %q must link exactly 2 chains, found %d", types.ErrTestnetInvalidChain, p, count)
+		}
+	}
+
+	for _, c := range cfg.Chains {
+		if c.ICSConsumerLink != "" && !chainIDs[c.ICSConsumerLink] {
+			return fmt.Errorf("%w: chain %q ics provider %q not found", types.ErrTestnetInvalidChain, c.ChainID, c.ICSConsumerLink)
+		}
+	}
+
+	return nil
+}
+
+// LocalDockerImages returns the unique images in the config built locally (`make local-image`).
+// Remote images are pulled by local-interchain on start.
+func LocalDockerImages(cfg *localictypes.ChainsConfig) []ibc.DockerImage {
+	seen := make(map[string]bool)
+	images := make([]ibc.DockerImage, 0)
+
+	for _, c := range cfg.Chains {
+		img := c.DockerImage
+		if img.Version != LocalImageVersion {
+			continue
+		}
+
+		ref := img.Ref()
+		if seen[ref] {
+			continue
+		}
+		seen[ref] = true
+
+		images = append(images, img)
+	}
+
+	return images
+}
+
+// DockerImageExists returns true if the image is available in the local docker daemon.
+func DockerImageExists(img ibc.DockerImage) bool {
+	return exec.Command("docker", "image", "inspect", img.Ref()).Run() == nil
+}
+
+// TestnetContainers returns the docker container names for a running testnet.
+func TestnetContainers(testnetName string) ([]string, error) {
+	out, err := ExecCommandWithOutput(
+		"docker", "ps", "-a",
+		"--filter", fmt.Sprintf("label=%s=%s", LocalICContainerLabel, testnetName),
+		"--format", "{{.Names}}",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error listing docker containers: %w: %s", err, string(out))
+	}
+
+	return strings.Fields(string(out)), nil
+}
+
+// StopTestnet asks the local-interchain API to shutdown, then removes any containers left behind.
+func StopTestnet(apiAddr, testnetName string) error {
+	// kill-all is best effort, the API may already be gone.
+	_ = PostLocalICAction(apiAddr, "kill-all")
+
+	containers, err := TestnetContainers(testnetName)
+	if err != nil {
+		return err
+	}
+
+	if len(containers) == 0 {
+		return nil
+	}
+
+	args := append([]string{"rm", "-f"}, containers...)
+	if out, err := ExecCommandWithOutput("docker", args...); err != nil {
+		return fmt.Errorf("error removing containers: %w: %s", err, string(out))
+	}
+
+	return nil
+}
+
+// PostLocalICAction sends an action (i.e. kill-all) to the local-interchain REST server.
+func PostLocalICAction(apiAddr, action string) error {
+	bz, err := json.Marshal(map[string]string{"action": action})
+	if err != nil {
+		return err
+	}
+
+	client := http.Client{Timeout: 10 * time.Second}
+	res, err := client.Post(apiAddr, "application/json", bytes.NewReader(bz))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("local-ic action %s failed: %s", action, string(body))
+	}
+
+	return nil
+}
+
+// ReadTestnetInfo reads the running chain information local-interchain saves once all chains are started.
+func ReadTestnetInfo(homeDir string) (*localictypes.MainLogs, error) {
+	bz, err := os.ReadFile(path.Join(homeDir, "configs", "logs.json"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", types.ErrTestnetNotRunning, err)
+	}
+
+	var logs localictypes.MainLogs
+	if err := json.Unmarshal(bz, &logs); err != nil {
+		return nil, err
+	}
+
+	if len(logs.Chains) == 0 {
+		return nil, types.ErrTestnetNotRunning
+	}
+
+	return &logs, nil
+}
+
+// StreamTestnetLogs writes the docker logs of every container to w, each line prefixed with the container name.
+// If follow is set, it blocks until all containers stop.
+func StreamTestnetLogs(w io.Writer, containers []string, follow bool, tail string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs = make([]error, 0)
+	)
+
+	for _, c := range containers {
+		c := c
+
+		args := []string{"logs", "--tail", tail}
+		if follow {
+			args = append(args, "--follow")
+		}
+		args = append(args, c)
+
+		cmd := exec.CommandContext(ctx, "docker", args...)
+		pr, pw := io.Pipe()
+		cmd.Stdout = pw
+		cmd.Stderr = pw
+
+		if err := cmd.Start(); err != nil {
+			pw.Close()
+			// stop the streams already started and wait for them to exit.
+			cancel()
+			wg.Wait()
+			return fmt.Errorf("error reading logs for %s: %w", c, err)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			scanner := bufio.NewScanner(pr)
+			for scanner.Scan() {
+				mu.Lock()
+				fmt.Fprintf(w, "%s | %s\n", c, scanner.Text())
+				mu.Unlock()
+			}
+			// drain so docker logs never blocks on a full pipe.
+			_, _ = io.Copy(io.Discard, pr)
+		}()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := cmd.Wait(); err != nil && ctx.Err() == nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("%s: %w", c, err))
+				mu.Unlock()
+			}
+			pw.Close()
+		}()
+	}
+
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return errs[0]
+	}
+
+	return nil
+}
@@ -0,0 +1,604 @@
+package spawn
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	localictypes "github.com/strangelove-ventures/interchaintest/local-interchain/interchain/types"
+	"github.com/strangelove-ventures/interchaintest/v8/chain/cosmos"
+	"github.com/strangelove-ventures/interchaintest/v8/ibc"
+	"github.com/strangelove-ventures/interchaintest/v8/testutil"
+
+	"github.com/rollchains/spawn/spawn/types"
+)
+
+const (
+	// NativeTestnetDir is the directory within the project where native testnet homes are saved.
+	NativeTestnetDir = ".native"
+	// NativeMaxValidators is the max number of validators per chain. Used to space out ports.
+	NativeMaxValidators = 10
+	// nativePortSpacing is the gap between each node's ports.
+	nativePortSpacing = 10
+	// nativeStakeAmount is given to every validator at genesis, and a portion is self bonded.
+	nativeStakeAmount = "10000000000000"
+	nativeBondAmount  = "1000000000"
+	nativeKeyName     = "validator"
+	nativeKeyring     = "test"
+
+	// IBCSmokeTestPattern matches the in-process ibctesting tests generated in app/.
+	IBCSmokeTestPattern = "TestIBC"
+)
+
+type (
+	// NativeTestnet runs a local-interchain chains config as host processes, without docker.
+	NativeTestnet struct {
+		Logger *slog.Logger
+		// ProjectDir is the root of the chain repository (where cmd/<binary> is found).
+		ProjectDir string
+		// HomeDir is where the binary, validator home directories and runtime state are saved.
+		HomeDir string
+		Config  *localictypes.ChainsConfig
+	}
+
+	// NativePorts are the host ports used by a single node.
+	NativePorts struct {
+		RPC   int `json:"rpc"`
+		P2P   int `json:"p2p"`
+		GRPC  int `json:"grpc"`
+		API   int `json:"api"`
+		PProf int `json:"pprof"`
+	}
+
+	// NativeNode is a running validator process.
+	NativeNode struct {
+		ChainID string      `json:"chain_id"`
+		Moniker string      `json:"moniker"`
+		Home    string      `json:"home"`
+		PID     int         `json:"pid"`
+		Ports   NativePorts `json:"ports"`
+	}
+
+	// NativeState is saved to the HomeDir on start so status, logs and stop can find the processes.
+	NativeState struct {
+		Nodes []NativeNode `json:"nodes"`
+	}
+)
+
+// NewNativePorts returns non-colliding ports for a chain's validator.
+func NewNativePorts(chainIdx, valIdx int) NativePorts {
+	offset := (chainIdx*NativeMaxValidators + valIdx) * nativePortSpacing
+
+	return NativePorts{
+		RPC:   26657 + offset,
+		P2P:   26656 + offset,
+		GRPC:  9090 + offset,
+		API:   1317 + offset,
+		PProf: 6060 + offset,
+	}
+}
+
+func (p NativePorts) all() []int {
+	return []int{p.RPC, p.P2P, p.GRPC, p.API, p.PProf}
+}
+
+func (n NativeNode) RPCAddress() string {
+	return fmt.Sprintf("http://127.0.0.1:%d", n.Ports.RPC)
+}
+
+func (n NativeNode) RESTAddress() string {
+	return fmt.Sprintf("http://127.0.0.1:%d", n.Ports.API)
+}
+
+func (n NativeNode) GRPCAddress() string {
+	return fmt.Sprintf("127.0.0.1:%d", n.Ports.GRPC)
+}
+
+func (n NativeNode) LogFile() string {
+	return path.Join(n.Home, "node.log")
+}
+
+// NewNativeTestnet creates a native runner for the config, saving homes to <projectDir>/.native/<name>.
+func NewNativeTestnet(logger *slog.Logger, projectDir, name string, cfg *localictypes.ChainsConfig) *NativeTestnet {
+	return &NativeTestnet{
+		Logger:     logger,
+		ProjectDir: projectDir,
+		HomeDir:    NativeTestnetHome(projectDir, name),
+		Config:     cfg,
+	}
+}
+
+// NativeTestnetHome returns the directory a native testnet is saved to.
+func NativeTestnetHome(projectDir, name string) string {
+	base := path.Base(name)
+	return path.Join(projectDir, NativeTestnetDir, strings.TrimSuffix(base, path.Ext(base)))
+}
+
+// Validate ensures every chain can be built from this project and will fit within the port range.
+func (nt *NativeTestnet) Validate() error {
+	if err := ValidateTestnetConfig(nt.Config); err != nil {
+		return err
+	}
+
+	for _, c := range nt.Config.Chains {
+		if _, err := os.Stat(path.Join(nt.ProjectDir, "cmd", c.Binary)); err != nil {
+			return fmt.Errorf("%w: %s (cmd/%s not found in %s)", types.ErrNativeUnsupportedChain, c.ChainID, c.Binary, nt.ProjectDir)
+		}
+
+		if c.NumberVals > NativeMaxValidators {
+			return fmt.Errorf("%w: %s has %d validators, max %d", types.ErrNativeUnsupportedChain, c.ChainID, c.NumberVals, NativeMaxValidators)
+		}
+
+		if c.ICSConsumerLink != "" {
+			return fmt.Errorf("%w: %s is an ICS consumer", types.ErrNativeUnsupportedChain, c.ChainID)
+		}
+	}
+
+	return nil
+}
+
+// Start builds the binaries, initializes every validator home and starts the processes.
+// It blocks until every chain has produced a block or the timeout is reached.
+func (nt *NativeTestnet) Start(timeout time.Duration) (*NativeState, error) {
+	if err := nt.Validate(); err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(nt.stateFile()); err == nil {
+		return nil, fmt.Errorf("%w: %s", types.ErrNativeAlreadyRunning, nt.HomeDir)
+	}
+
+	if err := os.RemoveAll(nt.HomeDir); err != nil {
+		return nil, err
+	}
+
+	state := &NativeState{}
+
+	for chainIdx, chain := range nt.Config.Chains {
+		bin, err := nt.build(chain.Binary)
+		if err != nil {
+			return nil, err
+		}
+
+		nodes, err := nt.initChain(bin, chainIdx, chain)
+		if err != nil {
+			return nil, fmt.Errorf("error initializing %s: %w", chain.ChainID, err)
+		}
+
+		for _, n := range nodes {
+			pid, err := nt.startNode(bin, n)
+			if err != nil {
+				return nil, err
+			}
+			n.PID = pid
+			state.Nodes = append(state.Nodes, n)
+
+			// saved per node so `testnet stop` can kill the started processes if a later one fails
+			if err := state.save(nt.stateFile()); err != nil {
+				return state, err
+			}
+		}
+
+		if len(chain.IBCPaths) > 0 {
+			nt.Logger.Warn("IBC paths are not connected in native mode (no relayer)", "chain_id", chain.ChainID, "paths", chain.IBCPaths)
+		}
+	}
+
+	for _, n := range state.Nodes {
+		if err := WaitForNativeBlock(n.RPCAddress(), timeout); err != nil {
+			return state, fmt.Errorf("%s did not produce a block, see %s: %w", n.Moniker, n.LogFile(), err)
+		}
+	}
+
+	return state, nil
+}
+
+// build compiles cmd/<binary> into the testnet home.
+func (nt *NativeTestnet) build(binary string) (string, error) {
+	out := path.Join(nt.HomeDir, "bin", binary)
+	if _, err := os.Stat(out); err == nil {
+		return out, nil
+	}
+
+	nt.Logger.Info("Building binary", "binary", binary, "output", out)
+	if bz, err := execInDir(nt.ProjectDir, "go", "build", "-o", out, "./cmd/"+binary); err != nil {
+		return "", fmt.Errorf("error building %s: %w: %s", binary, err, string(bz))
+	}
+
+	return out, nil
+}
+
+// initChain creates every validator home, then builds a shared genesis from all gentxs.
+func (nt *NativeTestnet) initChain(bin string, chainIdx int, chain localictypes.Chain) ([]NativeNode, error) {
+	nodes := make([]NativeNode, chain.NumberVals)
+	for i := range nodes {
+		nodes[i] = NativeNode{
+			ChainID: chain.ChainID,
+			Moniker: fmt.Sprintf("%s-val-%d", chain.ChainID, i),
+			Home:    path.Join(nt.HomeDir, chain.ChainID, fmt.Sprintf("val-%d", i)),
+			Ports:   NewNativePorts(chainIdx, i),
+		}
+
+		for _, port := range nodes[i].Ports.all() {
+			if !isPortAvailable(port) {
+				return nil, fmt.Errorf("%w: %d (%s)", types.ErrNativePortInUse, port, nodes[i].Moniker)
+			}
+		}
+	}
+
+	primary := nodes[0].Home
+	stake := nativeStakeAmount + chain.Denom
+
+	for _, n := range nodes {
+		n := n
+		run := func(args ...string) ([]byte, error) {
+			args = append(args, "--home", n.Home)
+			bz, err := execInDir(nt.ProjectDir, bin, args...)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w: %s", path.Base(bin), strings.Join(args, " "), err, string(bz))
+			}
+			return bz, nil
+		}
+
+		if _, err := run("init", n.Moniker, "--chain-id", chain.ChainID, "--default-denom", chain.Denom, "-o"); err != nil {
+			return nil, err
+		}
+
+		bz, err := run("keys", "add", nativeKeyName, "--keyring-backend", nativeKeyring, "--output", "json")
+		if err != nil {
+			return nil, err
+		}
+
+		var key struct {
+			Address string `json:"address"`
+		}
+		if err := json.Unmarshal(lastJSONLine(bz), &key); err != nil {
+			return nil, fmt.Errorf("error parsing key for %s: %w", n.Moniker, err)
+		}
+
+		// the validator must have funds in its own genesis to gentx, and in the primary genesis to be collected.
+		if _, err := run("genesis", "add-genesis-account", key.Address, stake); err != nil {
+			return nil, err
+		}
+		if n.Home != primary {
+			if _, err := execInDir(nt.ProjectDir, bin, "genesis", "add-genesis-account", key.Address, stake, "--home", primary); err != nil {
+				return nil, err
+			}
+		}
+
+		if _, err := run("genesis", "gentx", nativeKeyName, nativeBondAmount+chain.Denom,
+			"--chain-id", chain.ChainID, "--keyring-backend", nativeKeyring, "--moniker", n.Moniker); err != nil {
+			return nil, err
+		}
+
+		if n.Home != primary {
+			if err := copyDirFiles(path.Join(n.Home, "config", "gentx"), path.Join(primary, "config", "gentx")); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, acc := range chain.Genesis.Accounts {
+		if acc.Address == "" {
+			continue
+		}
+		if bz, err := execInDir(nt.ProjectDir, bin, "genesis", "add-genesis-account", acc.Address, acc.Amount, "--home", primary); err != nil {
+			return nil, fmt.Errorf("error adding genesis account %s: %w: %s", acc.Name, err, string(bz))
+		}
+	}
+
+	if bz, err := execInDir(nt.ProjectDir, bin, "genesis", "collect-gentxs", "--home", primary); err != nil {
+		return nil, fmt.Errorf("error collecting gentxs: %w: %s", err, string(bz))
+	}
+
+	genesisFile := path.Join(primary, "config", "genesis.json")
+	genesis, err := os.ReadFile(genesisFile)
+	if err != nil {
+		return nil, err
+	}
+
+	genesis, err = cosmos.ModifyGenesis(chain.Genesis.Modify)(ibc.ChainConfig{}, genesis)
+	if err != nil {
+		return nil, err
+	}
+
+	peers := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		bz, err := execInDir(nt.ProjectDir, bin, "comet", "show-node-id", "--home", n.Home)
+		if err != nil {
+			return nil, fmt.Errorf("error getting node id for %s: %w: %s", n.Moniker, err, string(bz))
+		}
+		peers = append(peers, fmt.Sprintf("%s@127.0.0.1:%d", strings.TrimSpace(string(bz)), n.Ports.P2P))
+	}
+
+	for i, n := range nodes {
+		if err := os.WriteFile(path.Join(n.Home, "config", "genesis.json"), genesis, 0644); err != nil {
+			return nil, err
+		}
+
+		otherPeers := make([]string, 0, len(peers)-1)
+		for j, p := range peers {
+			if j != i {
+				otherPeers = append(otherPeers, p)
+			}
+		}
+
+		if err := nt.writeNodeConfig(chain, n, otherPeers); err != nil {
+			return nil, err
+		}
+	}
+
+	return nodes, nil
+}
+
+// writeNodeConfig sets the node ports, peers, block time and any config overrides from the chains config.
+func (nt *NativeTestnet) writeNodeConfig(chain localictypes.Chain, n NativeNode, peers []string) error {
+	blockTime := chain.BlockTime
+	if blockTime == "" {
+		blockTime = "2s"
+	}
+
+	overrides := map[string]testutil.Toml{
+		"config/config.toml": {
+			"rpc.laddr":                  fmt.Sprintf("tcp://127.0.0.1:%d", n.Ports.RPC),
+			"rpc.pprof_laddr":            fmt.Sprintf("localhost:%d", n.Ports.PProf),
+			"p2p.laddr":                  fmt.Sprintf("tcp://127.0.0.1:%d", n.Ports.P2P),
+			"p2p.persistent_peers":       strings.Join(peers, ","),
+			"p2p.addr_book_strict":       false,
+			"p2p.allow_duplicate_ip":     true,
+			"consensus.timeout_commit":   blockTime,
+			"consensus.timeout_propose":  blockTime,
+			"proxy_app":                  fmt.Sprintf("tcp://127.0.0.1:%d", n.Ports.RPC+1),
+			"instrumentation.prometheus": false,
+		},
+		"config/app.toml": {
+			"minimum-gas-prices": chain.GasPrices,
+			"api.enable":         true,
+			"api.address":        fmt.Sprintf("tcp://127.0.0.1:%d", n.Ports.API),
+			"grpc.address":       fmt.Sprintf("127.0.0.1:%d", n.Ports.GRPC),
+			"grpc-web.enable":    false,
+		},
+	}
+
+	for _, o := range chain.ConfigFileOverrides {
+		if _, ok := overrides[o.File]; !ok {
+			overrides[o.File] = testutil.Toml{}
+		}
+		for k, v := range o.Paths {
+			overrides[o.File][k] = v
+		}
+	}
+
+	for file, changes := range overrides {
+		if err := modifyTomlFile(path.Join(n.Home, file), changes); err != nil {
+			return fmt.Errorf("error modifying %s for %s: %w", file, n.Moniker, err)
+		}
+	}
+
+	return nil
+}
+
+// startNode starts the validator process in the background, writing output to the node's log file.
+func (nt *NativeTestnet) startNode(bin string, n NativeNode) (int, error) {
+	logFile, err := os.Create(n.LogFile())
+	if err != nil {
+		return 0, err
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(bin, "start", "--home", n.Home)
+	cmd.Dir = nt.ProjectDir
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+
+	if err := cmd.Start(); err != nil {
+		return 0, fmt.Errorf("error starting %s: %w", n.Moniker, err)
+	}
+
+	pid := cmd.Process.Pid
+	nt.Logger.Info("Started node", "moniker", n.Moniker, "pid", pid, "rpc", n.RPCAddress(), "rest", n.RESTAddress())
+
+	return pid, cmd.Process.Release()
+}
+
+func (nt *NativeTestnet) stateFile() string {
+	return path.Join(nt.HomeDir, "native.json")
+}
+
+func (s *NativeState) save(loc string) error {
+	bz, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(loc, bz, 0644)
+}
+
+// ReadNativeState loads the running native testnet information from its home directory.
+func ReadNativeState(homeDir string) (*NativeState, error) {
+	bz, err := os.ReadFile(path.Join(homeDir, "native.json"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", types.ErrTestnetNotRunning, err)
+	}
+
+	var s NativeState
+	if err := json.Unmarshal(bz, &s); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// StopNativeTestnet stops every process of a native testnet and removes the runtime state.
+// The validator homes are kept for debugging until the next start.
+func StopNativeTestnet(homeDir string) error {
+	state, err := ReadNativeState(homeDir)
+	if err != nil {
+		return err
+	}
+
+	for _, n := range state.Nodes {
+		proc, err := os.FindProcess(n.PID)
+		if err != nil {
+			continue
+		}
+
+		if err := proc.Signal(os.Interrupt); err != nil {
+			// already exited, or interrupts are not supported (windows)
+			_ = proc.Kill()
+		}
+	}
+
+	return os.Remove(path.Join(homeDir, "native.json"))
+}
+
+// WaitForNativeBlock polls the node's RPC until the first block is committed.
+func WaitForNativeBlock(rpcAddr string, timeout time.Duration) error {
+	client := http.Client{Timeout: 2 * time.Second}
+	deadline := time.Now().Add(timeout)
+
+	for time.Now().Before(deadline) {
+		res, err := client.Get(rpcAddr + "/status")
+		if err == nil {
+			var status struct {
+				Result struct {
+					SyncInfo struct {
+						LatestBlockHeight string `json:"latest_block_height"`
+					} `json:"sync_info"`
+				} `json:"result"`
+			}
+			err = json.NewDecoder(res.Body).Decode(&status)
+			res.Body.Close()
+
+			if h := status.Result.SyncInfo.LatestBlockHeight; err == nil && h != "" && h != "0" {
+				return nil
+			}
+		}
+
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	return fmt.Errorf("timed out after %s waiting for %s", timeout, rpcAddr)
+}
+
+// modifyTomlFile applies dotted path changes (i.e. rpc.laddr) to a toml file.
+func modifyTomlFile(loc string, changes testutil.Toml) error {
+	bz, err := os.ReadFile(loc)
+	if err != nil {
+		return err
+	}
+
+	var c map[string]any
+	if err := toml.Unmarshal(bz, &c); err != nil {
+		return err
+	}
+
+	flat := make(map[string]any)
+	flattenToml("", changes, flat)
+
+	nested := make(testutil.Toml)
+	for k, v := range flat {
+		setNestedToml(nested, strings.Split(k, "."), v)
+	}
+
+	if err := testutil.RecursiveModifyToml(c, nested); err != nil {
+		return err
+	}
+
+	f, err := os.Create(loc)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return toml.NewEncoder(f).Encode(c)
+}
+
+// flattenToml converts nested tables (i.e. parsed from JSON) into dotted keys.
+func flattenToml(prefix string, value map[string]any, out map[string]any) {
+	for k, v := range value {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+
+		switch child := v.(type) {
+		case map[string]any:
+			flattenToml(key, child, out)
+		case testutil.Toml:
+			flattenToml(key, child, out)
+		default:
+			out[key] = v
+		}
+	}
+}
+
+// setNestedToml converts a dotted key into nested tables. Keys already nested are merged.
+func setNestedToml(t testutil.Toml, keys []string, value any) {
+	if len(keys) == 1 {
+		t[keys[0]] = value
+		return
+	}
+
+	child, ok := t[keys[0]].(testutil.Toml)
+	if !ok {
+		child = make(testutil.Toml)
+		t[keys[0]] = child
+	}
+
+	setNestedToml(child, keys[1:], value)
+}
+
+func isPortAvailable(port int) bool {
+	l, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		return false
+	}
+
+	return l.Close() == nil
+}
+
+func execInDir(dir, command string, args ...string) ([]byte, error) {
+	cmd := exec.Command(command, args...)
+	cmd.Dir = dir
+	return cmd.CombinedOutput()
+}
+
+func copyDirFiles(src, dst string) error {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+
+		bz, err := os.ReadFile(filepath.Join(src, e.Name()))
+		if err != nil {
+			return err
+		}
+
+		if err := os.WriteFile(filepath.Join(dst, e.Name()), bz, 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lastJSONLine returns the final line of output, some binaries print warnings before the JSON.
+func lastJSONLine(bz []byte) []byte {
+	lines := strings.Split(strings.TrimSpace(string(bz)), "\n")
+	return []byte(lines[len(lines)-1])
+}
@@ -0,0 +1,91 @@
+package spawn
+
+import (
+	"log/slog"
+	"os"
+	"path"
+	"testing"
+
+	localictypes "github.com/strangelove-ventures/interchaintest/local-interchain/interchain/types"
+	"github.com/strangelove-ventures/interchaintest/v8/ibc"
+	"github.com/strangelove-ventures/interchaintest/v8/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rollchains/spawn/spawn/types"
+)
+
+func TestNativePortsDoNotCollide(t *testing.T) {
+	seen := make(map[int]bool)
+
+	for chainIdx := 0; chainIdx < 3; chainIdx++ {
+		for valIdx := 0; valIdx < NativeMaxValidators; valIdx++ {
+			for _, p := range NewNativePorts(chainIdx, valIdx).all() {
+				require.False(t, seen[p], "port %d used twice", p)
+				seen[p] = true
+			}
+		}
+	}
+
+	require.Equal(t, 26657, NewNativePorts(0, 0).RPC)
+}
+
+func TestNativeTestnetValidate(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(path.Join(dir, "cmd", "appd"), 0755))
+
+	chain := func(chainID string, vals int) *localictypes.Chain {
+		return localictypes.NewChainBuilder("mychain", chainID, "appd", "utoken", "cosmos").
+			SetDockerImage(ibc.NewDockerImage("mychain", "local", "")).
+			SetValidators(vals)
+	}
+
+	consumer := chain("localchain-2", 1)
+	consumer.SetICSConsumerLink("localchain-1")
+
+	otherBinary := chain("localchain-1", 1)
+	otherBinary.Binary = "otherd"
+
+	testCases := []struct {
+		name string
+		cfg  localictypes.ChainsConfig
+		err  error
+	}{
+		{name: "valid", cfg: localictypes.NewChainsConfig(chain("localchain-1", 2))},
+		{name: "too many validators", cfg: localictypes.NewChainsConfig(chain("localchain-1", NativeMaxValidators+1)), err: types.ErrNativeUnsupportedChain},
+		{name: "ics consumer", cfg: localictypes.NewChainsConfig(chain("localchain-1", 1), consumer), err: types.ErrNativeUnsupportedChain},
+		{name: "binary not in project", cfg: localictypes.NewChainsConfig(otherBinary), err: types.ErrNativeUnsupportedChain},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			nt := NewNativeTestnet(slog.Default(), dir, "testnet.json", &tc.cfg)
+			require.Equal(t, path.Join(dir, NativeTestnetDir, "testnet"), nt.HomeDir)
+
+			err := nt.Validate()
+			if tc.err == nil {
+				require.NoError(t, err)
+				return
+			}
+
+			require.ErrorIs(t, err, tc.err)
+		})
+	}
+}
+
+func TestModifyTomlFile(t *testing.T) {
+	loc := path.Join(t.TempDir(), "config.toml")
+	require.NoError(t, os.WriteFile(loc, []byte("moniker = \"node\"\n\n[rpc]\nladdr = \"tcp://127.0.0.1:26657\"\ncors_allowed_origins = []\n"), 0644))
+
+	require.NoError(t, modifyTomlFile(loc, testutil.Toml{
+		"rpc.laddr": "tcp://0.0.0.0:26667",
+		"consensus": map[string]any{"timeout_commit": "1s"},
+	}))
+
+	bz, err := os.ReadFile(loc)
+	require.NoError(t, err)
+	require.Contains(t, string(bz), `laddr = "tcp://0.0.0.0:26667"`)
+	require.Contains(t, string(bz), `timeout_commit = "1s"`)
+	require.Contains(t, string(bz), `moniker = "node"`)
+}
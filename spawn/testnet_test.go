@@ -0,0 +1,126 @@
+package spawn_test
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	localictypes "github.com/strangelove-ventures/interchaintest/local-interchain/interchain/types"
+	"github.com/strangelove-ventures/interchaintest/v8/ibc"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rollchains/spawn/spawn"
+	"github.com/rollchains/spawn/spawn/types"
+)
+
+func testnetChain(chainID string) *localictypes.Chain {
+	return localictypes.NewChainBuilder("mychain", chainID, "appd", "utoken", "cosmos").
+		SetDockerImage(ibc.NewDockerImage("mychain", "local", ""))
+}
+
+func TestValidateTestnetConfig(t *testing.T) {
+	type tcase struct {
+		name string
+		cfg  localictypes.ChainsConfig
+		err  error
+	}
+
+	linkedA := testnetChain("localchain-1")
+	linkedB := testnetChain("localchain-2")
+	linkedA.SetAppendedIBCPathLink(linkedB)
+
+	unlinked := testnetChain("localchain-1")
+	unlinked.SetIBCPaths([]string{"localchain-1_localchain-2"})
+
+	consumer := testnetChain("localchain-1")
+	consumer.SetICSConsumerLink("localcosmos-1")
+
+	noBinary := testnetChain("localchain-1")
+	noBinary.Binary = ""
+
+	noImage := testnetChain("localchain-1")
+	noImage.DockerImage.Repository = ""
+
+	testCases := []tcase{
+		{
+			name: "standalone",
+			cfg:  localictypes.NewChainsConfig(testnetChain("localchain-1")),
+		},
+		{
+			name: "ibc pair",
+			cfg:  localictypes.NewChainsConfig(linkedA, linkedB),
+		},
+		{
+			name: "no chains",
+			cfg:  localictypes.NewChainsConfig(),
+			err:  types.ErrTestnetNoChains,
+		},
+		{
+			name: "duplicate chain id",
+			cfg:  localictypes.NewChainsConfig(testnetChain("localchain-1"), testnetChain("localchain-1")),
+			err:  types.ErrTestnetInvalidChain,
+		},
+		{
+			name: "ibc path with 1 chain",
+			cfg:  localictypes.NewChainsConfig(unlinked),
+			err:  types.ErrTestnetInvalidChain,
+		},
+		{
+			name: "ics provider missing",
+			cfg:  localictypes.NewChainsConfig(consumer),
+			err:  types.ErrTestnetInvalidChain,
+		},
+		{
+			name: "missing binary",
+			cfg:  localictypes.NewChainsConfig(noBinary),
+			err:  types.ErrTestnetInvalidChain,
+		},
+		{
+			name: "missing docker image",
+			cfg:  localictypes.NewChainsConfig(noImage),
+			err:  types.ErrTestnetInvalidChain,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			err := spawn.ValidateTestnetConfig(&tc.cfg)
+			if tc.err == nil {
+				require.NoError(t, err)
+				return
+			}
+
+			require.ErrorIs(t, err, tc.err)
+		})
+	}
+}
+
+func TestTestnetConfigPath(t *testing.T) {
+	dir := t.TempDir()
+
+	require.NoError(t, localictypes.NewChainsConfig(testnetChain("localchain-1")).SaveJSON(path.Join(dir, "chains", "testnet.json")))
+
+	for _, name := range []string{"testnet", "testnet.json", "chains/testnet.json"} {
+		loc, err := spawn.TestnetConfigPath(dir, name)
+		require.NoError(t, err, name)
+		require.Equal(t, path.Join(dir, "chains", "testnet.json"), loc)
+
+		cfg, err := spawn.LoadTestnetConfig(loc)
+		require.NoError(t, err)
+		require.Len(t, spawn.LocalDockerImages(cfg), 1)
+	}
+
+	_, err := spawn.TestnetConfigPath(dir, "missing")
+	require.ErrorIs(t, err, types.ErrTestnetConfigNotFound)
+
+	require.Equal(t, []string{"testnet"}, spawn.AvailableTestnets(dir))
+	require.Equal(t, "testnetic", spawn.TestnetName("chains/testnet.json"))
+	require.Equal(t, "http://127.0.0.1:8080", spawn.LocalICAPIURL(spawn.DefaultLocalICAPIAddress, spawn.DefaultLocalICAPIPort))
+
+	_, err = spawn.ReadTestnetInfo(dir)
+	require.ErrorIs(t, err, types.ErrTestnetNotRunning)
+
+	require.NoError(t, os.RemoveAll(dir))
+}
@@ -11,6 +11,7 @@ import (
 // - Codebase.Consensus: add omitempty
 // - Peers: add omitempty
 // - added: ChainRegistryFormat ChainType string
+// - Versions.Consensus: pointer so it is omitted when empty (the schema requires a type)
 
 type ChainRegistryFormat struct {
 	Schema       string      `json:"$schema"`
@@ -64,19 +65,19 @@ type Genesis struct {
 	GenesisURL string `json:"genesis_url"`
 }
 type Versions struct {
-	Name               string    `json:"name"`
-	Tag                string    `json:"tag"`
-	Height             int       `json:"height"`
-	NextVersionName    string    `json:"next_version_name"`
-	Proposal           int       `json:"proposal,omitempty"`
-	RecommendedVersion string    `json:"recommended_version,omitempty"`
-	CompatibleVersions []string  `json:"compatible_versions,omitempty"`
-	CosmosSdkVersion   string    `json:"cosmos_sdk_version,omitempty"`
-	Consensus          Consensus `json:"consensus,omitempty"`
-	CosmwasmVersion    string    `json:"cosmwasm_version,omitempty"`
-	CosmwasmEnabled    bool      `json:"cosmwasm_enabled,omitempty"`
-	IbcGoVersion       string    `json:"ibc_go_version,omitempty"`
-	IcsEnabled         []string  `json:"ics_enabled,omitempty"`
+	Name               string     `json:"name"`
+	Tag                string     `json:"tag"`
+	Height             int        `json:"height"`
+	NextVersionName    string     `json:"next_version_name"`
+	Proposal           int        `json:"proposal,omitempty"`
+	RecommendedVersion string     `json:"recommended_version,omitempty"`
+	CompatibleVersions []string   `json:"compatible_versions,omitempty"`
+	CosmosSdkVersion   string     `json:"cosmos_sdk_version,omitempty"`
+	Consensus          *Consensus `json:"consensus,omitempty"`
+	CosmwasmVersion    string     `json:"cosmwasm_version,omitempty"`
+	CosmwasmEnabled    bool       `json:"cosmwasm_enabled,omitempty"`
+	IbcGoVersion       string     `json:"ibc_go_version,omitempty"`
+	IcsEnabled         []string   `json:"ics_enabled,omitempty"`
 }
 type Codebase struct {
 	GitRepo            string     `json:"git_repo"`
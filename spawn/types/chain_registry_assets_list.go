@@ -7,6 +7,7 @@ import (
 
 // Update: Images -> ImagesAssetLists
 // - Fix: Assets.Images -> new type ImagesAssetLists
+// - added: Assets.TypeAsset (required by the schema)
 
 type ChainRegistryAssetsList struct {
 	Schema    string   `json:"$schema"`
@@ -29,6 +30,7 @@ type ImagesAssetLists struct {
 type Assets struct {
 	Description string             `json:"description"`
 	DenomUnits  []DenomUnits       `json:"denom_units"`
+	TypeAsset   string             `json:"type_asset"`
 	Base        string             `json:"base"`
 	Name        string             `json:"name"`
 	Display     string             `json:"display"`
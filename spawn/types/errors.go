@@ -14,6 +14,44 @@ var (
 	ErrCfgHomeDirTooShort  = errors.New("home directory is too short")
 	ErrCfgEmptyBech32      = errors.New("bech32 prefix cannot be empty")
 	ErrCfgBech32Alpha      = errors.New("bech32 prefix must only contain alphabetical characters")
+
+	ErrCfgUnknownVersionProfile = errors.New("unknown version profile")
+	ErrCfgUnknownWiring         = errors.New("unknown app wiring")
+	ErrCfgWiringUnsupported     = errors.New("feature is not supported by the app wiring")
+
+	ErrRegistryInvalid = errors.New("chain registry file does not match the schema")
+
+	ErrMetadataUnknownKey   = errors.New("unknown metadata key")
+	ErrMetadataInvalidValue = errors.New("invalid metadata value")
+
+	ErrTestnetConfigNotFound = errors.New("testnet config not found")
+	ErrTestnetNoChains       = errors.New("testnet config has no chains")
+	ErrTestnetInvalidChain   = errors.New("invalid testnet chain config")
+	ErrTestnetNotRunning     = errors.New("testnet is not running")
+	ErrTestnetMissingImage   = errors.New("docker image not found locally")
+
+	ErrNativeUnsupportedChain = errors.New("chain can not be run natively")
+	ErrNativeAlreadyRunning   = errors.New("native testnet is already running, stop it first")
+	ErrNativePortInUse        = errors.New("port already in use")
+
+	ErrStateInvalid = errors.New("invalid module state")
+	ErrStateExists  = errors.New("module state already exists")
+
+	ErrRPCInvalid = errors.New("invalid rpc")
+	ErrRPCExists  = errors.New("rpc already exists")
+
+	ErrModuleTemplateNotFound = errors.New("module template not found")
+	ErrKeeperDepUnknown       = errors.New("unknown keeper dependency")
+	ErrModuleTemplateDir      = errors.New("invalid module template directory")
+
+	ErrUpgradeInvalid = errors.New("invalid upgrade handler")
+	ErrUpgradeExists  = errors.New("upgrade handler already exists")
+
+	ErrAnteInvalid = errors.New("invalid ante handler")
+	ErrAnteExists  = errors.New("ante decorator already exists")
+
+	ErrICTestInvalid = errors.New("invalid module interchaintest")
+	ErrICTestExists  = errors.New("module interchaintest already exists")
 )
 
 func ErrExpectedRange(base error, expected int, actual int) error {
@@ -0,0 +1,489 @@
+package spawn
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/parser"
+	"go/token"
+	gotypes "go/types"
+	"os"
+	"os/exec"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rollchains/spawn/spawn/types"
+)
+
+// UpgradeDiff is the state change of the chain since a git ref, which its next upgrade handler has to apply.
+type UpgradeDiff struct {
+	Ref string
+	// Store keys of NewKVStoreKeys in app/app.go added & deleted since the ref
+	AddedStoreKeys   []StoreKey
+	DeletedStoreKeys []StoreKey
+	// Modules of x/ whose ConsensusVersion was bumped since the ref
+	Migrations []ModuleMigration
+}
+
+// ModuleMigration is a ConsensusVersion bump of a chain module, which needs an in-place store migration per version.
+type ModuleMigration struct {
+	Module   string
+	From, To uint64
+}
+
+// Empty is true when the upgrade has nothing to apply.
+func (d UpgradeDiff) Empty() bool {
+	return len(d.AddedStoreKeys) == 0 && len(d.DeletedStoreKeys) == 0 && len(d.Migrations) == 0
+}
+
+// StoreUpgrades returns the storetypes.StoreUpgrades of the upgrade.
+func (d UpgradeDiff) StoreUpgrades() string {
+	exprs := func(keys []StoreKey) string {
+		s := make([]string, len(keys))
+		for i, k := range keys {
+			s[i] = k.Expr
+		}
+		return strings.Join(s, ", ")
+	}
+	return fmt.Sprintf("storetypes.StoreUpgrades{\n\tAdded:   []string{%s},\n\tDeleted: []string{%s},\n}", exprs(d.AddedStoreKeys), exprs(d.DeletedStoreKeys))
+}
+
+// LatestGitTag returns the latest tag reachable from HEAD, the release the next upgrade starts from.
+func LatestGitTag(cwd string) (string, error) {
+	out, err := gitOutput(cwd, "describe", "--tags", "--abbrev=0")
+	if err != nil {
+		return "", fmt.Errorf("%w: no git tag found, give the ref of the last release: %w", types.ErrUpgradeInvalid, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// DetectUpgrade compares the store keys registered in NewKVStoreKeys of app/app.go and the ConsensusVersion of the
+// x/ modules between the git ref and the working tree of the chain in cwd.
+func DetectUpgrade(cwd, ref string) (UpgradeDiff, error) {
+	diff := UpgradeDiff{Ref: ref}
+
+	appGoLoc := path.Join("app", "app.go")
+	oldSrc, err := gitOutput(cwd, "show", ref+":./"+appGoLoc)
+	if err != nil {
+		return diff, fmt.Errorf("%w: reading %s at %s: %w", types.ErrUpgradeInvalid, appGoLoc, ref, err)
+	}
+	newSrc, err := os.ReadFile(path.Join(cwd, appGoLoc))
+	if err != nil {
+		return diff, err
+	}
+
+	oldKeys, err := appStoreKeys(appGoLoc, oldSrc)
+	if err != nil {
+		return diff, fmt.Errorf("%s at %s: %w", appGoLoc, ref, err)
+	}
+	newKeys, err := appStoreKeys(appGoLoc, newSrc)
+	if err != nil {
+		return diff, err
+	}
+
+	for _, k := range newKeys {
+		if !slices.ContainsFunc(oldKeys, k.sameKey) {
+			diff.AddedStoreKeys = append(diff.AddedStoreKeys, k.StoreKey)
+		}
+	}
+
+	goModName := ReadCurrentGoModuleName(path.Join(cwd, "go.mod"))
+	for _, k := range oldKeys {
+		if slices.ContainsFunc(newKeys, k.sameKey) {
+			continue
+		}
+		diff.DeletedStoreKeys = append(diff.DeletedStoreKeys, deletedStoreKey(cwd, ref, goModName, k))
+	}
+
+	diff.Migrations, err = detectModuleMigrations(cwd, ref)
+	return diff, err
+}
+
+// appStoreKey is a store key of NewKVStoreKeys, identified by the package it is declared in.
+type appStoreKey struct {
+	StoreKey
+	// id is the import path & name of the key, or the literal
+	id string
+	// sel is the name of the key in its package (StoreKey)
+	sel string
+}
+
+func (k appStoreKey) sameKey(other appStoreKey) bool {
+	return k.id == other.id
+}
+
+// appStoreKeys returns the arguments of the NewKVStoreKeys call of app.go.
+func appStoreKeys(fileName string, src []byte) ([]appStoreKey, error) {
+	f, err := parser.ParseFile(token.NewFileSet(), fileName, src, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	imports := make(map[string]string)
+	for _, imp := range f.Imports {
+		importPath, _ := strconv.Unquote(imp.Path.Value)
+		alias := path.Base(importPath)
+		if imp.Name != nil {
+			alias = imp.Name.Name
+		}
+		imports[alias] = importPath
+	}
+
+	var call *ast.CallExpr
+	ast.Inspect(f, func(n ast.Node) bool {
+		if c, ok := n.(*ast.CallExpr); ok && strings.HasSuffix(gotypes.ExprString(c.Fun), "NewKVStoreKeys") {
+			call = c
+		}
+		return call == nil
+	})
+	if call == nil {
+		return nil, fmt.Errorf("%w: NewKVStoreKeys not found", types.ErrUpgradeInvalid)
+	}
+
+	keys := make([]appStoreKey, 0, len(call.Args))
+	for _, arg := range call.Args {
+		k := appStoreKey{StoreKey: StoreKey{Expr: gotypes.ExprString(arg)}}
+		k.id = k.Expr
+
+		if sel, ok := arg.(*ast.SelectorExpr); ok {
+			if pkg, ok := sel.X.(*ast.Ident); ok && imports[pkg.Name] != "" {
+				k.Import = imports[pkg.Name] + ";" + pkg.Name
+				k.id = imports[pkg.Name] + "." + sel.Sel.Name
+				k.sel = sel.Sel.Name
+			}
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// deletedStoreKey returns the key of a deleted store. Packages of the chain removed since the ref can not be imported
+// anymore, so their key is resolved from the ref to its literal.
+func deletedStoreKey(cwd, ref, goModName string, k appStoreKey) StoreKey {
+	importPath := goImportPath(k.Import)
+	if k.sel == "" || goModName == "" || !strings.HasPrefix(importPath, goModName+"/") {
+		return k.StoreKey
+	}
+
+	dir := strings.TrimPrefix(importPath, goModName+"/")
+	if _, err := os.Stat(path.Join(cwd, dir)); err == nil {
+		return k.StoreKey
+	}
+
+	srcs, err := gitPackageSources(cwd, ref, dir)
+	if err != nil {
+		return k.StoreKey
+	}
+	if v, ok := resolveConst(srcs, ast.NewIdent(k.sel)); ok && v.Kind() == constant.String {
+		return StoreKey{Expr: strconv.Quote(constant.StringVal(v))}
+	}
+	return k.StoreKey
+}
+
+// detectModuleMigrations compares the ConsensusVersion of the x/ modules at the ref & in the working tree.
+func detectModuleMigrations(cwd, ref string) ([]ModuleMigration, error) {
+	entries, err := os.ReadDir(path.Join(cwd, "x"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var migrations []ModuleMigration
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+
+		dir := path.Join("x", e.Name())
+		oldSrcs, err := gitPackageSources(cwd, ref, dir)
+		if err != nil || len(oldSrcs) == 0 {
+			// new modules are initialized from their genesis by the upgrade
+			continue
+		}
+		newSrcs, err := packageSources(path.Join(cwd, dir))
+		if err != nil {
+			return nil, err
+		}
+
+		from, okFrom := moduleConsensusVersion(oldSrcs)
+		to, okTo := moduleConsensusVersion(newSrcs)
+		if !okFrom || !okTo {
+			continue
+		}
+		if to < from {
+			return nil, fmt.Errorf("%w: the ConsensusVersion of %s went down from %d to %d", types.ErrUpgradeInvalid, dir, from, to)
+		}
+		if to > from {
+			migrations = append(migrations, ModuleMigration{Module: e.Name(), From: from, To: to})
+		}
+	}
+	return migrations, nil
+}
+
+// moduleConsensusVersion returns the value of the ConsensusVersion method of the module package.
+func moduleConsensusVersion(srcs [][]byte) (uint64, bool) {
+	for _, src := range srcs {
+		f, err := parser.ParseFile(token.NewFileSet(), "", src, 0)
+		if err != nil {
+			continue
+		}
+
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv == nil || fn.Name.Name != "ConsensusVersion" || fn.Body == nil {
+				continue
+			}
+			for _, stmt := range fn.Body.List {
+				ret, ok := stmt.(*ast.ReturnStmt)
+				if !ok || len(ret.Results) != 1 {
+					continue
+				}
+				if v, ok := resolveConst(srcs, ret.Results[0]); ok {
+					if version, exact := constant.Uint64Val(constant.ToInt(v)); exact {
+						return version, true
+					}
+				}
+			}
+		}
+	}
+	return 0, false
+}
+
+// resolveConst evaluates a constant expression with the constants declared in the package sources.
+func resolveConst(srcs [][]byte, expr ast.Expr) (constant.Value, bool) {
+	consts := make(map[string]ast.Expr)
+	for _, src := range srcs {
+		f, err := parser.ParseFile(token.NewFileSet(), "", src, 0)
+		if err != nil {
+			continue
+		}
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.CONST {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				vs := spec.(*ast.ValueSpec)
+				for i, name := range vs.Names {
+					if i < len(vs.Values) {
+						consts[name.Name] = vs.Values[i]
+					}
+				}
+			}
+		}
+	}
+
+	var eval func(e ast.Expr, depth int) (constant.Value, bool)
+	eval = func(e ast.Expr, depth int) (constant.Value, bool) {
+		if depth > 16 {
+			return nil, false
+		}
+
+		switch e := e.(type) {
+		case *ast.BasicLit:
+			v := constant.MakeFromLiteral(e.Value, e.Kind, 0)
+			return v, v.Kind() != constant.Unknown
+		case *ast.ParenExpr:
+			return eval(e.X, depth+1)
+		case *ast.Ident:
+			if value, ok := consts[e.Name]; ok {
+				return eval(value, depth+1)
+			}
+		case *ast.BinaryExpr:
+			x, okX := eval(e.X, depth+1)
+			y, okY := eval(e.Y, depth+1)
+			if okX && okY {
+				v := constant.BinaryOp(x, e.Op, y)
+				return v, v.Kind() != constant.Unknown
+			}
+		}
+		return nil, false
+	}
+	return eval(expr, 0)
+}
+
+// AddModuleMigrations adds the Migrate<N>to<N+1> stubs of the version bump to the Migrator of the module keeper and
+// registers them in the RegisterServices of the module.
+func AddModuleMigrations(cwd string, m ModuleMigration) error {
+	moduleDir := path.Join(cwd, "x", m.Module)
+	keeperDir := path.Join(moduleDir, "keeper")
+
+	loc, err := findGoDecl(keeperDir, "Migrator")
+	if err != nil {
+		loc = path.Join(keeperDir, "migrations.go")
+		if err := os.WriteFile(loc, []byte(migratorSource), 0644); err != nil {
+			return err
+		}
+	}
+
+	src, err := os.ReadFile(loc)
+	if err != nil {
+		return err
+	}
+	f, err := parser.ParseFile(token.NewFileSet(), loc, src, 0)
+	if err != nil {
+		return err
+	}
+
+	var stubs strings.Builder
+	for v := m.From; v < m.To; v++ {
+		name := migrationName(v)
+		if findFunc(f, name) != nil {
+			continue
+		}
+		fmt.Fprintf(&stubs, `
+// %[1]s migrates the x/%[2]s state from the consensus version %[3]d to %[4]d.
+func (m Migrator) %[1]s(ctx sdk.Context) error {
+	// TODO: migrate the state with m.keeper
+	return nil
+}
+`, name, m.Module, v, v+1)
+	}
+	if stubs.Len() > 0 {
+		out, _, err := editSource(src, []sourceEdit{{offset: len(src), text: stubs.String()}})
+		if err != nil {
+			return err
+		}
+		if out, err = addGoImports(loc, out, []string{"github.com/cosmos/cosmos-sdk/types;sdk"}); err != nil {
+			return err
+		}
+		if err := os.WriteFile(loc, out, 0644); err != nil {
+			return err
+		}
+	}
+
+	var registered bool
+	err = editGoFunc(moduleDir, "RegisterServices", func(fset *token.FileSet, f *ast.File, src []byte, fn *ast.FuncDecl) ([]sourceEdit, error) {
+		if fn.Recv == nil || len(fn.Recv.List) == 0 || len(fn.Recv.List[0].Names) == 0 {
+			return nil, fmt.Errorf("%w: RegisterServices has no receiver", types.ErrUpgradeInvalid)
+		}
+		recv := fn.Recv.List[0].Names[0].Name
+		body := string(src[fset.Position(fn.Body.Lbrace).Offset:fset.Position(fn.Body.Rbrace).Offset])
+
+		var text strings.Builder
+		if !strings.Contains(body, "NewMigrator(") {
+			fmt.Fprintf(&text, "\nmigrator := %s.NewMigrator(%s.keeper)\n", importAlias(f, path.Join("x", m.Module, "keeper")), recv)
+		}
+		for v := m.From; v < m.To; v++ {
+			name := migrationName(v)
+			if strings.Contains(body, "."+name+")") {
+				continue
+			}
+			fmt.Fprintf(&text, `if err := cfg.RegisterMigration(%[1]s.ModuleName, %[2]d, migrator.%[3]s); err != nil {
+	panic(fmt.Sprintf("failed to migrate x/%%s from version %[2]d to %[4]d: %%v", %[1]s.ModuleName, err))
+}
+`, importAlias(f, path.Join("x", m.Module, "types")), v, name, v+1)
+		}
+
+		registered = text.Len() > 0
+		return []sourceEdit{lineStartEdit(src, fset.Position(fn.Body.Rbrace).Offset, text.String())}, nil
+	})
+	if err != nil || !registered {
+		return err
+	}
+
+	moduleLoc, err := findGoDecl(moduleDir, "RegisterServices")
+	if err != nil {
+		return err
+	}
+	moduleSrc, err := os.ReadFile(moduleLoc)
+	if err != nil {
+		return err
+	}
+	out, err := addGoImports(moduleLoc, moduleSrc, []string{"fmt"})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(moduleLoc, out, 0644)
+}
+
+const migratorSource = `package keeper
+
+import (
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// Migrator runs the in-place store migrations of the module, registered in RegisterServices.
+type Migrator struct {
+	keeper Keeper
+}
+
+// NewMigrator returns a new Migrator.
+func NewMigrator(keeper Keeper) Migrator {
+	return Migrator{keeper: keeper}
+}
+`
+
+func migrationName(from uint64) string {
+	return fmt.Sprintf("Migrate%dto%d", from, from+1)
+}
+
+// importAlias returns the name the file references the package of the import path ending in dir with.
+func importAlias(f *ast.File, dir string) string {
+	for _, imp := range f.Imports {
+		importPath, _ := strconv.Unquote(imp.Path.Value)
+		if imp.Name != nil && strings.HasSuffix(importPath, "/"+dir) {
+			return imp.Name.Name
+		}
+	}
+	return path.Base(dir)
+}
+
+// packageSources returns the non test Go files of the directory.
+func packageSources(dir string) ([][]byte, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var srcs [][]byte
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".go") || strings.HasSuffix(e.Name(), "_test.go") {
+			continue
+		}
+		src, err := os.ReadFile(path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		srcs = append(srcs, src)
+	}
+	return srcs, nil
+}
+
+// gitPackageSources returns the non test Go files of the directory, relative to cwd, at the git ref.
+func gitPackageSources(cwd, ref, dir string) ([][]byte, error) {
+	out, err := gitOutput(cwd, "ls-tree", "--name-only", ref, dir+"/")
+	if err != nil {
+		return nil, err
+	}
+
+	var srcs [][]byte
+	for _, name := range strings.Fields(string(out)) {
+		if !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		src, err := gitOutput(cwd, "show", ref+":./"+name)
+		if err != nil {
+			return nil, err
+		}
+		srcs = append(srcs, src)
+	}
+	return srcs, nil
+}
+
+// gitOutput runs git in cwd and returns its output, with the git error message on failure.
+func gitOutput(cwd string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = cwd
+
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return nil, fmt.Errorf("git %s: %s", strings.Join(args, " "), strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return out, err
+}
@@ -0,0 +1,120 @@
+package spawn
+
+import (
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rollchains/spawn/spawn/types"
+)
+
+// setupUpgradeRepo commits the upgrade chain with an x/old module in its store keys and tags it v1.0.0.
+func setupUpgradeRepo(t *testing.T) string {
+	t.Helper()
+
+	cwd := setupUpgradeChain(t)
+	require.NoError(t, os.MkdirAll(path.Join(cwd, "x/old/types"), 0755))
+	require.NoError(t, os.WriteFile(path.Join(cwd, "x/old/types/keys.go"), []byte(`package types
+
+const (
+	ModuleName = "old"
+
+	StoreKey = ModuleName + "store"
+)
+`), 0644))
+	editTestAppGo(t, cwd, `oldtypes "github.com/rollchains/spawn/simapp/x/old/types"`, "oldtypes.StoreKey")
+
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "-A"},
+		{"-c", "user.name=test", "-c", "user.email=test@test", "commit", "-q", "-m", "v1"},
+		{"tag", "v1.0.0"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = cwd
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+
+	return cwd
+}
+
+// editTestAppGo adds the import & store key to the NewKVStoreKeys of app.go, or removes them when already there.
+func editTestAppGo(t *testing.T, cwd, goImport, storeKey string) {
+	t.Helper()
+
+	appGo := readTestFile(t, cwd, "app/app.go")
+	if strings.Contains(appGo, storeKey+",") {
+		appGo = strings.Replace(appGo, "\t"+goImport+"\n", "", 1)
+		appGo = strings.Replace(appGo, "\t\t"+storeKey+",\n", "", 1)
+	} else {
+		appGo = strings.Replace(appGo, "import (\n", "import (\n\t"+goImport+"\n", 1)
+		appGo = strings.Replace(appGo, "storetypes.NewKVStoreKeys(\n", "storetypes.NewKVStoreKeys(\n\t\t"+storeKey+",\n", 1)
+	}
+	require.NoError(t, os.WriteFile(path.Join(cwd, "app/app.go"), []byte(appGo), 0644))
+}
+
+func TestDetectUpgrade(t *testing.T) {
+	cwd := setupUpgradeRepo(t)
+
+	ref, err := LatestGitTag(cwd)
+	require.NoError(t, err)
+	require.Equal(t, "v1.0.0", ref)
+
+	diff, err := DetectUpgrade(cwd, ref)
+	require.NoError(t, err)
+	require.True(t, diff.Empty())
+
+	// x/old is removed, x/example added & bumped from 1 to 3
+	require.NoError(t, os.RemoveAll(path.Join(cwd, "x/old")))
+	editTestAppGo(t, cwd, `oldtypes "github.com/rollchains/spawn/simapp/x/old/types"`, "oldtypes.StoreKey")
+	editTestAppGo(t, cwd, `exampletypes "github.com/rollchains/spawn/simapp/x/example/types"`, "exampletypes.StoreKey")
+	moduleGo := readTestFile(t, cwd, "x/example/module.go")
+	moduleGo = strings.Replace(moduleGo, "ConsensusVersion = 1", "ConsensusVersion = 3", 1)
+	require.NoError(t, os.WriteFile(path.Join(cwd, "x/example/module.go"), []byte(moduleGo), 0644))
+
+	diff, err = DetectUpgrade(cwd, ref)
+	require.NoError(t, err)
+	require.Equal(t, []StoreKey{{Expr: "exampletypes.StoreKey", Import: "github.com/rollchains/spawn/simapp/x/example/types;exampletypes"}}, diff.AddedStoreKeys)
+	require.Equal(t, []StoreKey{{Expr: `"oldstore"`}}, diff.DeletedStoreKeys)
+	require.Equal(t, []ModuleMigration{{Module: "example", From: 1, To: 3}}, diff.Migrations)
+	require.Equal(t, "storetypes.StoreUpgrades{\n\tAdded:   []string{exampletypes.StoreKey},\n\tDeleted: []string{\"oldstore\"},\n}", diff.StoreUpgrades())
+
+	require.NoError(t, NewUpgradeHandler(logger, cwd, UpgradeHandler{Name: "v2", AddedStoreKeys: diff.AddedStoreKeys, DeletedStoreKeys: diff.DeletedStoreKeys}))
+	upgrade := readTestFile(t, cwd, "app/upgrades/v2/upgrades.go")
+	require.Contains(t, upgrade, `exampletypes "github.com/rollchains/spawn/simapp/x/example/types"`)
+	require.Contains(t, upgrade, `Deleted: []string{"oldstore"},`)
+
+	_, err = DetectUpgrade(cwd, "v0.0.0")
+	require.ErrorIs(t, err, types.ErrUpgradeInvalid)
+}
+
+func TestAddModuleMigrations(t *testing.T) {
+	cwd := setupExampleModule(t)
+
+	m := ModuleMigration{Module: "example", From: 1, To: 3}
+	require.NoError(t, AddModuleMigrations(cwd, m))
+
+	migrations := readTestFile(t, cwd, "x/example/keeper/migrations.go")
+	require.Contains(t, migrations, "func NewMigrator(keeper Keeper) Migrator {")
+	require.Contains(t, migrations, "func (m Migrator) Migrate1to2(ctx sdk.Context) error {")
+	require.Contains(t, migrations, "func (m Migrator) Migrate2to3(ctx sdk.Context) error {")
+
+	moduleGo := readTestFile(t, cwd, "x/example/module.go")
+	require.Contains(t, moduleGo, "migrator := keeper.NewMigrator(a.keeper)")
+	require.Contains(t, moduleGo, "cfg.RegisterMigration(types.ModuleName, 1, migrator.Migrate1to2)")
+	require.Contains(t, moduleGo, "cfg.RegisterMigration(types.ModuleName, 2, migrator.Migrate2to3)")
+	require.Contains(t, moduleGo, `"fmt"`)
+
+	// the next bump only adds its own migration
+	require.NoError(t, AddModuleMigrations(cwd, ModuleMigration{Module: "example", From: 3, To: 4}))
+	moduleGo = readTestFile(t, cwd, "x/example/module.go")
+	require.Equal(t, 1, strings.Count(moduleGo, "NewMigrator("))
+	require.Equal(t, 1, strings.Count(moduleGo, "migrator.Migrate1to2)"))
+	require.Contains(t, moduleGo, "cfg.RegisterMigration(types.ModuleName, 3, migrator.Migrate3to4)")
+	require.Equal(t, 1, strings.Count(readTestFile(t, cwd, "x/example/keeper/migrations.go"), "func (m Migrator) Migrate1to2("))
+}
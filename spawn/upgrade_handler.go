@@ -0,0 +1,483 @@
+package spawn
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	gotypes "go/types"
+	"log/slog"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rollchains/spawn/spawn/types"
+)
+
+var upgradeNameRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// UpgradeHandler is a new chain upgrade of app/upgrades, see NewUpgradeHandler.
+type UpgradeHandler struct {
+	// Name of the upgrade plan (v2)
+	Name string
+	// Store keys the upgrade adds & deletes
+	AddedStores   []string
+	DeletedStores []string
+	// Store keys of other packages the upgrade adds & deletes, see DetectUpgrade
+	AddedStoreKeys   []StoreKey
+	DeletedStoreKeys []StoreKey
+	// Keepers of the ChainApp the handler uses, added to the upgrades.AppKeepers when missing
+	Keepers []string
+}
+
+// StoreKey is a store key of the app, as registered in NewKVStoreKeys (banktypes.StoreKey or "bank").
+type StoreKey struct {
+	Expr string
+	// Import of the expression package (path;alias), empty for literals
+	Import string
+}
+
+// goImport returns the import spec of the store key package.
+func (k StoreKey) goImport() string {
+	if k.Import == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s %q", goImportAlias(k.Import), goImportPath(k.Import))
+}
+
+// Package returns the Go package of the upgrade, the name with the characters Go does not allow replaced.
+func (u UpgradeHandler) Package() string {
+	pkg := strings.ToLower(strings.NewReplacer(".", "_", "-", "_").Replace(u.Name))
+	if pkg != "" && pkg[0] >= '0' && pkg[0] <= '9' {
+		pkg = "v" + pkg
+	}
+	return pkg
+}
+
+// Validate checks the upgrade can be generated.
+func (u UpgradeHandler) Validate() error {
+	if !upgradeNameRegex.MatchString(u.Name) {
+		return fmt.Errorf("%w: name %q must only contain letters, digits, '.', '-' & '_'", types.ErrUpgradeInvalid, u.Name)
+	}
+
+	for _, store := range append(slices.Clone(u.AddedStores), u.DeletedStores...) {
+		if !stateFieldNameRegex.MatchString(store) {
+			return fmt.Errorf("%w: store %q must be a lowercase store key", types.ErrUpgradeInvalid, store)
+		}
+	}
+	for _, store := range u.AddedStores {
+		if slices.Contains(u.DeletedStores, store) {
+			return fmt.Errorf("%w: store %s is both added & deleted", types.ErrUpgradeInvalid, store)
+		}
+	}
+
+	return nil
+}
+
+// NewUpgradeHandler creates the app/upgrades/<name> package of a chain upgrade with its store upgrades, a handler
+// running the module migrations & a test applying it to an in-memory app. The upgrade is registered in the Upgrades
+// of app/upgrades.go and the keepers it needs are added to the upgrades.AppKeepers.
+func NewUpgradeHandler(logger *slog.Logger, cwd string, u UpgradeHandler) error {
+	if err := u.Validate(); err != nil {
+		return err
+	}
+
+	appDir := path.Join(cwd, "app")
+	upgradeDir := path.Join(appDir, "upgrades", u.Package())
+	if _, err := os.Stat(upgradeDir); err == nil {
+		return fmt.Errorf("%w: %s", types.ErrUpgradeExists, upgradeDir)
+	}
+
+	goModName := ReadCurrentGoModuleName(path.Join(cwd, "go.mod"))
+	if goModName == "" {
+		return fmt.Errorf("go.mod not found in %s", cwd)
+	}
+
+	keepers, err := readAppKeepers(path.Join(appDir, "app.go"), u.Keepers)
+	if err != nil {
+		return err
+	}
+	if err := addUpgradeAppKeepers(appDir, keepers); err != nil {
+		return err
+	}
+
+	cfg, err := LoadStubGenConfig(cwd)
+	if err != nil {
+		return err
+	}
+
+	// new stores of a module in the chain use its StoreKey
+	addedKeys := make([]StoreKey, 0, len(u.AddedStores)+len(u.AddedStoreKeys))
+	for _, store := range u.AddedStores {
+		typesDir := path.Join(cfg.ModuleDir(cwd, store), "types")
+		if !declaresValue(typesDir, "StoreKey") {
+			addedKeys = append(addedKeys, StoreKey{Expr: strconv.Quote(store)})
+			continue
+		}
+
+		alias := store + "types"
+		importPath := goModName + "/" + strings.TrimPrefix(strings.TrimPrefix(typesDir, cwd), "/")
+		addedKeys = append(addedKeys, StoreKey{Expr: alias + ".StoreKey", Import: importPath + ";" + alias})
+	}
+	addedKeys = append(addedKeys, u.AddedStoreKeys...)
+
+	deletedKeys := make([]StoreKey, 0, len(u.DeletedStores)+len(u.DeletedStoreKeys))
+	for _, store := range u.DeletedStores {
+		deletedKeys = append(deletedKeys, StoreKey{Expr: strconv.Quote(store)})
+	}
+	deletedKeys = append(deletedKeys, u.DeletedStoreKeys...)
+
+	var added, deleted, imports []string
+	for _, k := range addedKeys {
+		added = append(added, k.Expr)
+		if imp := k.goImport(); imp != "" && !slices.Contains(imports, imp) {
+			imports = append(imports, imp)
+		}
+	}
+	for _, k := range deletedKeys {
+		deleted = append(deleted, k.Expr)
+		if imp := k.goImport(); imp != "" && !slices.Contains(imports, imp) {
+			imports = append(imports, imp)
+		}
+	}
+
+	keeperNames := make([]string, len(keepers))
+	for i, k := range keepers {
+		keeperNames[i] = "ak." + k.Name
+	}
+
+	files := map[string]string{
+		"upgrades.go":      u.upgradeSource(goModName, added, deleted, imports, keeperNames),
+		"upgrades_test.go": u.testSource(goModName),
+	}
+	if err := os.MkdirAll(upgradeDir, 0755); err != nil {
+		return err
+	}
+	for name, src := range files {
+		formatted, err := format.Source([]byte(src))
+		if err != nil {
+			return fmt.Errorf("formatting %s: %w", name, err)
+		}
+		if err := os.WriteFile(path.Join(upgradeDir, name), formatted, 0644); err != nil {
+			return err
+		}
+		logger.Debug("upgrade file", "path", path.Join(upgradeDir, name))
+	}
+
+	return registerUpgrade(appDir, goModName, u.Package())
+}
+
+func (u UpgradeHandler) upgradeSource(goModName string, added, deleted, imports, keepers []string) string {
+	migrations := "// migrate the state of the modules here, before or after their migrations"
+	if len(keepers) > 0 {
+		migrations += ", with " + strings.Join(keepers, ", ")
+	}
+
+	return fmt.Sprintf(`package %[1]s
+
+import (
+	"context"
+
+	storetypes "cosmossdk.io/store/types"
+	upgradetypes "cosmossdk.io/x/upgrade/types"
+
+	"github.com/cosmos/cosmos-sdk/types/module"
+
+	"%[2]s/app/upgrades"
+	%[3]s
+)
+
+// UpgradeName is the name of the upgrade plan, the handler runs when the chain reaches its height.
+const UpgradeName = %[4]q
+
+// Upgrade is the %[5]s upgrade, registered in app/upgrades.go.
+var Upgrade = upgrades.Upgrade{
+	UpgradeName:          UpgradeName,
+	CreateUpgradeHandler: CreateUpgradeHandler,
+	StoreUpgrades: storetypes.StoreUpgrades{
+		Added:   []string{%[6]s},
+		Deleted: []string{%[7]s},
+	},
+}
+
+// CreateUpgradeHandler runs the module migrations of the upgrade.
+func CreateUpgradeHandler(
+	mm upgrades.ModuleManager,
+	configurator module.Configurator,
+	ak *upgrades.AppKeepers,
+) upgradetypes.UpgradeHandler {
+	return func(ctx context.Context, plan upgradetypes.Plan, fromVM module.VersionMap) (module.VersionMap, error) {
+		%[8]s
+		return mm.RunMigrations(ctx, configurator, fromVM)
+	}
+}
+`, u.Package(), goModName, strings.Join(imports, "\n\t"), u.Name, u.Name, strings.Join(added, ", "), strings.Join(deleted, ", "), migrations)
+}
+
+func (u UpgradeHandler) testSource(goModName string) string {
+	return fmt.Sprintf(`package %[1]s_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	cmtproto "github.com/cometbft/cometbft/proto/tendermint/types"
+
+	upgradetypes "cosmossdk.io/x/upgrade/types"
+
+	"%[2]s/app"
+	%[1]s "%[2]s/app/upgrades/%[1]s"
+)
+
+func TestUpgrade(t *testing.T) {
+	chainApp := app.Setup(t)
+	ctx := chainApp.NewContextLegacy(false, cmtproto.Header{Height: chainApp.LastBlockHeight() + 1})
+
+	require.True(t, chainApp.UpgradeKeeper.HasHandler(%[1]s.UpgradeName), "the upgrade must be registered in app/upgrades.go")
+
+	plan := upgradetypes.Plan{Name: %[1]s.UpgradeName, Height: ctx.BlockHeight()}
+	require.NoError(t, chainApp.UpgradeKeeper.ApplyUpgrade(ctx, plan))
+
+	name, _, err := chainApp.UpgradeKeeper.GetLastCompletedUpgrade(ctx)
+	require.NoError(t, err)
+	require.Equal(t, %[1]s.UpgradeName, name)
+}
+`, u.Package(), goModName)
+}
+
+// appKeeper is a keeper field of the ChainApp.
+type appKeeper struct {
+	Name string
+	// Type is the AppKeepers field type, a pointer to the keeper
+	Type string
+	// Import of the keeper package (path;alias)
+	Import string
+	// Value is the keeper of the ChainApp (&app.BankKeeper)
+	Value string
+}
+
+// readAppKeepers returns the keeper fields of the ChainApp in app.go by name. Names are case insensitive & the
+// Keeper suffix is optional (bank, BankKeeper).
+func readAppKeepers(appGoLoc string, names []string) ([]appKeeper, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	f, err := parser.ParseFile(token.NewFileSet(), appGoLoc, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	imports := make(map[string]string)
+	for _, imp := range f.Imports {
+		importPath, _ := strconv.Unquote(imp.Path.Value)
+		alias := path.Base(importPath)
+		if imp.Name != nil {
+			alias = imp.Name.Name
+		}
+		imports[alias] = importPath
+	}
+
+	var chainApp *ast.StructType
+	ast.Inspect(f, func(n ast.Node) bool {
+		if ts, ok := n.(*ast.TypeSpec); ok && ts.Name.Name == "ChainApp" {
+			chainApp, _ = ts.Type.(*ast.StructType)
+		}
+		return chainApp == nil
+	})
+	if chainApp == nil {
+		return nil, fmt.Errorf("%s: ChainApp not found", appGoLoc)
+	}
+
+	keepers := make([]appKeeper, 0, len(names))
+	for _, name := range names {
+		fieldName := strings.TrimSpace(name)
+		if !strings.HasSuffix(strings.ToLower(fieldName), "keeper") {
+			fieldName += "Keeper"
+		}
+
+		var found *ast.Field
+		for _, field := range chainApp.Fields.List {
+			if len(field.Names) > 0 && strings.EqualFold(field.Names[0].Name, fieldName) {
+				found, fieldName = field, field.Names[0].Name
+			}
+		}
+		if found == nil {
+			return nil, fmt.Errorf("%w: the ChainApp has no %s", types.ErrUpgradeInvalid, fieldName)
+		}
+
+		k := appKeeper{Name: fieldName, Type: gotypes.ExprString(found.Type), Value: "app." + fieldName}
+		if _, ok := found.Type.(*ast.StarExpr); !ok {
+			k.Type, k.Value = "*"+k.Type, "&"+k.Value
+		}
+		if pkg := keeperTypePackage(found.Type); pkg != "" {
+			k.Import = imports[pkg] + ";" + pkg
+		}
+		keepers = append(keepers, k)
+	}
+	return keepers, nil
+}
+
+// keeperTypePackage returns the package of a keeper type (*bankkeeper.BaseKeeper -> bankkeeper).
+func keeperTypePackage(expr ast.Expr) string {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	if sel, ok := expr.(*ast.SelectorExpr); ok {
+		if pkg, ok := sel.X.(*ast.Ident); ok {
+			return pkg.Name
+		}
+	}
+	return ""
+}
+
+// addUpgradeAppKeepers adds the keepers to the upgrades.AppKeepers when missing, and sets them in the AppKeepers of
+// RegisterUpgradeHandlers.
+func addUpgradeAppKeepers(appDir string, keepers []appKeeper) error {
+	if len(keepers) == 0 {
+		return nil
+	}
+
+	typesLoc, err := findGoDecl(path.Join(appDir, "upgrades"), "AppKeepers")
+	if err != nil {
+		return err
+	}
+
+	var missing []appKeeper
+	err = editGoFile(typesLoc, func(fset *token.FileSet, f *ast.File, src []byte) []sourceEdit {
+		var st *ast.StructType
+		ast.Inspect(f, func(n ast.Node) bool {
+			if ts, ok := n.(*ast.TypeSpec); ok && ts.Name.Name == "AppKeepers" {
+				st, _ = ts.Type.(*ast.StructType)
+			}
+			return st == nil
+		})
+		if st == nil {
+			return nil
+		}
+
+		var fields strings.Builder
+		for _, k := range keepers {
+			if !slices.ContainsFunc(st.Fields.List, func(field *ast.Field) bool {
+				return len(field.Names) > 0 && field.Names[0].Name == k.Name
+			}) {
+				missing = append(missing, k)
+				fmt.Fprintf(&fields, "%s %s\n", k.Name, k.Type)
+			}
+		}
+		return []sourceEdit{lineStartEdit(src, fset.Position(st.Fields.Closing).Offset, fields.String())}
+	})
+	if err != nil || len(missing) == 0 {
+		return err
+	}
+
+	var imports []string
+	for _, k := range missing {
+		if k.Import != "" {
+			imports = append(imports, k.Import)
+		}
+	}
+	src, err := os.ReadFile(typesLoc)
+	if err != nil {
+		return err
+	}
+	out, err := addGoImports(typesLoc, src, imports)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(typesLoc, out, 0644); err != nil {
+		return err
+	}
+
+	return editGoFunc(appDir, "RegisterUpgradeHandlers", func(fset *token.FileSet, f *ast.File, src []byte, fn *ast.FuncDecl) ([]sourceEdit, error) {
+		var lit *ast.CompositeLit
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			if l, ok := n.(*ast.CompositeLit); ok && gotypes.ExprString(l.Type) == "upgrades.AppKeepers" {
+				lit = l
+			}
+			return lit == nil
+		})
+		if lit == nil {
+			return nil, fmt.Errorf("RegisterUpgradeHandlers must create the upgrades.AppKeepers{}")
+		}
+
+		edits := make([]sourceEdit, len(missing))
+		for i, k := range missing {
+			edits[i] = setCompositeField(fset, src, lit, k.Name, k.Value)
+		}
+		return edits, nil
+	})
+}
+
+// registerUpgrade adds the upgrade package to the Upgrades of the app.
+func registerUpgrade(appDir, goModName, pkg string) error {
+	loc, err := findGoDecl(appDir, "RegisterUpgradeHandlers")
+	if err != nil {
+		return err
+	}
+
+	found := false
+	err = editGoFile(loc, func(fset *token.FileSet, f *ast.File, src []byte) []sourceEdit {
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.VAR {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				vs := spec.(*ast.ValueSpec)
+				if len(vs.Names) != 1 || vs.Names[0].Name != "Upgrades" || len(vs.Values) != 1 {
+					continue
+				}
+				if lit, ok := vs.Values[0].(*ast.CompositeLit); ok {
+					found = true
+					return []sourceEdit{appendCompositeElt(fset, src, lit, pkg+".Upgrade")}
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("%s: the Upgrades slice was not found", loc)
+	}
+
+	src, err := os.ReadFile(loc)
+	if err != nil {
+		return err
+	}
+	out, err := addGoImports(loc, src, []string{goModName + "/app/upgrades/" + pkg + ";" + pkg})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(loc, out, 0644)
+}
+
+// declaresValue returns true when a non test file of the package directory declares the const or var.
+func declaresValue(dir, name string) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".go") || strings.HasSuffix(e.Name(), "_test.go") {
+			continue
+		}
+
+		f, err := parser.ParseFile(token.NewFileSet(), path.Join(dir, e.Name()), nil, 0)
+		if err != nil {
+			continue
+		}
+		if obj := f.Scope.Lookup(name); obj != nil && (obj.Kind == ast.Con || obj.Kind == ast.Var) {
+			return true
+		}
+	}
+	return false
+}
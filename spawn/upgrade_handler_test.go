@@ -0,0 +1,71 @@
+package spawn
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rollchains/spawn/simapp"
+	"github.com/rollchains/spawn/spawn/types"
+)
+
+// setupUpgradeChain copies the app & go.mod of the simapp next to its example module.
+func setupUpgradeChain(t *testing.T) string {
+	t.Helper()
+
+	cwd := setupExampleModule(t)
+	for _, relPath := range []string{"go.mod", "app/app.go", "app/upgrades.go", "app/upgrades/types.go", "app/upgrades/noop/upgrades.go"} {
+		bz, err := fs.ReadFile(simapp.SimAppFS, relPath)
+		require.NoError(t, err)
+
+		loc := path.Join(cwd, relPath)
+		require.NoError(t, os.MkdirAll(path.Dir(loc), 0755))
+		require.NoError(t, os.WriteFile(loc, bz, 0644))
+	}
+
+	return cwd
+}
+
+func TestUpgradeHandlerValidate(t *testing.T) {
+	require.Equal(t, "v2_1_0", UpgradeHandler{Name: "v2.1.0"}.Package())
+	require.Equal(t, "v2", UpgradeHandler{Name: "2"}.Package())
+
+	require.NoError(t, UpgradeHandler{Name: "v2", AddedStores: []string{"mymod"}, DeletedStores: []string{"old"}}.Validate())
+	require.ErrorIs(t, UpgradeHandler{Name: "v2 beta"}.Validate(), types.ErrUpgradeInvalid)
+	require.ErrorIs(t, UpgradeHandler{Name: "v2", AddedStores: []string{"My-Mod"}}.Validate(), types.ErrUpgradeInvalid)
+	require.ErrorIs(t, UpgradeHandler{Name: "v2", AddedStores: []string{"old"}, DeletedStores: []string{"old"}}.Validate(), types.ErrUpgradeInvalid)
+}
+
+func TestNewUpgradeHandler(t *testing.T) {
+	cwd := setupUpgradeChain(t)
+
+	u := UpgradeHandler{Name: "v2", AddedStores: []string{"example", "newstore"}, DeletedStores: []string{"old"}, Keepers: []string{"bank", "StakingKeeper", "ibc"}}
+	require.NoError(t, NewUpgradeHandler(logger, cwd, u))
+
+	upgrade := readTestFile(t, cwd, "app/upgrades/v2/upgrades.go")
+	require.Contains(t, upgrade, `const UpgradeName = "v2"`)
+	require.Contains(t, upgrade, `exampletypes "github.com/rollchains/spawn/simapp/x/example/types"`)
+	require.Contains(t, upgrade, `Added:   []string{exampletypes.StoreKey, "newstore"},`)
+	require.Contains(t, upgrade, `Deleted: []string{"old"},`)
+	require.Contains(t, readTestFile(t, cwd, "app/upgrades/v2/upgrades_test.go"), "chainApp.UpgradeKeeper.ApplyUpgrade(ctx, plan)")
+
+	upgrades := readTestFile(t, cwd, "app/upgrades.go")
+	require.Contains(t, upgrades, `"github.com/rollchains/spawn/simapp/app/upgrades/v2"`)
+	require.Contains(t, upgrades, "var Upgrades = []upgrades.Upgrade{\n\tv2.Upgrade,\n}")
+	require.Contains(t, upgrades, "BankKeeper:            &app.BankKeeper,")
+	require.Contains(t, upgrades, "StakingKeeper:         app.StakingKeeper,")
+
+	// the IBCKeeper is already an AppKeepers field
+	appKeepers := readTestFile(t, cwd, "app/upgrades/types.go")
+	require.Contains(t, appKeepers, `bankkeeper "github.com/cosmos/cosmos-sdk/x/bank/keeper"`)
+	require.Contains(t, appKeepers, "BankKeeper            *bankkeeper.BaseKeeper")
+	require.Contains(t, appKeepers, "StakingKeeper         *stakingkeeper.Keeper")
+	require.Equal(t, 1, strings.Count(appKeepers, "IBCKeeper "))
+
+	require.ErrorIs(t, NewUpgradeHandler(logger, cwd, u), types.ErrUpgradeExists)
+	require.ErrorIs(t, NewUpgradeHandler(logger, cwd, UpgradeHandler{Name: "v3", Keepers: []string{"oracle"}}), types.ErrUpgradeInvalid)
+}
@@ -0,0 +1,188 @@
+package spawn
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
+
+	"github.com/rollchains/spawn/simapp"
+	"github.com/rollchains/spawn/spawn/types"
+)
+
+const (
+	// DefaultVersionProfile is the stack new chains are generated for when one is not selected.
+	DefaultVersionProfile = "v0.50"
+
+	sdkModule      = "github.com/cosmos/cosmos-sdk"
+	ibcGoModule    = "github.com/cosmos/ibc-go"
+	cometbftModule = "github.com/cometbft/cometbft"
+	wasmdModule    = "github.com/CosmWasm/wasmd"
+)
+
+// VersionProfile is a Cosmos SDK + ibc-go stack new chains can be generated for.
+// Each profile is backed by its own embedded templates, the versions are read from the template go.mod.
+type VersionProfile struct {
+	// Name is the Cosmos SDK minor version (i.e. v0.50)
+	Name    string
+	Aliases []string
+
+	AppFS         embed.FS
+	ICTestFS      embed.FS
+	ProtoModuleFS embed.FS
+	ExtensionFS   embed.FS
+	// ModuleTemplateFS has the files of the module templates, see ModuleTemplates
+	ModuleTemplateFS embed.FS
+	// WiringFS has the app/ files of each non-manual wiring, see Wirings
+	WiringFS embed.FS
+}
+
+// StackVersions are the dependency versions a template is built against.
+type StackVersions struct {
+	CosmosSDK string
+	IBCGo     string
+	CometBFT  string
+	CosmWasm  string
+}
+
+// VersionProfiles are all stacks spawn has templates for. Only Cosmos SDK v0.50 + ibc-go v8 is available,
+// newer stacks (i.e. SDK v0.52 + ibc-go v10) are not generated until their templates are added. Until then
+// the new-chain --version-profile flag is hidden.
+// To support a new stack, embed its templates (like simapp/) and add the profile here. Aliases only name the
+// SDK version, the ibc-go version is the one of the profile templates.
+var VersionProfiles = []VersionProfile{
+	{
+		Name:          "v0.50",
+		Aliases:       []string{"0.50", "v50", "50", "sdk-v0.50"},
+		AppFS:         simapp.SimAppFS,
+		ICTestFS:      simapp.ICTestFS,
+		ProtoModuleFS: simapp.ProtoModuleFS,
+		ExtensionFS:   simapp.ExtensionFS,
+
+		ModuleTemplateFS: simapp.ModuleTemplateFS,
+		WiringFS:         simapp.WiringFS,
+	},
+}
+
+// VersionProfileNames returns the name of every available profile.
+func VersionProfileNames() []string {
+	names := make([]string, len(VersionProfiles))
+	for i, p := range VersionProfiles {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// VersionProfilesHelp lists every profile with the Cosmos SDK & ibc-go versions of its templates.
+func VersionProfilesHelp() string {
+	profiles := make([]string, len(VersionProfiles))
+	for i, p := range VersionProfiles {
+		profiles[i] = p.Name
+		if v, err := p.Versions(); err == nil {
+			profiles[i] = fmt.Sprintf("%s (cosmos-sdk %s, ibc-go %s)", p.Name, v.CosmosSDK, v.IBCGo)
+		}
+	}
+	return strings.Join(profiles, ", ")
+}
+
+// GetVersionProfile returns the profile by name or alias. An empty name returns the default profile.
+func GetVersionProfile(name string) (VersionProfile, error) {
+	if name == "" {
+		name = DefaultVersionProfile
+	}
+
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, p := range VersionProfiles {
+		if p.Name == name {
+			return p, nil
+		}
+
+		for _, alias := range p.Aliases {
+			if alias == name {
+				return p, nil
+			}
+		}
+	}
+
+	return VersionProfile{}, fmt.Errorf("%w: %s (available: %s)", types.ErrCfgUnknownVersionProfile, name, strings.Join(VersionProfileNames(), ", "))
+}
+
+// VersionProfileFromGoMod returns the profile a chain was generated with, based on the Cosmos SDK
+// version in its go.mod. Used when scaffolding into an existing chain.
+func VersionProfileFromGoMod(loc string) (VersionProfile, error) {
+	if !strings.HasSuffix(loc, "go.mod") {
+		loc = path.Join(loc, "go.mod")
+	}
+
+	bz, err := os.ReadFile(loc)
+	if err != nil {
+		return VersionProfile{}, err
+	}
+
+	v, err := parseStackVersions(loc, bz)
+	if err != nil {
+		return VersionProfile{}, err
+	}
+
+	return GetVersionProfile(semver.MajorMinor(v.CosmosSDK))
+}
+
+// Versions returns the dependency versions of the profile's templates.
+func (p VersionProfile) Versions() (StackVersions, error) {
+	bz, err := p.AppFS.ReadFile("go.mod")
+	if err != nil {
+		return StackVersions{}, fmt.Errorf("profile %s has no go.mod: %w", p.Name, err)
+	}
+
+	return parseStackVersions(p.Name+"/go.mod", bz)
+}
+
+// SDKMajorMinor returns the Cosmos SDK version without the v prefix or patch (i.e. 0.50).
+func (v StackVersions) SDKMajorMinor() string {
+	return strings.TrimPrefix(semver.MajorMinor(v.CosmosSDK), "v")
+}
+
+// parseStackVersions reads the stack versions from a go.mod. Replaced modules (i.e. forks)
+// report the replacement version since that is what the chain is built with.
+func parseStackVersions(file string, bz []byte) (StackVersions, error) {
+	mf, err := modfile.ParseLax(file, bz, nil)
+	if err != nil {
+		return StackVersions{}, err
+	}
+
+	versions := make(map[string]string)
+	for _, r := range mf.Require {
+		versions[baseModulePath(r.Mod.Path)] = r.Mod.Version
+	}
+	for _, r := range mf.Replace {
+		if r.New.Version != "" {
+			versions[baseModulePath(r.Old.Path)] = r.New.Version
+		}
+	}
+
+	v := StackVersions{
+		CosmosSDK: versions[sdkModule],
+		IBCGo:     versions[ibcGoModule],
+		CometBFT:  versions[cometbftModule],
+		CosmWasm:  versions[wasmdModule],
+	}
+
+	if v.CosmosSDK == "" {
+		return StackVersions{}, fmt.Errorf("%s does not require %s", file, sdkModule)
+	}
+
+	return v, nil
+}
+
+// baseModulePath removes the major version suffix (i.e. github.com/cosmos/ibc-go/v8 -> github.com/cosmos/ibc-go)
+func baseModulePath(modPath string) string {
+	base, ver := path.Split(modPath)
+	if len(ver) > 1 && ver[0] == 'v' && strings.Trim(ver[1:], "0123456789") == "" {
+		return strings.TrimSuffix(base, "/")
+	}
+	return modPath
+}
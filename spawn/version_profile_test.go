@@ -0,0 +1,74 @@
+package spawn_test
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/mod/semver"
+
+	"github.com/rollchains/spawn/spawn"
+	"github.com/rollchains/spawn/spawn/types"
+)
+
+func TestVersionProfiles(t *testing.T) {
+	for _, name := range spawn.VersionProfileNames() {
+		p, err := spawn.GetVersionProfile(name)
+		require.NoError(t, err)
+
+		v, err := p.Versions()
+		require.NoError(t, err, name)
+		require.Equal(t, name, semver.MajorMinor(v.CosmosSDK), "profile name must match the template cosmos-sdk version")
+		require.True(t, semver.IsValid(v.IBCGo), "ibc-go version %q", v.IBCGo)
+		require.True(t, semver.IsValid(v.CometBFT), "cometbft version %q", v.CometBFT)
+	}
+
+	def, err := spawn.GetVersionProfile("")
+	require.NoError(t, err)
+	require.Equal(t, spawn.DefaultVersionProfile, def.Name)
+
+	_, err = spawn.GetVersionProfile("v0.1")
+	require.ErrorIs(t, err, types.ErrCfgUnknownVersionProfile)
+
+	// ibc-go is not selectable on its own
+	_, err = spawn.GetVersionProfile("ibc-go-v8")
+	require.ErrorIs(t, err, types.ErrCfgUnknownVersionProfile)
+
+	require.Contains(t, spawn.VersionProfilesHelp(), "v0.50 (cosmos-sdk v0.50.")
+}
+
+func TestVersionProfileFromGoMod(t *testing.T) {
+	dir := t.TempDir()
+
+	goMod := `module github.com/myorg/mychain
+
+go 1.22
+
+require (
+	github.com/cosmos/cosmos-sdk v0.50.9
+	github.com/cosmos/ibc-go/v8 v8.4.0
+)
+
+replace github.com/cosmos/cosmos-sdk => github.com/rollchains/cosmos-sdk v0.50.8
+`
+	require.NoError(t, os.WriteFile(path.Join(dir, "go.mod"), []byte(goMod), 0644))
+
+	p, err := spawn.VersionProfileFromGoMod(dir)
+	require.NoError(t, err)
+	require.Equal(t, "v0.50", p.Name)
+
+	unsupported := `module github.com/myorg/mychain
+
+require github.com/cosmos/cosmos-sdk v0.45.16
+`
+	require.NoError(t, os.WriteFile(path.Join(dir, "go.mod"), []byte(unsupported), 0644))
+
+	_, err = spawn.VersionProfileFromGoMod(dir)
+	require.ErrorIs(t, err, types.ErrCfgUnknownVersionProfile)
+}
+
+func TestStackVersions(t *testing.T) {
+	v := spawn.StackVersions{CosmosSDK: "v0.50.8"}
+	require.Equal(t, "0.50", v.SDKMajorMinor())
+}
@@ -0,0 +1,222 @@
+package spawn
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/token"
+	gotypes "go/types"
+	"os"
+	"path"
+	"slices"
+	"strings"
+)
+
+const (
+	// WiringManual constructs the keepers & module manager by hand in app/app.go (default).
+	WiringManual = "manual"
+	// WiringDepinject builds the app with depinject from the module configs of app/app_config.go.
+	WiringDepinject = "depinject"
+
+	// WiringDir is the directory of the version profile WiringFS with the app/ files of each wiring.
+	WiringDir = "templates/wiring"
+)
+
+var (
+	// Wirings are the ways the modules of a new chain can be wired into the app.
+	Wirings = []string{WiringManual, WiringDepinject}
+
+	// DepinjectUnsupportedFeatures have no depinject module, they are disabled for depinject apps.
+	DepinjectUnsupportedFeatures = []string{CosmWasm, WasmLC, TokenFactory, PacketForward, IBCRateLimit}
+
+	appConfigGo = path.Join("app", "app_config.go")
+)
+
+// DepinjectUnsupported returns the DepinjectUnsupportedFeatures which are not in the disabled features.
+func DepinjectUnsupported(disabled []string) []string {
+	var feats []string
+	for _, feat := range DepinjectUnsupportedFeatures {
+		if !slices.ContainsFunc(disabled, func(d string) bool { return AliasName(d) == feat }) {
+			feats = append(feats, feat)
+		}
+	}
+	return feats
+}
+
+// IsDepinjectApp returns true if the chain in the directory is wired with depinject (has an app/app_config.go).
+func IsDepinjectApp(cwd string) bool {
+	_, err := os.Stat(path.Join(cwd, appConfigGo))
+	return err == nil
+}
+
+// AddModuleToAppConfig registers a module of the chain into a depinject app. The module config is added to
+// app_config.go with the block & genesis orders (and module account), app.go gets the keeper from the injector.
+func AddModuleToAppConfig(cwd, module string, moduleAccount, sendRestriction bool) error {
+	goModName := ReadCurrentGoModuleName(path.Join(cwd, "go.mod"))
+	typesName := module + "types"
+	moduleName := typesName + ".ModuleName"
+
+	configLoc := path.Join(cwd, appConfigGo)
+	err := editGoFile(configLoc, func(fset *token.FileSet, f *ast.File, src []byte) []sourceEdit {
+		var edits []sourceEdit
+		for _, name := range []string{"beginBlockers", "endBlockers", "genesisModuleOrder"} {
+			if lit := varCompositeLit(f, name); lit != nil && !hasCompositeElt(lit, moduleName) {
+				edits = append(edits, appendCompositeElt(fset, src, lit, moduleName))
+			}
+		}
+
+		if lit := varCompositeLit(f, "maccPerms"); moduleAccount && lit != nil && !hasCompositeElt(lit, moduleName) {
+			edits = append(edits, appendCompositeElt(fset, src, lit, moduleName+": nil"))
+		}
+
+		if lit := varCompositeLit(f, "moduleConfigs"); lit != nil && !strings.Contains(string(src), moduleName+", Config") {
+			edits = append(edits, appendCompositeElt(fset, src, lit,
+				fmt.Sprintf("{Name: %s, Config: appconfig.WrapAny(&%smodulev1.Module{})}", moduleName, module)))
+		}
+		return edits
+	})
+	if err != nil {
+		return err
+	}
+
+	appDir := path.Join(cwd, "app")
+	if err := addAppGoImports(appDir, "AppConfig", []string{
+		fmt.Sprintf("%s/x/%s/types;%s", goModName, module, typesName),
+		fmt.Sprintf("%s/api/%s/module/v1;%smodulev1", goModName, module, module),
+		fmt.Sprintf("%s/x/%s;_", goModName, module), // registers the module config (depinject.go init)
+	}); err != nil {
+		return err
+	}
+
+	keeperField := goCamelCase(module) + "Keeper"
+	appLoc := path.Join(cwd, appGo)
+	err = editGoFile(appLoc, func(fset *token.FileSet, f *ast.File, src []byte) []sourceEdit {
+		var edits []sourceEdit
+		if st := appStruct(f); st != nil && !hasStructField(st, keeperField) {
+			edits = append(edits, addKeeperField(fset, src, st, fmt.Sprintf("%s %skeeper.Keeper", keeperField, module)))
+		}
+
+		fn := findFunc(f, "NewChainApp")
+		if fn == nil {
+			return edits
+		}
+
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.CallExpr:
+				if gotypes.ExprString(n.Fun) != "depinject.Inject" || len(n.Args) == 0 {
+					return true
+				}
+
+				arg := "&app." + keeperField
+				if slices.ContainsFunc(n.Args, func(e ast.Expr) bool { return gotypes.ExprString(e) == arg }) {
+					return false
+				}
+				edits = append(edits, sourceEdit{offset: fset.Position(n.Args[len(n.Args)-1].End()).Offset, text: ",\n" + arg})
+				return false
+			case *ast.AssignStmt:
+				if !sendRestriction || len(n.Lhs) != 1 || gotypes.ExprString(n.Lhs[0]) != "app.App" {
+					return true
+				}
+
+				restriction := fmt.Sprintf("app.BankKeeper.AppendSendRestriction(app.%s.SendRestriction)", keeperField)
+				if !strings.Contains(string(src), restriction) {
+					edits = append(edits, lineStartEdit(src, fset.Position(n.Pos()).Offset, "\t"+restriction+"\n\n"))
+				}
+				return false
+			}
+			return true
+		})
+		return edits
+	})
+	if err != nil {
+		return err
+	}
+
+	return addAppGoImports(appDir, "NewChainApp", []string{fmt.Sprintf("%s/x/%s/keeper;%skeeper", goModName, module, module)})
+}
+
+// varCompositeLit returns the composite literal a package level variable is set to.
+func varCompositeLit(f *ast.File, name string) *ast.CompositeLit {
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.VAR {
+			continue
+		}
+
+		for _, spec := range gen.Specs {
+			vs := spec.(*ast.ValueSpec)
+			for i, n := range vs.Names {
+				if n.Name != name || i >= len(vs.Values) {
+					continue
+				}
+				if lit, ok := vs.Values[i].(*ast.CompositeLit); ok {
+					return lit
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// hasCompositeElt returns true if the literal has the element (or key of a map literal).
+func hasCompositeElt(lit *ast.CompositeLit, elt string) bool {
+	return slices.ContainsFunc(lit.Elts, func(e ast.Expr) bool {
+		if kv, ok := e.(*ast.KeyValueExpr); ok {
+			e = kv.Key
+		}
+		return gotypes.ExprString(e) == elt
+	})
+}
+
+// appStruct returns the ChainApp struct of app.go.
+func appStruct(f *ast.File) *ast.StructType {
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+
+		for _, spec := range gen.Specs {
+			if ts := spec.(*ast.TypeSpec); ts.Name.Name == "ChainApp" {
+				st, _ := ts.Type.(*ast.StructType)
+				return st
+			}
+		}
+	}
+	return nil
+}
+
+func hasStructField(st *ast.StructType, name string) bool {
+	return slices.ContainsFunc(st.Fields.List, func(field *ast.Field) bool {
+		return slices.ContainsFunc(field.Names, func(n *ast.Ident) bool { return n.Name == name })
+	})
+}
+
+// addKeeperField adds the field after the last keeper of the ChainApp struct, before the scoped keepers.
+func addKeeperField(fset *token.FileSet, src []byte, st *ast.StructType, field string) sourceEdit {
+	var last *ast.Field
+	for _, f := range st.Fields.List {
+		if len(f.Names) == 0 {
+			continue
+		}
+
+		name := f.Names[0].Name
+		if strings.HasPrefix(name, "Scoped") {
+			break
+		}
+		if strings.HasSuffix(name, "Keeper") {
+			last = f
+		}
+	}
+
+	if last == nil {
+		return sourceEdit{offset: fset.Position(st.Fields.Closing).Offset, text: "\n" + field + "\n"}
+	}
+	// after the line comment of the field, if any
+	end := fset.Position(last.End()).Offset
+	if i := bytes.IndexByte(src[end:], '\n'); i >= 0 {
+		end += i
+	}
+	return sourceEdit{offset: end, text: "\n" + field}
+}
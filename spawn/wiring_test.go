@@ -0,0 +1,117 @@
+package spawn
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rollchains/spawn/simapp"
+)
+
+// setupDepinjectChain copies the go.mod of the simapp & the app/ files of the depinject wiring.
+func setupDepinjectChain(t *testing.T) string {
+	t.Helper()
+
+	cwd := t.TempDir()
+	bz, err := fs.ReadFile(simapp.SimAppFS, "go.mod")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path.Join(cwd, "go.mod"), bz, 0644))
+
+	wiringDir := path.Join(WiringDir, WiringDepinject)
+	require.NoError(t, fs.WalkDir(simapp.WiringFS, wiringDir, func(relPath string, d fs.DirEntry, e error) error {
+		if e != nil || d.IsDir() {
+			return e
+		}
+
+		bz, err := fs.ReadFile(simapp.WiringFS, relPath)
+		if err != nil {
+			return err
+		}
+
+		loc := path.Join(cwd, strings.TrimSuffix(strings.TrimPrefix(relPath, wiringDir+"/"), ".tmpl"))
+		if err := os.MkdirAll(path.Dir(loc), 0755); err != nil {
+			return err
+		}
+		return os.WriteFile(loc, bz, 0644)
+	}))
+
+	return cwd
+}
+
+func TestIsDepinjectApp(t *testing.T) {
+	require.True(t, IsDepinjectApp(setupDepinjectChain(t)))
+	require.False(t, IsDepinjectApp(setupAnteChain(t)))
+}
+
+func TestAddModuleToAppConfig(t *testing.T) {
+	cwd := setupDepinjectChain(t)
+
+	require.NoError(t, AddModuleToAppConfig(cwd, "escrow", true, true))
+
+	cfg := readTestFile(t, cwd, "app/app_config.go")
+	require.Contains(t, cfg, `escrowtypes "github.com/rollchains/spawn/simapp/x/escrow/types"`)
+	require.Contains(t, cfg, `escrowmodulev1 "github.com/rollchains/spawn/simapp/api/escrow/module/v1"`)
+	require.Contains(t, cfg, `_ "github.com/rollchains/spawn/simapp/x/escrow"`)
+	require.Contains(t, cfg, "{Name: escrowtypes.ModuleName, Config: appconfig.WrapAny(&escrowmodulev1.Module{})},\n}")
+	require.Regexp(t, `escrowtypes.ModuleName: +nil,\n}`, cfg)
+	require.Equal(t, 3, strings.Count(cfg, "\tescrowtypes.ModuleName,\n}"), "begin, end blockers & genesis order")
+
+	app := readTestFile(t, cwd, "app/app.go")
+	require.Contains(t, app, `escrowkeeper "github.com/rollchains/spawn/simapp/x/escrow/keeper"`)
+	require.Contains(t, app, "POAKeeper    poakeeper.Keeper\n\tEscrowKeeper escrowkeeper.Keeper\n\n\tScopedIBCKeeper")
+	require.Contains(t, app, "&app.POAKeeper,\n\t\t&app.EscrowKeeper,\n\t); err != nil {")
+	require.Contains(t, app, "app.BankKeeper.AppendSendRestriction(app.EscrowKeeper.SendRestriction)\n\n\tapp.App = appBuilder.Build(")
+
+	// adding the module again is a no-op
+	require.NoError(t, AddModuleToAppConfig(cwd, "escrow", true, true))
+	require.Equal(t, cfg, readTestFile(t, cwd, "app/app_config.go"))
+	require.Equal(t, app, readTestFile(t, cwd, "app/app.go"))
+
+	// no module account or send restriction
+	require.NoError(t, AddModuleToAppConfig(cwd, "other", false, false))
+	cfg = readTestFile(t, cwd, "app/app_config.go")
+	require.NotContains(t, cfg, "othertypes.ModuleName: ")
+	require.Contains(t, cfg, "{Name: othertypes.ModuleName, Config: appconfig.WrapAny(&othermodulev1.Module{})},\n}")
+	require.NotContains(t, readTestFile(t, cwd, "app/app.go"), "app.OtherKeeper.SendRestriction")
+}
+
+func TestSetupMainChainAppDepinject(t *testing.T) {
+	cwd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(t.TempDir()))
+	defer os.Chdir(cwd)
+
+	cfg := NewChainConfig{
+		ProjectName:     "dichain",
+		Bech32Prefix:    "cosmos",
+		HomeDir:         ".dichain",
+		BinDaemon:       "dichaind",
+		Denom:           "token",
+		GithubOrg:       "myorg",
+		DisabledModules: []string{InterchainSecurity, POA},
+		Wiring:          WiringDepinject,
+	}
+	require.NoError(t, cfg.Validate())
+	cfg.SetProperFeaturePairs()
+	for _, feat := range DepinjectUnsupportedFeatures {
+		require.False(t, cfg.IsFeatureEnabled(feat), feat)
+	}
+	require.Empty(t, DepinjectUnsupported(cfg.DisabledModules))
+	require.Equal(t, []string{CosmWasm, PacketForward}, DepinjectUnsupported([]string{"wasm-light-client", "tokenfactory", "ibc-ratelimit"}))
+
+	require.NoError(t, cfg.SetupMainChainApp())
+
+	app := readTestFile(t, "dichain", "app/app.go")
+	require.Contains(t, app, "depinject.Inject(")
+	require.Contains(t, app, `appName      = "dichain"`)
+	require.NotContains(t, app, "POAKeeper")
+	require.NotContains(t, app, "wasm")
+
+	appCfg := readTestFile(t, "dichain", "app/app_config.go")
+	require.Contains(t, appCfg, "AppName:       appName,")
+	require.NotContains(t, appCfg, "poa")
+}